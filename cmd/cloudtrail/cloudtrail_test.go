@@ -1,11 +1,29 @@
 package cloudtrail
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
 	ctUtil "github.com/openshift/osdctl/cmd/cloudtrail/pkg"
+	ctAws "github.com/openshift/osdctl/cmd/cloudtrail/pkg/aws"
+	envConfig "github.com/openshift/osdctl/pkg/envConfig"
+	"github.com/openshift/osdctl/pkg/provider/aws/mock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
 )
 
 func TestIgnoreListFilter(t *testing.T) {
@@ -92,6 +110,25 @@ func TestIgnoreListFilter(t *testing.T) {
 
 }
 
+func TestEventFollower(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	event := func(id string, age time.Duration) types.Event {
+		return types.Event{EventId: aws.String(id), EventTime: aws.Time(now.Add(-age))}
+	}
+	follower := newEventFollower([]types.Event{event("event-2", 10*time.Minute), event("event-1", 20*time.Minute)})
+
+	// Already printed events are skipped, late events within the window are returned
+	newEvents := follower.next([]types.Event{event("event-4", time.Minute), event("event-3", 12*time.Minute), event("event-2", 10*time.Minute)}, now.Add(-15*time.Minute))
+	assert.Len(t, newEvents, 2)
+	assert.Equal(t, "event-4", *newEvents[0].EventId)
+	assert.Equal(t, "event-3", *newEvents[1].EventId)
+	assert.Empty(t, follower.next([]types.Event{event("event-4", time.Minute)}, now.Add(-15*time.Minute)))
+
+	// IDs older than the window are forgotten
+	assert.NotContains(t, follower.seen, "event-1")
+	assert.Contains(t, follower.seen, "event-2")
+}
+
 func TestPermissonDeniedFilter(t *testing.T) {
 
 	// Test Case 1 (Ignored)
@@ -187,3 +224,446 @@ func TestPermissonDeniedFilter(t *testing.T) {
 	})
 
 }
+
+func TestEventQuery(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	securityGroupEvent := types.Event{
+		EventId:     aws.String("event-1"),
+		EventName:   aws.String("AuthorizeSecurityGroupIngress"),
+		EventSource: aws.String("ec2.amazonaws.com"),
+		EventTime:   aws.Time(now.Add(-30 * time.Minute)),
+		Username:    aws.String("customer"),
+		Resources:   []types.Resource{{ResourceName: aws.String("sg-0123456789")}},
+		CloudTrailEvent: aws.String(`{"eventVersion": "1.08", "awsRegion": "us-east-2", "eventID": "event-1", "errorCode": "Client.UnauthorizedOperation",
+			"userIdentity": {"arn": "arn:aws:sts::123456789012:assumed-role/Customer-Role/customer"}}`),
+	}
+	roleEvent := types.Event{
+		EventId:         aws.String("event-2"),
+		EventName:       aws.String("DetachRolePolicy"),
+		EventSource:     aws.String("iam.amazonaws.com"),
+		EventTime:       aws.Time(now.Add(-3 * time.Hour)),
+		CloudTrailEvent: aws.String(`{"eventVersion": "1.08", "awsRegion": "us-east-1", "eventID": "event-2"}`),
+	}
+	events := []types.Event{securityGroupEvent, roleEvent}
+
+	tests := []struct {
+		name    string
+		query   ctUtil.EventQuery
+		want    []string
+		wantErr bool
+	}{
+		{name: "time window", query: ctUtil.EventQuery{Since: "1h"}, want: []string{"event-1"}},
+		{name: "absolute time window", query: ctUtil.EventQuery{Since: "2024-01-31T08:00:00Z", Until: "2024-01-31T10:00:00Z"}, want: []string{"event-2"}},
+		{name: "invalid since", query: ctUtil.EventQuery{Since: "1d"}, wantErr: true},
+		{name: "event name", query: ctUtil.EventQuery{Since: "24h", EventNames: []string{".*securitygroup.*"}}, want: []string{"event-1"}},
+		{name: "event name is anchored", query: ctUtil.EventQuery{Since: "24h", EventNames: []string{"Detach"}}, want: []string{}},
+		{name: "event source or'ed", query: ctUtil.EventQuery{Since: "24h", EventSources: []string{"iam.amazonaws.com", "ec2.amazonaws.com"}}, want: []string{"event-1", "event-2"}},
+		{name: "username matches arn", query: ctUtil.EventQuery{Since: "24h", Usernames: []string{".*Customer-Role.*"}}, want: []string{"event-1"}},
+		{name: "resource name", query: ctUtil.EventQuery{Since: "24h", ResourceNames: []string{"sg-0123456789"}}, want: []string{"event-1"}},
+		{name: "region", query: ctUtil.EventQuery{Since: "24h", Regions: []string{"us-east-1"}}, want: []string{"event-2"}},
+		{name: "fields are and'ed", query: ctUtil.EventQuery{Since: "24h", Regions: []string{"us-east-1"}, ErrorCodes: []string{".*Unauthorized.*"}}, want: []string{}},
+		{name: "invalid regex", query: ctUtil.EventQuery{Since: "24h", ErrorCodes: []string{"("}}, wantErr: true},
+		{name: "since after until", query: ctUtil.EventQuery{Since: "1h", Until: "2h"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Complete(now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			filtered, err := ctUtil.ApplyFilters(events, tt.query.Filter())
+			assert.NoError(t, err)
+			got := []string{}
+			for _, event := range filtered {
+				got = append(got, *event.EventId)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPrintFormattedEvents(t *testing.T) {
+	newer := types.Event{
+		EventId:         aws.String("event-2"),
+		EventName:       aws.String("DeleteVpc"),
+		EventTime:       aws.Time(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)),
+		CloudTrailEvent: aws.String(`{"eventVersion": "1.08", "awsRegion": "us-east-2", "eventID": "event-2"}`),
+	}
+	older := types.Event{
+		EventId:   aws.String("event-1"),
+		EventName: aws.String("CreateVpc"),
+		EventTime: aws.Time(time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)),
+	}
+	events := []types.Event{older, newer}
+	ctUtil.SortEvents(events)
+
+	var out bytes.Buffer
+	assert.NoError(t, ctUtil.PrintFormattedEvents(&out, events, ctUtil.OutputJSON, false, false))
+	var records []ctUtil.EventRecord
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &records))
+	assert.Len(t, records, 2)
+	assert.Equal(t, "event-1", records[0].EventID)
+	assert.Equal(t, "https://us-east-2.console.aws.amazon.com/cloudtrailv2/home?region=us-east-2#/events/event-2", records[1].URL)
+
+	out.Reset()
+	assert.NoError(t, ctUtil.PrintFormattedEvents(&out, events, ctUtil.OutputNDJSON, false, false))
+	assert.Equal(t, 2, strings.Count(out.String(), "\n"))
+
+	out.Reset()
+	assert.NoError(t, ctUtil.PrintFormattedEvents(&out, events, ctUtil.OutputCSV, false, false))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[1], "2024-01-31T11:00:00Z,event-1,"))
+
+	out.Reset()
+	assert.NoError(t, ctUtil.PrintFormattedEvents(&out, events, ctUtil.OutputText, false, false))
+	assert.Contains(t, out.String(), "CreateVpc")
+	assert.Less(t, strings.Index(out.String(), "CreateVpc"), strings.Index(out.String(), "DeleteVpc"))
+
+	assert.Error(t, ctUtil.ValidateOutput("yaml"))
+}
+
+func TestSplitWindow(t *testing.T) {
+	cutoff := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	before := cutoff.Add(-24 * time.Hour)
+	after := cutoff.Add(24 * time.Hour)
+
+	trailStart, trailEnd, lookupStart, lookupEnd := splitWindow(after, after.Add(time.Hour), cutoff)
+	assert.True(t, trailStart.IsZero() && trailEnd.IsZero())
+	assert.Equal(t, after, lookupStart)
+	assert.Equal(t, after.Add(time.Hour), lookupEnd)
+
+	trailStart, trailEnd, lookupStart, lookupEnd = splitWindow(before, after, cutoff)
+	assert.Equal(t, before, trailStart)
+	assert.Equal(t, cutoff, trailEnd)
+	assert.Equal(t, cutoff, lookupStart)
+	assert.Equal(t, after, lookupEnd)
+
+	trailStart, trailEnd, lookupStart, _ = splitWindow(before.Add(-time.Hour), before, cutoff)
+	assert.Equal(t, before.Add(-time.Hour), trailStart)
+	assert.Equal(t, before, trailEnd)
+	assert.True(t, lookupStart.IsZero())
+}
+
+func TestLookupRegions(t *testing.T) {
+	lookup := &lookupOptions{Regions: []string{"eu-west-1", "us-east-1", "us-east-2"}}
+	assert.Equal(t, []string{"us-east-2", "us-east-1", "eu-west-1"}, lookup.regions("us-east-2"))
+	assert.Equal(t, []string{"us-east-1"}, (&lookupOptions{}).regions("us-east-1"))
+}
+
+func TestDedupeEvents(t *testing.T) {
+	events := []types.Event{
+		{EventId: aws.String("event-1"), EventName: aws.String("first")},
+		{EventId: aws.String("event-2")},
+		{EventId: aws.String("event-1"), EventName: aws.String("second")},
+		{},
+		{},
+	}
+	deduped := ctUtil.DedupeEvents(events)
+	assert.Len(t, deduped, 4)
+	assert.Equal(t, "first", *deduped[0].EventName)
+}
+
+func TestGetTrailEvents(t *testing.T) {
+	start := time.Date(2023, 6, 1, 23, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 6, 2, 1, 0, 0, 0, time.UTC)
+	logFile := `{"Records": [
+		{"eventVersion": "1.08", "eventID": "event-1", "eventName": "DeleteSecurityGroup", "eventSource": "ec2.amazonaws.com", "eventTime": "2023-06-01T23:30:00Z", "readOnly": false,
+		 "userIdentity": {"arn": "arn:aws:sts::123456789012:assumed-role/Customer-Role/customer"}, "resources": [{"ARN": "arn:aws:ec2:us-east-2:123456789012:security-group/sg-1", "type": "AWS::EC2::SecurityGroup"}]},
+		{"eventVersion": "1.08", "eventID": "event-2", "eventName": "DescribeInstances", "eventSource": "ec2.amazonaws.com", "eventTime": "2023-06-01T23:40:00Z", "readOnly": true},
+		{"eventVersion": "1.08", "eventID": "event-3", "eventName": "RunInstances", "eventSource": "ec2.amazonaws.com", "eventTime": "2023-06-01T22:00:00Z", "readOnly": false}
+	]}`
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(logFile))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	ctrl := gomock.NewController(t)
+	client := mock.NewMockClient(ctrl)
+	client.EXPECT().ListObjects(&s3.ListObjectsInput{
+		Bucket: aws.String("trail"),
+		Prefix: aws.String("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-2/2023/06/01/"),
+	}).Return(&s3.ListObjectsOutput{Contents: []s3types.Object{
+		{Key: aws.String("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-2/2023/06/01/file.json.gz")},
+		{Key: aws.String("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-2/2023/06/01/digest.txt")},
+	}}, nil)
+	client.EXPECT().ListObjects(&s3.ListObjectsInput{
+		Bucket: aws.String("trail"),
+		Prefix: aws.String("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-2/2023/06/02/"),
+	}).Return(&s3.ListObjectsOutput{}, nil)
+	client.EXPECT().GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("trail"),
+		Key:    aws.String("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-2/2023/06/01/file.json.gz"),
+	}).Return(&s3.GetObjectOutput{Body: io.NopCloser(&compressed)}, nil)
+
+	trail := ctAws.TrailBucket{Bucket: "trail", Prefix: "AWSLogs/o-abc123", AccountID: "123456789012"}
+	events, err := ctAws.GetTrailEvents(client, trail, []string{"us-east-2"}, start, end, true)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "event-1", *events[0].EventId)
+	assert.Equal(t, "customer", *events[0].Username)
+	assert.Equal(t, "arn:aws:ec2:us-east-2:123456789012:security-group/sg-1", *events[0].Resources[0].ResourceName)
+
+	record := ctUtil.NewEventRecord(events[0], false)
+	assert.Equal(t, "arn:aws:sts::123456789012:assumed-role/Customer-Role/customer", record.Arn)
+}
+
+func TestEventCache(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().UTC().Truncate(time.Hour).Add(-6 * time.Hour)
+	event := func(id string, offset time.Duration) types.Event {
+		return types.Event{EventId: aws.String(id), EventTime: aws.Time(base.Add(offset))}
+	}
+	fetched := [][2]time.Time{}
+	fetch := func(start time.Time, end time.Time) ([]types.Event, error) {
+		fetched = append(fetched, [2]time.Time{start, end})
+		events := []types.Event{}
+		for _, e := range []types.Event{event("event-3", 150*time.Minute), event("event-2", 90*time.Minute), event("event-1", 30*time.Minute)} {
+			if !e.EventTime.Before(start) && e.EventTime.Before(end) {
+				events = append(events, e)
+			}
+		}
+		return events, nil
+	}
+
+	cache, err := ctAws.NewEventCache(dir, ctAws.DefaultEventCacheMaxAge)
+	assert.NoError(t, err)
+
+	// Adjacent missing buckets are fetched with a single lookup
+	events, err := cache.GetEvents("123456789012", "us-east-1", true, base.Add(time.Hour), base.Add(3*time.Hour), fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]time.Time{{base.Add(time.Hour), base.Add(3 * time.Hour)}}, fetched)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "event-3", *events[0].EventId)
+
+	// Cached buckets are not fetched again, the missing ones are
+	events, err = cache.GetEvents("123456789012", "us-east-1", true, base, base.Add(3*time.Hour), fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]time.Time{{base.Add(time.Hour), base.Add(3 * time.Hour)}, {base, base.Add(time.Hour)}}, fetched)
+	assert.Len(t, events, 3)
+
+	// Other regions have their own buckets
+	_, err = cache.GetEvents("123456789012", "us-east-2", true, base, base.Add(time.Hour), fetch)
+	assert.NoError(t, err)
+	assert.Len(t, fetched, 3)
+
+	// A failed lookup still returns the events newer than its range
+	failing := func(start time.Time, end time.Time) ([]types.Event, error) {
+		return nil, errors.New("throttled")
+	}
+	events, err = cache.GetEvents("123456789012", "us-east-1", true, base.Add(-time.Hour), base.Add(3*time.Hour), failing)
+	assert.EqualError(t, err, "throttled")
+	assert.Len(t, events, 3)
+
+	// Buckets not updated for longer than the max age are evicted
+	bucket := filepath.Join(dir, "123456789012", "us-east-1", "write", base.Format("2006-01-02T15")+".json")
+	assert.FileExists(t, bucket)
+	old := time.Now().Add(-8 * 24 * time.Hour)
+	assert.NoError(t, os.Chtimes(bucket, old, old))
+	_, err = ctAws.NewEventCache(dir, ctAws.DefaultEventCacheMaxAge)
+	assert.NoError(t, err)
+	assert.NoFileExists(t, bucket)
+}
+
+func TestBuildTimeline(t *testing.T) {
+	start := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	events := []types.Event{
+		{
+			EventId:         aws.String("event-2"),
+			EventName:       aws.String("DeleteSecurityGroup"),
+			EventSource:     aws.String("ec2.amazonaws.com"),
+			EventTime:       aws.Time(start.Add(3 * time.Hour)),
+			Username:        aws.String("customer"),
+			CloudTrailEvent: aws.String(`{"eventVersion": "1.08", "awsRegion": "us-east-2", "eventID": "event-2"}`),
+		},
+		{
+			EventId:     aws.String("event-1"),
+			EventName:   aws.String("CreateSecurityGroup"),
+			EventSource: aws.String("ec2.amazonaws.com"),
+			EventTime:   aws.Time(start.Add(time.Hour)),
+			Username:    aws.String("ManagedOpenShift-Installer-Role"),
+		},
+		{
+			EventId:   aws.String("event-0"),
+			EventName: aws.String("AssumeRole"),
+			EventTime: aws.Time(start.Add(30 * time.Minute)),
+		},
+	}
+	serviceLog, _ := slv1.NewLogEntry().ID("sl-1").Summary("Security group modified").Timestamp(start.Add(4 * time.Hour)).InternalOnly(true).Build()
+	oldServiceLog, _ := slv1.NewLogEntry().ID("sl-0").Summary("Old").Timestamp(start.Add(-time.Hour)).Build()
+	reason, _ := cmv1.NewLimitedSupportReason().ID("reason-1").Summary("Cluster is in Limited Support").CreationTimestamp(start.Add(5 * time.Hour)).Build()
+
+	timeline := buildTimeline(events, ".*-Installer-Role", []*slv1.LogEntry{serviceLog, oldServiceLog}, []*cmv1.LimitedSupportReason{reason}, start, end)
+
+	assert.Len(t, timeline, 5)
+	ids := []string{}
+	actors := []string{}
+	for _, entry := range timeline {
+		ids = append(ids, entry.ID)
+		actors = append(actors, entry.Actor)
+	}
+	assert.Equal(t, []string{"event-0", "event-1", "event-2", "sl-1", "reason-1"}, ids)
+	assert.Equal(t, []string{actorUnknown, actorRedHat, actorCustomer, actorRedHat, actorRedHat}, actors)
+	assert.Equal(t, "ec2:DeleteSecurityGroup", timeline[2].Summary)
+	assert.Equal(t, "[internal] Security group modified", timeline[3].Summary)
+	assert.Equal(t, timelineSourceLimitedSupport, timeline[4].Source)
+
+	var out bytes.Buffer
+	assert.NoError(t, printTimeline(&out, timeline, ctUtil.OutputCSV))
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 6)
+}
+
+func TestSuspiciousChangeRules(t *testing.T) {
+	configured := []envConfig.SuspiciousChangeRule{
+		{Name: "route-table-change", Disabled: true},
+		{Name: "security-group-change", Severity: ctUtil.SeverityLow, EventNames: []string{"DeleteSecurityGroup"}},
+		{Name: "load-balancer-deleted", EventNames: []string{"DeleteLoadBalancer"}, RequestPattern: "${INFRA_ID}"},
+	}
+	names := []string{}
+	for _, rule := range ctUtil.SuspiciousChangeRules(configured) {
+		names = append(names, rule.Name)
+	}
+	assert.Equal(t, []string{"installer-resource-deleted", "installer-role-detached", "security-group-change", "load-balancer-deleted"}, names)
+
+	_, err := ctUtil.NewChangeClassifier([]envConfig.SuspiciousChangeRule{{Name: "bad", Severity: "urgent"}}, "", nil)
+	assert.Error(t, err)
+	_, err = ctUtil.NewChangeClassifier([]envConfig.SuspiciousChangeRule{{Name: "bad", EventNames: []string{"("}}}, "", nil)
+	assert.Error(t, err)
+}
+
+func TestFindSuspiciousChanges(t *testing.T) {
+	classifier, err := ctUtil.NewChangeClassifier(ctUtil.DefaultSuspiciousChangeRules, "mycluster-abc12", []string{"i-0123456789"})
+	assert.NoError(t, err)
+
+	event := func(id string, source string, name string, username string, resource string, request string) types.Event {
+		e := types.Event{
+			EventId:         aws.String(id),
+			EventSource:     aws.String(source),
+			EventName:       aws.String(name),
+			EventTime:       aws.Time(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)),
+			Username:        aws.String(username),
+			CloudTrailEvent: aws.String(`{"eventVersion": "1.08", "awsRegion": "us-east-1", "eventID": "` + id + `", "requestParameters": ` + request + `}`),
+		}
+		if resource != "" {
+			e.Resources = []types.Resource{{ResourceName: aws.String(resource)}}
+		}
+		return e
+	}
+	events := []types.Event{
+		event("sg", "ec2.amazonaws.com", "AuthorizeSecurityGroupIngress", "customer", "sg-1", `{}`),
+		event("route", "ec2.amazonaws.com", "ReplaceRoute", "customer", "", `{}`),
+		event("owned-instance", "ec2.amazonaws.com", "TerminateInstances", "customer", "i-0123456789", `{}`),
+		event("other-instance", "ec2.amazonaws.com", "TerminateInstances", "customer", "i-9876543210", `{}`),
+		event("named-resource", "elasticloadbalancing.amazonaws.com", "DeleteLoadBalancer", "customer", "", `{"loadBalancerName": "mycluster-abc12-ext"}`),
+		event("role", "iam.amazonaws.com", "DetachRolePolicy", "customer", "", `{"roleName": "ManagedOpenShift-Installer-Role", "policyArn": "arn:aws:iam::123456789012:policy/foo"}`),
+		event("other-role", "iam.amazonaws.com", "DetachRolePolicy", "customer", "", `{"roleName": "my-app-role"}`),
+		event("sre", "ec2.amazonaws.com", "RevokeSecurityGroupEgress", "ManagedOpenShift-Installer-Role", "", `{}`),
+		event("read", "ec2.amazonaws.com", "DescribeInstances", "customer", "", `{}`),
+		// Resources deleted since, tagged earlier in the time window
+		event("deleted-instance", "ec2.amazonaws.com", "TerminateInstances", "customer", "i-0000000001", `{}`),
+		event("deleted-volume", "ec2.amazonaws.com", "DeleteVolume", "customer", "vol-0000000002", `{}`),
+		event("untagged-volume", "ec2.amazonaws.com", "DeleteVolume", "customer", "vol-0000000003", `{}`),
+		event("tag-volume", "ec2.amazonaws.com", "CreateTags", "ManagedOpenShift-Installer-Role", "",
+			`{"resourcesSet": {"items": [{"resourceId": "vol-0000000002"}]}, "tagSet": {"items": [{"key": "kubernetes.io/cluster/mycluster-abc12", "value": "owned"}]}}`),
+		event("tag-other-volume", "ec2.amazonaws.com", "CreateTags", "customer", "",
+			`{"resourcesSet": {"items": [{"resourceId": "vol-0000000003"}]}, "tagSet": {"items": [{"key": "kubernetes.io/cluster/othercluster-xyz89", "value": "owned"}]}}`),
+	}
+	runInstances := event("run-instance", "ec2.amazonaws.com", "RunInstances", "ManagedOpenShift-Installer-Role", "ami-0123456789",
+		`{"tagSpecificationSet": {"items": [{"resourceType": "instance", "tags": [{"key": "kubernetes.io/cluster/mycluster-abc12", "value": "owned"}]}]}}`)
+	runInstances.CloudTrailEvent = aws.String(strings.TrimSuffix(*runInstances.CloudTrailEvent, "}") + `, "responseElements": {"instancesSet": {"items": [{"instanceId": "i-0000000001"}]}}}`)
+	events = append(events, runInstances)
+
+	findings := findSuspiciousChanges(events, classifier, ".*-Installer-Role")
+	got := map[string]string{}
+	for _, finding := range findings {
+		got[finding.URL[strings.LastIndex(finding.URL, "/")+1:]] = finding.Rule
+		assert.Equal(t, actorCustomer, finding.Actor)
+	}
+	assert.Equal(t, map[string]string{
+		"sg":               "security-group-change",
+		"route":            "route-table-change",
+		"owned-instance":   "installer-resource-deleted",
+		"named-resource":   "installer-resource-deleted",
+		"role":             "installer-role-detached",
+		"deleted-instance": "installer-resource-deleted",
+		"deleted-volume":   "installer-resource-deleted",
+	}, got)
+
+	classifier, err = ctUtil.NewChangeClassifier(ctUtil.DefaultSuspiciousChangeRules, "mycluster-abc12", []string{"i-0123456789"})
+	assert.NoError(t, err)
+	assert.Len(t, findSuspiciousChanges(events, classifier, ""), 8)
+	assert.Equal(t, "sg-0123456789", resourceIDFromArn("arn:aws:ec2:us-east-1:123456789012:security-group/sg-0123456789"))
+	assert.Equal(t, "my-bucket", resourceIDFromArn("arn:aws:s3:::my-bucket"))
+}
+
+func TestAggregateMissingPermissions(t *testing.T) {
+	dir := t.TempDir()
+	credentialsRequest := `apiVersion: cloudcredential.openshift.io/v1
+kind: CredentialsRequest
+metadata:
+  name: openshift-machine-api-aws
+  namespace: openshift-cloud-credential-operator
+spec:
+  secretRef:
+    name: aws-cloud-credentials
+    namespace: openshift-machine-api
+  providerSpec:
+    apiVersion: cloudcredential.openshift.io/v1
+    kind: AWSProviderSpec
+    statementEntries:
+    - effect: Allow
+      action:
+      - ec2:CreateTags
+      - ec2:Describe*
+      resource: "*"
+`
+	ingressRequest := strings.NewReplacer("openshift-machine-api-aws", "openshift-ingress", "aws-cloud-credentials", "cloud-credentials", "namespace: openshift-machine-api", "namespace: openshift-ingress-operator",
+		"ec2:CreateTags", "route53:ChangeResourceRecordSets", "ec2:Describe*", "elasticloadbalancing:DescribeLoadBalancers").Replace(credentialsRequest)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "machine-api.yaml"), []byte(credentialsRequest), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "ingress.yaml"), []byte(ingressRequest), 0600))
+
+	components, err := loadComponentPolicies("", dir)
+	assert.NoError(t, err)
+	assert.Len(t, components, 2)
+
+	machineAPIRole := "arn:aws:iam::123456789012:role/mycluster-openshift-machine-api-aws-cloud-credentials"
+	event := func(minute int, role string, source string, name string) types.Event {
+		return types.Event{
+			EventName:   aws.String(name),
+			EventSource: aws.String(source),
+			EventTime:   aws.Time(time.Date(2024, 1, 31, 12, minute, 0, 0, time.UTC)),
+			CloudTrailEvent: aws.String(`{"eventVersion": "1.08", "errorCode": "Client.UnauthorizedOperation",
+				"userIdentity": {"sessionContext": {"sessionIssuer": {"arn": "` + role + `"}}}}`),
+		}
+	}
+	events := []types.Event{
+		event(2, machineAPIRole, "ec2.amazonaws.com", "DescribeInstances"),
+		event(1, machineAPIRole, "ec2.amazonaws.com", "DescribeInstances"),
+		event(3, machineAPIRole, "route53.amazonaws.com", "ChangeResourceRecordSets"),
+		event(4, machineAPIRole, "s3.amazonaws.com", "DeleteBucket"),
+		event(5, "arn:aws:iam::123456789012:role/customer", "ec2.amazonaws.com", "CreateTags"),
+	}
+
+	missing := aggregateMissingPermissions(events, components)
+	assert.Len(t, missing, 4)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/customer", missing[0].Principal)
+	assert.Equal(t, permissionRequired, missing[0].Status)
+	assert.Equal(t, []string{"openshift-machine-api-aws"}, missing[0].RequiredBy)
+
+	assert.Equal(t, "ec2:DescribeInstances", missing[1].Action)
+	assert.Equal(t, 2, missing[1].Count)
+	assert.Equal(t, permissionRequiredByComponent, missing[1].Status)
+	assert.Equal(t, time.Date(2024, 1, 31, 12, 1, 0, 0, time.UTC), missing[1].FirstSeen)
+	assert.Equal(t, []string{"Client.UnauthorizedOperation"}, missing[1].ErrorCodes)
+
+	assert.Equal(t, "route53:ChangeResourceRecordSets", missing[2].Action)
+	assert.Equal(t, permissionOtherComponent, missing[2].Status)
+	assert.Equal(t, []string{"openshift-ingress"}, missing[2].RequiredBy)
+
+	assert.Equal(t, "s3:DeleteBucket", missing[3].Action)
+	assert.Equal(t, permissionUnexpected, missing[3].Status)
+}
@@ -0,0 +1,169 @@
+package cloudtrail
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	ctUtil "github.com/openshift/osdctl/cmd/cloudtrail/pkg"
+	ctAws "github.com/openshift/osdctl/cmd/cloudtrail/pkg/aws"
+	awsprovider "github.com/openshift/osdctl/pkg/provider/aws"
+	"github.com/spf13/cobra"
+)
+
+// lookupOptions configures where events are fetched from
+type lookupOptions struct {
+	Regions      []string
+	TrailBucket  string
+	TrailPrefix  string
+	TrailProfile string
+	TrailRegion  string
+	NoCache      bool
+
+	// quiet skips the progress messages, eg. for the polls of --follow
+	quiet bool
+}
+
+func (l *lookupOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&l.Regions, "lookup-regions", nil, "Additional AWS regions to fetch events from, the cluster region and "+DefaultRegion+" (global events) are always fetched")
+	cmd.Flags().StringVar(&l.TrailBucket, "trail-bucket", "", "S3 bucket of an (organization) trail to read events older than the 90 days LookupEvents returns")
+	cmd.Flags().StringVar(&l.TrailPrefix, "trail-prefix", "AWSLogs", "Key prefix of the trail log files up to the account ID, eg. AWSLogs/o-abc123 for organization trails")
+	cmd.Flags().StringVar(&l.TrailProfile, "trail-profile", "", "AWS profile with read access to the trail bucket, defaults to the default credential chain")
+	cmd.Flags().StringVar(&l.TrailRegion, "trail-region", DefaultRegion, "AWS region of the trail bucket")
+	cmd.Flags().BoolVar(&l.NoCache, "no-cache", false, "Don't read or update the local cache of looked up events")
+}
+
+// regions returns the deduplicated list of regions to fetch events from
+func (l *lookupOptions) regions(clusterRegion string) []string {
+	regions := []string{clusterRegion}
+	for _, region := range append([]string{DefaultRegion}, l.Regions...) {
+		if region != "" && !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// splitWindow splits the query window at the LookupEvents retention cutoff. The part
+// before the cutoff is read from the trail bucket, the rest with LookupEvents.
+// A zero start time means the part isn't needed.
+func splitWindow(start time.Time, end time.Time, cutoff time.Time) (trailStart, trailEnd, lookupStart, lookupEnd time.Time) {
+	if start.Before(cutoff) {
+		trailStart = start
+		trailEnd = end
+		if cutoff.Before(end) {
+			trailEnd = cutoff
+		}
+	}
+	if end.After(cutoff) {
+		lookupStart = start
+		if start.Before(cutoff) {
+			lookupStart = cutoff
+		}
+		lookupEnd = end
+	}
+	return trailStart, trailEnd, lookupStart, lookupEnd
+}
+
+// fetchEvents fetches the events of the query window from every relevant region concurrently,
+// and from the trail bucket for the part of the window LookupEvents doesn't cover.
+// The events are returned newest first and de-duplicated by event ID.
+// Progress is printed to stderr so structured output can be piped.
+func fetchEvents(cfg aws.Config, accountId string, query *ctUtil.EventQuery, lookup *lookupOptions, writeOnly bool) ([]types.Event, error) {
+	regions := lookup.regions(cfg.Region)
+	cutoff := time.Now().UTC().Add(-ctAws.LookupEventsRetention)
+	trailStart, trailEnd, lookupStart, lookupEnd := splitWindow(query.StartTime, query.EndTime, cutoff)
+	if lookup.TrailBucket == "" && !trailStart.IsZero() {
+		fmt.Fprintf(os.Stderr, "[WARNING] LookupEvents only returns events of the last 90 days, events before %v are missing. Use --trail-bucket to read them from the trail.\n", cutoff)
+	}
+
+	// The trail client is created upfront as it may prompt about the proxy configuration
+	var trailClient awsprovider.Client
+	if lookup.TrailBucket != "" && !trailStart.IsZero() {
+		var err error
+		trailClient, err = awsprovider.NewAwsClient(lookup.TrailProfile, lookup.TrailRegion, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for the trail bucket: %w", err)
+		}
+	}
+
+	// Looked up events are cached so repeated queries only fetch new events
+	var cache *ctAws.EventCache
+	if !lookup.NoCache {
+		var err error
+		cache, err = ctAws.NewEventCache("", ctAws.DefaultEventCacheMaxAge)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] Not using the cloudtrail cache: %v\n", err)
+		}
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		events = []types.Event{}
+		errs   = []error{}
+	)
+	collect := func(fetched []types.Event, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		events = append(events, fetched...)
+	}
+
+	if !lookupStart.IsZero() {
+		for _, region := range regions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if !lookup.quiet {
+					fmt.Fprintf(os.Stderr, "[INFO] Fetching %v Event History...\n", region)
+				}
+				client := cloudtrail.New(cloudtrail.Options{
+					Region:      region,
+					Credentials: cfg.Credentials,
+					HTTPClient:  cfg.HTTPClient,
+				})
+				fetch := func(start time.Time, end time.Time) ([]types.Event, error) {
+					return ctAws.GetEvents(client, start, end, writeOnly)
+				}
+				var fetched []types.Event
+				var err error
+				if cache != nil {
+					fetched, err = cache.GetEvents(accountId, region, writeOnly, lookupStart, lookupEnd, fetch)
+				} else {
+					fetched, err = fetch(lookupStart, lookupEnd)
+				}
+				if err != nil {
+					err = fmt.Errorf("failed to fetch %v events: %w", region, err)
+				}
+				collect(fetched, err)
+			}()
+		}
+	}
+
+	if trailClient != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fmt.Fprintf(os.Stderr, "[INFO] Reading events before %v from s3://%v/%v...\n", trailEnd, lookup.TrailBucket, lookup.TrailPrefix)
+			trail := ctAws.TrailBucket{Bucket: lookup.TrailBucket, Prefix: lookup.TrailPrefix, AccountID: accountId}
+			collect(ctAws.GetTrailEvents(trailClient, trail, regions, trailStart, trailEnd, writeOnly))
+		}()
+	}
+
+	wg.Wait()
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	ctUtil.SortEvents(events)
+	return ctUtil.DedupeEvents(events), nil
+}
@@ -0,0 +1,211 @@
+package cloudtrail
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	ctUtil "github.com/openshift/osdctl/cmd/cloudtrail/pkg"
+	"github.com/openshift/osdctl/pkg/policies"
+	"github.com/openshift/osdctl/pkg/printer"
+)
+
+const (
+	permissionRequired            = "required"
+	permissionRequiredByComponent = "required-by-component"
+	permissionOtherComponent      = "other-component"
+	permissionUnexpected          = "unexpected"
+)
+
+// componentPolicy is the policy an OpenShift component requests through its CredentialsRequest
+type componentPolicy struct {
+	Name string
+	// Role is the <namespace>-<secret name> suffix of the STS operator role of the component
+	Role    string
+	Actions []string
+}
+
+// allows returns true if one of the actions of the policy, which can contain wildcards, matches the action
+func (c componentPolicy) allows(action string) bool {
+	for _, allowed := range c.Actions {
+		if matched, _ := path.Match(strings.ToLower(allowed), strings.ToLower(action)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingPermission aggregates the permission denied events of a principal for an action
+type MissingPermission struct {
+	Principal  string    `json:"principal"`
+	Action     string    `json:"action"`
+	Count      int       `json:"count"`
+	ErrorCodes []string  `json:"errorCodes"`
+	FirstSeen  time.Time `json:"firstSeen"`
+	LastSeen   time.Time `json:"lastSeen"`
+	Status     string    `json:"status"`
+	RequiredBy []string  `json:"requiredBy,omitempty"`
+}
+
+// loadComponentPolicies reads the AWS CredentialsRequests of the release, either from
+// credentialsRequestsDir or extracted from the release payload with oc
+func loadComponentPolicies(version string, credentialsRequestsDir string) ([]componentPolicy, error) {
+	if credentialsRequestsDir == "" {
+		fmt.Fprintf(os.Stderr, "[INFO] Extracting the CredentialsRequests of %s...\n", version)
+		dir, err := policies.DownloadCredentialRequests(version, policies.AWS)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		credentialsRequestsDir = dir
+	}
+
+	credReqs, err := policies.ParseCredentialsRequestsInDir(credentialsRequestsDir)
+	if err != nil {
+		return nil, err
+	}
+	components := []componentPolicy{}
+	for _, credReq := range credReqs {
+		document, err := policies.AWSCredentialsRequestToPolicyDocument(credReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the policy of %s: %w", credReq.Name, err)
+		}
+		component := componentPolicy{
+			Name: credReq.Name,
+			Role: credReq.Spec.SecretRef.Namespace + "-" + credReq.Spec.SecretRef.Name,
+		}
+		for _, statement := range document.Statement {
+			if strings.EqualFold(statement.Effect, "Allow") {
+				component.Actions = append(component.Actions, statement.Action...)
+			}
+		}
+		components = append(components, component)
+	}
+	return components, nil
+}
+
+// eventAction returns the IAM action of the event, eg. ec2:DescribeInstances
+func eventAction(record ctUtil.EventRecord) string {
+	service := strings.TrimSuffix(record.EventSource, ".amazonaws.com")
+	return service + ":" + record.EventName
+}
+
+// aggregateMissingPermissions groups the permission denied events by principal and action,
+// and compares the actions with the policies of the OpenShift components
+func aggregateMissingPermissions(events []types.Event, components []componentPolicy) []MissingPermission {
+	aggregated := map[string]*MissingPermission{}
+	for _, event := range events {
+		record := ctUtil.NewEventRecord(event, false)
+		principal := record.Arn
+		if principal == "" {
+			principal = record.Username
+		}
+		action := eventAction(record)
+		key := principal + "\x00" + action
+
+		missing, ok := aggregated[key]
+		if !ok {
+			missing = &MissingPermission{Principal: principal, Action: action, FirstSeen: record.EventTime, LastSeen: record.EventTime}
+			aggregated[key] = missing
+		}
+		missing.Count++
+		if record.ErrorCode != "" && !slices.Contains(missing.ErrorCodes, record.ErrorCode) {
+			missing.ErrorCodes = append(missing.ErrorCodes, record.ErrorCode)
+		}
+		if record.EventTime.Before(missing.FirstSeen) {
+			missing.FirstSeen = record.EventTime
+		}
+		if record.EventTime.After(missing.LastSeen) {
+			missing.LastSeen = record.EventTime
+		}
+	}
+
+	result := []MissingPermission{}
+	for _, missing := range aggregated {
+		missing.Status, missing.RequiredBy = classifyPermission(missing.Principal, missing.Action, components)
+		result = append(result, *missing)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Principal != result[j].Principal {
+			return result[i].Principal < result[j].Principal
+		}
+		return result[i].Action < result[j].Action
+	})
+	return result
+}
+
+// classifyPermission checks if an OpenShift component needs the action. When the principal is
+// the operator role of a component, it tells if that component or another one needs it.
+func classifyPermission(principal string, action string, components []componentPolicy) (string, []string) {
+	requiredBy := []string{}
+	principalComponent := ""
+	for _, component := range components {
+		if component.allows(action) {
+			requiredBy = append(requiredBy, component.Name)
+		}
+		if component.Role != "-" && strings.Contains(principal, component.Role) {
+			principalComponent = component.Name
+		}
+	}
+
+	switch {
+	case len(requiredBy) == 0:
+		return permissionUnexpected, nil
+	case principalComponent == "":
+		return permissionRequired, requiredBy
+	case slices.Contains(requiredBy, principalComponent):
+		return permissionRequiredByComponent, []string{principalComponent}
+	default:
+		return permissionOtherComponent, requiredBy
+	}
+}
+
+func printMissingPermissions(w io.Writer, missing []MissingPermission, output string) error {
+	switch output {
+	case ctUtil.OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(missing)
+	case ctUtil.OutputNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, m := range missing {
+			if err := encoder.Encode(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ctUtil.OutputCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"principal", "action", "count", "error_codes", "first_seen", "last_seen", "status", "required_by"}); err != nil {
+			return err
+		}
+		for _, m := range missing {
+			if err := writer.Write([]string{m.Principal, m.Action, strconv.Itoa(m.Count), strings.Join(m.ErrorCodes, " "),
+				m.FirstSeen.Format(time.RFC3339), m.LastSeen.Format(time.RFC3339), m.Status, strings.Join(m.RequiredBy, " ")}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	if len(missing) == 0 {
+		fmt.Fprintln(w, "No permission denied events found")
+		return nil
+	}
+	p := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	p.AddRow([]string{"PRINCIPAL", "ACTION", "COUNT", "LAST SEEN", "STATUS", "REQUIRED BY"})
+	for _, m := range missing {
+		p.AddRow([]string{m.Principal, m.Action, strconv.Itoa(m.Count), m.LastSeen.Format(time.RFC3339), m.Status, strings.Join(m.RequiredBy, ", ")})
+	}
+	return p.Flush()
+}
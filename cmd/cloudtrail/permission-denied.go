@@ -1,13 +1,12 @@
 package cloudtrail
 
 import (
-	"context"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
 	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	ctUtil "github.com/openshift/osdctl/cmd/cloudtrail/pkg"
@@ -19,9 +18,15 @@ import (
 
 type permissionDeniedEventsOptions struct {
 	ClusterID string
-	StartTime string
 	PrintUrl  bool
 	PrintRaw  bool
+	Output    string
+	Query     ctUtil.EventQuery
+	Lookup    lookupOptions
+
+	Aggregate              bool
+	ReleaseVersion         string
+	CredentialsRequestsDir string
 }
 
 func newCmdPermissionDenied() *cobra.Command {
@@ -30,20 +35,30 @@ func newCmdPermissionDenied() *cobra.Command {
 	permissionDeniedCmd := &cobra.Command{
 		Use:   "permission-denied-events",
 		Short: "Prints cloudtrail permission-denied events to console.",
+		Example: `  # Permission denied events of the last hour
+  osdctl cloudtrail permission-denied-events -C <cluster-id> --since 1h
+
+  # Missing permissions grouped by principal and action, compared with the policies of the cluster version
+  osdctl cloudtrail permission-denied-events -C <cluster-id> --since 24h --aggregate`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return opts.run()
 		},
 	}
 	permissionDeniedCmd.Flags().StringVarP(&opts.ClusterID, "cluster-id", "C", "", "Cluster ID")
-	permissionDeniedCmd.Flags().StringVarP(&opts.StartTime, "since", "", "5m", "Specifies that only events that occur within the specified time are returned.Defaults to 5m. Valid time units are \"ns\", \"us\" (or \"µs\"), \"ms\", \"s\", \"m\", \"h\".")
 	permissionDeniedCmd.Flags().BoolVarP(&opts.PrintUrl, "url", "u", false, "Generates Url link to cloud console cloudtrail event")
 	permissionDeniedCmd.Flags().BoolVarP(&opts.PrintRaw, "raw-event", "r", false, "Prints the cloudtrail events to the console in raw json format")
+	opts.Query.AddFlags(permissionDeniedCmd, "5m")
+	opts.Lookup.addFlags(permissionDeniedCmd)
+	permissionDeniedCmd.Flags().BoolVar(&opts.Aggregate, "aggregate", false, "Group the events by principal and action, and tell which denied actions are required by an OpenShift component")
+	permissionDeniedCmd.Flags().StringVar(&opts.ReleaseVersion, "release-version", "", "OpenShift version or release image to read the expected policies of, defaults to the cluster version (requires oc)")
+	permissionDeniedCmd.Flags().StringVar(&opts.CredentialsRequestsDir, "credentials-requests-dir", "", "Directory of already extracted AWS CredentialsRequests, see 'osdctl iampermissions get'")
+	ctUtil.AddOutputFlag(permissionDeniedCmd, &opts.Output)
 	permissionDeniedCmd.MarkFlagRequired("cluster-id")
 	return permissionDeniedCmd
 }
 
 func isforbiddenEvent(event types.Event) (bool, error) {
-	permissionDeniedErrorRegexp := ".*Client.UnauthorizedOperation.*"
+	permissionDeniedErrorRegexp := ".*(Client.UnauthorizedOperation|AccessDenied).*"
 
 	check, err := regexp.Compile(permissionDeniedErrorRegexp)
 	if err != nil {
@@ -66,6 +81,12 @@ func (p *permissionDeniedEventsOptions) run() error {
 	if err != nil {
 		return err
 	}
+	if err := ctUtil.ValidateOutput(p.Output); err != nil {
+		return err
+	}
+	if err := p.Query.Complete(time.Now().UTC()); err != nil {
+		return err
+	}
 
 	connection, err := utils.CreateConnection()
 	if err != nil {
@@ -87,19 +108,12 @@ func (p *permissionDeniedEventsOptions) run() error {
 		return err
 	}
 
-	startTime, err := ctUtil.ParseDurationToUTC(p.StartTime)
-	if err != nil {
-		return err
-	}
-
 	arn, accountId, err := ctAws.Whoami(*sts.NewFromConfig(cfg))
 	if err != nil {
 		return err
 	}
-	fmt.Printf("[INFO] Checking Permission Denied History since %v for AWS Account %v as %v \n", startTime, accountId, arn)
-	cloudTrailclient := cloudtrail.NewFromConfig(cfg)
-	fmt.Printf("[INFO] Fetching %v Event History...", cfg.Region)
-	lookupOutput, err := ctAws.GetEvents(cloudTrailclient, startTime, false)
+	fmt.Fprintf(os.Stderr, "[INFO] Checking Permission Denied History between %v and %v for AWS Account %v as %v \n", p.Query.StartTime, p.Query.EndTime, accountId, arn)
+	lookupOutput, err := fetchEvents(cfg, accountId, &p.Query, &p.Lookup, false)
 	if err != nil {
 		return err
 	}
@@ -108,42 +122,27 @@ func (p *permissionDeniedEventsOptions) run() error {
 		func(event types.Event) (bool, error) {
 			return isforbiddenEvent(event)
 		},
+		p.Query.Filter(),
 	)
 	if err != nil {
 		return err
 	}
 
-	ctUtil.PrintEvents(filteredEvents, p.PrintUrl, p.PrintRaw)
-
-	if DefaultRegion != cfg.Region {
-		defaultConfig, err := config.LoadDefaultConfig(
-			context.Background(),
-			config.WithRegion(DefaultRegion))
-		if err != nil {
-			return err
+	if p.Aggregate {
+		version := p.ReleaseVersion
+		if version == "" {
+			version = cluster.Version().RawID()
 		}
-
-		defaultCloudtrailClient := cloudtrail.New(cloudtrail.Options{
-			Region:      DefaultRegion,
-			Credentials: cfg.Credentials,
-			HTTPClient:  cfg.HTTPClient,
-		})
-		fmt.Printf("[INFO] Fetching Cloudtrail Global Permission Denied Event History from %v Region...", defaultConfig.Region)
-		lookupOutput, err := ctAws.GetEvents(defaultCloudtrailClient, startTime, false)
+		components, err := loadComponentPolicies(version, p.CredentialsRequestsDir)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to load the expected policies: %w", err)
 		}
-		filteredEvents, err := ctUtil.ApplyFilters(lookupOutput,
-			func(event types.Event) (bool, error) {
-				return isforbiddenEvent(event)
-			},
-		)
-		if err != nil {
-			return err
+		output := p.Output
+		if output == ctUtil.OutputText {
+			output = ctUtil.OutputTable
 		}
-		ctUtil.PrintEvents(filteredEvents, p.PrintUrl, p.PrintRaw)
+		return printMissingPermissions(os.Stdout, aggregateMissingPermissions(filteredEvents, components), output)
 	}
 
-	return err
-
+	return ctUtil.PrintFormattedEvents(os.Stdout, filteredEvents, p.Output, p.PrintUrl, p.PrintRaw)
 }
@@ -17,6 +17,8 @@ import (
 type RawEventDetails struct {
 	EventVersion string `json:"eventVersion"`
 	UserIdentity struct {
+		Type           string `json:"type"`
+		Arn            string `json:"arn"`
 		AccountId      string `json:"accountId"`
 		SessionContext struct {
 			SessionIssuer struct {
@@ -26,9 +28,15 @@ type RawEventDetails struct {
 			} `json:"sessionIssuer"`
 		} `json:"sessionContext"`
 	} `json:"userIdentity"`
-	EventRegion string `json:"awsRegion"`
-	EventId     string `json:"eventID"`
-	ErrorCode   string `json:"errorCode"`
+	EventRegion  string `json:"awsRegion"`
+	EventId      string `json:"eventID"`
+	EventName    string `json:"eventName"`
+	EventSource  string `json:"eventSource"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+	// RequestParameters is kept raw as its content depends on the event
+	RequestParameters json.RawMessage `json:"requestParameters"`
+	ResponseElements  json.RawMessage `json:"responseElements"`
 }
 
 type QueryOptions struct {
@@ -75,14 +83,17 @@ func Whoami(stsClient sts.Client) (accountArn string, accountId string, err erro
 	return userArn.String(), userArn.AccountID, nil
 }
 
-// getWriteEvents retrieves cloudtrail events since the specified time
-// using the provided cloudtrail client and starttime from since flag.
-func GetEvents(cloudtailClient *cloudtrail.Client, startTime time.Time, writeOnly bool) ([]types.Event, error) {
+// getWriteEvents retrieves cloudtrail events between startTime and endTime
+// using the provided cloudtrail client. A zero endTime means now.
+func GetEvents(cloudtailClient *cloudtrail.Client, startTime time.Time, endTime time.Time, writeOnly bool) ([]types.Event, error) {
 
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
 	alllookupEvents := []types.Event{}
 	input := cloudtrail.LookupEventsInput{
 		StartTime: &startTime,
-		EndTime:   aws.Time(time.Now()),
+		EndTime:   aws.Time(endTime),
 	}
 
 	if writeOnly {
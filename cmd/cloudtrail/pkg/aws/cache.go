@@ -0,0 +1,275 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+const (
+	// DefaultEventCacheMaxAge is how long unused cache buckets are kept
+	DefaultEventCacheMaxAge = 7 * 24 * time.Hour
+
+	// cacheBucketSize is the time range stored per cache file
+	cacheBucketSize = time.Hour
+
+	// deliveryDelay is how long cloudtrail can take to deliver an event, events
+	// more recent than this are fetched again by the next query
+	deliveryDelay = 15 * time.Minute
+)
+
+// FetchFunc looks up the events between start and end
+type FetchFunc func(start time.Time, end time.Time) ([]types.Event, error)
+
+// EventCache stores looked up events on disk in one file per account, region and hour,
+// so repeated queries only fetch the events which aren't cached yet
+type EventCache struct {
+	dir string
+	now func() time.Time
+}
+
+// cacheBucket is the content of a cache file
+type cacheBucket struct {
+	// FetchedUntil is the time up to which all the events of the bucket are cached
+	FetchedUntil time.Time     `json:"fetchedUntil"`
+	Events       []types.Event `json:"events"`
+
+	path    string
+	start   time.Time
+	changed bool
+}
+
+// DefaultEventCacheDir returns the directory of the cache in the user cache dir
+func DefaultEventCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "osdctl", "cloudtrail"), nil
+}
+
+// NewEventCache opens the cache in dir, defaulting to DefaultEventCacheDir, and
+// evicts the buckets which weren't updated for maxAge
+func NewEventCache(dir string, maxAge time.Duration) (*EventCache, error) {
+	if dir == "" {
+		var err error
+		if dir, err = DefaultEventCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the cloudtrail cache: %w", err)
+	}
+	cache := &EventCache{dir: dir, now: time.Now}
+	if err := cache.evict(maxAge); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// evict removes the buckets which weren't updated for maxAge
+func (c *EventCache) evict(maxAge time.Duration) error {
+	cutoff := c.now().Add(-maxAge)
+	return filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(cutoff) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// GetEvents returns the events between start and end, newest first. Only the parts of the
+// range which aren't cached yet are fetched, and the fetched events are added to the cache.
+// The missing parts are fetched newest first, if fetching one fails the error is returned
+// together with the events newer than the part which failed.
+func (c *EventCache) GetEvents(accountID string, region string, writeOnly bool, start time.Time, end time.Time, fetch FetchFunc) ([]types.Event, error) {
+	kind := "all"
+	if writeOnly {
+		kind = "write"
+	}
+	dir := filepath.Join(c.dir, accountID, region, kind)
+
+	buckets := []*cacheBucket{}
+	for bucketStart := start.UTC().Truncate(cacheBucketSize); bucketStart.Before(end); bucketStart = bucketStart.Add(cacheBucketSize) {
+		bucket, err := loadBucket(filepath.Join(dir, bucketStart.Format("2006-01-02T15")+".json"), bucketStart)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	// Collect the missing ranges, merging adjacent ones into a single lookup
+	type missingRange struct {
+		first, last int
+		from, until time.Time
+	}
+	var missingRanges []missingRange
+	for i := 0; i < len(buckets); {
+		from, until := buckets[i].missing(end)
+		if !from.Before(until) {
+			i++
+			continue
+		}
+		j := i + 1
+		for ; j < len(buckets); j++ {
+			nextFrom, nextUntil := buckets[j].missing(end)
+			if !nextFrom.Equal(until) || !nextFrom.Before(nextUntil) {
+				break
+			}
+			until = nextUntil
+		}
+		missingRanges = append(missingRanges, missingRange{first: i, last: j, from: from, until: until})
+		i = j
+	}
+
+	settled := c.now().UTC().Add(-deliveryDelay)
+	var fetchErr error
+	complete := buckets
+	for k := len(missingRanges) - 1; k >= 0; k-- {
+		missing := missingRanges[k]
+		events, err := fetch(missing.from, missing.until)
+		if err != nil {
+			fetchErr = err
+			complete = buckets[missing.last:]
+			break
+		}
+		for _, bucket := range buckets[missing.first:missing.last] {
+			bucket.add(events, missing.until, settled)
+		}
+	}
+
+	for _, bucket := range buckets {
+		if bucket.changed {
+			if err := bucket.save(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := []types.Event{}
+	for _, bucket := range complete {
+		for _, event := range bucket.Events {
+			if event.EventTime != nil && !event.EventTime.Before(start) && !event.EventTime.After(end) {
+				result = append(result, event)
+			}
+		}
+	}
+	// Buckets are sorted oldest first, LookupEvents returns newest first
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result, fetchErr
+}
+
+func loadBucket(path string, start time.Time) (*cacheBucket, error) {
+	bucket := &cacheBucket{path: path, start: start, FetchedUntil: start}
+	content, err := os.ReadFile(path) //#nosec G304 -- path is built from the cache dir
+	if errors.Is(err, fs.ErrNotExist) {
+		return bucket, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cloudtrail cache: %w", err)
+	}
+	if err := json.Unmarshal(content, bucket); err != nil {
+		// A corrupted bucket is fetched again
+		return &cacheBucket{path: path, start: start, FetchedUntil: start}, nil
+	}
+	if bucket.FetchedUntil.Before(start) {
+		bucket.FetchedUntil = start
+	}
+	return bucket, nil
+}
+
+// missing returns the range of the bucket not cached yet, capped at end
+func (b *cacheBucket) missing(end time.Time) (time.Time, time.Time) {
+	until := b.start.Add(cacheBucketSize)
+	if end.Before(until) {
+		until = end
+	}
+	return b.FetchedUntil, until
+}
+
+// add stores the events of the bucket range, ordered oldest first, and marks the
+// bucket as fetched until the given time, as long as the events are settled
+func (b *cacheBucket) add(events []types.Event, until time.Time, settled time.Time) {
+	bucketEnd := b.start.Add(cacheBucketSize)
+	seen := map[string]bool{}
+	for _, event := range b.Events {
+		if event.EventId != nil {
+			seen[*event.EventId] = true
+		}
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		if event.EventTime == nil || event.EventTime.Before(b.start) || !event.EventTime.Before(bucketEnd) {
+			continue
+		}
+		if event.EventId != nil {
+			if seen[*event.EventId] {
+				continue
+			}
+			seen[*event.EventId] = true
+		}
+		b.Events = append(b.Events, event)
+	}
+	sortOldestFirst(b.Events)
+
+	fetchedUntil := until
+	if bucketEnd.Before(fetchedUntil) {
+		fetchedUntil = bucketEnd
+	}
+	if settled.Before(fetchedUntil) {
+		fetchedUntil = settled
+	}
+	if fetchedUntil.After(b.FetchedUntil) {
+		b.FetchedUntil = fetchedUntil
+	}
+	b.changed = true
+}
+
+func (b *cacheBucket) save() error {
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return fmt.Errorf("failed to write the cloudtrail cache: %w", err)
+	}
+	content, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so concurrent runs never read a partial bucket
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".bucket-*")
+	if err != nil {
+		return fmt.Errorf("failed to write the cloudtrail cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the cloudtrail cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
+
+func sortOldestFirst(events []types.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventTime.Before(*events[j].EventTime)
+	})
+}
@@ -0,0 +1,206 @@
+package pkg
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awsprovider "github.com/openshift/osdctl/pkg/provider/aws"
+)
+
+// LookupEventsRetention is how far back LookupEvents can return events
+const LookupEventsRetention = 90 * 24 * time.Hour
+
+// trailDownloadConcurrency limits how many log files are downloaded at once
+const trailDownloadConcurrency = 10
+
+// TrailBucket describes the S3 bucket a trail delivers its log files to
+type TrailBucket struct {
+	Bucket string
+	// Prefix is the key prefix up to the account ID, eg. AWSLogs or AWSLogs/o-abc123 for organization trails
+	Prefix    string
+	AccountID string
+}
+
+// trailRecord holds the fields of a trail log record needed to build a types.Event
+type trailRecord struct {
+	EventID      string    `json:"eventID"`
+	EventName    string    `json:"eventName"`
+	EventSource  string    `json:"eventSource"`
+	EventTime    time.Time `json:"eventTime"`
+	ReadOnly     *bool     `json:"readOnly"`
+	UserIdentity struct {
+		UserName string `json:"userName"`
+		Arn      string `json:"arn"`
+	} `json:"userIdentity"`
+	Resources []struct {
+		ARN  string `json:"ARN"`
+		Type string `json:"type"`
+	} `json:"resources"`
+}
+
+// GetTrailEvents reads the events between startTime and endTime from the log files
+// the trail delivered to S3 for the given regions. Unlike GetEvents it isn't limited
+// to the last 90 days.
+func GetTrailEvents(client awsprovider.Client, trail TrailBucket, regions []string, startTime time.Time, endTime time.Time, writeOnly bool) ([]types.Event, error) {
+	keys := []string{}
+	for _, region := range regions {
+		for _, prefix := range trailPrefixes(trail, region, startTime, endTime) {
+			regionKeys, err := listTrailKeys(client, trail.Bucket, prefix)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, regionKeys...)
+		}
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		events = []types.Event{}
+		errs   = []error{}
+		sem    = make(chan struct{}, trailDownloadConcurrency)
+	)
+	for _, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fileEvents, err := readTrailFile(client, trail.Bucket, key, startTime, endTime, writeOnly)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			events = append(events, fileEvents...)
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to read %d trail log files, first error: %w", len(errs), errs[0])
+	}
+	return events, nil
+}
+
+// trailPrefixes returns the key prefix of every day between startTime and endTime,
+// trail log files are stored as <prefix>/<account>/CloudTrail/<region>/YYYY/MM/DD/
+func trailPrefixes(trail TrailBucket, region string, startTime time.Time, endTime time.Time) []string {
+	prefixes := []string{}
+	day := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, time.UTC)
+	for ; !day.After(endTime); day = day.AddDate(0, 0, 1) {
+		prefixes = append(prefixes, path.Join(trail.Prefix, trail.AccountID, "CloudTrail", region, day.Format("2006/01/02"))+"/")
+	}
+	return prefixes
+}
+
+func listTrailKeys(client awsprovider.Client, bucket string, prefix string) ([]string, error) {
+	keys := []string{}
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		output, err := client.ListObjects(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		for _, object := range output.Contents {
+			if object.Key != nil && strings.HasSuffix(*object.Key, ".json.gz") {
+				keys = append(keys, *object.Key)
+			}
+		}
+		if output.IsTruncated == nil || !*output.IsTruncated || len(output.Contents) == 0 {
+			return keys, nil
+		}
+		input.Marker = output.NextMarker
+		if input.Marker == nil {
+			input.Marker = output.Contents[len(output.Contents)-1].Key
+		}
+	}
+}
+
+func readTrailFile(client awsprovider.Client, bucket string, key string, startTime time.Time, endTime time.Time, writeOnly bool) ([]types.Event, error) {
+	output, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	defer output.Body.Close()
+
+	reader, err := gzip.NewReader(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress s3://%s/%s: %w", bucket, key, err)
+	}
+	defer reader.Close()
+
+	events, err := ParseTrailRecords(reader, startTime, endTime, writeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse s3://%s/%s: %w", bucket, key, err)
+	}
+	return events, nil
+}
+
+// ParseTrailRecords converts the records of a trail log file into events as returned by
+// LookupEvents, keeping only records between startTime and endTime
+func ParseTrailRecords(r io.Reader, startTime time.Time, endTime time.Time, writeOnly bool) ([]types.Event, error) {
+	var logFile struct {
+		Records []json.RawMessage `json:"Records"`
+	}
+	if err := json.NewDecoder(r).Decode(&logFile); err != nil {
+		return nil, err
+	}
+
+	events := []types.Event{}
+	for _, raw := range logFile.Records {
+		var record trailRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return nil, err
+		}
+		if record.EventTime.Before(startTime) || record.EventTime.After(endTime) {
+			continue
+		}
+		if writeOnly && record.ReadOnly != nil && *record.ReadOnly {
+			continue
+		}
+
+		username := record.UserIdentity.UserName
+		if username == "" && record.UserIdentity.Arn != "" {
+			username = record.UserIdentity.Arn[strings.LastIndex(record.UserIdentity.Arn, "/")+1:]
+		}
+		event := types.Event{
+			EventId:         aws.String(record.EventID),
+			EventName:       aws.String(record.EventName),
+			EventSource:     aws.String(record.EventSource),
+			EventTime:       aws.Time(record.EventTime),
+			CloudTrailEvent: aws.String(string(raw)),
+		}
+		if username != "" {
+			event.Username = aws.String(username)
+		}
+		if record.ReadOnly != nil {
+			event.ReadOnly = aws.String(fmt.Sprint(*record.ReadOnly))
+		}
+		for _, resource := range record.Resources {
+			event.Resources = append(event.Resources, types.Resource{
+				ResourceName: aws.String(resource.ARN),
+				ResourceType: aws.String(resource.Type),
+			})
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	pkg "github.com/openshift/osdctl/cmd/cloudtrail/pkg/aws"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/spf13/cobra"
+)
+
+const (
+	OutputText   = "text"
+	OutputTable  = "table"
+	OutputJSON   = "json"
+	OutputNDJSON = "ndjson"
+	OutputCSV    = "csv"
+)
+
+// OutputFormats lists the formats supported by PrintFormattedEvents
+var OutputFormats = []string{OutputText, OutputTable, OutputJSON, OutputNDJSON, OutputCSV}
+
+// AddOutputFlag binds the --output flag to the command
+func AddOutputFlag(cmd *cobra.Command, output *string) {
+	cmd.Flags().StringVarP(output, "output", "o", OutputText, "Output format, one of "+strings.Join(OutputFormats, ", "))
+}
+
+// ValidateOutput returns an error if the output format is not supported
+func ValidateOutput(output string) error {
+	for _, format := range OutputFormats {
+		if output == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %q, valid formats are %s", output, strings.Join(OutputFormats, ", "))
+}
+
+// EventRecord is the structured representation of a cloudtrail event
+type EventRecord struct {
+	EventID      string          `json:"eventId"`
+	EventTime    time.Time       `json:"eventTime"`
+	EventName    string          `json:"eventName"`
+	EventSource  string          `json:"eventSource"`
+	Region       string          `json:"region,omitempty"`
+	Username     string          `json:"username,omitempty"`
+	Arn          string          `json:"arn,omitempty"`
+	ErrorCode    string          `json:"errorCode,omitempty"`
+	ErrorMessage string          `json:"errorMessage,omitempty"`
+	Resources    []string        `json:"resources,omitempty"`
+	URL          string          `json:"url,omitempty"`
+	Raw          json.RawMessage `json:"cloudTrailEvent,omitempty"`
+}
+
+// NewEventRecord converts an event into a record, details only available in
+// the raw event are left empty if it can't be parsed
+func NewEventRecord(event types.Event, includeRaw bool) EventRecord {
+	record := EventRecord{
+		EventID:     deref(event.EventId),
+		EventName:   deref(event.EventName),
+		EventSource: deref(event.EventSource),
+		Username:    deref(event.Username),
+	}
+	if event.EventTime != nil {
+		record.EventTime = event.EventTime.UTC()
+	}
+	for _, resource := range event.Resources {
+		if name := deref(resource.ResourceName); name != "" {
+			record.Resources = append(record.Resources, name)
+		}
+	}
+	if includeRaw && event.CloudTrailEvent != nil && json.Valid([]byte(*event.CloudTrailEvent)) {
+		record.Raw = json.RawMessage(*event.CloudTrailEvent)
+	}
+
+	raw, err := pkg.ExtractUserDetails(event.CloudTrailEvent)
+	if err != nil {
+		return record
+	}
+	record.Region = raw.EventRegion
+	record.Arn = raw.UserIdentity.SessionContext.SessionIssuer.Arn
+	if record.Arn == "" {
+		record.Arn = raw.UserIdentity.Arn
+	}
+	record.ErrorCode = raw.ErrorCode
+	record.ErrorMessage = raw.ErrorMessage
+	if record.EventID == "" {
+		record.EventID = raw.EventId
+	}
+	if raw.EventRegion != "" && raw.EventId != "" {
+		record.URL = generateLink(*raw)
+	}
+	return record
+}
+
+// SortEvents sorts the events newest first, the order LookupEvents returns them in
+func SortEvents(events []types.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].EventTime == nil || events[j].EventTime == nil {
+			return events[j].EventTime == nil && events[i].EventTime != nil
+		}
+		return events[i].EventTime.After(*events[j].EventTime)
+	})
+}
+
+// PrintFormattedEvents prints the events to w in the given output format. The events have to be sorted
+// newest first, as SortEvents does, and are printed oldest first. The text format keeps the historic one
+// line per event output of PrintEvents.
+func PrintFormattedEvents(w io.Writer, events []types.Event, output string, printUrl bool, printRaw bool) error {
+	if output == OutputText || output == "" {
+		PrintEvents(w, events, printUrl, printRaw)
+		return nil
+	}
+
+	records := make([]EventRecord, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		records = append(records, NewEventRecord(events[i], printRaw))
+	}
+
+	switch output {
+	case OutputTable:
+		return printEventTable(w, records, printUrl)
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case OutputNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputCSV:
+		return printEventCSV(w, records)
+	}
+	return ValidateOutput(output)
+}
+
+func printEventTable(w io.Writer, records []EventRecord, printUrl bool) error {
+	p := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	header := []string{"TIME", "REGION", "EVENT SOURCE", "EVENT NAME", "USERNAME", "ERROR CODE", "RESOURCES"}
+	if printUrl {
+		header = append(header, "URL")
+	}
+	p.AddRow(header)
+	for _, record := range records {
+		row := []string{
+			record.EventTime.Format(time.RFC3339),
+			record.Region,
+			record.EventSource,
+			record.EventName,
+			record.Username,
+			record.ErrorCode,
+			strings.Join(record.Resources, ","),
+		}
+		if printUrl {
+			row = append(row, record.URL)
+		}
+		p.AddRow(row)
+	}
+	return p.Flush()
+}
+
+func printEventCSV(w io.Writer, records []EventRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"event_time", "event_id", "region", "event_source", "event_name", "username", "arn", "error_code", "error_message", "resources", "url"}); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := writer.Write([]string{
+			record.EventTime.Format(time.RFC3339),
+			record.EventID,
+			record.Region,
+			record.EventSource,
+			record.EventName,
+			record.Username,
+			record.Arn,
+			record.ErrorCode,
+			record.ErrorMessage,
+			strings.Join(record.Resources, " "),
+			record.URL,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
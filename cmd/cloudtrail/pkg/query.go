@@ -0,0 +1,152 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	pkg "github.com/openshift/osdctl/cmd/cloudtrail/pkg/aws"
+	"github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// EventQuery holds composable filters for cloudtrail events.
+// Patterns passed for the same field are OR'ed, different fields are AND'ed.
+// Every pattern is a case insensitive regular expression matching the whole value.
+type EventQuery struct {
+	EventNames    []string
+	EventSources  []string
+	Usernames     []string
+	ResourceNames []string
+	ErrorCodes    []string
+	Regions       []string
+
+	Since string
+	Until string
+
+	// StartTime and EndTime are parsed from Since and Until by Complete
+	StartTime time.Time
+	EndTime   time.Time
+
+	matchers map[string]*regexp.Regexp
+}
+
+// AddFlags binds the query flags to the command, defaultSince is the default of --since
+func (q *EventQuery) AddFlags(cmd *cobra.Command, defaultSince string) {
+	cmd.Flags().StringSliceVar(&q.EventNames, "event-name", nil, "Only show events with the given name (eg. RunInstances), can be a regular expression and repeated")
+	cmd.Flags().StringSliceVar(&q.EventSources, "event-source", nil, "Only show events from the given source (eg. ec2.amazonaws.com), can be a regular expression and repeated")
+	cmd.Flags().StringSliceVar(&q.Usernames, "username", nil, "Only show events made by the given username or ARN, can be a regular expression and repeated")
+	cmd.Flags().StringSliceVar(&q.ResourceNames, "resource-name", nil, "Only show events touching the given resource name (eg. sg-0123456789), can be a regular expression and repeated")
+	cmd.Flags().StringSliceVar(&q.ErrorCodes, "error-code", nil, "Only show events failing with the given error code (eg. AccessDenied), can be a regular expression and repeated")
+	cmd.Flags().StringSliceVar(&q.Regions, "region", nil, "Only show events recorded in the given AWS region, can be repeated")
+	cmd.Flags().StringVar(&q.Since, "since", defaultSince, "Only show events newer than a relative duration (eg. 30m, 2h) or an absolute time (eg. 2024-01-31 or 2024-01-31T12:00:00Z). Valid time units are \"ns\", \"us\" (or \"µs\"), \"ms\", \"s\", \"m\", \"h\".")
+	cmd.Flags().StringVar(&q.Until, "until", "", "Only show events older than a relative duration or an absolute time, defaults to now")
+}
+
+// Complete parses the time window and compiles the patterns of the query
+func (q *EventQuery) Complete(now time.Time) error {
+	var err error
+	if q.StartTime, err = utils.ParseTime(q.Since, now); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	q.EndTime = now
+	if q.Until != "" {
+		if q.EndTime, err = utils.ParseTime(q.Until, now); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	if !q.StartTime.Before(q.EndTime) {
+		return fmt.Errorf("--since (%v) must be before --until (%v)", q.StartTime, q.EndTime)
+	}
+
+	q.matchers = map[string]*regexp.Regexp{}
+	for flag, patterns := range map[string][]string{
+		"event-name":    q.EventNames,
+		"event-source":  q.EventSources,
+		"username":      q.Usernames,
+		"resource-name": q.ResourceNames,
+		"error-code":    q.ErrorCodes,
+		"region":        q.Regions,
+	} {
+		if len(patterns) == 0 {
+			continue
+		}
+		matcher, err := regexp.Compile("(?i)^(?:" + MergeRegex(patterns) + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", flag, err)
+		}
+		q.matchers[flag] = matcher
+	}
+	return nil
+}
+
+// Filter returns the query as a Filter usable with ApplyFilters
+func (q *EventQuery) Filter() Filter {
+	return func(event types.Event) (bool, error) {
+		return q.Matches(event), nil
+	}
+}
+
+// Matches returns true if the event passes every filter of the query.
+// Events that can't be parsed only match filters that don't need the raw event.
+func (q *EventQuery) Matches(event types.Event) bool {
+	if event.EventTime != nil {
+		if !q.StartTime.IsZero() && event.EventTime.Before(q.StartTime) {
+			return false
+		}
+		if !q.EndTime.IsZero() && event.EventTime.After(q.EndTime) {
+			return false
+		}
+	}
+
+	raw, err := pkg.ExtractUserDetails(event.CloudTrailEvent)
+	if err != nil {
+		raw = &pkg.RawEventDetails{}
+	}
+
+	if !q.matchAny("event-name", deref(event.EventName), raw.EventName) {
+		return false
+	}
+	if !q.matchAny("event-source", deref(event.EventSource), raw.EventSource) {
+		return false
+	}
+	if !q.matchAny("username", deref(event.Username), raw.UserIdentity.Arn,
+		raw.UserIdentity.SessionContext.SessionIssuer.Arn, raw.UserIdentity.SessionContext.SessionIssuer.UserName) {
+		return false
+	}
+	resourceNames := []string{}
+	for _, resource := range event.Resources {
+		resourceNames = append(resourceNames, deref(resource.ResourceName))
+	}
+	if !q.matchAny("resource-name", resourceNames...) {
+		return false
+	}
+	if !q.matchAny("error-code", raw.ErrorCode) {
+		return false
+	}
+	return q.matchAny("region", raw.EventRegion)
+}
+
+// matchAny returns true if there is no filter for the flag, or if one of the
+// non empty values matches it
+func (q *EventQuery) matchAny(flag string, values ...string) bool {
+	matcher, ok := q.matchers[flag]
+	if !ok {
+		return true
+	}
+	for _, value := range values {
+		if value != "" && matcher.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
@@ -0,0 +1,232 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	pkg "github.com/openshift/osdctl/cmd/cloudtrail/pkg/aws"
+	envConfig "github.com/openshift/osdctl/pkg/envConfig"
+)
+
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+
+	infraIDPlaceholder = "${INFRA_ID}"
+	clusterTagPrefix   = "kubernetes.io/cluster/"
+)
+
+// DefaultSuspiciousChangeRules are the built-in rules, they can be replaced or disabled in the osdctl configuration
+var DefaultSuspiciousChangeRules = []envConfig.SuspiciousChangeRule{
+	{
+		Name:         "security-group-change",
+		Description:  "Security group rules were changed",
+		Severity:     SeverityHigh,
+		EventSources: []string{"ec2.amazonaws.com"},
+		EventNames:   []string{"(Authorize|Revoke)SecurityGroup(Ingress|Egress)", "ModifySecurityGroupRules", "DeleteSecurityGroup"},
+	},
+	{
+		Name:         "route-table-change",
+		Description:  "Routes or route tables were changed",
+		Severity:     SeverityHigh,
+		EventSources: []string{"ec2.amazonaws.com"},
+		EventNames:   []string{"(Create|Delete|Replace)Route", "(Create|Delete)RouteTable", "(Associate|Disassociate)RouteTable", "ReplaceRouteTableAssociation"},
+	},
+	{
+		Name:           "installer-resource-deleted",
+		Description:    "A resource owned by the cluster was deleted",
+		Severity:       SeverityHigh,
+		EventNames:     []string{"Delete.*", "Terminate.*", "Release.*", "Detach.*", "Disassociate.*"},
+		InstallerOwned: true,
+	},
+	{
+		Name:           "installer-role-detached",
+		Description:    "A policy of the installer or support role was detached, deleted or changed",
+		Severity:       SeverityHigh,
+		EventSources:   []string{"iam.amazonaws.com"},
+		EventNames:     []string{"DetachRolePolicy", "DeleteRolePolicy", "PutRolePolicy", "DeleteRole", "UpdateAssumeRolePolicy", "DeletePolicy", "CreatePolicyVersion", "SetDefaultPolicyVersion"},
+		RequestPattern: `"(roleName|policyArn)":"[^"]*(Installer|Support)[^"]*"`,
+	},
+}
+
+// SuspiciousChangeRules merges the configured rules into the built-in ones
+func SuspiciousChangeRules(configured []envConfig.SuspiciousChangeRule) []envConfig.SuspiciousChangeRule {
+	rules := []envConfig.SuspiciousChangeRule{}
+	for _, rule := range DefaultSuspiciousChangeRules {
+		overridden := false
+		for _, c := range configured {
+			if c.Name == rule.Name {
+				overridden = true
+			}
+		}
+		if !overridden {
+			rules = append(rules, rule)
+		}
+	}
+	for _, rule := range configured {
+		if !rule.Disabled {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ChangeClassifier matches cloudtrail events against suspicious change rules
+type ChangeClassifier struct {
+	rules []compiledRule
+	// ownedResources are the IDs of the resources tagged kubernetes.io/cluster/<infraID>,
+	// either still existing or tagged by an event classified earlier
+	ownedResources map[string]bool
+	infraID        string
+}
+
+type compiledRule struct {
+	envConfig.SuspiciousChangeRule
+	eventSources *regexp.Regexp
+	eventNames   *regexp.Regexp
+	request      *regexp.Regexp
+}
+
+// NewChangeClassifier compiles the rules for the cluster with the given infra ID
+func NewChangeClassifier(rules []envConfig.SuspiciousChangeRule, infraID string, ownedResources []string) (*ChangeClassifier, error) {
+	classifier := &ChangeClassifier{infraID: infraID, ownedResources: map[string]bool{}}
+	for _, id := range ownedResources {
+		classifier.ownedResources[id] = true
+	}
+
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("suspicious change rules need a name")
+		}
+		switch rule.Severity {
+		case "":
+			rule.Severity = SeverityMedium
+		case SeverityHigh, SeverityMedium, SeverityLow:
+		default:
+			return nil, fmt.Errorf("rule %s: invalid severity %q, must be one of %s, %s or %s", rule.Name, rule.Severity, SeverityHigh, SeverityMedium, SeverityLow)
+		}
+		compiled := compiledRule{SuspiciousChangeRule: rule}
+		var err error
+		if compiled.eventSources, err = compileAnchored(rule.EventSources); err != nil {
+			return nil, fmt.Errorf("rule %s: invalid event_sources: %w", rule.Name, err)
+		}
+		if compiled.eventNames, err = compileAnchored(rule.EventNames); err != nil {
+			return nil, fmt.Errorf("rule %s: invalid event_names: %w", rule.Name, err)
+		}
+		if rule.RequestPattern != "" {
+			pattern := strings.ReplaceAll(rule.RequestPattern, infraIDPlaceholder, regexp.QuoteMeta(infraID))
+			if compiled.request, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("rule %s: invalid request_pattern: %w", rule.Name, err)
+			}
+		}
+		classifier.rules = append(classifier.rules, compiled)
+	}
+	return classifier, nil
+}
+
+// Classify returns the rules matched by the event
+func (c *ChangeClassifier) Classify(event types.Event) []envConfig.SuspiciousChangeRule {
+	raw, err := pkg.ExtractUserDetails(event.CloudTrailEvent)
+	if err != nil {
+		raw = &pkg.RawEventDetails{}
+	}
+	request := string(raw.RequestParameters)
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, raw.RequestParameters) == nil {
+		request = compacted.String()
+	}
+
+	matched := []envConfig.SuspiciousChangeRule{}
+	for _, rule := range c.rules {
+		if rule.eventSources != nil && !rule.eventSources.MatchString(deref(event.EventSource)) {
+			continue
+		}
+		if rule.eventNames != nil && !rule.eventNames.MatchString(deref(event.EventName)) {
+			continue
+		}
+		if rule.request != nil && !rule.request.MatchString(request) {
+			continue
+		}
+		if rule.InstallerOwned && !c.isInstallerOwned(event, request) {
+			continue
+		}
+		matched = append(matched, rule.SuspiciousChangeRule)
+	}
+	return matched
+}
+
+// isInstallerOwned returns true if the event touches a resource tagged for the
+// cluster, or if its request references the infra ID (eg. named resources)
+func (c *ChangeClassifier) isInstallerOwned(event types.Event, request string) bool {
+	for _, resource := range event.Resources {
+		if c.ownedResources[deref(resource.ResourceName)] {
+			return true
+		}
+	}
+	return c.infraID != "" && strings.Contains(request, c.infraID)
+}
+
+// TrackOwnership marks the resources tagged kubernetes.io/cluster/<infraID> by a successful
+// CreateTags or RunInstances event as owned. All events, including the ignored ones, must be
+// tracked oldest first, so resources deleted by later events are recognised even though they
+// don't exist anymore.
+func (c *ChangeClassifier) TrackOwnership(event types.Event) {
+	if c.infraID == "" || event.CloudTrailEvent == nil || !strings.Contains(*event.CloudTrailEvent, clusterTagPrefix+c.infraID+`"`) {
+		return
+	}
+	raw, err := pkg.ExtractUserDetails(event.CloudTrailEvent)
+	if err != nil || raw.ErrorCode != "" {
+		return
+	}
+
+	switch deref(event.EventName) {
+	case "CreateTags":
+		var parameters struct {
+			ResourcesSet struct {
+				Items []struct {
+					ResourceID string `json:"resourceId"`
+				} `json:"items"`
+			} `json:"resourcesSet"`
+		}
+		if json.Unmarshal(raw.RequestParameters, &parameters) == nil {
+			for _, item := range parameters.ResourcesSet.Items {
+				c.addOwnedResource(item.ResourceID)
+			}
+		}
+		for _, resource := range event.Resources {
+			c.addOwnedResource(deref(resource.ResourceName))
+		}
+	case "RunInstances":
+		// The other resources of the event (image, subnet, security groups...) aren't tagged
+		var response struct {
+			InstancesSet struct {
+				Items []struct {
+					InstanceID string `json:"instanceId"`
+				} `json:"items"`
+			} `json:"instancesSet"`
+		}
+		if json.Unmarshal(raw.ResponseElements, &response) == nil {
+			for _, item := range response.InstancesSet.Items {
+				c.addOwnedResource(item.InstanceID)
+			}
+		}
+	}
+}
+
+func (c *ChangeClassifier) addOwnedResource(id string) {
+	if id != "" {
+		c.ownedResources[id] = true
+	}
+}
+
+func compileAnchored(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return regexp.Compile("(?i)^(?:" + MergeRegex(patterns) + ")$")
+}
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -38,21 +39,38 @@ func ApplyFilters(records []types.Event, filters ...Filter) ([]types.Event, erro
 	return filteredRecords, nil
 }
 
-// PrintEvents prints the details of each event in the provided slice of events.
-// It takes a slice of types.Event
-func PrintEvents(filterEvents []types.Event, printUrl bool, printRaw bool) {
+// DedupeEvents removes events with an already seen event ID, keeping the first one.
+// The same global event can be returned by several regions and by the trail bucket.
+func DedupeEvents(events []types.Event) []types.Event {
+	seen := map[string]bool{}
+	deduped := make([]types.Event, 0, len(events))
+	for _, event := range events {
+		if event.EventId != nil {
+			if seen[*event.EventId] {
+				continue
+			}
+			seen[*event.EventId] = true
+		}
+		deduped = append(deduped, event)
+	}
+	return deduped
+}
+
+// PrintEvents prints the details of each event in the provided slice of events to w.
+// It takes a slice of types.Event, sorted newest first, and prints them oldest first
+func PrintEvents(w io.Writer, filterEvents []types.Event, printUrl bool, printRaw bool) {
 	var eventStringBuilder = strings.Builder{}
 
 	for i := len(filterEvents) - 1; i >= 0; i-- {
 		if printRaw {
 			if filterEvents[i].CloudTrailEvent != nil {
-				fmt.Printf("%v \n", *filterEvents[i].CloudTrailEvent)
+				fmt.Fprintf(w, "%v \n", *filterEvents[i].CloudTrailEvent)
 				return
 			}
 		}
 		rawEventDetails, err := pkg.ExtractUserDetails(filterEvents[i].CloudTrailEvent)
 		if err != nil {
-			fmt.Printf("[Error] Error extracting event details: %v", err)
+			fmt.Fprintf(w, "[Error] Error extracting event details: %v", err)
 		}
 		sessionIssuer := rawEventDetails.UserIdentity.SessionContext.SessionIssuer.UserName
 		if filterEvents[i].EventName != nil {
@@ -72,12 +90,12 @@ func PrintEvents(filterEvents []types.Event, printUrl bool, printRaw bool) {
 			if err == nil {
 				eventStringBuilder.WriteString(fmt.Sprintf("\n%v |", generateLink(*rawEventDetails)))
 			} else {
-				fmt.Println("EventLink: <not available>")
+				fmt.Fprintln(w, "EventLink: <not available>")
 			}
 		}
 
 	}
-	fmt.Println(eventStringBuilder.String())
+	fmt.Fprintln(w, eventStringBuilder.String())
 }
 
 // generateLink generates a hyperlink to aws cloudTrail event.
@@ -0,0 +1,282 @@
+package cloudtrail
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	tagtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ctUtil "github.com/openshift/osdctl/cmd/cloudtrail/pkg"
+	ctAws "github.com/openshift/osdctl/cmd/cloudtrail/pkg/aws"
+	envConfig "github.com/openshift/osdctl/pkg/envConfig"
+	"github.com/openshift/osdctl/pkg/osdCloud"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+type suspiciousChangesOptions struct {
+	ClusterID string
+	Output    string
+	PrintAll  bool
+	Query     ctUtil.EventQuery
+	Lookup    lookupOptions
+}
+
+// Finding is a write event matching a suspicious change rule
+type Finding struct {
+	Rule        string    `json:"rule"`
+	Severity    string    `json:"severity"`
+	Description string    `json:"description"`
+	EventTime   time.Time `json:"eventTime"`
+	EventName   string    `json:"eventName"`
+	EventSource string    `json:"eventSource"`
+	Actor       string    `json:"actor"`
+	Username    string    `json:"username,omitempty"`
+	Arn         string    `json:"arn,omitempty"`
+	Resources   []string  `json:"resources,omitempty"`
+	ErrorCode   string    `json:"errorCode,omitempty"`
+	URL         string    `json:"url,omitempty"`
+}
+
+func newCmdSuspiciousChanges() *cobra.Command {
+	opts := &suspiciousChangesOptions{}
+	suspiciousChangesCmd := &cobra.Command{
+		Use:   "suspicious-changes",
+		Short: "Reports cloudtrail write events likely to have broken the cluster",
+		Long: `Classifies the cloudtrail write events of the cluster account against a set of rules and reports the matches:
+
+  security-group-change       security group ingress or egress rules were changed
+  route-table-change          routes or route tables were changed
+  installer-resource-deleted  a resource tagged kubernetes.io/cluster/<infraID> was deleted or detached, the
+                              resource either still exists or was tagged by an earlier event of the time window
+  installer-role-detached     a policy of the installer or support role was detached, deleted or changed
+
+Events made by users of the cloudtrail_cmd_lists filter list are skipped unless --all is passed.
+
+Rules can be replaced, disabled or added in the osdctl configuration next to the filter list:
+
+  cloudtrail_cmd_lists:
+    filter_regex_patterns:
+      - .*-Installer-Role
+    suspicious_change_rules:
+      - name: route-table-change
+        disabled: true
+      - name: load-balancer-deleted
+        description: A load balancer of the cluster was deleted
+        severity: high          # high, medium or low
+        event_sources: [elasticloadbalancing.amazonaws.com]
+        event_names: [DeleteLoadBalancer]
+        request_pattern: ${INFRA_ID}   # matched against the request parameters
+        installer_owned: false`,
+		Example: `  # Suspicious changes of the last 3 days
+  osdctl cloudtrail suspicious-changes -C <cluster-id> --since 72h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.run()
+		},
+	}
+	suspiciousChangesCmd.Flags().StringVarP(&opts.ClusterID, "cluster-id", "C", "", "Cluster ID")
+	suspiciousChangesCmd.Flags().StringVarP(&opts.Output, "output", "o", ctUtil.OutputTable, "Output format, one of "+strings.Join(reportOutputFormats, ", "))
+	suspiciousChangesCmd.Flags().BoolVarP(&opts.PrintAll, "all", "A", false, "Also report changes made by users of the filter list")
+	opts.Query.AddFlags(suspiciousChangesCmd, "24h")
+	opts.Lookup.addFlags(suspiciousChangesCmd)
+	_ = suspiciousChangesCmd.MarkFlagRequired("cluster-id")
+	return suspiciousChangesCmd
+}
+
+func (o *suspiciousChangesOptions) run() error {
+	if err := utils.IsValidClusterKey(o.ClusterID); err != nil {
+		return err
+	}
+	if !slices.Contains(reportOutputFormats, o.Output) {
+		return fmt.Errorf("invalid output format %q, valid formats are %s", o.Output, strings.Join(reportOutputFormats, ", "))
+	}
+	if err := o.Query.Complete(time.Now().UTC()); err != nil {
+		return err
+	}
+
+	ignore, err := envConfig.LoadCloudTrailConfig()
+	if err != nil {
+		return fmt.Errorf("[ERROR] error Loading cloudtrail configuration file: %w", err)
+	}
+	configuredRules, err := envConfig.LoadSuspiciousChangeRules()
+	if err != nil {
+		return fmt.Errorf("[ERROR] error Loading cloudtrail configuration file: %w", err)
+	}
+
+	connection, err := utils.CreateConnection()
+	if err != nil {
+		return fmt.Errorf("unable to create connection to ocm: %w", err)
+	}
+	defer connection.Close()
+
+	cluster, err := utils.GetClusterAnyStatus(connection, o.ClusterID)
+	if err != nil {
+		return err
+	}
+	if strings.ToUpper(cluster.CloudProvider().ID()) != "AWS" {
+		return fmt.Errorf("[ERROR] this command is only available for AWS clusters")
+	}
+
+	cfg, err := osdCloud.CreateAWSV2Config(connection, cluster)
+	if err != nil {
+		return err
+	}
+	_, accountId, err := ctAws.Whoami(*sts.NewFromConfig(cfg))
+	if err != nil {
+		return err
+	}
+
+	ownedResources, err := getInstallerOwnedResources(cfg, cluster.InfraID())
+	if err != nil {
+		return err
+	}
+	classifier, err := ctUtil.NewChangeClassifier(ctUtil.SuspiciousChangeRules(configuredRules), cluster.InfraID(), ownedResources)
+	if err != nil {
+		return fmt.Errorf("invalid suspicious_change_rules: %w", err)
+	}
+
+	events, err := fetchEvents(cfg, accountId, &o.Query, &o.Lookup, true)
+	if err != nil {
+		return err
+	}
+	events, err = ctUtil.ApplyFilters(events, o.Query.Filter())
+	if err != nil {
+		return err
+	}
+
+	mergedRegex := ctUtil.MergeRegex(ignore)
+	if o.PrintAll {
+		mergedRegex = ""
+	}
+	findings := findSuspiciousChanges(events, classifier, mergedRegex)
+	fmt.Fprintf(os.Stderr, "[INFO] Found %d suspicious changes in %d write events\n", len(findings), len(events))
+	return printFindings(os.Stdout, findings, o.Output)
+}
+
+// getInstallerOwnedResources returns the IDs of the resources tagged kubernetes.io/cluster/<infraID> in the cluster region.
+// Deleted resources aren't returned, the classifier learns them from the tagging events of the time window.
+func getInstallerOwnedResources(cfg aws.Config, infraID string) ([]string, error) {
+	if infraID == "" {
+		return nil, nil
+	}
+	client := resourcegroupstaggingapi.NewFromConfig(cfg)
+	paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(client, &resourcegroupstaggingapi.GetResourcesInput{
+		TagFilters: []tagtypes.TagFilter{{Key: aws.String("kubernetes.io/cluster/" + infraID)}},
+	})
+	ids := []string{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the resources of the cluster: %w", err)
+		}
+		for _, resource := range page.ResourceTagMappingList {
+			if resource.ResourceARN != nil {
+				ids = append(ids, resourceIDFromArn(*resource.ResourceARN))
+			}
+		}
+	}
+	return ids, nil
+}
+
+// resourceIDFromArn returns the resource ID of an ARN, as used in the resources of cloudtrail events
+// eg. sg-0123456789 for arn:aws:ec2:us-east-1:123456789012:security-group/sg-0123456789
+func resourceIDFromArn(arn string) string {
+	if i := strings.LastIndex(arn, "/"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn[strings.LastIndex(arn, ":")+1:]
+}
+
+// findSuspiciousChanges classifies the events, events by users of the ignore list are skipped
+// but still tracked for the ownership of the resources they tag.
+// The findings are returned oldest first.
+func findSuspiciousChanges(events []types.Event, classifier *ctUtil.ChangeClassifier, mergedRegex string) []Finding {
+	var ignoreRegex *regexp.Regexp
+	if mergedRegex != "" {
+		ignoreRegex = regexp.MustCompile(mergedRegex)
+	}
+
+	findings := []Finding{}
+	for i := len(events) - 1; i >= 0; i-- {
+		classifier.TrackOwnership(events[i])
+		record := ctUtil.NewEventRecord(events[i], false)
+		actor := eventActor(record, ignoreRegex)
+		if actor == actorRedHat {
+			continue
+		}
+		for _, rule := range classifier.Classify(events[i]) {
+			findings = append(findings, Finding{
+				Rule:        rule.Name,
+				Severity:    rule.Severity,
+				Description: rule.Description,
+				EventTime:   record.EventTime,
+				EventName:   record.EventName,
+				EventSource: record.EventSource,
+				Actor:       actor,
+				Username:    record.Username,
+				Arn:         record.Arn,
+				Resources:   record.Resources,
+				ErrorCode:   record.ErrorCode,
+				URL:         record.URL,
+			})
+		}
+	}
+	return findings
+}
+
+func printFindings(w io.Writer, findings []Finding, output string) error {
+	switch output {
+	case ctUtil.OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(findings)
+	case ctUtil.OutputNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, finding := range findings {
+			if err := encoder.Encode(finding); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ctUtil.OutputCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"event_time", "severity", "rule", "event_source", "event_name", "actor", "username", "arn", "resources", "error_code", "url"}); err != nil {
+			return err
+		}
+		for _, f := range findings {
+			if err := writer.Write([]string{f.EventTime.Format(time.RFC3339), f.Severity, f.Rule, f.EventSource, f.EventName, f.Actor, f.Username, f.Arn, strings.Join(f.Resources, " "), f.ErrorCode, f.URL}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "No suspicious changes found")
+		return nil
+	}
+	p := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	p.AddRow([]string{"TIME", "SEVERITY", "RULE", "EVENT", "ACTOR", "USERNAME", "RESOURCES", "URL"})
+	for _, f := range findings {
+		event := f.EventName
+		if f.ErrorCode != "" {
+			event += " (" + f.ErrorCode + ")"
+		}
+		p.AddRow([]string{f.EventTime.Format(time.RFC3339), f.Severity, f.Rule, event, f.Actor, f.Username, strings.Join(f.Resources, ","), f.URL})
+	}
+	return p.Flush()
+}
@@ -0,0 +1,268 @@
+package cloudtrail
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	ctUtil "github.com/openshift/osdctl/cmd/cloudtrail/pkg"
+	ctAws "github.com/openshift/osdctl/cmd/cloudtrail/pkg/aws"
+	"github.com/openshift/osdctl/cmd/servicelog"
+	envConfig "github.com/openshift/osdctl/pkg/envConfig"
+	"github.com/openshift/osdctl/pkg/osdCloud"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+const (
+	timelineSourceCloudTrail     = "cloudtrail"
+	timelineSourceServiceLog     = "servicelog"
+	timelineSourceLimitedSupport = "limited-support"
+
+	actorCustomer = "customer"
+	actorRedHat   = "red-hat"
+	actorUnknown  = "unknown"
+)
+
+// reportOutputFormats are the formats supported by the timeline and the suspicious changes report
+var reportOutputFormats = []string{ctUtil.OutputTable, ctUtil.OutputJSON, ctUtil.OutputNDJSON, ctUtil.OutputCSV}
+
+type timelineOptions struct {
+	ClusterID string
+	Output    string
+	Query     ctUtil.EventQuery
+	Lookup    lookupOptions
+}
+
+// TimelineEntry is a single cloudtrail event, service log or limited support change
+type TimelineEntry struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Actor    string    `json:"actor"`
+	Username string    `json:"username,omitempty"`
+	Summary  string    `json:"summary"`
+	Details  string    `json:"details,omitempty"`
+	ID       string    `json:"id,omitempty"`
+	URL      string    `json:"url,omitempty"`
+}
+
+func newCmdTimeline() *cobra.Command {
+	opts := &timelineOptions{}
+	timelineCmd := &cobra.Command{
+		Use:   "timeline",
+		Short: "Prints a timeline of cloudtrail write events, service logs and limited support reasons of a cluster",
+		Long: `Prints a chronologically sorted timeline merging the cloudtrail write events of the cluster account with
+the service logs and the limited support reasons of the cluster.
+
+Cloudtrail events made by users matching the cloudtrail_cmd_lists of the osdctl configuration are
+marked as red-hat actions, all others as customer actions.`,
+		Example: `  # What happened on the cluster during the last 6 hours
+  osdctl cloudtrail timeline -C <cluster-id> --since 6h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.run()
+		},
+	}
+	timelineCmd.Flags().StringVarP(&opts.ClusterID, "cluster-id", "C", "", "Cluster ID")
+	timelineCmd.Flags().StringVarP(&opts.Output, "output", "o", ctUtil.OutputTable, "Output format, one of "+strings.Join(reportOutputFormats, ", "))
+	opts.Query.AddFlags(timelineCmd, "24h")
+	opts.Lookup.addFlags(timelineCmd)
+	_ = timelineCmd.MarkFlagRequired("cluster-id")
+	return timelineCmd
+}
+
+func (o *timelineOptions) run() error {
+	if err := utils.IsValidClusterKey(o.ClusterID); err != nil {
+		return err
+	}
+	if !slices.Contains(reportOutputFormats, o.Output) {
+		return fmt.Errorf("invalid output format %q, valid formats are %s", o.Output, strings.Join(reportOutputFormats, ", "))
+	}
+	if err := o.Query.Complete(time.Now().UTC()); err != nil {
+		return err
+	}
+
+	connection, err := utils.CreateConnection()
+	if err != nil {
+		return fmt.Errorf("unable to create connection to ocm: %w", err)
+	}
+	defer connection.Close()
+
+	cluster, err := utils.GetClusterAnyStatus(connection, o.ClusterID)
+	if err != nil {
+		return err
+	}
+	if strings.ToUpper(cluster.CloudProvider().ID()) != "AWS" {
+		return fmt.Errorf("[ERROR] this command is only available for AWS clusters")
+	}
+
+	ignore, err := envConfig.LoadCloudTrailConfig()
+	if err != nil {
+		return fmt.Errorf("[ERROR] error Loading cloudtrail configuration file: %w", err)
+	}
+	if len(ignore) == 0 {
+		fmt.Fprintln(os.Stderr, "[WARNING] No filter list detected, cloudtrail events can't be attributed to customers or Red Hat. Please add cloudtrail_cmd_lists to your osdctl configuration file.")
+	}
+
+	cfg, err := osdCloud.CreateAWSV2Config(connection, cluster)
+	if err != nil {
+		return err
+	}
+	_, accountId, err := ctAws.Whoami(*sts.NewFromConfig(cfg))
+	if err != nil {
+		return err
+	}
+	events, err := fetchEvents(cfg, accountId, &o.Query, &o.Lookup, true)
+	if err != nil {
+		return err
+	}
+	events, err = ctUtil.ApplyFilters(events, o.Query.Filter())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "[INFO] Fetching service logs and limited support reasons...")
+	serviceLogs, err := servicelog.GetServiceLogsSince(cluster.ID(), o.Query.StartTime, true, false)
+	if err != nil {
+		return err
+	}
+	reasons, err := utils.GetClusterLimitedSupportReasons(connection, cluster.ID())
+	if err != nil {
+		return err
+	}
+
+	timeline := buildTimeline(events, ctUtil.MergeRegex(ignore), serviceLogs, reasons, o.Query.StartTime, o.Query.EndTime)
+	return printTimeline(os.Stdout, timeline, o.Output)
+}
+
+// buildTimeline merges the events, service logs and limited support reasons within
+// the time window into a single timeline sorted oldest first
+func buildTimeline(events []types.Event, mergedRegex string, serviceLogs []*slv1.LogEntry, reasons []*cmv1.LimitedSupportReason, start time.Time, end time.Time) []TimelineEntry {
+	var ignoreRegex *regexp.Regexp
+	if mergedRegex != "" {
+		ignoreRegex = regexp.MustCompile(mergedRegex)
+	}
+
+	timeline := []TimelineEntry{}
+	for _, event := range events {
+		record := ctUtil.NewEventRecord(event, false)
+		details := strings.Join(record.Resources, ", ")
+		if record.ErrorCode != "" {
+			details = strings.TrimPrefix(details+", error: "+record.ErrorCode, ", ")
+		}
+		timeline = append(timeline, TimelineEntry{
+			Time:     record.EventTime,
+			Source:   timelineSourceCloudTrail,
+			Actor:    eventActor(record, ignoreRegex),
+			Username: record.Username,
+			Summary:  strings.TrimSuffix(record.EventSource, ".amazonaws.com") + ":" + record.EventName,
+			Details:  details,
+			ID:       record.EventID,
+			URL:      record.URL,
+		})
+	}
+
+	for _, serviceLog := range serviceLogs {
+		if serviceLog.Timestamp().Before(start) || serviceLog.Timestamp().After(end) {
+			continue
+		}
+		summary := serviceLog.Summary()
+		if serviceLog.InternalOnly() {
+			summary = "[internal] " + summary
+		}
+		timeline = append(timeline, TimelineEntry{
+			Time:     serviceLog.Timestamp().UTC(),
+			Source:   timelineSourceServiceLog,
+			Actor:    actorRedHat,
+			Username: serviceLog.Username(),
+			Summary:  summary,
+			Details:  serviceLog.Description(),
+			ID:       serviceLog.ID(),
+		})
+	}
+
+	for _, reason := range reasons {
+		if reason.CreationTimestamp().Before(start) || reason.CreationTimestamp().After(end) {
+			continue
+		}
+		timeline = append(timeline, TimelineEntry{
+			Time:    reason.CreationTimestamp().UTC(),
+			Source:  timelineSourceLimitedSupport,
+			Actor:   actorRedHat,
+			Summary: "Limited support: " + reason.Summary(),
+			Details: reason.Details(),
+			ID:      reason.ID(),
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline
+}
+
+// eventActor marks events made by a user of the ignore list as red-hat actions,
+// and all other events as customer actions
+func eventActor(record ctUtil.EventRecord, ignoreRegex *regexp.Regexp) string {
+	if ignoreRegex == nil || (record.Username == "" && record.Arn == "") {
+		return actorUnknown
+	}
+	for _, user := range []string{record.Username, record.Arn} {
+		if user != "" && ignoreRegex.MatchString(user) {
+			return actorRedHat
+		}
+	}
+	return actorCustomer
+}
+
+func printTimeline(w io.Writer, timeline []TimelineEntry, output string) error {
+	switch output {
+	case ctUtil.OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(timeline)
+	case ctUtil.OutputNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, entry := range timeline {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ctUtil.OutputCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"time", "source", "actor", "username", "summary", "details", "id", "url"}); err != nil {
+			return err
+		}
+		for _, entry := range timeline {
+			if err := writer.Write([]string{entry.Time.Format(time.RFC3339), entry.Source, entry.Actor, entry.Username, entry.Summary, entry.Details, entry.ID, entry.URL}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	p := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	p.AddRow([]string{"TIME", "SOURCE", "ACTOR", "USERNAME", "SUMMARY", "DETAILS"})
+	for _, entry := range timeline {
+		details := strings.ReplaceAll(entry.Details, "\n", " ")
+		if len(details) > 80 {
+			details = details[:77] + "..."
+		}
+		p.AddRow([]string{entry.Time.Format(time.RFC3339), entry.Source, entry.Actor, entry.Username, entry.Summary, details})
+	}
+	return p.Flush()
+}
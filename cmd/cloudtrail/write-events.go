@@ -3,11 +3,15 @@ package cloudtrail
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"regexp"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	ctUtil "github.com/openshift/osdctl/cmd/cloudtrail/pkg"
@@ -23,43 +27,52 @@ var DefaultRegion = "us-east-1"
 // LookupEventsOptions struct for holding options for event lookup
 type writeEventsOptions struct {
 	ClusterID string
-	StartTime string
 	PrintUrl  bool
 	PrintRaw  bool
 	PrintAll  bool
-}
+	Output    string
+	Query     ctUtil.EventQuery
+	Lookup    lookupOptions
 
-// RawEventDetails struct represents the structure of an AWS raw event
-type RawEventDetails struct {
-	EventVersion string `json:"eventVersion"`
-	UserIdentity struct {
-		AccountId      string `json:"accountId"`
-		SessionContext struct {
-			SessionIssuer struct {
-				Type     string `json:"type"`
-				UserName string `json:"userName"`
-				Arn      string `json:"arn"`
-			} `json:"sessionIssuer"`
-		} `json:"sessionContext"`
-	} `json:"userIdentity"`
-	EventRegion string `json:"awsRegion"`
-	EventId     string `json:"eventID"`
+	Follow       bool
+	PollInterval time.Duration
 }
 
+const (
+	// followLookback is how far back every poll of --follow looks, cloudtrail
+	// usually delivers events within a few minutes but can take up to 15
+	followLookback = 15 * time.Minute
+)
+
+// followOutputFormats are the formats which can be streamed by --follow
+var followOutputFormats = []string{ctUtil.OutputText, ctUtil.OutputNDJSON}
+
 func newCmdWriteEvents() *cobra.Command {
 	ops := &writeEventsOptions{}
 	listEventsCmd := &cobra.Command{
 		Use:   "write-events",
 		Short: "Prints cloudtrail write events to console with optional filtering",
+		Example: `  # Security group changes of the last 2 hours as a table
+  osdctl cloudtrail write-events -C <cluster-id> --since 2h --event-source ec2.amazonaws.com --event-name '.*SecurityGroup.*' -o table
+
+  # Failed calls made by a given role during an incident window, for jq
+  osdctl cloudtrail write-events -C <cluster-id> --since 2024-01-31T10:00:00Z --until 2024-01-31T12:00:00Z --username '.*-Installer-Role' --error-code '.+' -o ndjson
+
+  # Watch write events as they arrive while a change is reproduced, stop with Ctrl+C
+  osdctl cloudtrail write-events -C <cluster-id> --since 10m --follow`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return ops.run()
 		},
 	}
 	listEventsCmd.Flags().StringVarP(&ops.ClusterID, "cluster-id", "C", "", "Cluster ID")
-	listEventsCmd.Flags().StringVarP(&ops.StartTime, "since", "", "1h", "Specifies that only events that occur within the specified time are returned.Defaults to 1h.Valid time units are \"ns\", \"us\" (or \"µs\"), \"ms\", \"s\", \"m\", \"h\".")
 	listEventsCmd.Flags().BoolVarP(&ops.PrintUrl, "url", "u", false, "Generates Url link to cloud console cloudtrail event")
 	listEventsCmd.Flags().BoolVarP(&ops.PrintRaw, "raw-event", "r", false, "Prints the cloudtrail events to the console in raw json format")
 	listEventsCmd.Flags().BoolVarP(&ops.PrintAll, "all", "A", false, "Prints all cloudtrail write events without filtering")
+	listEventsCmd.Flags().BoolVarP(&ops.Follow, "follow", "f", false, "Keep polling for new events and print them as they arrive, only with the text and ndjson outputs")
+	listEventsCmd.Flags().DurationVar(&ops.PollInterval, "poll-interval", 30*time.Second, "How often new events are polled with --follow")
+	ops.Query.AddFlags(listEventsCmd, "1h")
+	ops.Lookup.addFlags(listEventsCmd)
+	ctUtil.AddOutputFlag(listEventsCmd, &ops.Output)
 	listEventsCmd.MarkFlagRequired("cluster-id")
 	return listEventsCmd
 }
@@ -102,6 +115,23 @@ func (o *writeEventsOptions) run() error {
 	if err != nil {
 		return err
 	}
+	if err := ctUtil.ValidateOutput(o.Output); err != nil {
+		return err
+	}
+	if err := o.Query.Complete(time.Now().UTC()); err != nil {
+		return err
+	}
+	if o.Follow {
+		if o.Query.Until != "" {
+			return fmt.Errorf("--follow can't be used with --until")
+		}
+		if !slices.Contains(followOutputFormats, o.Output) {
+			return fmt.Errorf("--follow only supports the %s outputs", strings.Join(followOutputFormats, " and "))
+		}
+		if o.PollInterval < time.Second {
+			return fmt.Errorf("--poll-interval must be at least 1s")
+		}
+	}
 	connection, err := utils.CreateConnection()
 	if err != nil {
 		return fmt.Errorf("unable to create connection to ocm: %w", err)
@@ -121,7 +151,7 @@ func (o *writeEventsOptions) run() error {
 		return fmt.Errorf("[ERROR] error Loading cloudtrail configuration file: %w", err)
 	}
 	if len(Ignore) == 0 {
-		fmt.Println("\n[WARNING] No filter list detected! If you want intend to apply user filtering for the cloudtrail events, please add cloudtrail_cmd_lists to your osdctl configuration file.")
+		fmt.Fprintln(os.Stderr, "\n[WARNING] No filter list detected! If you want intend to apply user filtering for the cloudtrail events, please add cloudtrail_cmd_lists to your osdctl configuration file.")
 
 	}
 
@@ -133,67 +163,104 @@ func (o *writeEventsOptions) run() error {
 	if err != nil {
 		return err
 	}
-	DefaultRegion := "us-east-1"
-	startTime, err := ctUtil.ParseDurationToUTC(o.StartTime)
-	if err != nil {
-		return err
-	}
-
-	// FilterAndPrintEvents fetches events and filters them based on a regex string.
-	// It then prints the filtered events.
 
 	arn, accountId, err := ctAws.Whoami(*sts.NewFromConfig(cfg))
 	if err != nil {
 		return err
 	}
-	fmt.Printf("[INFO] Checking write event history since %v for AWS Account %v as %v \n", startTime, accountId, arn)
-	cloudTrailclient := cloudtrail.NewFromConfig(cfg)
-	fmt.Printf("[INFO] Fetching %v Event History...", cfg.Region)
-	queriedEvents, err := ctAws.GetEvents(cloudTrailclient, startTime, true)
+	fmt.Fprintf(os.Stderr, "[INFO] Checking write event history between %v and %v for AWS Account %v as %v \n", o.Query.StartTime, o.Query.EndTime, accountId, arn)
+	queriedEvents, err := fetchEvents(cfg, accountId, &o.Query, &o.Lookup, true)
 	if err != nil {
 		return err
 	}
 
-	filteredEvents, err := ctUtil.ApplyFilters(queriedEvents,
-		func(event types.Event) (bool, error) {
-			return isIgnoredEvent(event, mergedRegex)
-		},
-	)
+	// Filter out ignored users, then apply the query
+	filter := func(events []types.Event) ([]types.Event, error) {
+		return ctUtil.ApplyFilters(events,
+			func(event types.Event) (bool, error) {
+				return isIgnoredEvent(event, mergedRegex)
+			},
+			o.Query.Filter(),
+		)
+	}
+	filteredEvents, err := filter(queriedEvents)
 	if err != nil {
 		return err
 	}
 
-	ctUtil.PrintEvents(filteredEvents, o.PrintUrl, o.PrintRaw)
-	fmt.Println("")
+	if err := ctUtil.PrintFormattedEvents(os.Stdout, filteredEvents, o.Output, o.PrintUrl, o.PrintRaw); err != nil || !o.Follow {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "[INFO] Following new write events every %v, press Ctrl+C to stop\n", o.PollInterval)
 
-	if DefaultRegion != cfg.Region {
-		defaultConfig, err := config.LoadDefaultConfig(
-			context.Background(),
-			config.WithRegion(DefaultRegion))
-		if err != nil {
-			return err
+	follower := newEventFollower(filteredEvents)
+	poll := o.Lookup
+	poll.NoCache = true
+	poll.TrailBucket = ""
+	poll.quiet = true
+	ticker := time.NewTicker(o.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
 		}
 
-		defaultCloudtrailClient := cloudtrail.New(cloudtrail.Options{
-			Region:      DefaultRegion,
-			Credentials: cfg.Credentials,
-			HTTPClient:  cfg.HTTPClient,
-		})
-		fmt.Printf("[INFO] Fetching Cloudtrail Global Event History from %v Region...", defaultConfig.Region)
-		lookupOutput, err := ctAws.GetEvents(defaultCloudtrailClient, startTime, true)
+		query := o.Query
+		query.EndTime = time.Now().UTC()
+		query.StartTime = query.EndTime.Add(-followLookback)
+		polledEvents, err := fetchEvents(cfg, accountId, &query, &poll, true)
 		if err != nil {
-			return err
+			// A failed poll is retried at the next tick rather than stopping the tail
+			fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+			continue
 		}
-		filteredEvents, err := ctUtil.ApplyFilters(lookupOutput,
-			func(event types.Event) (bool, error) {
-				return isIgnoredEvent(event, mergedRegex)
-			},
-		)
+		newEvents, err := filter(follower.next(polledEvents, query.StartTime))
 		if err != nil {
 			return err
 		}
-		ctUtil.PrintEvents(filteredEvents, o.PrintUrl, o.PrintRaw)
+		if len(newEvents) == 0 {
+			continue
+		}
+		if err := ctUtil.PrintFormattedEvents(os.Stdout, newEvents, o.Output, o.PrintUrl, o.PrintRaw); err != nil {
+			return err
+		}
 	}
+}
 
-	return err
+// eventFollower remembers the IDs of the events already printed by --follow, polls
+// overlap so cloudtrail events delivered late aren't missed
+type eventFollower struct {
+	seen map[string]time.Time
+}
+
+func newEventFollower(printed []types.Event) *eventFollower {
+	f := &eventFollower{seen: map[string]time.Time{}}
+	f.next(printed, time.Time{})
+	return f
+}
+
+// next returns the events which weren't returned before, IDs of events older than
+// windowStart can't be polled again and are forgotten
+func (f *eventFollower) next(events []types.Event, windowStart time.Time) []types.Event {
+	for id, eventTime := range f.seen {
+		if eventTime.Before(windowStart) {
+			delete(f.seen, id)
+		}
+	}
+	newEvents := []types.Event{}
+	for _, event := range events {
+		if event.EventId != nil {
+			if _, ok := f.seen[*event.EventId]; ok {
+				continue
+			}
+			f.seen[*event.EventId] = aws.ToTime(event.EventTime)
+		}
+		newEvents = append(newEvents, event)
+	}
+	return newEvents
 }
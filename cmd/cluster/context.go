@@ -1,30 +1,33 @@
 package cluster
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	sdk "github.com/openshift-online/ocm-sdk-go"
 	v1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
 
-	"github.com/openshift/osdctl/cmd/servicelog"
-
 	pd "github.com/PagerDuty/go-pagerduty"
 	"github.com/andygrunwald/go-jira"
 	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
 	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ctUtil "github.com/openshift/osdctl/cmd/cloudtrail/pkg"
+	ctAws "github.com/openshift/osdctl/cmd/cloudtrail/pkg/aws"
 	"github.com/openshift/osdctl/cmd/dynatrace"
 	"github.com/openshift/osdctl/pkg/osdCloud"
 	"github.com/openshift/osdctl/pkg/osdctlConfig"
 	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/openshift/osdctl/pkg/provider/aws"
 	"github.com/openshift/osdctl/pkg/provider/pagerduty"
 	"github.com/openshift/osdctl/pkg/utils"
 	"github.com/spf13/cobra"
@@ -41,8 +44,14 @@ const (
 	longOutputConfigValue         = "long"
 	jsonOutputConfigValue         = "json"
 	delimiter                     = ">> "
+
+	// cloudTrailPageSize is the number of events returned per LookupEvents page
+	cloudTrailPageSize = 50
 )
 
+// errCloudTrailPagesExhausted stops the cached cloudtrail lookup once it used up its pages
+var errCloudTrailPagesExhausted = errors.New("cloudtrail page limit reached")
+
 type contextOptions struct {
 	cluster *cmv1.Cluster
 
@@ -55,12 +64,18 @@ type contextOptions struct {
 	organizationID    string
 	days              int
 	pages             int
+	noCache           bool
 	oauthtoken        string
 	usertoken         string
 	infraID           string
 	awsProfile        string
 	jiratoken         string
 	team_ids          []string
+	sections          []string
+	skipSections      []string
+	saveFile          string
+
+	ocmClient *sdk.Connection
 }
 
 type contextData struct {
@@ -100,6 +115,11 @@ type contextData struct {
 	UserBanned     bool
 	BanCode        string
 	BanDescription string
+
+	// Names of the collected sections, empty in contexts saved before they were recorded
+	Sections []string `json:",omitempty"`
+	// Errors encountered while collecting the sections, keyed by section name
+	SectionErrors map[string]string `json:",omitempty"`
 }
 
 // newCmdContext implements the context command to show the current context of a cluster
@@ -124,16 +144,22 @@ func newCmdContext() *cobra.Command {
 	contextCmd.Flags().StringVarP(&ops.clusterID, "cluster-id", "c", "", "Provide internal ID of the cluster")
 	_ = contextCmd.MarkFlagRequired("cluster-id")
 
-	contextCmd.Flags().StringVarP(&ops.output, "output", "o", "long", "Valid formats are ['long', 'short', 'json']. Output is set to 'long' by default")
+	contextCmd.Flags().StringVarP(&ops.output, "output", "o", "long", "Valid formats are ['long', 'short', 'json', 'markdown', 'html']. Output is set to 'long' by default")
 	contextCmd.Flags().StringVarP(&ops.awsProfile, "profile", "p", "", "AWS Profile")
 	contextCmd.Flags().BoolVarP(&ops.verbose, "verbose", "", false, "Verbose output")
 	contextCmd.Flags().BoolVar(&ops.full, "full", false, "Run full suite of checks.")
 	contextCmd.Flags().IntVarP(&ops.days, "days", "d", 30, "Command will display X days of Error SLs sent to the cluster. Days is set to 30 by default")
 	contextCmd.Flags().IntVar(&ops.pages, "pages", 40, "Command will display X pages of Cloud Trail logs for the cluster. Pages is set to 40 by default")
+	contextCmd.Flags().BoolVar(&ops.noCache, "no-cache", false, "Don't read or update the local cache of Cloud Trail logs")
 	contextCmd.Flags().StringVar(&ops.oauthtoken, "oauthtoken", "", fmt.Sprintf("Pass in PD oauthtoken directly. If not passed in, by default will read `pd_oauth_token` from ~/.config/%s.\nPD OAuth tokens can be generated by visiting %s", osdctlConfig.ConfigFileName, PagerDutyTokenRegistrationUrl))
 	contextCmd.Flags().StringVar(&ops.usertoken, "usertoken", "", fmt.Sprintf("Pass in PD usertoken directly. If not passed in, by default will read `pd_user_token` from ~/config/%s", osdctlConfig.ConfigFileName))
 	contextCmd.Flags().StringVar(&ops.jiratoken, "jiratoken", "", fmt.Sprintf("Pass in the Jira access token directly. If not passed in, by default will read `jira_token` from ~/.config/%s.\nJira access tokens can be registered by visiting %s/%s", osdctlConfig.ConfigFileName, JiraBaseURL, JiraTokenRegistrationPath))
+	contextCmd.Flags().StringSliceVar(&ops.sections, "sections", []string{}, fmt.Sprintf("Only collect the given sections. Valid sections are %v", contextSectionNames()))
+	contextCmd.Flags().StringSliceVar(&ops.skipSections, "skip-sections", []string{}, "Do not collect the given sections")
+	contextCmd.Flags().StringVar(&ops.saveFile, "save", "", "Save the collected context as a snapshot to the given file, to be compared later with 'osdctl cluster context diff'")
 	contextCmd.Flags().StringArrayVarP(&ops.team_ids, "team-ids", "t", []string{}, fmt.Sprintf("Pass in PD team IDs directly to filter the PD Alerts by team. Can also be defined as `team_ids` in ~/.config/%s\nWill show all PD Alerts for all PD service IDs if none is defined", osdctlConfig.ConfigFileName))
+
+	contextCmd.AddCommand(newCmdContextDiff())
 	return contextCmd
 }
 
@@ -199,21 +225,32 @@ func (o *contextOptions) run() error {
 		printFunc = o.printLongOutput
 	case jsonOutputConfigValue:
 		printFunc = o.printJsonOutput
+	case markdownOutputConfigValue:
+		printFunc = o.printMarkdownOutput
+	case htmlOutputConfigValue:
+		printFunc = o.printHTMLOutput
 	default:
 		return fmt.Errorf("unknown Output Format: %s", o.output)
 	}
 
-	currentData, dataErrors := o.generateContextData()
-	if currentData == nil {
-		fmt.Fprintf(os.Stderr, "Failed to query cluster info: %+v", dataErrors)
-		os.Exit(1)
+	collectors, err := o.selectedCollectors()
+	if err != nil {
+		return err
+	}
+
+	currentData, err := o.generateContextData(collectors)
+	defer o.closeClients()
+	if err != nil {
+		return fmt.Errorf("failed to query cluster info: %v", err)
 	}
 
-	if len(dataErrors) > 0 {
-		fmt.Fprintf(os.Stderr, "Encountered Errors during data collection. Displayed data may be incomplete: \n")
-		for _, dataError := range dataErrors {
-			fmt.Fprintf(os.Stderr, "\t%v\n", dataError)
+	printSectionErrors(currentData.SectionErrors, os.Stderr)
+
+	if o.saveFile != "" {
+		if err := saveContextSnapshot(currentData, o.saveFile); err != nil {
+			return fmt.Errorf("failed to save context snapshot: %v", err)
 		}
+		fmt.Fprintf(os.Stderr, "Saved context snapshot to %s\n", o.saveFile)
 	}
 
 	printFunc(currentData, os.Stdout)
@@ -224,88 +261,42 @@ func (o *contextOptions) run() error {
 func (o *contextOptions) printLongOutput(data *contextData, w io.Writer) {
 	data.printClusterHeader(w)
 
-	fmt.Fprintln(w, strings.TrimSpace(data.Description))
-	fmt.Println()
-	utils.PrintLimitedSupportReasons(data.LimitedSupportReasons)
-	fmt.Println()
-	printJIRASupportExceptions(data.SupportExceptions, w)
-	fmt.Println()
-	utils.PrintServiceLogs(data.ServiceLogs, o.verbose, o.days)
-	fmt.Println()
-	utils.PrintJiraIssues(data.JiraIssues)
-	fmt.Println()
-	utils.PrintPDAlerts(data.PdAlerts, data.pdServiceID)
-	fmt.Println()
-
-	if o.full {
-		printHistoricalPDAlertSummary(data.HistoricalAlerts, data.pdServiceID, o.days, w)
-		fmt.Println()
-
-		printCloudTrailLogs(data.CloudtrailEvents, w)
-		fmt.Println()
+	collectors, err := o.selectedCollectors()
+	if err != nil {
+		fmt.Fprintf(w, "Error printing Long Output: %v\n", err)
+		return
 	}
 
-	// Print other helpful links
-	o.printOtherLinks(data, w)
-	fmt.Println()
-
-	// Print Dynatrace URL
-	printDynatraceResources(data, w)
-
-	// Print User Banned Details
-	printUserBannedStatus(data, w)
+	for _, c := range collectors {
+		c.PrintLong(o, data, w)
+		if sectionErr, found := data.SectionErrors[c.Name()]; found {
+			fmt.Fprintf(w, "(incomplete, %s)\n", sectionErr)
+		}
+		fmt.Fprintln(w)
+	}
 }
 
 func (o *contextOptions) printShortOutput(data *contextData, w io.Writer) {
 	data.printClusterHeader(w)
 
-	highAlertCount := 0
-	lowAlertCount := 0
-	for _, alerts := range data.PdAlerts {
-		for _, alert := range alerts {
-			if strings.ToLower(alert.Urgency) == "high" {
-				highAlertCount++
-			} else {
-				lowAlertCount++
-			}
-		}
-	}
-
-	historicalAlertsString := "N/A"
-	historicalAlertsCount := 0
-	if data.HistoricalAlerts != nil {
-		for _, histAlerts := range data.HistoricalAlerts {
-			for _, histAlert := range histAlerts {
-				historicalAlertsCount += histAlert.Count
-			}
-		}
-		historicalAlertsString = fmt.Sprintf("%d", historicalAlertsCount)
+	collectors, err := o.selectedCollectors()
+	if err != nil {
+		fmt.Fprintf(w, "Error printing Short Output: %v\n", err)
+		return
 	}
 
-	var numInternalServiceLogs int
-	for _, serviceLog := range data.ServiceLogs {
-		if serviceLog.InternalOnly() {
-			numInternalServiceLogs++
+	headers := []string{"Version"}
+	values := []string{data.ClusterVersion}
+	for _, c := range collectors {
+		for _, column := range c.ShortColumns(o, data) {
+			headers = append(headers, column[0])
+			values = append(values, column[1])
 		}
 	}
 
 	table := printer.NewTablePrinter(w, 20, 1, 2, ' ')
-	table.AddRow([]string{
-		"Version",
-		"Supported?",
-		fmt.Sprintf("SLs (last %d d)", o.days),
-		"Jira Tickets",
-		"Current Alerts",
-		fmt.Sprintf("Historical Alerts (last %d d)", o.days),
-	})
-	table.AddRow([]string{
-		data.ClusterVersion,
-		fmt.Sprintf("%t", len(data.LimitedSupportReasons) == 0),
-		fmt.Sprintf("%d (%d internal)", len(data.ServiceLogs), numInternalServiceLogs),
-		fmt.Sprintf("%d", len(data.JiraIssues)),
-		fmt.Sprintf("H: %d | L: %d", highAlertCount, lowAlertCount),
-		historicalAlertsString,
-	})
+	table.AddRow(headers)
+	table.AddRow(values)
 
 	if err := table.Flush(); err != nil {
 		fmt.Fprintf(w, "Error printing Short Output: %v\n", err)
@@ -322,45 +313,27 @@ func (o *contextOptions) printJsonOutput(data *contextData, w io.Writer) {
 	fmt.Fprintln(w, string(jsonOut))
 }
 
-// generateContextData Creates a contextData struct that contains all the
-// cluster context information requested by the contextOptions. if a certain
-// data point can not be queried, the appropriate field will be null and the
-// errors array will contain information about the error. The first return
-// value will only be nil, if this function fails to get basic cluster
-// information. The second return value will *never* be nil, but instead have a
-// length of 0 if no errors occurred
-func (o *contextOptions) generateContextData() (*contextData, []error) {
+// generateContextData Creates a contextData struct that contains the cluster
+// context information gathered by the given collectors. If a section can not
+// be queried, its fields will be left empty and the error is recorded in
+// SectionErrors under the section name. An error is only returned if this
+// function fails to get basic cluster information.
+func (o *contextOptions) generateContextData(collectors []contextCollector) (*contextData, error) {
 	data := &contextData{}
-	errors := []error{}
-
-	wg := sync.WaitGroup{}
-
-	// For PD query dependencies
-	pdwg := sync.WaitGroup{}
-	var skipPagerDutyCollection bool
-	pdProvider, err := pagerduty.NewClient().
-		WithUserToken(o.usertoken).
-		WithOauthToken(o.oauthtoken).
-		WithBaseDomain(o.baseDomain).
-		WithTeamIdList(viper.GetStringSlice(pagerduty.PagerDutyTeamIDsKey)).
-		Init()
-	if err != nil {
-		skipPagerDutyCollection = true
-		errors = append(errors, fmt.Errorf("skipping PagerDuty context collection: %v", err))
-	}
 
+	// The connection is closed by closeClients once the output is rendered
 	ocmClient, err := utils.CreateConnection()
 	if err != nil {
-		return nil, []error{err}
+		return nil, err
 	}
-	defer ocmClient.Close()
+	o.ocmClient = ocmClient
+
 	// Normally the o.cluster would be set by complete function, but in case we want to call this function
 	// in an other context, we can make sure o.cluster is set properly from o.clusterID
 	if o.cluster == nil {
 		cluster, err := utils.GetCluster(ocmClient, o.clusterID)
 		if err != nil {
-			errors = append(errors, err)
-			return nil, errors
+			return nil, err
 		}
 		o.cluster = cluster
 	}
@@ -370,194 +343,71 @@ func (o *contextOptions) generateContextData() (*contextData, []error) {
 	data.ClusterVersion = o.cluster.Version().RawID()
 	data.OCMEnv = utils.GetCurrentOCMEnv(ocmClient)
 
-	GetLimitedSupport := func() {
-		defer wg.Done()
-		defer utils.StartDelayTracker(o.verbose, "Limited Support reasons").End()
-		limitedSupportReasons, err := utils.GetClusterLimitedSupportReasons(ocmClient, o.clusterID)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error while getting Limited Support status reasons: %v", err))
-		} else {
-			data.LimitedSupportReasons = append(data.LimitedSupportReasons, limitedSupportReasons...)
-		}
+	for _, c := range collectors {
+		data.Sections = append(data.Sections, c.Name())
 	}
-
-	GetServiceLogs := func() {
-		defer wg.Done()
-		defer utils.StartDelayTracker(o.verbose, "Service Logs").End()
-		timeToCheckSvcLogs := time.Now().AddDate(0, 0, -o.days)
-		data.ServiceLogs, err = servicelog.GetServiceLogsSince(o.clusterID, timeToCheckSvcLogs, false, false)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error while getting the service logs: %v", err))
+	sectionErrors := o.runCollectors(data, collectors)
+	if len(sectionErrors) > 0 {
+		data.SectionErrors = map[string]string{}
+		for name, sectionErr := range sectionErrors {
+			data.SectionErrors[name] = sectionErr.Error()
 		}
 	}
 
-	GetBannedUser := func() {
-		defer wg.Done()
-		defer utils.StartDelayTracker(o.verbose, "Check Banned User").End()
-		subscription, err := utils.GetSubscription(ocmClient, data.ClusterID)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error while getting subscripton %v", err))
-		}
-		creator, err := utils.GetAccount(ocmClient, subscription.Creator().ID())
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error while checking if user is banned %v", err))
-		}
-		data.UserBanned = creator.Banned()
-		data.BanCode = creator.BanCode()
-		data.BanDescription = creator.BanDescription()
-	}
+	return data, nil
+}
 
-	GetJiraIssues := func() {
-		defer wg.Done()
-		defer utils.StartDelayTracker(o.verbose, "Jira Issues").End()
-		data.JiraIssues, err = utils.GetJiraIssuesForCluster(o.clusterID, o.externalClusterID, o.jiratoken)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error while getting the open jira tickets: %v", err))
-		}
+// closeClients closes the clients shared by the collectors. A Fetch which timed out may
+// still be using them, so they are only closed once the output is rendered.
+func (o *contextOptions) closeClients() {
+	if o.ocmClient == nil {
+		return
 	}
-
-	GetSupportExceptions := func() {
-		defer wg.Done()
-		defer utils.StartDelayTracker(o.verbose, "Support Exceptions").End()
-		data.SupportExceptions, err = utils.GetJiraSupportExceptionsForOrg(o.organizationID, o.jiratoken)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error while getting support exceptions: %v", err))
-		}
+	if err := o.ocmClient.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot close the ocmClient (possible memory leak): %q\n", err)
 	}
+}
 
-	GetDynatraceDetails := func() {
-		var clusterID string = o.clusterID
-		defer wg.Done()
-		defer utils.StartDelayTracker(o.verbose, "Dynatrace URL").End()
-
-		hcpCluster, err := dynatrace.FetchClusterDetails(clusterID)
-		if err != nil {
-			if err == dynatrace.ErrUnsupportedCluster {
-				data.DyntraceEnvURL = dynatrace.ErrUnsupportedCluster.Error()
-			} else {
-				errors = append(errors, fmt.Errorf("failed to acquire cluster details %v", err))
-				data.DyntraceEnvURL = "Failed to fetch Dynatrace URL"
-			}
-			return
-		} else {
-			query, err := dynatrace.GetQuery(hcpCluster)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("failed to build query for Dynatrace %v", err))
-			}
-			queryTxt := query.Build()
-			data.DyntraceEnvURL = hcpCluster.DynatraceURL
-			data.DyntraceLogsURL, err = dynatrace.GetLinkToWebConsole(hcpCluster.DynatraceURL, 10, queryTxt)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("failed to get url: %v", err))
-			}
-		}
+// GetCloudTrailLogsForCluster returns the recent events of the cluster's account, no further
+// pages are looked up once ctx is done
+func GetCloudTrailLogsForCluster(ctx context.Context, awsProfile string, clusterID string, region string, maxPages int, noCache bool) ([]*types.Event, error) {
+	awsJumpClient, err := osdCloud.GenerateAWSClientForCluster(awsProfile, clusterID)
+	if err != nil {
+		return nil, err
 	}
 
-	GetPagerDutyAlerts := func() {
-		pdwg.Add(1)
-		defer wg.Done()
-		defer pdwg.Done()
-
-		if skipPagerDutyCollection {
-			return
-		}
-
-		delayTracker := utils.StartDelayTracker(o.verbose, "PagerDuty Service")
-		data.pdServiceID, err = pdProvider.GetPDServiceIDs()
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error getting PD Service ID: %v", err))
-		}
-		delayTracker.End()
-
-		defer utils.StartDelayTracker(o.verbose, "current PagerDuty Alerts").End()
-		data.PdAlerts, err = pdProvider.GetFiringAlertsForCluster(data.pdServiceID)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error while getting current PD Alerts: %v", err))
-		}
+	var foundEvents []types.Event
+	if noCache {
+		foundEvents, err = lookupCloudTrailPages(ctx, awsJumpClient, cloudtrail.LookupEventsInput{}, maxPages)
+	} else {
+		foundEvents, err = lookupCachedCloudTrailEvents(ctx, awsJumpClient, region, maxPages)
 	}
-
-	var retrievers []func()
-
-	retrievers = append(
-		retrievers,
-		GetLimitedSupport,
-		GetServiceLogs,
-		GetJiraIssues,
-		GetSupportExceptions,
-		GetPagerDutyAlerts,
-		GetDynatraceDetails,
-		GetBannedUser,
-	)
-
-	if o.output == longOutputConfigValue {
-
-		GetDescription := func() {
-			defer wg.Done()
-			defer utils.StartDelayTracker(o.verbose, "Cluster Description").End()
-
-			cmd := "ocm describe cluster " + o.clusterID
-			output, err := exec.Command("bash", "-c", cmd).Output()
-			if err != nil {
-				fmt.Fprintln(os.Stderr, string(output))
-				fmt.Fprintln(os.Stderr, err)
-			}
-			data.Description = string(output)
-		}
-
-		retrievers = append(
-			retrievers,
-			GetDescription,
-		)
+	if err != nil {
+		return nil, err
 	}
 
-	if o.full {
-		GetHistoricalPagerDutyAlerts := func() {
-			pdwg.Wait()
-			defer wg.Done()
-			defer utils.StartDelayTracker(o.verbose, "historical PagerDuty Alerts").End()
-			data.HistoricalAlerts, err = pdProvider.GetHistoricalAlertsForCluster(data.pdServiceID)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("error while getting historical PD Alert Data: %v", err))
-			}
+	var filteredEvents []*types.Event
+	for _, event := range foundEvents {
+		if skippableEvent(*event.EventName) {
+			continue
 		}
-
-		GetCloudTrailLogs := func() {
-			defer wg.Done()
-			defer utils.StartDelayTracker(o.verbose, fmt.Sprintf("past %d pages of Cloudtrail data", o.pages)).End()
-			data.CloudtrailEvents, err = GetCloudTrailLogsForCluster(o.awsProfile, o.clusterID, o.pages)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("error getting cloudtrail logs for cluster: %v", err))
-			}
+		if event.Username != nil && strings.Contains(*event.Username, "RH-SRE-") {
+			continue
 		}
-
-		retrievers = append(
-			retrievers,
-			GetHistoricalPagerDutyAlerts,
-			GetCloudTrailLogs,
-		)
-	}
-
-	for _, retriever := range retrievers {
-		wg.Add(1)
-		go retriever()
+		filteredEvents = append(filteredEvents, &event)
 	}
 
-	wg.Wait()
-
-	return data, errors
+	return filteredEvents, nil
 }
 
-func GetCloudTrailLogsForCluster(awsProfile string, clusterID string, maxPages int) ([]*types.Event, error) {
-	awsJumpClient, err := osdCloud.GenerateAWSClientForCluster(awsProfile, clusterID)
-	if err != nil {
-		return nil, err
-	}
-
+// lookupCloudTrailPages returns the events of up to maxPages+1 pages of LookupEvents, newest first
+func lookupCloudTrailPages(ctx context.Context, awsJumpClient aws.Client, eventSearchInput cloudtrail.LookupEventsInput, maxPages int) ([]types.Event, error) {
 	var foundEvents []types.Event
-
-	eventSearchInput := cloudtrail.LookupEventsInput{}
-
 	for counter := 0; counter <= maxPages; counter++ {
+		// The aws client doesn't take a context, stop between the pages instead
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		print(".")
 		cloudTrailEvents, err := awsJumpClient.LookupEvents(&eventSearchInput)
 		if err != nil {
@@ -572,18 +422,83 @@ func GetCloudTrailLogsForCluster(awsProfile string, clusterID string, maxPages i
 			break
 		}
 	}
-	var filteredEvents []*types.Event
-	for _, event := range foundEvents {
-		if skippableEvent(*event.EventName) {
-			continue
-		}
-		if event.Username != nil && strings.Contains(*event.Username, "RH-SRE-") {
-			continue
+	return foundEvents, nil
+}
+
+// lookupCachedCloudTrailEvents returns the same most recent events as lookupCloudTrailPages,
+// but goes back in time through growing windows read from the local cache, so only the
+// events which aren't cached yet are looked up. At most maxPages+1 pages are looked up
+// over all the windows.
+func lookupCachedCloudTrailEvents(ctx context.Context, awsJumpClient aws.Client, region string, maxPages int) ([]types.Event, error) {
+	cache, err := ctAws.NewEventCache("", ctAws.DefaultEventCacheMaxAge)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] Not using the cloudtrail cache: %v\n", err)
+		return lookupCloudTrailPages(ctx, awsJumpClient, cloudtrail.LookupEventsInput{}, maxPages)
+	}
+	identity, err := awsJumpClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	pagesLeft := maxPages + 1
+	// partialEvents are the newest events of the range which ran out of pages
+	var partialEvents []types.Event
+	fetch := func(start time.Time, end time.Time) ([]types.Event, error) {
+		// The window is bounded, page through all of it so it can be cached
+		var events []types.Event
+		input := cloudtrail.LookupEventsInput{StartTime: &start, EndTime: &end}
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if pagesLeft == 0 {
+				// An incomplete range mustn't be cached
+				partialEvents = events
+				return nil, errCloudTrailPagesExhausted
+			}
+			pagesLeft--
+			print(".")
+			output, err := awsJumpClient.LookupEvents(&input)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, output.Events...)
+			if output.NextToken == nil {
+				return events, nil
+			}
+			input.NextToken = output.NextToken
 		}
-		filteredEvents = append(filteredEvents, &event)
 	}
 
-	return filteredEvents, nil
+	wanted := (maxPages + 1) * cloudTrailPageSize
+	now := time.Now().UTC()
+	oldest := now.Add(-ctAws.LookupEventsRetention)
+	var foundEvents []types.Event
+	end := now
+	for window := time.Hour; len(foundEvents) < wanted && end.After(oldest); window *= 2 {
+		start := end.Add(-window)
+		if start.Before(oldest) {
+			start = oldest
+		}
+		events, err := cache.GetEvents(*identity.Account, region, false, start, end, fetch)
+		if errors.Is(err, errCloudTrailPagesExhausted) {
+			// The events newer than the incomplete range are returned along with the error
+			foundEvents = append(foundEvents, events...)
+			foundEvents = append(foundEvents, partialEvents...)
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		foundEvents = append(foundEvents, events...)
+		end = start
+	}
+	// Events at the boundary of two windows are returned twice
+	foundEvents = ctUtil.DedupeEvents(foundEvents)
+	if len(foundEvents) > wanted {
+		foundEvents = foundEvents[:wanted]
+	}
+	return foundEvents, nil
 }
 
 func printHistoricalPDAlertSummary(incidentCounters map[string][]*pagerduty.IncidentOccurrenceTracker, serviceIDs []string, sinceDays int, w io.Writer) {
@@ -634,17 +549,7 @@ func (o *contextOptions) printOtherLinks(data *contextData, w io.Writer) {
 	var name string = "External resources"
 	fmt.Fprintln(w, delimiter+name)
 
-	links := map[string]string{
-		"OHSS Cards":        fmt.Sprintf("%s/issues/?jql=project%%20%%3D%%20OHSS%%20and%%20(%%22Cluster%%20ID%%22%%20~%%20%%20%%22%s%%22%%20OR%%20%%22Cluster%%20ID%%22%%20~%%20%%22%s%%22)", JiraBaseURL, o.clusterID, o.externalClusterID),
-		"CCX dashboard":     fmt.Sprintf("https://kraken.psi.redhat.com/clusters/%s", o.externalClusterID),
-		"Splunk Audit Logs": o.buildSplunkURL(data),
-	}
-
-	if data.pdServiceID != nil {
-		for _, id := range data.pdServiceID {
-			links[fmt.Sprintf("PagerDuty Service %s", id)] = fmt.Sprintf("https://redhat.pagerduty.com/service-directory/%s", id)
-		}
-	}
+	links := o.otherLinks(data)
 
 	// Sort, so it's always a predictable order
 	var keys []string
@@ -663,6 +568,23 @@ func (o *contextOptions) printOtherLinks(data *contextData, w io.Writer) {
 	}
 }
 
+// otherLinks returns helpful external links for the cluster, keyed by name
+func (o *contextOptions) otherLinks(data *contextData) map[string]string {
+	links := map[string]string{
+		"OHSS Cards":        fmt.Sprintf("%s/issues/?jql=project%%20%%3D%%20OHSS%%20and%%20(%%22Cluster%%20ID%%22%%20~%%20%%20%%22%s%%22%%20OR%%20%%22Cluster%%20ID%%22%%20~%%20%%22%s%%22)", JiraBaseURL, o.clusterID, o.externalClusterID),
+		"CCX dashboard":     fmt.Sprintf("https://kraken.psi.redhat.com/clusters/%s", o.externalClusterID),
+		"Splunk Audit Logs": o.buildSplunkURL(data),
+	}
+
+	if data.pdServiceID != nil {
+		for _, id := range data.pdServiceID {
+			links[fmt.Sprintf("PagerDuty Service %s", id)] = fmt.Sprintf("https://redhat.pagerduty.com/service-directory/%s", id)
+		}
+	}
+
+	return links
+}
+
 func (o *contextOptions) buildSplunkURL(data *contextData) string {
 	// Determine the relevant Splunk URL
 	if o.cluster.Hypershift().Enabled() {
@@ -0,0 +1,558 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/osdctl/cmd/dynatrace"
+	"github.com/openshift/osdctl/cmd/servicelog"
+	"github.com/openshift/osdctl/pkg/provider/pagerduty"
+	"github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/viper"
+)
+
+const defaultCollectorTimeout = 2 * time.Minute
+
+// contextCollector gathers and renders one section of the cluster context.
+// Collectors run concurrently, each bound by its own timeout. A collector's
+// Fetch is handed a private copy of the base cluster data and only has its
+// results merged back into the shared contextData if it returns in time.
+type contextCollector interface {
+	// Name is the identifier used by --sections and --skip-sections
+	Name() string
+	// EnabledByDefault reports whether the section is collected when no
+	// sections were explicitly requested
+	EnabledByDefault(o *contextOptions) bool
+	// Timeout is the maximum time the section may take to be collected
+	Timeout() time.Duration
+	// Fetch populates the fields owned by this collector in data
+	Fetch(ctx context.Context, o *contextOptions, data *contextData) error
+	// Merge copies the fields owned by this collector from src into dst
+	Merge(dst, src *contextData)
+	// PrintLong renders the section for the long output
+	PrintLong(o *contextOptions, data *contextData, w io.Writer)
+	// ShortColumns returns the header/value pairs the section contributes
+	// to the short output table
+	ShortColumns(o *contextOptions, data *contextData) [][2]string
+}
+
+var contextCollectors []contextCollector
+
+// registerContextCollector adds a collector to the set of sections available
+// to `cluster context`. Sections are rendered in registration order.
+func registerContextCollector(c contextCollector) {
+	for _, existing := range contextCollectors {
+		if existing.Name() == c.Name() {
+			panic(fmt.Sprintf("context collector %q registered twice", c.Name()))
+		}
+	}
+	contextCollectors = append(contextCollectors, c)
+}
+
+func init() {
+	registerContextCollector(descriptionCollector{})
+	registerContextCollector(limitedSupportCollector{})
+	registerContextCollector(supportExceptionsCollector{})
+	registerContextCollector(serviceLogsCollector{})
+	registerContextCollector(jiraIssuesCollector{})
+	registerContextCollector(pagerDutyCollector{})
+	registerContextCollector(cloudTrailCollector{})
+	registerContextCollector(externalLinksCollector{})
+	registerContextCollector(dynatraceCollector{})
+	registerContextCollector(bannedUserCollector{})
+}
+
+// contextSectionNames returns the names of all registered sections
+func contextSectionNames() []string {
+	names := make([]string, 0, len(contextCollectors))
+	for _, c := range contextCollectors {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+// selectedCollectors resolves --sections and --skip-sections against the
+// registered collectors. When no sections are requested explicitly, the
+// collectors enabled by default are used.
+func (o *contextOptions) selectedCollectors() ([]contextCollector, error) {
+	known := map[string]bool{}
+	for _, c := range contextCollectors {
+		known[c.Name()] = true
+	}
+
+	requested := map[string]bool{}
+	for _, name := range o.sections {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown section %q, valid sections are: %s", name, strings.Join(contextSectionNames(), ", "))
+		}
+		requested[name] = true
+	}
+
+	skipped := map[string]bool{}
+	for _, name := range o.skipSections {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown section %q, valid sections are: %s", name, strings.Join(contextSectionNames(), ", "))
+		}
+		skipped[name] = true
+	}
+
+	var selected []contextCollector
+	for _, c := range contextCollectors {
+		if skipped[c.Name()] {
+			continue
+		}
+		if len(requested) > 0 && !requested[c.Name()] {
+			continue
+		}
+		if len(requested) == 0 && !c.EnabledByDefault(o) {
+			continue
+		}
+		selected = append(selected, c)
+	}
+
+	return selected, nil
+}
+
+type collectorResult struct {
+	collector contextCollector
+	data      *contextData
+	err       error
+}
+
+// runCollectors runs all collectors concurrently and merges their results
+// into data. The returned map holds the error of every section that failed
+// or timed out, keyed by section name. A Fetch is expected to stop at the
+// deadline of its context, one which doesn't is abandoned, so the shared
+// clients are only closed once the output is rendered.
+func (o *contextOptions) runCollectors(data *contextData, collectors []contextCollector) map[string]error {
+	results := make(chan collectorResult, len(collectors))
+
+	for _, c := range collectors {
+		scratch := *data
+		go func() {
+			defer utils.StartDelayTracker(o.verbose, c.Name()).End()
+
+			ctx, cancel := context.WithTimeout(context.Background(), c.Timeout())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- c.Fetch(ctx, o, &scratch)
+			}()
+
+			select {
+			case err := <-done:
+				results <- collectorResult{collector: c, data: &scratch, err: err}
+			case <-ctx.Done():
+				results <- collectorResult{collector: c, err: fmt.Errorf("timed out after %s", c.Timeout())}
+			}
+		}()
+	}
+
+	sectionErrors := map[string]error{}
+	for range collectors {
+		result := <-results
+		if result.data != nil {
+			result.collector.Merge(data, result.data)
+		}
+		if result.err != nil {
+			sectionErrors[result.collector.Name()] = result.err
+		}
+	}
+
+	return sectionErrors
+}
+
+// printSectionErrors prints the collection errors grouped by section
+func printSectionErrors(sectionErrors map[string]string, w io.Writer) {
+	if len(sectionErrors) == 0 {
+		return
+	}
+
+	var names []string
+	for name := range sectionErrors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Encountered errors during data collection. Displayed data may be incomplete:")
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%s: %s\n", name, sectionErrors[name])
+	}
+}
+
+type descriptionCollector struct{}
+
+func (descriptionCollector) Name() string { return "description" }
+
+// The description relies on the ocm cli and is only useful for the long output
+func (descriptionCollector) EnabledByDefault(o *contextOptions) bool {
+	return o.output != shortOutputConfigValue && o.output != jsonOutputConfigValue
+}
+
+func (descriptionCollector) Timeout() time.Duration { return time.Minute }
+
+func (descriptionCollector) Fetch(ctx context.Context, o *contextOptions, data *contextData) error {
+	output, err := exec.CommandContext(ctx, "ocm", "describe", "cluster", o.clusterID).Output()
+	data.Description = string(output)
+	if err != nil {
+		return fmt.Errorf("error while describing the cluster: %v", err)
+	}
+	return nil
+}
+
+func (descriptionCollector) Merge(dst, src *contextData) {
+	dst.Description = src.Description
+}
+
+func (descriptionCollector) PrintLong(_ *contextOptions, data *contextData, w io.Writer) {
+	fmt.Fprintln(w, strings.TrimSpace(data.Description))
+}
+
+func (descriptionCollector) ShortColumns(*contextOptions, *contextData) [][2]string { return nil }
+
+type limitedSupportCollector struct{}
+
+func (limitedSupportCollector) Name() string { return "limited-support" }
+
+func (limitedSupportCollector) EnabledByDefault(*contextOptions) bool { return true }
+
+func (limitedSupportCollector) Timeout() time.Duration { return defaultCollectorTimeout }
+
+func (limitedSupportCollector) Fetch(ctx context.Context, o *contextOptions, data *contextData) error {
+	limitedSupportReasons, err := utils.GetClusterLimitedSupportReasonsWithContext(ctx, o.ocmClient, o.clusterID)
+	if err != nil {
+		return fmt.Errorf("error while getting Limited Support status reasons: %v", err)
+	}
+	data.LimitedSupportReasons = limitedSupportReasons
+	return nil
+}
+
+func (limitedSupportCollector) Merge(dst, src *contextData) {
+	dst.LimitedSupportReasons = src.LimitedSupportReasons
+}
+
+func (limitedSupportCollector) PrintLong(_ *contextOptions, data *contextData, _ io.Writer) {
+	utils.PrintLimitedSupportReasons(data.LimitedSupportReasons)
+}
+
+func (limitedSupportCollector) ShortColumns(_ *contextOptions, data *contextData) [][2]string {
+	return [][2]string{{"Supported?", fmt.Sprintf("%t", len(data.LimitedSupportReasons) == 0)}}
+}
+
+type supportExceptionsCollector struct{}
+
+func (supportExceptionsCollector) Name() string { return "support-exceptions" }
+
+func (supportExceptionsCollector) EnabledByDefault(*contextOptions) bool { return true }
+
+func (supportExceptionsCollector) Timeout() time.Duration { return defaultCollectorTimeout }
+
+func (supportExceptionsCollector) Fetch(ctx context.Context, o *contextOptions, data *contextData) error {
+	var err error
+	data.SupportExceptions, err = utils.GetJiraSupportExceptionsForOrgWithContext(ctx, o.organizationID, o.jiratoken)
+	if err != nil {
+		return fmt.Errorf("error while getting support exceptions: %v", err)
+	}
+	return nil
+}
+
+func (supportExceptionsCollector) Merge(dst, src *contextData) {
+	dst.SupportExceptions = src.SupportExceptions
+}
+
+func (supportExceptionsCollector) PrintLong(_ *contextOptions, data *contextData, w io.Writer) {
+	printJIRASupportExceptions(data.SupportExceptions, w)
+}
+
+func (supportExceptionsCollector) ShortColumns(*contextOptions, *contextData) [][2]string { return nil }
+
+type serviceLogsCollector struct{}
+
+func (serviceLogsCollector) Name() string { return "service-logs" }
+
+func (serviceLogsCollector) EnabledByDefault(*contextOptions) bool { return true }
+
+func (serviceLogsCollector) Timeout() time.Duration { return defaultCollectorTimeout }
+
+func (serviceLogsCollector) Fetch(ctx context.Context, o *contextOptions, data *contextData) error {
+	var err error
+	timeToCheckSvcLogs := time.Now().AddDate(0, 0, -o.days)
+	data.ServiceLogs, err = servicelog.GetClusterServiceLogsSince(ctx, o.ocmClient, o.cluster, timeToCheckSvcLogs, false, false)
+	if err != nil {
+		return fmt.Errorf("error while getting the service logs: %v", err)
+	}
+	return nil
+}
+
+func (serviceLogsCollector) Merge(dst, src *contextData) {
+	dst.ServiceLogs = src.ServiceLogs
+}
+
+func (serviceLogsCollector) PrintLong(o *contextOptions, data *contextData, _ io.Writer) {
+	utils.PrintServiceLogs(data.ServiceLogs, o.verbose, o.days)
+}
+
+func (serviceLogsCollector) ShortColumns(o *contextOptions, data *contextData) [][2]string {
+	var numInternalServiceLogs int
+	for _, serviceLog := range data.ServiceLogs {
+		if serviceLog.InternalOnly() {
+			numInternalServiceLogs++
+		}
+	}
+	return [][2]string{{
+		fmt.Sprintf("SLs (last %d d)", o.days),
+		fmt.Sprintf("%d (%d internal)", len(data.ServiceLogs), numInternalServiceLogs),
+	}}
+}
+
+type jiraIssuesCollector struct{}
+
+func (jiraIssuesCollector) Name() string { return "jira" }
+
+func (jiraIssuesCollector) EnabledByDefault(*contextOptions) bool { return true }
+
+func (jiraIssuesCollector) Timeout() time.Duration { return defaultCollectorTimeout }
+
+func (jiraIssuesCollector) Fetch(ctx context.Context, o *contextOptions, data *contextData) error {
+	var err error
+	data.JiraIssues, err = utils.GetJiraIssuesForClusterWithContext(ctx, o.clusterID, o.externalClusterID, o.jiratoken)
+	if err != nil {
+		return fmt.Errorf("error while getting the open jira tickets: %v", err)
+	}
+	return nil
+}
+
+func (jiraIssuesCollector) Merge(dst, src *contextData) {
+	dst.JiraIssues = src.JiraIssues
+}
+
+func (jiraIssuesCollector) PrintLong(_ *contextOptions, data *contextData, _ io.Writer) {
+	utils.PrintJiraIssues(data.JiraIssues)
+}
+
+func (jiraIssuesCollector) ShortColumns(_ *contextOptions, data *contextData) [][2]string {
+	return [][2]string{{"Jira Tickets", fmt.Sprintf("%d", len(data.JiraIssues))}}
+}
+
+// pagerDutyCollector collects the current alerts and, with --full, the
+// historical alerts. Both depend on the PD service IDs for the cluster, so
+// they are gathered by the same collector.
+type pagerDutyCollector struct{}
+
+func (pagerDutyCollector) Name() string { return "pagerduty" }
+
+func (pagerDutyCollector) EnabledByDefault(*contextOptions) bool { return true }
+
+func (pagerDutyCollector) Timeout() time.Duration { return defaultCollectorTimeout }
+
+func (pagerDutyCollector) Fetch(ctx context.Context, o *contextOptions, data *contextData) error {
+	pdProvider, err := pagerduty.NewClient().
+		WithContext(ctx).
+		WithUserToken(o.usertoken).
+		WithOauthToken(o.oauthtoken).
+		WithBaseDomain(o.baseDomain).
+		WithTeamIdList(viper.GetStringSlice(pagerduty.PagerDutyTeamIDsKey)).
+		Init()
+	if err != nil {
+		return fmt.Errorf("skipping PagerDuty context collection: %v", err)
+	}
+
+	var errs []error
+	data.pdServiceID, err = pdProvider.GetPDServiceIDs()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error getting PD Service ID: %v", err))
+	}
+
+	data.PdAlerts, err = pdProvider.GetFiringAlertsForCluster(data.pdServiceID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error while getting current PD Alerts: %v", err))
+	}
+
+	if o.full {
+		data.HistoricalAlerts, err = pdProvider.GetHistoricalAlertsForCluster(data.pdServiceID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error while getting historical PD Alert Data: %v", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (pagerDutyCollector) Merge(dst, src *contextData) {
+	dst.pdServiceID = src.pdServiceID
+	dst.PdAlerts = src.PdAlerts
+	dst.HistoricalAlerts = src.HistoricalAlerts
+}
+
+func (pagerDutyCollector) PrintLong(o *contextOptions, data *contextData, w io.Writer) {
+	utils.PrintPDAlerts(data.PdAlerts, data.pdServiceID)
+
+	if o.full {
+		fmt.Fprintln(w)
+		printHistoricalPDAlertSummary(data.HistoricalAlerts, data.pdServiceID, o.days, w)
+	}
+}
+
+func (pagerDutyCollector) ShortColumns(o *contextOptions, data *contextData) [][2]string {
+	highAlertCount := 0
+	lowAlertCount := 0
+	for _, alerts := range data.PdAlerts {
+		for _, alert := range alerts {
+			if strings.ToLower(alert.Urgency) == "high" {
+				highAlertCount++
+			} else {
+				lowAlertCount++
+			}
+		}
+	}
+
+	historicalAlertsString := "N/A"
+	historicalAlertsCount := 0
+	if data.HistoricalAlerts != nil {
+		for _, histAlerts := range data.HistoricalAlerts {
+			for _, histAlert := range histAlerts {
+				historicalAlertsCount += histAlert.Count
+			}
+		}
+		historicalAlertsString = fmt.Sprintf("%d", historicalAlertsCount)
+	}
+
+	return [][2]string{
+		{"Current Alerts", fmt.Sprintf("H: %d | L: %d", highAlertCount, lowAlertCount)},
+		{fmt.Sprintf("Historical Alerts (last %d d)", o.days), historicalAlertsString},
+	}
+}
+
+type cloudTrailCollector struct{}
+
+func (cloudTrailCollector) Name() string { return "cloudtrail" }
+
+func (cloudTrailCollector) EnabledByDefault(o *contextOptions) bool { return o.full }
+
+// Paging through CloudTrail is slow, allow it more time than other sections
+func (cloudTrailCollector) Timeout() time.Duration { return 5 * time.Minute }
+
+func (cloudTrailCollector) Fetch(ctx context.Context, o *contextOptions, data *contextData) error {
+	var err error
+	data.CloudtrailEvents, err = GetCloudTrailLogsForCluster(ctx, o.awsProfile, o.clusterID, o.cluster.Region().ID(), o.pages, o.noCache)
+	if err != nil {
+		return fmt.Errorf("error getting cloudtrail logs for cluster: %v", err)
+	}
+	return nil
+}
+
+func (cloudTrailCollector) Merge(dst, src *contextData) {
+	dst.CloudtrailEvents = src.CloudtrailEvents
+}
+
+func (cloudTrailCollector) PrintLong(_ *contextOptions, data *contextData, w io.Writer) {
+	printCloudTrailLogs(data.CloudtrailEvents, w)
+}
+
+func (cloudTrailCollector) ShortColumns(*contextOptions, *contextData) [][2]string { return nil }
+
+// externalLinksCollector only renders links derived from the cluster and
+// the data of other sections, there is nothing to fetch.
+type externalLinksCollector struct{}
+
+func (externalLinksCollector) Name() string { return "links" }
+
+func (externalLinksCollector) EnabledByDefault(*contextOptions) bool { return true }
+
+func (externalLinksCollector) Timeout() time.Duration { return defaultCollectorTimeout }
+
+func (externalLinksCollector) Fetch(context.Context, *contextOptions, *contextData) error {
+	return nil
+}
+
+func (externalLinksCollector) Merge(_, _ *contextData) {}
+
+func (externalLinksCollector) PrintLong(o *contextOptions, data *contextData, w io.Writer) {
+	o.printOtherLinks(data, w)
+}
+
+func (externalLinksCollector) ShortColumns(*contextOptions, *contextData) [][2]string { return nil }
+
+type dynatraceCollector struct{}
+
+func (dynatraceCollector) Name() string { return "dynatrace" }
+
+func (dynatraceCollector) EnabledByDefault(*contextOptions) bool { return true }
+
+func (dynatraceCollector) Timeout() time.Duration { return defaultCollectorTimeout }
+
+func (dynatraceCollector) Fetch(_ context.Context, o *contextOptions, data *contextData) error {
+	hcpCluster, err := dynatrace.FetchClusterDetails(o.clusterID)
+	if err != nil {
+		if err == dynatrace.ErrUnsupportedCluster {
+			data.DyntraceEnvURL = dynatrace.ErrUnsupportedCluster.Error()
+			return nil
+		}
+		data.DyntraceEnvURL = "Failed to fetch Dynatrace URL"
+		return fmt.Errorf("failed to acquire cluster details %v", err)
+	}
+
+	data.DyntraceEnvURL = hcpCluster.DynatraceURL
+	query, err := dynatrace.GetQuery(hcpCluster)
+	if err != nil {
+		return fmt.Errorf("failed to build query for Dynatrace %v", err)
+	}
+	data.DyntraceLogsURL, err = dynatrace.GetLinkToWebConsole(hcpCluster.DynatraceURL, 10, query.Build())
+	if err != nil {
+		return fmt.Errorf("failed to get url: %v", err)
+	}
+	return nil
+}
+
+func (dynatraceCollector) Merge(dst, src *contextData) {
+	dst.DyntraceEnvURL = src.DyntraceEnvURL
+	dst.DyntraceLogsURL = src.DyntraceLogsURL
+}
+
+func (dynatraceCollector) PrintLong(_ *contextOptions, data *contextData, w io.Writer) {
+	printDynatraceResources(data, w)
+}
+
+func (dynatraceCollector) ShortColumns(*contextOptions, *contextData) [][2]string { return nil }
+
+type bannedUserCollector struct{}
+
+func (bannedUserCollector) Name() string { return "banned-user" }
+
+func (bannedUserCollector) EnabledByDefault(*contextOptions) bool { return true }
+
+func (bannedUserCollector) Timeout() time.Duration { return defaultCollectorTimeout }
+
+func (bannedUserCollector) Fetch(_ context.Context, o *contextOptions, data *contextData) error {
+	subscription, err := utils.GetSubscription(o.ocmClient, o.clusterID)
+	if err != nil {
+		return fmt.Errorf("error while getting subscripton %v", err)
+	}
+	creator, err := utils.GetAccount(o.ocmClient, subscription.Creator().ID())
+	if err != nil {
+		return fmt.Errorf("error while checking if user is banned %v", err)
+	}
+	data.UserBanned = creator.Banned()
+	data.BanCode = creator.BanCode()
+	data.BanDescription = creator.BanDescription()
+	return nil
+}
+
+func (bannedUserCollector) Merge(dst, src *contextData) {
+	dst.UserBanned = src.UserBanned
+	dst.BanCode = src.BanCode
+	dst.BanDescription = src.BanDescription
+}
+
+func (bannedUserCollector) PrintLong(_ *contextOptions, data *contextData, w io.Writer) {
+	printUserBannedStatus(data, w)
+}
+
+func (bannedUserCollector) ShortColumns(*contextOptions, *contextData) [][2]string { return nil }
@@ -0,0 +1,413 @@
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/openshift/osdctl/cmd/dynatrace"
+)
+
+const (
+	markdownOutputConfigValue = "markdown"
+	htmlOutputConfigValue     = "html"
+	pagerDutyServiceURL       = "https://redhat.pagerduty.com/service-directory/"
+)
+
+// contextReportSection is an output agnostic representation of a context
+// section, rendered by the markdown and html outputs.
+type contextReportSection struct {
+	Title string
+	// Text lines printed before the table
+	Lines []string
+	// Preformatted text, printed as-is
+	Preformatted string
+	Headers      []string
+	Rows         [][]reportCell
+	// Error that occurred while collecting the section
+	Error string
+}
+
+// reportCell is a single table cell, rendered as a link if Link is set
+type reportCell struct {
+	Text string
+	Link string
+}
+
+func (s contextReportSection) isEmpty() bool {
+	return len(s.Lines) == 0 && s.Preformatted == "" && len(s.Rows) == 0
+}
+
+// contextReporter is implemented by collectors that can describe their
+// section for the markdown and html outputs. Collectors that don't implement
+// it are rendered with their long output as preformatted text.
+type contextReporter interface {
+	ReportSections(o *contextOptions, data *contextData) []contextReportSection
+}
+
+// reportSections builds the report sections for all selected collectors
+func (o *contextOptions) reportSections(data *contextData) ([]contextReportSection, error) {
+	collectors, err := o.selectedCollectors()
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []contextReportSection
+	for _, c := range collectors {
+		var collectorSections []contextReportSection
+		if reporter, ok := c.(contextReporter); ok {
+			collectorSections = reporter.ReportSections(o, data)
+		} else {
+			var buf bytes.Buffer
+			c.PrintLong(o, data, &buf)
+			collectorSections = []contextReportSection{{Title: c.Name(), Preformatted: buf.String()}}
+		}
+
+		if sectionErr, found := data.SectionErrors[c.Name()]; found {
+			for i := range collectorSections {
+				collectorSections[i].Error = sectionErr
+			}
+		}
+		sections = append(sections, collectorSections...)
+	}
+
+	return sections, nil
+}
+
+func (o *contextOptions) printMarkdownOutput(data *contextData, w io.Writer) {
+	sections, err := o.reportSections(data)
+	if err != nil {
+		fmt.Fprintf(w, "> :warning: Error printing Markdown Output: %s\n", markdownEscape(err.Error()))
+		return
+	}
+
+	fmt.Fprintf(w, "# %s -- %s\n\n", data.ClusterName, data.ClusterID)
+	fmt.Fprintf(w, "**Version:** %s | **OCM Environment:** %s\n\n", data.ClusterVersion, data.OCMEnv)
+
+	for _, section := range sections {
+		fmt.Fprintf(w, "<details open>\n<summary><b>%s</b></summary>\n\n", section.Title)
+
+		if section.Error != "" {
+			fmt.Fprintf(w, "> :warning: Data may be incomplete: %s\n\n", markdownEscape(section.Error))
+		}
+
+		if section.isEmpty() {
+			fmt.Fprint(w, "None\n\n")
+		}
+
+		for _, line := range section.Lines {
+			fmt.Fprintf(w, "%s\n\n", markdownEscape(line))
+		}
+
+		if section.Preformatted != "" {
+			fmt.Fprintf(w, "```\n%s\n```\n\n", strings.TrimSpace(section.Preformatted))
+		}
+
+		if len(section.Rows) > 0 {
+			fmt.Fprintf(w, "| %s |\n", strings.Join(section.Headers, " | "))
+			fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(section.Headers)))
+			for _, row := range section.Rows {
+				cells := make([]string, 0, len(row))
+				for _, cell := range row {
+					cells = append(cells, markdownCell(cell))
+				}
+				fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+			}
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprint(w, "</details>\n\n")
+	}
+}
+
+func markdownCell(cell reportCell) string {
+	text := markdownEscape(cell.Text)
+	if cell.Link == "" {
+		return text
+	}
+	if text == "" {
+		text = cell.Link
+	}
+	return fmt.Sprintf("[%s](%s)", text, cell.Link)
+}
+
+var markdownReplacer = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "")
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(strings.TrimSpace(s))
+}
+
+var contextHTMLTemplate = template.Must(template.New("context").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Data.ClusterName }} -- {{ .Data.ClusterID }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+summary { font-weight: bold; font-size: 1.2em; cursor: pointer; margin: 0.5em 0; }
+table { border-collapse: collapse; margin: 0.5em 0 1em 0; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+pre { background: #f6f6f6; padding: 0.5em; overflow-x: auto; }
+.warning { color: #a94442; }
+</style>
+</head>
+<body>
+<h1>{{ .Data.ClusterName }} -- {{ .Data.ClusterID }}</h1>
+<p><b>Version:</b> {{ .Data.ClusterVersion }} | <b>OCM Environment:</b> {{ .Data.OCMEnv }}</p>
+{{- range .Sections }}
+<details open>
+<summary>{{ .Title }}</summary>
+{{- if .Error }}
+<p class="warning">Data may be incomplete: {{ .Error }}</p>
+{{- end }}
+{{- if .IsEmpty }}
+<p>None</p>
+{{- end }}
+{{- range .Lines }}
+<p>{{ . }}</p>
+{{- end }}
+{{- if .Preformatted }}
+<pre>{{ .Preformatted }}</pre>
+{{- end }}
+{{- if .Rows }}
+<table>
+<tr>{{ range .Headers }}<th>{{ . }}</th>{{ end }}</tr>
+{{- range .Rows }}
+<tr>{{ range . }}<td>{{ if .Link }}<a href="{{ .Link }}">{{ if .Text }}{{ .Text }}{{ else }}{{ .Link }}{{ end }}</a>{{ else }}{{ .Text }}{{ end }}</td>{{ end }}</tr>
+{{- end }}
+</table>
+{{- end }}
+</details>
+{{- end }}
+<p><small>Generated by osdctl on {{ .Generated }}</small></p>
+</body>
+</html>
+`))
+
+// htmlReportSection exposes isEmpty to the html template
+type htmlReportSection struct {
+	contextReportSection
+	IsEmpty bool
+}
+
+func (o *contextOptions) printHTMLOutput(data *contextData, w io.Writer) {
+	sections, err := o.reportSections(data)
+	if err != nil {
+		printHTMLError(err, w)
+		return
+	}
+
+	htmlSections := make([]htmlReportSection, 0, len(sections))
+	for _, section := range sections {
+		section.Preformatted = strings.TrimSpace(section.Preformatted)
+		htmlSections = append(htmlSections, htmlReportSection{contextReportSection: section, IsEmpty: section.isEmpty()})
+	}
+
+	err = contextHTMLTemplate.Execute(w, struct {
+		Data      *contextData
+		Sections  []htmlReportSection
+		Generated string
+	}{
+		Data:      data,
+		Sections:  htmlSections,
+		Generated: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		printHTMLError(err, w)
+	}
+}
+
+// printHTMLError renders an error of the html output into the report itself
+func printHTMLError(err error, w io.Writer) {
+	fmt.Fprintf(w, "<p class=\"warning\">Error printing HTML Output: %s</p>\n", template.HTMLEscapeString(err.Error()))
+}
+
+func (descriptionCollector) ReportSections(_ *contextOptions, data *contextData) []contextReportSection {
+	return []contextReportSection{{Title: "Cluster Description", Preformatted: data.Description}}
+}
+
+func (limitedSupportCollector) ReportSections(_ *contextOptions, data *contextData) []contextReportSection {
+	section := contextReportSection{Title: "Limited Support Status"}
+	if len(data.LimitedSupportReasons) == 0 {
+		section.Lines = []string{"Fully supported"}
+		return []contextReportSection{section}
+	}
+
+	section.Headers = []string{"Reason ID", "Summary", "Overridden (SUPPORTEX)", "Details"}
+	for _, reason := range data.LimitedSupportReasons {
+		section.Rows = append(section.Rows, []reportCell{
+			{Text: reason.ID()},
+			{Text: reason.Summary()},
+			{Text: strconv.FormatBool(reason.Override().Enabled())},
+			{Text: reason.Details()},
+		})
+	}
+	return []contextReportSection{section}
+}
+
+func (supportExceptionsCollector) ReportSections(_ *contextOptions, data *contextData) []contextReportSection {
+	section := contextReportSection{Title: "Support Exceptions"}
+	section.Headers = []string{"Key", "Type", "Priority", "Summary", "Status"}
+	for _, issue := range data.SupportExceptions {
+		section.Rows = append(section.Rows, jiraIssueCells(issue.Key, issue.Fields))
+	}
+	return []contextReportSection{section}
+}
+
+func (serviceLogsCollector) ReportSections(o *contextOptions, data *contextData) []contextReportSection {
+	section := contextReportSection{Title: fmt.Sprintf("Service Logs in the past %v days", o.days)}
+	section.Headers = []string{"Created At", "Severity", "Internal", "Summary", "Description"}
+	for _, serviceLog := range data.ServiceLogs {
+		section.Rows = append(section.Rows, []reportCell{
+			{Text: serviceLog.CreatedAt().Format(time.RFC3339)},
+			{Text: string(serviceLog.Severity())},
+			{Text: strconv.FormatBool(serviceLog.InternalOnly())},
+			{Text: serviceLog.Summary()},
+			{Text: serviceLog.Description()},
+		})
+	}
+	return []contextReportSection{section}
+}
+
+func (jiraIssuesCollector) ReportSections(_ *contextOptions, data *contextData) []contextReportSection {
+	section := contextReportSection{Title: "OHSS Issues"}
+	section.Headers = []string{"Key", "Type", "Priority", "Summary", "Status"}
+	for _, issue := range data.JiraIssues {
+		section.Rows = append(section.Rows, jiraIssueCells(issue.Key, issue.Fields))
+	}
+	return []contextReportSection{section}
+}
+
+func (pagerDutyCollector) ReportSections(o *contextOptions, data *contextData) []contextReportSection {
+	current := contextReportSection{Title: "PagerDuty Alerts"}
+	if len(data.pdServiceID) == 0 {
+		current.Lines = []string{"No PD Service Found"}
+	}
+	current.Headers = []string{"Service", "Urgency", "Title", "Created At"}
+	for _, serviceID := range data.pdServiceID {
+		for _, incident := range data.PdAlerts[serviceID] {
+			current.Rows = append(current.Rows, []reportCell{
+				{Text: serviceID, Link: pagerDutyServiceURL + serviceID},
+				{Text: incident.Urgency},
+				{Text: incident.Title, Link: incident.HTMLURL},
+				{Text: incident.CreatedAt},
+			})
+		}
+	}
+
+	if !o.full {
+		return []contextReportSection{current}
+	}
+
+	historical := contextReportSection{Title: fmt.Sprintf("PagerDuty Historical Alerts (last %d days)", o.days)}
+	historical.Headers = []string{"Service", "Type", "Count", "Last Occurrence"}
+	for _, serviceID := range data.pdServiceID {
+		for _, incident := range data.HistoricalAlerts[serviceID] {
+			historical.Rows = append(historical.Rows, []reportCell{
+				{Text: serviceID, Link: pagerDutyServiceURL + serviceID},
+				{Text: incident.IncidentName},
+				{Text: strconv.Itoa(incident.Count)},
+				{Text: incident.LastOccurrence},
+			})
+		}
+	}
+
+	return []contextReportSection{current, historical}
+}
+
+func (cloudTrailCollector) ReportSections(_ *contextOptions, data *contextData) []contextReportSection {
+	section := contextReportSection{Title: "Potentially interesting CloudTrail events"}
+	section.Headers = []string{"EventId", "EventName", "Username", "EventTime"}
+	for _, event := range data.CloudtrailEvents {
+		var eventID, eventName, username, eventTime string
+		if event.EventId != nil {
+			eventID = *event.EventId
+		}
+		if event.EventName != nil {
+			eventName = *event.EventName
+		}
+		if event.Username != nil {
+			username = *event.Username
+		}
+		if event.EventTime != nil {
+			eventTime = event.EventTime.String()
+		}
+		section.Rows = append(section.Rows, []reportCell{{Text: eventID}, {Text: eventName}, {Text: username}, {Text: eventTime}})
+	}
+	return []contextReportSection{section}
+}
+
+func (externalLinksCollector) ReportSections(o *contextOptions, data *contextData) []contextReportSection {
+	section := contextReportSection{Title: "External resources"}
+	section.Headers = []string{"Resource", "Link"}
+
+	links := o.otherLinks(data)
+	var keys []string
+	for k := range links {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, name := range keys {
+		section.Rows = append(section.Rows, []reportCell{{Text: name}, {Link: strings.TrimSpace(links[name])}})
+	}
+	return []contextReportSection{section}
+}
+
+func (dynatraceCollector) ReportSections(_ *contextOptions, data *contextData) []contextReportSection {
+	section := contextReportSection{Title: "Dynatrace Details"}
+	if data.DyntraceEnvURL == dynatrace.ErrUnsupportedCluster.Error() {
+		section.Lines = []string{dynatrace.ErrUnsupportedCluster.Error()}
+		return []contextReportSection{section}
+	}
+
+	section.Headers = []string{"Resource", "Link"}
+	if url := strings.TrimSpace(data.DyntraceEnvURL); url != "" {
+		section.Rows = append(section.Rows, []reportCell{{Text: "Dynatrace Tenant URL"}, {Link: url}})
+	}
+	if url := strings.TrimSpace(data.DyntraceLogsURL); url != "" {
+		section.Rows = append(section.Rows, []reportCell{{Text: "Logs App URL"}, {Link: url}})
+	}
+	return []contextReportSection{section}
+}
+
+func (bannedUserCollector) ReportSections(_ *contextOptions, data *contextData) []contextReportSection {
+	section := contextReportSection{Title: "User Ban Details"}
+	if !data.UserBanned {
+		section.Lines = []string{"User is not banned"}
+		return []contextReportSection{section}
+	}
+
+	section.Lines = []string{
+		"User is banned",
+		fmt.Sprintf("Ban code = %v", data.BanCode),
+		fmt.Sprintf("Ban description = %v", data.BanDescription),
+	}
+	if data.BanCode == BanCodeExportControlCompliance {
+		section.Lines = append(section.Lines, "User banned due to export control compliance, please follow https://github.com/openshift/ops-sop/blob/master/v4/alerts/UpgradeConfigSyncFailureOver4HrSRE.md#user-banneddisabled-due-to-export-control-compliance")
+	}
+	return []contextReportSection{section}
+}
+
+func jiraIssueCells(key string, fields *jira.IssueFields) []reportCell {
+	cells := []reportCell{{Text: key, Link: fmt.Sprintf("%s/browse/%s", JiraBaseURL, key)}}
+	if fields == nil {
+		return append(cells, reportCell{}, reportCell{}, reportCell{}, reportCell{})
+	}
+
+	var priority, status string
+	if fields.Priority != nil {
+		priority = fields.Priority.Name
+	}
+	if fields.Status != nil {
+		status = fields.Status.Name
+	}
+	return append(cells, reportCell{Text: fields.Type.Name}, reportCell{Text: priority}, reportCell{Text: fields.Summary}, reportCell{Text: status})
+}
@@ -0,0 +1,418 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	v1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	"github.com/spf13/cobra"
+)
+
+// contextSnapshotVersion is bumped whenever the snapshot format changes in an
+// incompatible way
+const contextSnapshotVersion = 1
+
+// contextSnapshot is the on-disk representation of a contextData. The OCM
+// types can't be serialized with encoding/json, so they are stored separately
+// using the OCM SDK marshallers.
+type contextSnapshot struct {
+	Version               int             `json:"version"`
+	SavedAt               time.Time       `json:"saved_at"`
+	PagerDutyServiceIDs   []string        `json:"pagerduty_service_ids,omitempty"`
+	LimitedSupportReasons json.RawMessage `json:"limited_support_reasons,omitempty"`
+	ServiceLogs           json.RawMessage `json:"service_logs,omitempty"`
+	Context               *contextData    `json:"context"`
+}
+
+// saveContextSnapshot writes data as a versioned snapshot to the given path
+func saveContextSnapshot(data *contextData, path string) error {
+	snapshot := contextSnapshot{
+		Version:             contextSnapshotVersion,
+		SavedAt:             time.Now().UTC(),
+		PagerDutyServiceIDs: data.pdServiceID,
+	}
+
+	if data.LimitedSupportReasons != nil {
+		var buf bytes.Buffer
+		if err := cmv1.MarshalLimitedSupportReasonList(data.LimitedSupportReasons, &buf); err != nil {
+			return fmt.Errorf("failed to marshal limited support reasons: %w", err)
+		}
+		snapshot.LimitedSupportReasons = buf.Bytes()
+	}
+
+	if data.ServiceLogs != nil {
+		var buf bytes.Buffer
+		if err := v1.MarshalLogEntryList(data.ServiceLogs, &buf); err != nil {
+			return fmt.Errorf("failed to marshal service logs: %w", err)
+		}
+		snapshot.ServiceLogs = buf.Bytes()
+	}
+
+	contextCopy := *data
+	contextCopy.LimitedSupportReasons = nil
+	contextCopy.ServiceLogs = nil
+	snapshot.Context = &contextCopy
+
+	out, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal context snapshot: %w", err)
+	}
+
+	return os.WriteFile(filepath.Clean(path), out, 0600)
+}
+
+// loadContextSnapshot reads a snapshot previously written by saveContextSnapshot
+func loadContextSnapshot(path string) (*contextSnapshot, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read context snapshot: %w", err)
+	}
+
+	snapshot := &contextSnapshot{}
+	if err := json.Unmarshal(content, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse context snapshot %s: %w", path, err)
+	}
+
+	if snapshot.Version != contextSnapshotVersion {
+		return nil, fmt.Errorf("unsupported context snapshot version %d in %s, expected %d", snapshot.Version, path, contextSnapshotVersion)
+	}
+	if snapshot.Context == nil {
+		return nil, fmt.Errorf("context snapshot %s does not contain any context data", path)
+	}
+
+	snapshot.Context.pdServiceID = snapshot.PagerDutyServiceIDs
+
+	if len(snapshot.LimitedSupportReasons) > 0 {
+		snapshot.Context.LimitedSupportReasons, err = cmv1.UnmarshalLimitedSupportReasonList([]byte(snapshot.LimitedSupportReasons))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse limited support reasons in %s: %w", path, err)
+		}
+	}
+
+	if len(snapshot.ServiceLogs) > 0 {
+		snapshot.Context.ServiceLogs, err = v1.UnmarshalLogEntryList([]byte(snapshot.ServiceLogs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse service logs in %s: %w", path, err)
+		}
+	}
+
+	return snapshot, nil
+}
+
+type contextDiffOptions struct {
+	output     string
+	days       int
+	pages      int
+	full       bool
+	awsProfile string
+	verbose    bool
+}
+
+// contextDiffEntry is a single item which appeared or disappeared between two snapshots
+type contextDiffEntry struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// contextDiffSection holds the changes of one kind of item between two snapshots
+type contextDiffSection struct {
+	Name        string             `json:"name"`
+	Appeared    []contextDiffEntry `json:"appeared"`
+	Disappeared []contextDiffEntry `json:"disappeared"`
+	// Unavailable is why the section can't be compared, eg. it failed to be collected in a snapshot
+	Unavailable string `json:"unavailable,omitempty"`
+}
+
+type contextDiff struct {
+	ClusterID string               `json:"cluster_id"`
+	OldTime   time.Time            `json:"old_time"`
+	NewTime   time.Time            `json:"new_time"`
+	Sections  []contextDiffSection `json:"sections"`
+}
+
+// newCmdContextDiff implements the context diff command to compare saved cluster contexts
+func newCmdContextDiff() *cobra.Command {
+	ops := &contextDiffOptions{}
+	diffCmd := &cobra.Command{
+		Use:   "diff <old-snapshot> [new-snapshot]",
+		Short: "Shows what changed between two saved cluster contexts",
+		Long: `Shows which limited support reasons, service logs, PagerDuty incidents, Jira issues
+and CloudTrail events appeared or disappeared between two cluster context snapshots
+saved with 'osdctl cluster context --save'.
+
+If only one snapshot is given, it is compared against the live context of the cluster.
+Sections which failed to be collected, or weren't collected, in either snapshot are
+reported as unavailable instead of compared.`,
+		Example: `  # Save the context at handover
+  osdctl cluster context -c ${CLUSTER_ID} --save handover.json
+
+  # Compare two saved contexts
+  osdctl cluster context diff handover.json now.json
+
+  # Compare a saved context against the live cluster
+  osdctl cluster context diff handover.json`,
+		Args:              cobra.RangeArgs(1, 2),
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ops.run(args)
+		},
+	}
+
+	diffCmd.Flags().StringVarP(&ops.output, "output", "o", "long", "Valid formats are ['long', 'json']")
+	diffCmd.Flags().IntVarP(&ops.days, "days", "d", 30, "Days of service logs to collect when comparing against the live cluster")
+	diffCmd.Flags().IntVar(&ops.pages, "pages", 40, "Pages of CloudTrail logs to collect when comparing against the live cluster")
+	diffCmd.Flags().BoolVar(&ops.full, "full", false, "Collect CloudTrail events and historical alerts when comparing against the live cluster")
+	diffCmd.Flags().StringVarP(&ops.awsProfile, "profile", "p", "", "AWS Profile")
+	diffCmd.Flags().BoolVarP(&ops.verbose, "verbose", "", false, "Verbose output")
+
+	return diffCmd
+}
+
+func (o *contextDiffOptions) run(args []string) error {
+	if o.output != longOutputConfigValue && o.output != jsonOutputConfigValue {
+		return fmt.Errorf("unknown Output Format: %s", o.output)
+	}
+
+	oldSnapshot, err := loadContextSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+
+	var newSnapshot *contextSnapshot
+	if len(args) == 2 {
+		newSnapshot, err = loadContextSnapshot(args[1])
+		if err != nil {
+			return err
+		}
+	} else {
+		// Only compare CloudTrail events if they were collected in the old snapshot as well
+		withCloudTrail := o.full || oldSnapshot.Context.collected("cloudtrail")
+		newSnapshot, err = o.liveSnapshot(oldSnapshot.Context.ClusterID, withCloudTrail)
+		if err != nil {
+			return err
+		}
+	}
+
+	if oldSnapshot.Context.ClusterID != newSnapshot.Context.ClusterID {
+		fmt.Fprintf(os.Stderr, "Warning: comparing contexts of different clusters (%s and %s)\n", oldSnapshot.Context.ClusterID, newSnapshot.Context.ClusterID)
+	}
+
+	diff := diffContextSnapshots(oldSnapshot, newSnapshot)
+	if o.output == jsonOutputConfigValue {
+		out, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return fmt.Errorf("can't marshal results to json: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	printContextDiff(diff, os.Stdout)
+	return nil
+}
+
+// liveSnapshot collects the current context of the cluster for the sections a diff covers
+func (o *contextDiffOptions) liveSnapshot(clusterID string, withCloudTrail bool) (*contextSnapshot, error) {
+	ops := &contextOptions{
+		clusterID:  clusterID,
+		output:     jsonOutputConfigValue,
+		days:       o.days,
+		pages:      o.pages,
+		full:       o.full,
+		awsProfile: o.awsProfile,
+		verbose:    o.verbose,
+		sections:   []string{"limited-support", "support-exceptions", "service-logs", "jira", "pagerduty"},
+	}
+	if withCloudTrail {
+		ops.sections = append(ops.sections, "cloudtrail")
+	}
+
+	if err := ops.setup(); err != nil {
+		return nil, err
+	}
+
+	collectors, err := ops.selectedCollectors()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ops.generateContextData(collectors)
+	defer ops.closeClients()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cluster info: %v", err)
+	}
+	printSectionErrors(data.SectionErrors, os.Stderr)
+
+	return &contextSnapshot{
+		Version: contextSnapshotVersion,
+		SavedAt: time.Now().UTC(),
+		Context: data,
+	}, nil
+}
+
+// diffContextSnapshots compares the items of two snapshots by their IDs
+func diffContextSnapshots(oldSnapshot, newSnapshot *contextSnapshot) *contextDiff {
+	oldData, newData := oldSnapshot.Context, newSnapshot.Context
+
+	return &contextDiff{
+		ClusterID: newData.ClusterID,
+		OldTime:   oldSnapshot.SavedAt,
+		NewTime:   newSnapshot.SavedAt,
+		Sections: []contextDiffSection{
+			diffSection("Limited Support Reasons", "limited-support", oldData, newData, limitedSupportEntries),
+			diffSection("Service Logs", "service-logs", oldData, newData, serviceLogEntries),
+			diffSection("PagerDuty Incidents", "pagerduty", oldData, newData, pagerDutyEntries),
+			diffSection("Jira Issues", "jira", oldData, newData, jiraEntries),
+			diffSection("Support Exceptions", "support-exceptions", oldData, newData, supportExceptionEntries),
+			diffSection("CloudTrail Events", "cloudtrail", oldData, newData, cloudTrailEntries),
+		},
+	}
+}
+
+// collected returns true if the section was collected in the context, which is the case for
+// all of them in contexts saved before the collected sections were recorded
+func (data *contextData) collected(section string) bool {
+	return len(data.Sections) == 0 || slices.Contains(data.Sections, section)
+}
+
+// diffSection compares the entries of the section named collector in both contexts, unless
+// it is missing or incomplete in one of them
+func diffSection(name string, collector string, oldData, newData *contextData, entries func(*contextData) map[string]string) contextDiffSection {
+	for _, snapshot := range []struct {
+		name string
+		data *contextData
+	}{{"old", oldData}, {"new", newData}} {
+		reason := ""
+		if sectionErr, found := snapshot.data.SectionErrors[collector]; found {
+			reason = fmt.Sprintf("failed to collect in the %s context: %s", snapshot.name, sectionErr)
+		} else if !snapshot.data.collected(collector) {
+			reason = fmt.Sprintf("not collected in the %s context", snapshot.name)
+		}
+		if reason != "" {
+			return contextDiffSection{Name: name, Appeared: []contextDiffEntry{}, Disappeared: []contextDiffEntry{}, Unavailable: reason}
+		}
+	}
+	return diffEntries(name, entries(oldData), entries(newData))
+}
+
+func diffEntries(name string, oldEntries, newEntries map[string]string) contextDiffSection {
+	section := contextDiffSection{Name: name, Appeared: []contextDiffEntry{}, Disappeared: []contextDiffEntry{}}
+
+	for id, description := range newEntries {
+		if _, found := oldEntries[id]; !found {
+			section.Appeared = append(section.Appeared, contextDiffEntry{ID: id, Description: description})
+		}
+	}
+	for id, description := range oldEntries {
+		if _, found := newEntries[id]; !found {
+			section.Disappeared = append(section.Disappeared, contextDiffEntry{ID: id, Description: description})
+		}
+	}
+
+	// Sort, so it's always a predictable order
+	sort.Slice(section.Appeared, func(i, j int) bool { return section.Appeared[i].ID < section.Appeared[j].ID })
+	sort.Slice(section.Disappeared, func(i, j int) bool { return section.Disappeared[i].ID < section.Disappeared[j].ID })
+
+	return section
+}
+
+func limitedSupportEntries(data *contextData) map[string]string {
+	entries := map[string]string{}
+	for _, reason := range data.LimitedSupportReasons {
+		entries[reason.ID()] = reason.Summary()
+	}
+	return entries
+}
+
+func serviceLogEntries(data *contextData) map[string]string {
+	entries := map[string]string{}
+	for _, serviceLog := range data.ServiceLogs {
+		entries[serviceLog.ID()] = fmt.Sprintf("%s (%s)", serviceLog.Summary(), serviceLog.CreatedAt().Format(time.RFC3339))
+	}
+	return entries
+}
+
+func pagerDutyEntries(data *contextData) map[string]string {
+	entries := map[string]string{}
+	for _, incidents := range data.PdAlerts {
+		for _, incident := range incidents {
+			entries[incident.ID] = fmt.Sprintf("[%s] %s", incident.Urgency, incident.Title)
+		}
+	}
+	return entries
+}
+
+func jiraEntries(data *contextData) map[string]string {
+	entries := map[string]string{}
+	for _, issue := range data.JiraIssues {
+		entries[issue.Key] = jiraSummary(issue.Fields)
+	}
+	return entries
+}
+
+func supportExceptionEntries(data *contextData) map[string]string {
+	entries := map[string]string{}
+	for _, issue := range data.SupportExceptions {
+		entries[issue.Key] = jiraSummary(issue.Fields)
+	}
+	return entries
+}
+
+func cloudTrailEntries(data *contextData) map[string]string {
+	entries := map[string]string{}
+	for _, event := range data.CloudtrailEvents {
+		if event == nil || event.EventId == nil {
+			continue
+		}
+		var eventName, username string
+		if event.EventName != nil {
+			eventName = *event.EventName
+		}
+		if event.Username != nil {
+			username = *event.Username
+		}
+		entries[*event.EventId] = fmt.Sprintf("%s by %s", eventName, username)
+	}
+	return entries
+}
+
+func printContextDiff(diff *contextDiff, w io.Writer) {
+	fmt.Fprintf(w, "Changes for cluster %s between %s and %s\n\n", diff.ClusterID, diff.OldTime.Format(time.RFC3339), diff.NewTime.Format(time.RFC3339))
+
+	for _, section := range diff.Sections {
+		fmt.Fprintln(w, delimiter+section.Name)
+		if section.Unavailable != "" {
+			fmt.Fprintf(w, "Unavailable, %s\n\n", section.Unavailable)
+			continue
+		}
+		if len(section.Appeared) == 0 && len(section.Disappeared) == 0 {
+			fmt.Fprintln(w, "No changes")
+		}
+		for _, entry := range section.Appeared {
+			fmt.Fprintf(w, "+ %s: %s\n", entry.ID, entry.Description)
+		}
+		for _, entry := range section.Disappeared {
+			fmt.Fprintf(w, "- %s: %s\n", entry.ID, entry.Description)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func jiraSummary(fields *jira.IssueFields) string {
+	if fields == nil {
+		return ""
+	}
+	if fields.Status == nil {
+		return fields.Summary
+	}
+	return fmt.Sprintf("%s [Status: %s]", fields.Summary, fields.Status.Name)
+}
@@ -2,19 +2,27 @@ package cluster
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	pd "github.com/PagerDuty/go-pagerduty"
 	"github.com/andygrunwald/go-jira"
+	awsSdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
 	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	v1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	v2 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	"github.com/openshift/osdctl/pkg/provider/aws/mock"
 	"github.com/openshift/osdctl/pkg/provider/pagerduty"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
 )
 
 type MockOCMClient struct{}
@@ -581,3 +589,317 @@ func TestPrintUserBannedStatus(t *testing.T) {
 		})
 	}
 }
+
+type fakeCollector struct {
+	name    string
+	delay   time.Duration
+	err     error
+	fetched *atomic.Bool
+}
+
+func (f fakeCollector) Name() string                          { return f.name }
+func (f fakeCollector) EnabledByDefault(*contextOptions) bool { return true }
+func (f fakeCollector) Timeout() time.Duration                { return 50 * time.Millisecond }
+func (f fakeCollector) Fetch(_ context.Context, _ *contextOptions, data *contextData) error {
+	time.Sleep(f.delay)
+	data.Description = f.name
+	if f.fetched != nil {
+		f.fetched.Store(true)
+	}
+	return f.err
+}
+func (f fakeCollector) Merge(dst, src *contextData)                            { dst.Description = src.Description }
+func (f fakeCollector) PrintLong(*contextOptions, *contextData, io.Writer)     {}
+func (f fakeCollector) ShortColumns(*contextOptions, *contextData) [][2]string { return nil }
+
+func TestSelectedCollectors(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         contextOptions
+		expected     []string
+		unexpected   []string
+		errorMessage string
+	}{
+		{
+			name:       "Defaults for long output",
+			opts:       contextOptions{output: longOutputConfigValue},
+			expected:   []string{"description", "limited-support", "service-logs", "pagerduty"},
+			unexpected: []string{"cloudtrail"},
+		},
+		{
+			name:       "Full suite includes cloudtrail",
+			opts:       contextOptions{output: shortOutputConfigValue, full: true},
+			expected:   []string{"cloudtrail", "jira"},
+			unexpected: []string{"description"},
+		},
+		{
+			name:       "Explicit sections",
+			opts:       contextOptions{sections: []string{"service-logs", "cloudtrail"}},
+			expected:   []string{"service-logs", "cloudtrail"},
+			unexpected: []string{"jira", "pagerduty"},
+		},
+		{
+			name:       "Skipped sections",
+			opts:       contextOptions{skipSections: []string{"pagerduty"}},
+			expected:   []string{"jira"},
+			unexpected: []string{"pagerduty"},
+		},
+		{
+			name:         "Unknown section",
+			opts:         contextOptions{sections: []string{"unknown"}},
+			errorMessage: `unknown section "unknown"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collectors, err := tt.opts.selectedCollectors()
+			if tt.errorMessage != "" {
+				assert.ErrorContains(t, err, tt.errorMessage)
+				return
+			}
+			assert.NoError(t, err)
+
+			var names []string
+			for _, c := range collectors {
+				names = append(names, c.Name())
+			}
+			for _, name := range tt.expected {
+				assert.Contains(t, names, name)
+			}
+			for _, name := range tt.unexpected {
+				assert.NotContains(t, names, name)
+			}
+		})
+	}
+}
+
+func TestRunCollectors(t *testing.T) {
+	o := &contextOptions{}
+	data := &contextData{}
+
+	slowFetched := &atomic.Bool{}
+	start := time.Now()
+	sectionErrors := o.runCollectors(data, []contextCollector{
+		fakeCollector{name: "fast"},
+		fakeCollector{name: "slow", delay: time.Second, fetched: slowFetched},
+		fakeCollector{name: "failing", err: fmt.Errorf("boom")},
+	})
+
+	assert.Len(t, sectionErrors, 2)
+	assert.ErrorContains(t, sectionErrors["slow"], "timed out")
+	assert.ErrorContains(t, sectionErrors["failing"], "boom")
+	assert.NotEqual(t, "slow", data.Description)
+	// A fetch ignoring its deadline doesn't hold up the others, it is abandoned
+	assert.Less(t, time.Since(start), time.Second)
+	assert.False(t, slowFetched.Load())
+}
+
+func TestPrintMarkdownOutput(t *testing.T) {
+	limitedSupportReason, _ := v1.NewLimitedSupportReason().ID("ls-1").Summary("Cluster | broken").Build()
+
+	o := &contextOptions{
+		output:    markdownOutputConfigValue,
+		days:      7,
+		clusterID: "cluster-123",
+		sections:  []string{"limited-support", "jira", "links"},
+	}
+	data := &contextData{
+		ClusterName:           "md-cluster",
+		ClusterID:             "cluster-123",
+		LimitedSupportReasons: []*v1.LimitedSupportReason{limitedSupportReason},
+		SectionErrors:         map[string]string{"jira": "jira is down"},
+	}
+
+	var buf bytes.Buffer
+	o.printMarkdownOutput(data, &buf)
+	output := buf.String()
+
+	assert.Contains(t, output, "# md-cluster -- cluster-123")
+	assert.Contains(t, output, "<summary><b>Limited Support Status</b></summary>")
+	assert.Contains(t, output, "| ls-1 | Cluster \\| broken |")
+	assert.Contains(t, output, "Data may be incomplete: jira is down")
+	assert.Contains(t, output, "[https://kraken.psi.redhat.com/clusters/](https://kraken.psi.redhat.com/clusters/)")
+	assert.NotContains(t, output, "PagerDuty Alerts")
+}
+
+func TestPrintHTMLOutput(t *testing.T) {
+	o := &contextOptions{
+		output:   htmlOutputConfigValue,
+		sections: []string{"jira", "banned-user"},
+	}
+	data := &contextData{
+		ClusterName: "<html-cluster>",
+		ClusterID:   "cluster-123",
+		JiraIssues: []jira.Issue{
+			{
+				Key: "OHSS-1",
+				Fields: &jira.IssueFields{
+					Type:     jira.IssueType{Name: "Bug"},
+					Priority: &jira.Priority{Name: "High"},
+					Summary:  "Broken <script>",
+					Status:   &jira.Status{Name: "Open"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	o.printHTMLOutput(data, &buf)
+	output := buf.String()
+
+	assert.Contains(t, output, "<!DOCTYPE html>")
+	assert.Contains(t, output, "&lt;html-cluster&gt; -- cluster-123")
+	assert.Contains(t, output, `<a href="https://issues.redhat.com/browse/OHSS-1">OHSS-1</a>`)
+	assert.Contains(t, output, "Broken &lt;script&gt;")
+	assert.Contains(t, output, "<details open>")
+	assert.Contains(t, output, "User is not banned")
+}
+
+func TestPrintReportOutputErrors(t *testing.T) {
+	o := &contextOptions{sections: []string{"unknown"}}
+	data := &contextData{ClusterID: "cluster-123"}
+
+	var buf bytes.Buffer
+	o.printMarkdownOutput(data, &buf)
+	assert.Contains(t, buf.String(), `> :warning: Error printing Markdown Output: unknown section "unknown"`)
+
+	buf.Reset()
+	o.printHTMLOutput(data, &buf)
+	assert.Contains(t, buf.String(), `<p class="warning">Error printing HTML Output: unknown section &#34;unknown&#34;`)
+}
+
+func TestContextSnapshotRoundTrip(t *testing.T) {
+	limitedSupportReason, _ := v1.NewLimitedSupportReason().ID("ls-1").Summary("Broken").Build()
+	serviceLog, _ := v2.NewLogEntry().ID("sl-1").Summary("Hello").Build()
+	eventID := "event-1"
+
+	data := &contextData{
+		ClusterName:           "snapshot-cluster",
+		ClusterID:             "cluster-123",
+		LimitedSupportReasons: []*v1.LimitedSupportReason{limitedSupportReason},
+		ServiceLogs:           []*v2.LogEntry{serviceLog},
+		JiraIssues:            []jira.Issue{{Key: "OHSS-1"}},
+		PdAlerts:              map[string][]pd.Incident{"PD1": {{APIObject: pd.APIObject{ID: "incident-1"}}}},
+		CloudtrailEvents:      []*types.Event{{EventId: &eventID}},
+		pdServiceID:           []string{"PD1"},
+	}
+
+	path := t.TempDir() + "/snapshot.json"
+	assert.NoError(t, saveContextSnapshot(data, path))
+
+	snapshot, err := loadContextSnapshot(path)
+	assert.NoError(t, err)
+	assert.Equal(t, contextSnapshotVersion, snapshot.Version)
+	assert.Equal(t, "cluster-123", snapshot.Context.ClusterID)
+	assert.Equal(t, []string{"PD1"}, snapshot.Context.pdServiceID)
+	assert.Len(t, snapshot.Context.LimitedSupportReasons, 1)
+	assert.Equal(t, "ls-1", snapshot.Context.LimitedSupportReasons[0].ID())
+	assert.Len(t, snapshot.Context.ServiceLogs, 1)
+	assert.Equal(t, "sl-1", snapshot.Context.ServiceLogs[0].ID())
+	assert.Equal(t, "event-1", *snapshot.Context.CloudtrailEvents[0].EventId)
+}
+
+func TestDiffContextSnapshots(t *testing.T) {
+	oldReason, _ := v1.NewLimitedSupportReason().ID("ls-old").Summary("Old reason").Build()
+	newReason, _ := v1.NewLimitedSupportReason().ID("ls-new").Summary("New reason").Build()
+	serviceLog, _ := v2.NewLogEntry().ID("sl-1").Summary("Same").Build()
+
+	oldSnapshot := &contextSnapshot{Context: &contextData{
+		ClusterID:             "cluster-123",
+		LimitedSupportReasons: []*v1.LimitedSupportReason{oldReason},
+		ServiceLogs:           []*v2.LogEntry{serviceLog},
+		JiraIssues:            []jira.Issue{{Key: "OHSS-1"}},
+	}}
+	newSnapshot := &contextSnapshot{Context: &contextData{
+		ClusterID:             "cluster-123",
+		LimitedSupportReasons: []*v1.LimitedSupportReason{newReason},
+		ServiceLogs:           []*v2.LogEntry{serviceLog},
+		JiraIssues:            []jira.Issue{{Key: "OHSS-1"}, {Key: "OHSS-2"}},
+	}}
+
+	diff := diffContextSnapshots(oldSnapshot, newSnapshot)
+
+	sections := map[string]contextDiffSection{}
+	for _, section := range diff.Sections {
+		sections[section.Name] = section
+	}
+
+	assert.Equal(t, []contextDiffEntry{{ID: "ls-new", Description: "New reason"}}, sections["Limited Support Reasons"].Appeared)
+	assert.Equal(t, []contextDiffEntry{{ID: "ls-old", Description: "Old reason"}}, sections["Limited Support Reasons"].Disappeared)
+	assert.Empty(t, sections["Service Logs"].Appeared)
+	assert.Empty(t, sections["Service Logs"].Disappeared)
+	assert.Equal(t, []contextDiffEntry{{ID: "OHSS-2"}}, sections["Jira Issues"].Appeared)
+
+	var buf bytes.Buffer
+	printContextDiff(diff, &buf)
+	assert.Contains(t, buf.String(), "+ ls-new: New reason")
+	assert.Contains(t, buf.String(), "- ls-old: Old reason")
+}
+
+func TestDiffContextSnapshotsUnavailableSections(t *testing.T) {
+	oldSnapshot := &contextSnapshot{Context: &contextData{
+		ClusterID:  "cluster-123",
+		Sections:   []string{"service-logs", "jira", "pagerduty"},
+		JiraIssues: []jira.Issue{{Key: "OHSS-1"}},
+	}}
+	eventID := "event-1"
+	newSnapshot := &contextSnapshot{Context: &contextData{
+		ClusterID:        "cluster-123",
+		Sections:         []string{"service-logs", "jira", "pagerduty", "cloudtrail"},
+		SectionErrors:    map[string]string{"jira": "timed out after 30s"},
+		CloudtrailEvents: []*types.Event{{EventId: &eventID}},
+	}}
+
+	diff := diffContextSnapshots(oldSnapshot, newSnapshot)
+
+	sections := map[string]contextDiffSection{}
+	for _, section := range diff.Sections {
+		sections[section.Name] = section
+	}
+
+	assert.Equal(t, "failed to collect in the new context: timed out after 30s", sections["Jira Issues"].Unavailable)
+	assert.Empty(t, sections["Jira Issues"].Disappeared)
+	assert.Equal(t, "not collected in the old context", sections["CloudTrail Events"].Unavailable)
+	assert.Empty(t, sections["CloudTrail Events"].Appeared)
+	assert.Equal(t, "not collected in the old context", sections["Limited Support Reasons"].Unavailable)
+	assert.Empty(t, sections["Service Logs"].Unavailable)
+
+	var buf bytes.Buffer
+	printContextDiff(diff, &buf)
+	assert.Contains(t, buf.String(), "Unavailable, failed to collect in the new context: timed out after 30s")
+	assert.NotContains(t, buf.String(), "OHSS-1")
+}
+
+func TestContextDataCollected(t *testing.T) {
+	// CloudTrail was collected even though it didn't find any events
+	withCloudTrail := &contextData{Sections: []string{"service-logs", "cloudtrail"}}
+	assert.True(t, withCloudTrail.collected("cloudtrail"))
+	assert.False(t, withCloudTrail.collected("jira"))
+
+	// Contexts saved before the sections were recorded have all of them
+	assert.True(t, (&contextData{}).collected("cloudtrail"))
+}
+
+func TestLookupCachedCloudTrailEventsPageLimit(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	maxPages := 2
+
+	ctrl := gomock.NewController(t)
+	client := mock.NewMockClient(ctrl)
+	client.EXPECT().GetCallerIdentity(gomock.Any()).Return(&sts.GetCallerIdentityOutput{Account: awsSdk.String("123456789012")}, nil)
+	// Every window has more pages than allowed
+	page := 0
+	client.EXPECT().LookupEvents(gomock.Any()).DoAndReturn(func(input *cloudtrail.LookupEventsInput) (*cloudtrail.LookupEventsOutput, error) {
+		page++
+		return &cloudtrail.LookupEventsOutput{
+			Events:    []types.Event{{EventId: awsSdk.String(fmt.Sprintf("event-%d", page)), EventTime: awsSdk.Time(input.EndTime.Add(-time.Duration(page) * time.Minute))}},
+			NextToken: awsSdk.String("next"),
+		}, nil
+	}).Times(maxPages + 1)
+
+	events, err := lookupCachedCloudTrailEvents(context.Background(), client, "us-east-1", maxPages)
+	assert.NoError(t, err)
+	assert.Len(t, events, maxPages+1)
+	assert.Equal(t, "event-1", *events[0].EventId)
+}
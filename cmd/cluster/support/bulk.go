@@ -0,0 +1,532 @@
+package support
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/internal/servicelog"
+	"github.com/openshift/osdctl/pkg/printer"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultBulkConcurrency = 5
+
+	bulkActionPost   = "post"
+	bulkActionDelete = "delete"
+)
+
+// bulkTargets are the flags selecting the clusters of a bulk operation
+type bulkTargets struct {
+	queries      []string
+	clustersFile string
+	concurrency  int
+	reportFile   string
+}
+
+func (b *bulkTargets) addFlags(cmd *cobra.Command, action string) {
+	cmd.Flags().StringArrayVarP(&b.queries, "query", "q", nil, fmt.Sprintf("Specify a search query (eg. -q \"name like foo\") to %s limited support reasons for all matching clusters", action))
+	cmd.Flags().StringVar(&b.clustersFile, "clusters-file", "", `Read a list of clusters from a file. The format of the file is: {"clusters":["$CLUSTERID"]}`)
+	cmd.Flags().IntVar(&b.concurrency, "concurrency", defaultBulkConcurrency, "Number of clusters to process in parallel when using --query or --clusters-file")
+	cmd.Flags().StringVar(&b.reportFile, "report", "", fmt.Sprintf("File to write the result report to when using --query or --clusters-file, updated after every cluster, defaults to limited-support-%s-<timestamp>.json", action))
+}
+
+// isBulk returns true if the clusters are selected by a query or clusters file
+func (b *bulkTargets) isBulk() bool {
+	return len(b.queries) > 0 || b.clustersFile != ""
+}
+
+// filters returns the OCM search filters selecting the clusters
+func (b *bulkTargets) filters() ([]string, error) {
+	filters := append([]string{}, b.queries...)
+	if b.clustersFile == "" {
+		return filters, nil
+	}
+
+	content, err := os.ReadFile(filepath.Clean(b.clustersFile))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file %s: %w", b.clustersFile, err)
+	}
+	clustersFile := servicelog.ClustersFile{}
+	if err := json.Unmarshal(content, &clustersFile); err != nil {
+		return nil, fmt.Errorf("cannot parse file %s: %w", b.clustersFile, err)
+	}
+	if len(clustersFile.Clusters) == 0 {
+		return nil, fmt.Errorf("no clusters found in %s", b.clustersFile)
+	}
+
+	var queries []string
+	for _, cluster := range clustersFile.Clusters {
+		queries = append(queries, ctlutil.GenerateQuery(cluster))
+	}
+	return append(filters, strings.Join(queries, " or ")), nil
+}
+
+// clusters returns the clusters matching the queries and clusters file
+func (b *bulkTargets) clusters(connection *sdk.Connection) ([]*cmv1.Cluster, error) {
+	filters, err := b.filters()
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := ctlutil.ApplyFilters(connection, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for clusters with provided filters (%v): %w", filters, err)
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no clusters match the given filters (%v)", filters)
+	}
+	return clusters, nil
+}
+
+// forEach calls fn for every index up to n, running at most concurrency calls in parallel
+func (b *bulkTargets) forEach(n int, fn func(i int)) {
+	concurrency := b.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// BulkReport records the outcome of a bulk limited support operation. The
+// report of a bulk post can be passed to 'cluster support delete --from-report'
+// to remove the same reasons again.
+type BulkReport struct {
+	Action    string              `json:"action"`
+	CreatedAt time.Time           `json:"created_at"`
+	Clusters  []BulkReportCluster `json:"clusters"`
+
+	// path is the file the report is saved to after every cluster, so it is
+	// complete up to the last cluster if the operation is interrupted
+	path  string
+	mutex sync.Mutex
+}
+
+// BulkReportCluster is the outcome of a bulk operation for a single cluster
+type BulkReportCluster struct {
+	ClusterID    string   `json:"cluster_id"`
+	ExternalID   string   `json:"external_id"`
+	Name         string   `json:"name"`
+	ReasonIDs    []string `json:"reason_ids,omitempty"`
+	ServiceLogID string   `json:"service_log_id,omitempty"`
+	Skipped      bool     `json:"skipped,omitempty"`
+	Error        string   `json:"error,omitempty"`
+	// Pending is true until the operation was done for the cluster
+	Pending bool `json:"pending,omitempty"`
+}
+
+// newBulkReport returns the report of the operation on the clusters, saved to path or to a
+// timestamped file in the current directory
+func newBulkReport(action string, clusters []*cmv1.Cluster, path string) *BulkReport {
+	report := &BulkReport{Action: action, CreatedAt: time.Now().UTC(), path: path}
+	if report.path == "" {
+		report.path = fmt.Sprintf("limited-support-%s-%s.json", action, report.CreatedAt.Format("20060102-150405"))
+	}
+	for _, cluster := range clusters {
+		report.Clusters = append(report.Clusters, BulkReportCluster{
+			ClusterID:  cluster.ID(),
+			ExternalID: cluster.ExternalID(),
+			Name:       cluster.Name(),
+			Pending:    true,
+		})
+	}
+	return report
+}
+
+// update records the outcome of the operation for the cluster at index i and saves the report
+func (r *BulkReport) update(i int, fn func(cluster *BulkReportCluster)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	fn(&r.Clusters[i])
+	r.Clusters[i].Pending = false
+	if err := r.write(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// failed returns the number of clusters the operation failed for
+func (r *BulkReport) failed() int {
+	failed := 0
+	for _, cluster := range r.Clusters {
+		if cluster.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// pending returns the number of clusters the operation wasn't done for, eg. when it was interrupted
+func (r *BulkReport) pending() int {
+	pending := 0
+	for _, cluster := range r.Clusters {
+		if cluster.Pending {
+			pending++
+		}
+	}
+	return pending
+}
+
+// save writes the report to its file
+func (r *BulkReport) save() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.write()
+}
+
+// write replaces the file of the report through a temporary file, so an interrupted
+// write doesn't leave a corrupted report behind. The mutex must be held.
+func (r *BulkReport) write() error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal report: %w", err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return fmt.Errorf("cannot write report %s: %w", r.path, err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("cannot write report %s: %w", r.path, err)
+	}
+	return nil
+}
+
+func loadBulkReport(path string) (*BulkReport, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read report %s: %w", path, err)
+	}
+	report := &BulkReport{}
+	if err := json.Unmarshal(content, report); err != nil {
+		return nil, fmt.Errorf("cannot parse report %s: %w", path, err)
+	}
+	return report, nil
+}
+
+func (r *BulkReport) print(w io.Writer) error {
+	table := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	table.AddRow([]string{"CLUSTER ID", "NAME", "REASON IDS", "SERVICE LOG ID", "RESULT"})
+	for _, cluster := range r.Clusters {
+		result := "ok"
+		switch {
+		case cluster.Error != "":
+			result = cluster.Error
+		case cluster.Skipped:
+			result = "skipped"
+		case cluster.Pending:
+			result = "pending"
+		}
+		table.AddRow([]string{cluster.ClusterID, cluster.Name, strings.Join(cluster.ReasonIDs, ","), cluster.ServiceLogID, result})
+	}
+	table.AddRow([]string{})
+	return table.Flush()
+}
+
+// printClusterSummary prints the clusters a bulk operation is about to change
+func printClusterSummary(w io.Writer, clusters []*cmv1.Cluster, critical []bool) error {
+	table := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	table.AddRow([]string{"NAME", "ID", "STATE", "LIMITED SUPPORT REASONS", "CRITICAL CUSTOMER"})
+	for i, cluster := range clusters {
+		table.AddRow([]string{
+			cluster.Name(),
+			cluster.ID(),
+			string(cluster.State()),
+			fmt.Sprintf("%d", cluster.Status().LimitedSupportReasonCount()),
+			fmt.Sprintf("%t", critical[i]),
+		})
+	}
+	table.AddRow([]string{})
+	return table.Flush()
+}
+
+// runBulk posts the limited support reason to all clusters matching the query or clusters file
+func (p *Post) runBulk() error {
+	connection, err := ctlutil.CreateConnection()
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	clusters, err := p.bulk.clusters(connection)
+	if err != nil {
+		return err
+	}
+
+	limitedSupport, err := p.buildReason()
+	if err != nil {
+		return err
+	}
+
+	critical := make([]bool, len(clusters))
+	errs := make([]error, len(clusters))
+	p.bulk.forEach(len(clusters), func(i int) {
+		critical[i], errs[i] = isCriticalCustomer(connection, clusters[i])
+	})
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("cluster %s: %w", clusters[i].ID(), err)
+		}
+	}
+
+	fmt.Println("The following limited support reason will be sent:")
+	if err = printLimitedSupportReason(limitedSupport); err != nil {
+		return fmt.Errorf("failed to print limited support reason template: %w", err)
+	}
+	fmt.Printf("\nto the following %d clusters:\n", len(clusters))
+	if err := printClusterSummary(os.Stdout, clusters, critical); err != nil {
+		return err
+	}
+
+	if p.isDryRun {
+		return nil
+	}
+	if !ctlutil.ConfirmPrompt() {
+		return nil
+	}
+
+	report := newBulkReport(bulkActionPost, clusters, p.bulk.reportFile)
+	if err := report.save(); err != nil {
+		return err
+	}
+
+	// Critical customers have to be confirmed one by one
+	var toPost []int
+	for i, cluster := range clusters {
+		if critical[i] {
+			fmt.Printf("Cluster %s (%s):\n%s\n", cluster.Name(), cluster.ID(), criticalCustomerWarning)
+			if !ctlutil.ConfirmPrompt() {
+				report.update(i, func(c *BulkReportCluster) { c.Skipped = true })
+				continue
+			}
+		}
+		toPost = append(toPost, i)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	p.bulk.forEach(len(toPost), func(j int) {
+		if ctx.Err() != nil {
+			return
+		}
+		i := toPost[j]
+		reasonID, serviceLogID, err := p.postToCluster(connection, clusters[i], limitedSupport)
+		report.update(i, func(c *BulkReportCluster) {
+			if reasonID != "" {
+				c.ReasonIDs = []string{reasonID}
+			}
+			c.ServiceLogID = serviceLogID
+			if err != nil {
+				c.Error = err.Error()
+			}
+		})
+	})
+
+	return finishBulkReport(report)
+}
+
+// postToCluster posts the limited support reason, and the internal service log
+// if there is evidence, to a single cluster and returns their IDs
+func (p *Post) postToCluster(connection *sdk.Connection, cluster *cmv1.Cluster, limitedSupport *cmv1.LimitedSupportReason) (string, string, error) {
+	response, err := sendLimitedSupportPostRequest(connection, cluster.ID(), limitedSupport)
+	if err != nil {
+		return "", "", err
+	}
+	reasonID := response.Body().ID()
+	if p.Evidence == "" {
+		return reasonID, "", nil
+	}
+
+	clusterPost := *p
+	clusterPost.cluster = cluster
+	var subscriptionID string
+	if subscription, ok := cluster.GetSubscription(); ok {
+		subscriptionID = subscription.ID()
+	}
+	internalServiceLog, err := clusterPost.buildInternalServiceLog(reasonID, subscriptionID)
+	if err != nil {
+		return reasonID, "", err
+	}
+	serviceLogResponse, err := sendInternalServiceLogPostRequest(connection, internalServiceLog)
+	if err != nil {
+		return reasonID, "", err
+	}
+	return reasonID, serviceLogResponse.Body().ID(), nil
+}
+
+// selectReasons returns the limited support reasons to delete, either those
+// with the given IDs, all of them, or those with the given summary
+func selectReasons(reasons []*cmv1.LimitedSupportReason, ids []string, all bool, summary string) []*cmv1.LimitedSupportReason {
+	var selected []*cmv1.LimitedSupportReason
+	for _, reason := range reasons {
+		switch {
+		case ids != nil:
+			if slices.Contains(ids, reason.ID()) {
+				selected = append(selected, reason)
+			}
+		case all, summary != "" && reason.Summary() == summary:
+			selected = append(selected, reason)
+		}
+	}
+	return selected
+}
+
+// runBulk deletes the limited support reasons of all clusters matching the
+// query or clusters file, or the reasons recorded in a bulk post report
+func (o *deleteOptions) runBulk() error {
+	connection, err := ctlutil.CreateConnection()
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	var reportedIDs map[string][]string
+	if o.fromReport != "" {
+		postReport, err := loadBulkReport(o.fromReport)
+		if err != nil {
+			return err
+		}
+		if postReport.Action != bulkActionPost {
+			return fmt.Errorf("%s is not the report of a bulk post", o.fromReport)
+		}
+		reportedIDs = map[string][]string{}
+		for _, cluster := range postReport.Clusters {
+			if len(cluster.ReasonIDs) > 0 {
+				reportedIDs[cluster.ClusterID] = cluster.ReasonIDs
+				o.bulk.queries = append(o.bulk.queries, ctlutil.GenerateQuery(cluster.ClusterID))
+			}
+		}
+		if len(reportedIDs) == 0 {
+			return fmt.Errorf("no limited support reasons were posted according to %s", o.fromReport)
+		}
+		o.bulk.queries = []string{strings.Join(o.bulk.queries, " or ")}
+	}
+
+	clusters, err := o.bulk.clusters(connection)
+	if err != nil {
+		return err
+	}
+
+	selected := make([][]*cmv1.LimitedSupportReason, len(clusters))
+	errs := make([]error, len(clusters))
+	o.bulk.forEach(len(clusters), func(i int) {
+		reasons, err := ctlutil.GetClusterLimitedSupportReasons(connection, clusters[i].ID())
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		var ids []string
+		if reportedIDs != nil {
+			ids = append([]string{}, reportedIDs[clusters[i].ID()]...)
+		}
+		selected[i] = selectReasons(reasons, ids, o.removeAll, o.summary)
+	})
+
+	var toDelete []*cmv1.Cluster
+	var reasons [][]*cmv1.LimitedSupportReason
+	table := printer.NewTablePrinter(os.Stdout, 20, 1, 3, ' ')
+	table.AddRow([]string{"CLUSTER ID", "NAME", "REASON ID", "SUMMARY"})
+	for i, cluster := range clusters {
+		if errs[i] != nil {
+			return fmt.Errorf("cluster %s: %w", cluster.ID(), errs[i])
+		}
+		if len(selected[i]) == 0 {
+			continue
+		}
+		toDelete = append(toDelete, cluster)
+		reasons = append(reasons, selected[i])
+		for _, reason := range selected[i] {
+			table.AddRow([]string{cluster.ID(), cluster.Name(), reason.ID(), reason.Summary()})
+		}
+	}
+	if len(toDelete) == 0 {
+		fmt.Println("No matching limited support reasons found, nothing to delete")
+		return nil
+	}
+
+	fmt.Printf("The following limited support reasons will be deleted from %d clusters:\n", len(toDelete))
+	table.AddRow([]string{})
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if o.isDryRun {
+		return nil
+	}
+	if !ctlutil.ConfirmPrompt() {
+		return nil
+	}
+
+	report := newBulkReport(bulkActionDelete, toDelete, o.bulk.reportFile)
+	if err := report.save(); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	o.bulk.forEach(len(toDelete), func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		var deleted, failed []string
+		for _, reason := range reasons[i] {
+			if err := deleteLimitedSupportReason(connection, toDelete[i], reason.ID()); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", reason.ID(), err))
+				continue
+			}
+			deleted = append(deleted, reason.ID())
+		}
+		report.update(i, func(c *BulkReportCluster) {
+			c.ReasonIDs = deleted
+			c.Error = strings.Join(failed, "; ")
+		})
+	})
+
+	return finishBulkReport(report)
+}
+
+// finishBulkReport saves and prints the report, and returns an error if the operation failed for any
+// cluster or was interrupted
+func finishBulkReport(report *BulkReport) error {
+	if err := report.save(); err != nil {
+		return err
+	}
+	if err := report.print(os.Stdout); err != nil {
+		return err
+	}
+
+	fmt.Printf("The report was saved to %s\n", report.path)
+	if report.Action == bulkActionPost {
+		fmt.Printf("To delete the posted limited support reasons again, run: osdctl cluster support delete --from-report %s\n", report.path)
+	}
+
+	if pending := report.pending(); pending > 0 {
+		return fmt.Errorf("interrupted, %d of %d clusters are still pending in the report %s", pending, len(report.Clusters), report.path)
+	}
+	if failed := report.failed(); failed > 0 {
+		return fmt.Errorf("failed to %s limited support reasons for %d of %d clusters", report.Action, failed, len(report.Clusters))
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func TestBulkTargetsFilters(t *testing.T) {
+	clustersFile := filepath.Join(t.TempDir(), "clusters.json")
+	if err := os.WriteFile(clustersFile, []byte(`{"clusters": ["1a2b3c4d5e6f7g8h9i0j1k2l3m4n5o6p", "my-cluster"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &bulkTargets{queries: []string{"region.id = 'us-east-1'"}, clustersFile: clustersFile}
+	got, err := b.filters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"region.id = 'us-east-1'", "(id = '1a2b3c4d5e6f7g8h9i0j1k2l3m4n5o6p') or (display_name like 'my-cluster')"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filters() got %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(clustersFile, []byte(`{"clusters": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.filters(); err == nil {
+		t.Error("filters() expected an error for an empty clusters file")
+	}
+}
+
+func TestBulkTargetsForEach(t *testing.T) {
+	b := &bulkTargets{concurrency: 3}
+	var running, maxRunning, calls int32
+	seen := make([]bool, 20)
+	b.forEach(len(seen), func(i int) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			previous := atomic.LoadInt32(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+				break
+			}
+		}
+		seen[i] = true
+		atomic.AddInt32(&calls, 1)
+		atomic.AddInt32(&running, -1)
+	})
+
+	if calls != 20 {
+		t.Errorf("forEach() called fn %d times, want 20", calls)
+	}
+	if maxRunning > 3 {
+		t.Errorf("forEach() ran %d calls in parallel, want at most 3", maxRunning)
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("forEach() didn't call fn for %d", i)
+		}
+	}
+}
+
+func TestSelectReasons(t *testing.T) {
+	reason1, _ := cmv1.NewLimitedSupportReason().ID("reason-1").Summary("Cloud").Build()
+	reason2, _ := cmv1.NewLimitedSupportReason().ID("reason-2").Summary("Cluster").Build()
+	reasons := []*cmv1.LimitedSupportReason{reason1, reason2}
+
+	tests := []struct {
+		name    string
+		ids     []string
+		all     bool
+		summary string
+		want    []*cmv1.LimitedSupportReason
+	}{
+		{name: "by id", ids: []string{"reason-2"}, want: []*cmv1.LimitedSupportReason{reason2}},
+		{name: "no reported ids", ids: []string{}, all: true},
+		{name: "all", all: true, want: reasons},
+		{name: "by summary", summary: "Cloud", want: []*cmv1.LimitedSupportReason{reason1}},
+		{name: "nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectReasons(reasons, tt.ids, tt.all, tt.summary); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectReasons() got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkReportRoundTrip(t *testing.T) {
+	cluster1, _ := cmv1.NewCluster().ID("cluster-1").Name("one").Build()
+	cluster2, _ := cmv1.NewCluster().ID("cluster-2").Name("two").Build()
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	report := newBulkReport(bulkActionPost, []*cmv1.Cluster{cluster1, cluster2}, path)
+	if err := report.save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadBulkReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := loaded.pending(); pending != 2 {
+		t.Errorf("pending() got %d, want 2", pending)
+	}
+
+	// Every update is saved, so the report is complete up to the last cluster if interrupted
+	report.update(0, func(c *BulkReportCluster) { c.ReasonIDs = []string{"reason-1"} })
+	loaded, err = loadBulkReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Clusters, report.Clusters) || loaded.Action != bulkActionPost {
+		t.Errorf("loadBulkReport() got %+v, want %+v", loaded.Clusters, report.Clusters)
+	}
+	if pending := loaded.pending(); pending != 1 {
+		t.Errorf("pending() got %d, want 1", pending)
+	}
+
+	report.update(1, func(c *BulkReportCluster) { c.Error = "boom" })
+	loaded, err = loadBulkReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed, pending := loaded.failed(), loaded.pending(); failed != 1 || pending != 0 {
+		t.Errorf("failed() and pending() got %d and %d, want 1 and 0", failed, pending)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary report file left behind: %v", err)
+	}
+}
+
+func TestPostCheckTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		post    *Post
+		wantErr bool
+	}{
+		{name: "cluster id", post: &Post{ClusterID: "cluster-1", Template: "template.json"}},
+		{name: "query", post: &Post{bulk: bulkTargets{queries: []string{"name like foo"}}, Template: "template.json"}},
+		{name: "no target", post: &Post{Template: "template.json"}, wantErr: true},
+		{name: "cluster id and query", post: &Post{ClusterID: "cluster-1", bulk: bulkTargets{clustersFile: "clusters.json"}, Template: "template.json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.post.check(); (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ func NewCmdSupport(streams genericclioptions.IOStreams, client client.Client, gl
 	supportCmd.AddCommand(newCmdstatus(streams, globalOpts))
 	supportCmd.AddCommand(newCmdPost())
 	supportCmd.AddCommand(newCmddelete(streams, globalOpts))
+	supportCmd.AddCommand(newCmdTemplates())
 
 	return supportCmd
 }
@@ -25,6 +25,9 @@ type deleteOptions struct {
 	limitedSupportReasonID string
 	removeAll              bool
 	isDryRun               bool
+	fromReport             string
+	summary                string
+	bulk                   bulkTargets
 
 	genericclioptions.IOStreams
 	GlobalOptions *globalflags.GlobalOptions
@@ -34,8 +37,13 @@ func newCmddelete(streams genericclioptions.IOStreams, globalOpts *globalflags.G
 
 	ops := newDeleteOptions(streams, globalOpts)
 	deleteCmd := &cobra.Command{
-		Use:               "delete --cluster-id <cluster-identifier>",
-		Short:             "Delete specified limited support reason for a given cluster",
+		Use:   "delete --cluster-id <cluster-identifier>",
+		Short: "Delete specified limited support reason for a given cluster",
+		Example: `# Delete the limited support reasons posted by a bulk post
+osdctl cluster support delete --from-report limited-support-post-20240131-120000.json
+
+# Delete the limited support reasons with the given summary from all matching clusters
+osdctl cluster support delete -q "region.id = 'us-east-1'" --summary "Cluster is in Limited Support due to unsupported cloud provider configuration" --dry-run`,
 		Args:              cobra.NoArgs,
 		DisableAutoGenTag: true,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -45,14 +53,14 @@ func newCmddelete(streams genericclioptions.IOStreams, globalOpts *globalflags.G
 	}
 
 	// Defined required flags
-	deleteCmd.Flags().StringVarP(&ops.clusterID, "cluster-id", "c", "", "Internal cluster ID (required)")
+	deleteCmd.Flags().StringVarP(&ops.clusterID, "cluster-id", "c", "", "Internal cluster ID")
 	deleteCmd.Flags().BoolVar(&ops.removeAll, "all", false, "Remove all limited support reasons")
 	deleteCmd.Flags().StringVarP(&ops.limitedSupportReasonID, "limited-support-reason-id", "i", "", "Limited support reason ID")
 	deleteCmd.Flags().BoolVarP(&ops.isDryRun, "dry-run", "d", false, "Dry-run - print the limited support reason about to be sent but don't send it.")
 	deleteCmd.Flags().BoolVarP(&ops.verbose, "verbose", "", false, "Verbose output")
-
-	// Mark cluster-id as required
-	deleteCmd.MarkFlagRequired("cluster-id")
+	deleteCmd.Flags().StringVar(&ops.fromReport, "from-report", "", "Delete the limited support reasons recorded in the report of a bulk 'cluster support post'")
+	deleteCmd.Flags().StringVar(&ops.summary, "summary", "", "Delete the limited support reasons with this summary, when using --query or --clusters-file")
+	ops.bulk.addFlags(deleteCmd, bulkActionDelete)
 
 	return deleteCmd
 }
@@ -71,12 +79,38 @@ func (o *deleteOptions) complete(cmd *cobra.Command, args []string) error {
 		return cmdutil.UsageErrorf(cmd, "Cannot provide a reason ID with the `all` flag. Please provide one or the other.")
 	}
 
+	targets := 0
+	for _, set := range []bool{o.clusterID != "", o.bulk.isBulk(), o.fromReport != ""} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return cmdutil.UsageErrorf(cmd, "Please provide exactly one of --cluster-id, --from-report, or --query and --clusters-file.")
+	}
+	if o.bulk.isBulk() {
+		if o.limitedSupportReasonID != "" {
+			return cmdutil.UsageErrorf(cmd, "Cannot provide a reason ID with --query or --clusters-file, use --all or --summary instead.")
+		}
+		if o.removeAll == (o.summary != "") {
+			return cmdutil.UsageErrorf(cmd, "Please provide one of --all or --summary with --query or --clusters-file.")
+		}
+	} else if o.summary != "" {
+		return cmdutil.UsageErrorf(cmd, "--summary can only be used with --query or --clusters-file.")
+	}
+	if o.fromReport != "" && (o.removeAll || o.limitedSupportReasonID != "") {
+		return cmdutil.UsageErrorf(cmd, "Cannot provide a reason ID or the `all` flag with --from-report, the reasons are read from the report.")
+	}
+
 	o.output = o.GlobalOptions.Output
 
 	return nil
 }
 
 func (o *deleteOptions) run() error {
+	if o.bulk.isBulk() || o.fromReport != "" {
+		return o.runBulk()
+	}
 
 	// Check that the cluster key (name, identifier or external identifier) given by the user
 	// is reasonably safe so that there is no risk of SQL injection
@@ -191,5 +225,5 @@ func checkDelete(response *sdk.Response) error {
 	if err := json.Unmarshal(body, &badReply); err != nil {
 		return fmt.Errorf("cannot parse the error JSON meessage: %q", err)
 	}
-	return nil
+	return fmt.Errorf("%s", badReply.Reason)
 }
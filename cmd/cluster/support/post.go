@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/openshift-online/ocm-cli/pkg/dump"
@@ -18,8 +19,10 @@ import (
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
 	"github.com/openshift/osdctl/internal/utils"
+	"github.com/openshift/osdctl/pkg/osdctlConfig"
 	ctlutil "github.com/openshift/osdctl/pkg/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 const (
@@ -35,6 +38,9 @@ const (
 	InternalServiceLogServiceName                        = "SREManualAction"
 	InternalServiceLogSummary                            = "LimitedSupportEvidence"
 	managedCriticalCustomerLabel                         = "capability.organization.managed_critical_customer"
+	criticalCustomerWarning                              = `WARNING: This cluster is owned by a critical customer. Make sure that an SL has been sent and proactive case opened with the customer. Only continue if there has been no customer response for 24 hours.
+
+See: https://source.redhat.com/groups/public/sre/wiki/defining_limited_support_process_for_osdrosa_for_critical_customers`
 )
 
 type Post struct {
@@ -46,14 +52,17 @@ type Post struct {
 	Evidence         string
 	cluster          *cmv1.Cluster
 	ClusterID        string
+	isDryRun         bool
+	bulk             bulkTargets
 }
 
 type TemplateFile struct {
-	Severity      string             `json:"severity"`
-	Summary       string             `json:"summary"`
-	LogType       string             `json:"log_type"`
-	Details       string             `json:"details"`
-	DetectionType cmv1.DetectionType `json:"detection_type"`
+	Severity      string              `json:"severity"`
+	Summary       string              `json:"summary"`
+	LogType       string              `json:"log_type"`
+	Details       string              `json:"details"`
+	DetectionType cmv1.DetectionType  `json:"detection_type"`
+	Parameters    []TemplateParameter `json:"parameters,omitempty"`
 }
 
 var (
@@ -75,6 +84,12 @@ osdctl cluster support post --cluster-id=1a2B3c4DefghIjkLMNOpQrSTUV5 --misconfig
 
 Will result in the following limited-support text sent to the customer:
 The cluster has a second failing ingress controller, which is not supported and can cause issues with SLA. Remove the additional ingress controller 'my-custom-ingresscontroller'. 'oc get ingresscontroller -n openshift-ingress-operator' should yield only 'default'.
+
+# Post a limited support reason from a template to all clusters in a region, 10 at a time
+osdctl cluster support post -q "region.id = 'us-east-1' and cloud_provider.id = 'aws'" -t cloud/region-outage --concurrency 10 --evidence="See OHSS-1234"
+
+# Preview which clusters of a list would be placed into limited support
+osdctl cluster support post --clusters-file clusters.json -t cloud/region-outage --dry-run
 `,
 		Args:              cobra.NoArgs,
 		DisableAutoGenTag: true,
@@ -84,15 +99,15 @@ The cluster has a second failing ingress controller, which is not supported and
 	}
 
 	// Define required flags
-	postCmd.Flags().StringVarP(&p.ClusterID, "cluster-id", "c", "", "Internal Cluster ID (required)")
-	postCmd.Flags().StringVarP(&p.Template, "template", "t", "", "Message template file or URL")
+	postCmd.Flags().StringVarP(&p.ClusterID, "cluster-id", "c", "", "Internal Cluster ID")
+	postCmd.Flags().StringVarP(&p.Template, "template", "t", "", "Message template file, URL or name of a template in the limited support template registry (see 'osdctl cluster support templates')")
 	postCmd.Flags().StringArrayVarP(&p.TemplateParams, "param", "p", p.TemplateParams, "Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template.")
 	postCmd.Flags().Var(&p.Misconfiguration, MisconfigurationFlag, "The type of misconfiguration responsible for the cluster being placed into limited support. Valid values are `cloud` or `cluster`.")
 	postCmd.Flags().StringVar(&p.Problem, ProblemFlag, "", "Complete sentence(s) describing the problem responsible for the cluster being placed into limited support. Will form the limited support message with the contents of --resolution appended")
 	postCmd.Flags().StringVar(&p.Resolution, ResolutionFlag, "", "Complete sentence(s) describing the steps for the customer to take to resolve the issue and move out of limited support. Will form the limited support message with the contents of --problem prepended")
-	postCmd.Flags().StringVar(&p.Evidence, EvidenceFlag, "", "(optional) The reasoning that led to the decision to place the cluster in limited support. Can also be a link to a Jira case. Used for internal service log only.")
-
-	_ = postCmd.MarkFlagRequired("cluster-id")
+	postCmd.Flags().StringVar(&p.Evidence, EvidenceFlag, "", "(optional) The reasoning that led to the decision to place the cluster in limited support. Can also be a link to a Jira case. Used for internal service log only, also with --template.")
+	postCmd.Flags().BoolVarP(&p.isDryRun, "dry-run", "d", false, "Dry-run - print the limited support reason and the clusters it would be sent to, but don't send it.")
+	p.bulk.addFlags(postCmd, bulkActionPost)
 
 	return postCmd
 }
@@ -113,9 +128,16 @@ func validateResolutionString(res string) error {
 }
 
 func (p *Post) check() error {
+	if p.ClusterID == "" && !p.bulk.isBulk() {
+		return fmt.Errorf("\nOne of --cluster-id, --query or --clusters-file is required")
+	}
+	if p.ClusterID != "" && p.bulk.isBulk() {
+		return fmt.Errorf("\n--cluster-id cannot be combined with --query or --clusters-file")
+	}
 	if p.Template != "" {
-		if p.Problem != "" || p.Resolution != "" || p.Misconfiguration != "" || p.Evidence != "" {
-			return fmt.Errorf("\nIf --template flag is used, --problem, --resolution, --misconfiguration and --evidence flags cannot be used")
+		// --evidence is allowed, the internal service log is sent for templates as well
+		if p.Problem != "" || p.Resolution != "" || p.Misconfiguration != "" {
+			return fmt.Errorf("\nIf --template flag is used, --problem, --resolution and --misconfiguration flags cannot be used")
 		}
 	} else {
 		if p.Problem == "" || p.Resolution == "" || p.Misconfiguration == "" {
@@ -139,6 +161,10 @@ func (p *Post) Run(clusterID string) error {
 		return err
 	}
 
+	if p.bulk.isBulk() {
+		return p.runBulk()
+	}
+
 	// Check that the cluster key (name, identifier or external identifier) given by the user
 	// is reasonably safe so that there is no risk of SQL injection
 	if err := ctlutil.IsValidClusterKey(clusterID); err != nil {
@@ -161,49 +187,20 @@ func (p *Post) Run(clusterID string) error {
 		return fmt.Errorf("can't retrieve cluster: %w", err)
 	}
 
-	subscriptionResponse, err := connection.
-		AccountsMgmt().
-		V1().
-		Subscriptions().
-		Subscription(p.cluster.Subscription().ID()).
-		Get().Send()
+	critical, err := isCriticalCustomer(connection, p.cluster)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve cluster subscription: %w", err)
+		return err
 	}
-
-	labelResponse, err := connection.
-		AccountsMgmt().
-		V1().
-		Organizations().
-		Organization(subscriptionResponse.Body().OrganizationID()).
-		Labels().
-		Label(managedCriticalCustomerLabel).
-		Get().Send()
-	if err != nil {
-		// if the label is missing, there's no need to show an error to the user
-		if labelResponse.Error().Status() != http.StatusNotFound {
-			return fmt.Errorf("failed to retrieve cluster labels: %w", err)
-		}
-	} else if labelResponse.Body().Value() == "true" {
-		fmt.Println(`WARNING: This cluster is owned by a critical customer. Make sure that an SL has been sent and proactive case opened with the customer. Only continue if there has been no customer response for 24 hours.
-
-See: https://source.redhat.com/groups/public/sre/wiki/defining_limited_support_process_for_osdrosa_for_critical_customers`)
+	if critical {
+		fmt.Println(criticalCustomerWarning)
 		if !ctlutil.ConfirmPrompt() {
 			return nil
 		}
 	}
 
-	var limitedSupport *cmv1.LimitedSupportReason
-	if p.Template != "" {
-		limitedSupport, err = p.buildLimitedSupportTemplate()
-		if err != nil {
-			return err
-		}
-	} else {
-		limitedSupport, err = p.buildLimitedSupport()
-		if err != nil {
-			return err
-		}
+	limitedSupport, err := p.buildReason()
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("The following limited support reason will be sent to %s:\n", clusterID)
@@ -211,6 +208,10 @@ See: https://source.redhat.com/groups/public/sre/wiki/defining_limited_support_p
 		return fmt.Errorf("failed to print limited support reason template: %w", err)
 	}
 
+	if p.isDryRun {
+		return nil
+	}
+
 	if !ctlutil.ConfirmPrompt() {
 		return nil
 	}
@@ -246,6 +247,44 @@ See: https://source.redhat.com/groups/public/sre/wiki/defining_limited_support_p
 	return nil
 }
 
+// isCriticalCustomer returns true if the cluster is owned by an organization labeled as critical customer
+func isCriticalCustomer(connection *sdk.Connection, cluster *cmv1.Cluster) (bool, error) {
+	subscriptionResponse, err := connection.
+		AccountsMgmt().
+		V1().
+		Subscriptions().
+		Subscription(cluster.Subscription().ID()).
+		Get().Send()
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve cluster subscription: %w", err)
+	}
+
+	labelResponse, err := connection.
+		AccountsMgmt().
+		V1().
+		Organizations().
+		Organization(subscriptionResponse.Body().OrganizationID()).
+		Labels().
+		Label(managedCriticalCustomerLabel).
+		Get().Send()
+	if err != nil {
+		// if the label is missing, there's no need to show an error to the user
+		if labelResponse.Error().Status() != http.StatusNotFound {
+			return false, fmt.Errorf("failed to retrieve cluster labels: %w", err)
+		}
+		return false, nil
+	}
+	return labelResponse.Body().Value() == "true", nil
+}
+
+// buildReason builds the limited support reason from the template or the flags
+func (p *Post) buildReason() (*cmv1.LimitedSupportReason, error) {
+	if p.Template != "" {
+		return p.buildLimitedSupportTemplate()
+	}
+	return p.buildLimitedSupport()
+}
+
 func (p *Post) buildLimitedSupport() (*cmv1.LimitedSupportReason, error) {
 	limitedSupportBuilder := cmv1.NewLimitedSupportReason().
 		Details(fmt.Sprintf("%s %s", p.Problem, p.Resolution)).
@@ -271,12 +310,25 @@ func (p *Post) buildLimitedSupportTemplate() (*cmv1.LimitedSupportReason, error)
 	}
 
 	p.parseUserParameters() // parse all the '-p' user flags
+	// Use the default value of declared parameters which weren't set
+	for _, param := range t.Parameters {
+		placeholder := fmt.Sprintf("${%v}", param.Name)
+		if param.Default != "" && !slices.Contains(userParameterNames, placeholder) {
+			userParameterNames = append(userParameterNames, placeholder)
+			userParameterValues = append(userParameterValues, param.Default)
+		}
+	}
 	// For every '-p' flag, replace its related placeholder in the template
 	for k := range userParameterNames {
 		p.replaceFlags(t, userParameterNames[k], userParameterValues[k])
 	}
 	p.checkLeftovers(t)
 
+	return p.buildLimitedSupportFromTemplate(t)
+}
+
+// buildLimitedSupportFromTemplate builds the limited support reason of a template with all parameters replaced
+func (p *Post) buildLimitedSupportFromTemplate(t *TemplateFile) (*cmv1.LimitedSupportReason, error) {
 	limitedSupportBuilder := cmv1.NewLimitedSupportReason().Summary(t.Summary).Details(t.Details).DetectionType(t.DetectionType)
 	limitedSupport, err := limitedSupportBuilder.Build()
 
@@ -304,9 +356,24 @@ func (p *Post) parseUserParameters() {
 }
 
 func (p *Post) readTemplate() (*TemplateFile, error) {
-	templateObj, err := p.accessFile(p.Template)
-	if err != nil { //check the presence of this URL or file and also if this can be accessed
-		return nil, err
+	var templateObj []byte
+	var err error
+	if !utils.IsValidUrl(p.Template) && !utils.FileExists(filepath.Clean(p.Template)) && viper.GetString(osdctlConfig.LimitedSupportTemplatesDir) != "" {
+		// not a file or URL, look the template up in the registry
+		registry, err := newTemplateRegistry("")
+		if err != nil {
+			return nil, err
+		}
+		registryTemplate, err := registry.get(p.Template)
+		if err != nil {
+			return nil, err
+		}
+		templateObj = registryTemplate.Content
+	} else {
+		templateObj, err = p.accessFile(p.Template)
+		if err != nil { //check the presence of this URL or file and also if this can be accessed
+			return nil, err
+		}
 	}
 
 	var template TemplateFile
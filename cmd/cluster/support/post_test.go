@@ -26,6 +26,28 @@ func TestValidateResolutionString(t *testing.T) {
 	}
 }
 
+func TestPostCheckTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		post    *Post
+		wantErr bool
+	}{
+		{name: "template", post: &Post{ClusterID: "cluster-1", Template: "template.json"}},
+		// The internal service log with the evidence is sent for templates too
+		{name: "template with evidence", post: &Post{ClusterID: "cluster-1", Template: "template.json", Evidence: "OHSS-1234"}},
+		{name: "template with problem", post: &Post{ClusterID: "cluster-1", Template: "template.json", Problem: "Broken"}, wantErr: true},
+		{name: "template with resolution", post: &Post{ClusterID: "cluster-1", Template: "template.json", Resolution: "Fix it"}, wantErr: true},
+		{name: "template with misconfiguration", post: &Post{ClusterID: "cluster-1", Template: "template.json", Misconfiguration: cloud}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.post.check(); (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func Test_buildLimitedSupport(t *testing.T) {
 	tests := []struct {
 		name        string
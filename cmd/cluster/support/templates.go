@@ -0,0 +1,468 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/internal/servicelog"
+	"github.com/openshift/osdctl/pkg/osdctlConfig"
+	"github.com/openshift/osdctl/pkg/printer"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	templatesDirFlag = "templates-dir"
+
+	previewLimitedSupportID = "<limited-support-reason-id>"
+	previewClusterID        = "<cluster-id>"
+	previewClusterUUID      = "<cluster-uuid>"
+)
+
+var (
+	templatePlaceholderRegex = regexp.MustCompile(`\${[^{}]*}`)
+	templateParamNameRegex   = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+)
+
+// TemplateParameter declares a ${NAME} placeholder of a limited support template
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+	Default     string `json:"default,omitempty"`
+}
+
+// registryTemplate is a limited support template found in the registry
+type registryTemplate struct {
+	Name    string
+	Path    string
+	Content []byte
+}
+
+// templateRegistry is a directory, usually a git checkout, of limited support templates
+type templateRegistry struct {
+	dir string
+}
+
+// newTemplateRegistry returns the registry in dir, or the one configured in ~/.config/osdctl if dir is empty
+func newTemplateRegistry(dir string) (*templateRegistry, error) {
+	if dir == "" {
+		dir = viper.GetString(osdctlConfig.LimitedSupportTemplatesDir)
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("no limited support template registry configured, set %s in ~/.config/osdctl or use --%s", osdctlConfig.LimitedSupportTemplatesDir, templatesDirFlag)
+	}
+
+	if strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(home, dir[2:])
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access the limited support template registry: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("the limited support template registry %s is not a directory", dir)
+	}
+
+	return &templateRegistry{dir: dir}, nil
+}
+
+// list returns all templates in the registry, sorted by name. Hidden
+// directories, like .git, are skipped.
+func (r *templateRegistry) list() ([]*registryTemplate, error) {
+	var templates []*registryTemplate
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != r.dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path) //#nosec G304 -- Potential file inclusion via variable
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(r.dir, path)
+		if err != nil {
+			return err
+		}
+		templates = append(templates, &registryTemplate{
+			Name:    filepath.ToSlash(strings.TrimSuffix(name, ".json")),
+			Path:    path,
+			Content: content,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the limited support template registry: %w", err)
+	}
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// get returns the template with the given name
+func (r *templateRegistry) get(name string) (*registryTemplate, error) {
+	templates, err := r.list()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSuffix(name, ".json")
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("template %q not found in %s", name, r.dir)
+}
+
+// parse decodes the template, rejecting unknown fields
+func (t *registryTemplate) parse() (*TemplateFile, error) {
+	var template TemplateFile
+	decoder := json.NewDecoder(bytes.NewReader(t.Content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// lintTemplate returns the problems found in the template
+func lintTemplate(template *TemplateFile) []string {
+	var problems []string
+
+	if strings.TrimSpace(template.Summary) == "" {
+		problems = append(problems, "summary is missing")
+	}
+	if strings.TrimSpace(template.Details) == "" {
+		problems = append(problems, "details are missing")
+	}
+	if template.DetectionType != cmv1.DetectionTypeManual && template.DetectionType != cmv1.DetectionTypeAuto {
+		problems = append(problems, fmt.Sprintf("invalid detection_type %q, must be %q or %q", template.DetectionType, cmv1.DetectionTypeManual, cmv1.DetectionTypeAuto))
+	}
+	if placeholders := templatePlaceholderRegex.FindAllString(template.Summary, -1); len(placeholders) > 0 {
+		problems = append(problems, fmt.Sprintf("summary cannot contain parameters, found %s", strings.Join(placeholders, ", ")))
+	}
+
+	declared := map[string]bool{}
+	for _, param := range template.Parameters {
+		switch {
+		case !templateParamNameRegex.MatchString(param.Name):
+			problems = append(problems, fmt.Sprintf("parameter %q must be upper case, like FOO_BAR", param.Name))
+		case declared[param.Name]:
+			problems = append(problems, fmt.Sprintf("parameter %s is declared more than once", param.Name))
+		}
+		if strings.TrimSpace(param.Description) == "" {
+			problems = append(problems, fmt.Sprintf("parameter %s has no description", param.Name))
+		}
+		if param.Required && param.Default != "" {
+			problems = append(problems, fmt.Sprintf("parameter %s is required, but has a default value", param.Name))
+		}
+		declared[param.Name] = true
+	}
+
+	used := map[string]bool{}
+	for _, placeholder := range templatePlaceholderRegex.FindAllString(template.Details, -1) {
+		name := strings.TrimSuffix(strings.TrimPrefix(placeholder, "${"), "}")
+		if used[name] {
+			continue
+		}
+		used[name] = true
+		if !declared[name] {
+			problems = append(problems, fmt.Sprintf("parameter %s is used in the details, but not declared", placeholder))
+		}
+	}
+	for _, param := range template.Parameters {
+		if !used[param.Name] {
+			problems = append(problems, fmt.Sprintf("parameter %s is declared, but not used in the details", param.Name))
+		}
+	}
+
+	return problems
+}
+
+// renderTemplate replaces the parameters in the details with the given
+// values, or the declared defaults. The names of the required parameters
+// without a value are returned.
+func renderTemplate(template *TemplateFile, values map[string]string) (*TemplateFile, []string) {
+	rendered := *template
+	var missing []string
+	for _, param := range template.Parameters {
+		value, found := values[param.Name]
+		if !found {
+			value = param.Default
+		}
+		if value == "" {
+			if param.Required {
+				missing = append(missing, param.Name)
+			}
+			continue
+		}
+		rendered.Details = strings.ReplaceAll(rendered.Details, fmt.Sprintf("${%s}", param.Name), value)
+	}
+	return &rendered, missing
+}
+
+func newCmdTemplates() *cobra.Command {
+	var templatesDir string
+
+	templatesCmd := &cobra.Command{
+		Use:   "templates",
+		Short: "Manage the limited support template registry",
+		Long: fmt.Sprintf(`Manage the limited support template registry.
+
+The registry is a directory, usually a git checkout, of limited support
+templates in JSON format. It is configured with the %s key in
+~/.config/osdctl, or the --%s flag. Templates are referred to by their path
+relative to the registry, without the .json extension, and can be passed to
+'osdctl cluster support post --template'.
+
+Besides the limited support reason fields, templates declare the parameters
+used in their details:
+
+  {
+    "summary": "Cluster is in Limited Support due to unsupported cloud provider configuration",
+    "details": "The security group ${SECURITY_GROUP} was modified.",
+    "detection_type": "manual",
+    "parameters": [
+      {"name": "SECURITY_GROUP", "description": "ID of the modified security group", "required": true}
+    ]
+  }`, osdctlConfig.LimitedSupportTemplatesDir, templatesDirFlag),
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run:               help,
+	}
+	templatesCmd.PersistentFlags().StringVar(&templatesDir, templatesDirFlag, "", fmt.Sprintf("Directory of the limited support templates, overrides %s in ~/.config/osdctl", osdctlConfig.LimitedSupportTemplatesDir))
+
+	templatesCmd.AddCommand(newCmdTemplatesList(&templatesDir))
+	templatesCmd.AddCommand(newCmdTemplatesShow(&templatesDir))
+	templatesCmd.AddCommand(newCmdTemplatesLint(&templatesDir))
+
+	return templatesCmd
+}
+
+func newCmdTemplatesList(templatesDir *string) *cobra.Command {
+	return &cobra.Command{
+		Use:               "list",
+		Short:             "List the templates in the limited support template registry",
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registry, err := newTemplateRegistry(*templatesDir)
+			if err != nil {
+				return err
+			}
+			templates, err := registry.list()
+			if err != nil {
+				return err
+			}
+
+			table := printer.NewTablePrinter(cmd.OutOrStdout(), 20, 1, 3, ' ')
+			table.AddRow([]string{"NAME", "PARAMETERS", "SUMMARY"})
+			for _, t := range templates {
+				template, err := t.parse()
+				if err != nil {
+					table.AddRow([]string{t.Name, "", fmt.Sprintf("invalid template: %v", err)})
+					continue
+				}
+				var params []string
+				for _, param := range template.Parameters {
+					if param.Required {
+						params = append(params, param.Name+"*")
+					} else {
+						params = append(params, param.Name)
+					}
+				}
+				table.AddRow([]string{t.Name, strings.Join(params, ","), template.Summary})
+			}
+			return table.Flush()
+		},
+	}
+}
+
+type templatesShowOptions struct {
+	templatesDir *string
+	params       []string
+	evidence     string
+	clusterID    string
+}
+
+func newCmdTemplatesShow(templatesDir *string) *cobra.Command {
+	o := &templatesShowOptions{templatesDir: templatesDir}
+	showCmd := &cobra.Command{
+		Use:   "show <template>",
+		Short: "Show a limited support template and preview what would be sent",
+		Example: `# Preview the limited support reason and internal service log for a template
+osdctl cluster support templates show cloud/security-group -p SECURITY_GROUP=sg-1234 --evidence "See OHSS-1234"`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(args[0])
+		},
+	}
+
+	showCmd.Flags().StringArrayVarP(&o.params, "param", "p", nil, "Specify a key-value pair (eg. -p FOO=BAR) to set a parameter value in the preview")
+	showCmd.Flags().StringVar(&o.evidence, EvidenceFlag, "", "Evidence to preview the internal service log with")
+	showCmd.Flags().StringVarP(&o.clusterID, "cluster-id", "c", "", "Internal cluster ID to preview the internal service log for, a placeholder cluster is used if not set")
+
+	return showCmd
+}
+
+func (o *templatesShowOptions) run(name string) error {
+	values, err := servicelog.ParseTemplateParams(o.params)
+	if err != nil {
+		return err
+	}
+
+	registry, err := newTemplateRegistry(*o.templatesDir)
+	if err != nil {
+		return err
+	}
+	t, err := registry.get(name)
+	if err != nil {
+		return err
+	}
+	template, err := t.parse()
+	if err != nil {
+		return fmt.Errorf("invalid template %s: %w", t.Name, err)
+	}
+
+	fmt.Printf("Template: %s\nPath:     %s\n\n", t.Name, t.Path)
+	if len(template.Parameters) > 0 {
+		table := printer.NewTablePrinter(os.Stdout, 20, 1, 3, ' ')
+		table.AddRow([]string{"PARAMETER", "REQUIRED", "DEFAULT", "DESCRIPTION"})
+		for _, param := range template.Parameters {
+			table.AddRow([]string{param.Name, fmt.Sprintf("%t", param.Required), param.Default, param.Description})
+		}
+		table.AddRow([]string{})
+		if err := table.Flush(); err != nil {
+			return err
+		}
+	}
+
+	rendered, missing := renderTemplate(template, values)
+	if len(missing) > 0 {
+		fmt.Printf("WARNING: required parameters without a value: %s\n\n", strings.Join(missing, ", "))
+	}
+
+	p := &Post{Evidence: o.evidence}
+	limitedSupport, err := p.buildLimitedSupportFromTemplate(rendered)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Limited support reason sent to the customer:")
+	if err := printLimitedSupportReason(limitedSupport); err != nil {
+		return fmt.Errorf("failed to print limited support reason template: %w", err)
+	}
+
+	if o.evidence == "" {
+		fmt.Println("\nNo internal service log is sent without --evidence")
+		return nil
+	}
+
+	var subscriptionID string
+	if o.clusterID != "" {
+		connection, err := ctlutil.CreateConnection()
+		if err != nil {
+			return err
+		}
+		defer connection.Close()
+		p.cluster, err = ctlutil.GetCluster(connection, o.clusterID)
+		if err != nil {
+			return fmt.Errorf("can't retrieve cluster: %w", err)
+		}
+		if subscription, ok := p.cluster.GetSubscription(); ok {
+			subscriptionID = subscription.ID()
+		}
+	} else {
+		p.cluster, err = cmv1.NewCluster().ID(previewClusterID).ExternalID(previewClusterUUID).Build()
+		if err != nil {
+			return err
+		}
+	}
+
+	internalServiceLog, err := p.buildInternalServiceLog(previewLimitedSupportID, subscriptionID)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nInternal service log:")
+	if err := printInternalServiceLog(internalServiceLog); err != nil {
+		return fmt.Errorf("failed to print internal service log template: %w", err)
+	}
+	return nil
+}
+
+func newCmdTemplatesLint(templatesDir *string) *cobra.Command {
+	return &cobra.Command{
+		Use:               "lint [template]...",
+		Short:             "Check the templates of the limited support template registry",
+		Long:              "Check the templates of the limited support template registry for unknown fields, missing fields, invalid detection types and undeclared or unused parameters. All templates are checked if none is given.",
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registry, err := newTemplateRegistry(*templatesDir)
+			if err != nil {
+				return err
+			}
+
+			var templates []*registryTemplate
+			if len(args) == 0 {
+				if templates, err = registry.list(); err != nil {
+					return err
+				}
+			}
+			for _, name := range args {
+				t, err := registry.get(name)
+				if err != nil {
+					return err
+				}
+				templates = append(templates, t)
+			}
+
+			failed := 0
+			for _, t := range templates {
+				var problems []string
+				template, err := t.parse()
+				if err != nil {
+					problems = []string{fmt.Sprintf("invalid template: %v", err)}
+				} else {
+					problems = lintTemplate(template)
+				}
+
+				if len(problems) == 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: ok\n", t.Name)
+					continue
+				}
+				failed++
+				for _, problem := range problems {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", t.Name, problem)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d templates failed linting", failed, len(templates))
+			}
+			return nil
+		},
+	}
+}
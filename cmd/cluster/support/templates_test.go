@@ -0,0 +1,142 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func TestTemplateRegistry(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cloud/security-group.json": `{"summary": "Cloud", "details": "Group ${SECURITY_GROUP}", "detection_type": "manual"}`,
+		"cluster/ingress.json":      `{"summary": "Cluster", "details": "Ingress", "detection_type": "manual"}`,
+		"README.md":                 "not a template",
+		".git/config.json":          "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry, err := newTemplateRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := registry.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+	if want := []string{"cloud/security-group", "cluster/ingress"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("list() got %v, want %v", names, want)
+	}
+
+	template, err := registry.get("cluster/ingress.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.Path != filepath.Join(dir, "cluster/ingress.json") {
+		t.Errorf("get() got path %s", template.Path)
+	}
+
+	if _, err := registry.get("cluster/missing"); err == nil {
+		t.Error("get() expected an error for a missing template")
+	}
+
+	if _, err := newTemplateRegistry(filepath.Join(dir, "README.md")); err == nil {
+		t.Error("newTemplateRegistry() expected an error for a file")
+	}
+}
+
+func TestLintTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template *TemplateFile
+		want     []string
+	}{
+		{
+			name: "valid template",
+			template: &TemplateFile{
+				Summary:       "Summary",
+				Details:       "The group ${SECURITY_GROUP} was modified, ${SECURITY_GROUP} must be restored",
+				DetectionType: cmv1.DetectionTypeManual,
+				Parameters:    []TemplateParameter{{Name: "SECURITY_GROUP", Description: "ID of the group", Required: true}},
+			},
+		},
+		{
+			name: "missing fields",
+			template: &TemplateFile{
+				DetectionType: "sometimes",
+			},
+			want: []string{
+				"summary is missing",
+				"details are missing",
+				`invalid detection_type "sometimes", must be "manual" or "auto"`,
+			},
+		},
+		{
+			name: "parameter problems",
+			template: &TemplateFile{
+				Summary:       "Summary ${FOO}",
+				Details:       "Details ${UNDECLARED} ${foo}",
+				DetectionType: cmv1.DetectionTypeAuto,
+				Parameters: []TemplateParameter{
+					{Name: "foo", Description: "lower case"},
+					{Name: "UNUSED", Required: true, Default: "bar"},
+				},
+			},
+			want: []string{
+				"summary cannot contain parameters, found ${FOO}",
+				`parameter "foo" must be upper case, like FOO_BAR`,
+				"parameter UNUSED has no description",
+				"parameter UNUSED is required, but has a default value",
+				"parameter ${UNDECLARED} is used in the details, but not declared",
+				"parameter UNUSED is declared, but not used in the details",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lintTemplate(tt.template); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lintTemplate() got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	template := &TemplateFile{
+		Summary: "Summary",
+		Details: "Group ${SECURITY_GROUP} in ${REGION}, see ${LINK}",
+		Parameters: []TemplateParameter{
+			{Name: "SECURITY_GROUP", Required: true},
+			{Name: "REGION", Default: "us-east-1"},
+			{Name: "LINK", Required: true},
+		},
+	}
+
+	rendered, missing := renderTemplate(template, map[string]string{"SECURITY_GROUP": "sg-1234"})
+	if want := "Group sg-1234 in us-east-1, see ${LINK}"; rendered.Details != want {
+		t.Errorf("renderTemplate() got details %q, want %q", rendered.Details, want)
+	}
+	if want := []string{"LINK"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("renderTemplate() got missing %v, want %v", missing, want)
+	}
+	if template.Details != "Group ${SECURITY_GROUP} in ${REGION}, see ${LINK}" {
+		t.Errorf("renderTemplate() modified the template")
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os/user"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	"github.com/openshift/osdctl/pkg/utils"
 	"github.com/spf13/cobra"
@@ -18,6 +21,14 @@ const (
 	awsResourceName     = "red-hat-sre-jumphost"
 	publicSubnetTagKey  = "kubernetes.io/role/elb"
 	privateSubnetTagKey = "kubernetes.io/role/internal-elb"
+
+	// createdByTagKey and expiryTagKey are only set on creation, they aren't used to find the jumphost resources
+	createdByTagKey = "red-hat-sre-jumphost-created-by"
+	expiryTagKey    = "red-hat-sre-jumphost-expiry"
+	// defaultExpiry is how long a jumphost is kept before "osdctl jumphost gc" deletes it
+	defaultExpiry = 8 * time.Hour
+	// clusterTagKeyPrefix prefixes the tag of the subnets of a cluster, followed by its infra ID
+	clusterTagKeyPrefix = "kubernetes.io/cluster/"
 )
 
 func NewCmdJumphost() *cobra.Command {
@@ -29,6 +40,9 @@ func NewCmdJumphost() *cobra.Command {
 	jumphost.AddCommand(
 		newCmdCreateJumphost(),
 		newCmdDeleteJumphost(),
+		newCmdListJumphosts(),
+		newCmdGCJumphosts(),
+		newCmdConnectJumphost(),
 	)
 
 	return jumphost
@@ -42,6 +56,8 @@ type jumphostConfig struct {
 
 	keyFilepath string
 	ec2PublicIp string
+	// expiry is how long the created jumphost is kept, it isn't deleted by "osdctl jumphost gc" if 0
+	expiry time.Duration
 }
 
 type jumphostAWSClient interface {
@@ -57,6 +73,7 @@ type jumphostAWSClient interface {
 	DescribeImages(ctx context.Context, params *ec2.DescribeImagesInput, optFns ...func(options *ec2.Options)) (*ec2.DescribeImagesOutput, error)
 	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(options *ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
 	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(options *ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	GetConsoleOutput(ctx context.Context, params *ec2.GetConsoleOutputInput, optFns ...func(options *ec2.Options)) (*ec2.GetConsoleOutputOutput, error)
 	RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(options *ec2.Options)) (*ec2.RunInstancesOutput, error)
 	TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(options *ec2.Options)) (*ec2.TerminateInstancesOutput, error)
 
@@ -64,6 +81,10 @@ type jumphostAWSClient interface {
 	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(options *ec2.Options)) (*ec2.CreateTagsOutput, error)
 }
 
+type callerIdentityClient interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(options *sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
 // initJumphostConfig initializes a jumphostConfig struct for use with jumphost commands.
 // Generally, this function should always be used as opposed to initializing the struct by hand.
 func initJumphostConfig(ctx context.Context, clusterId, subnetId string) (*jumphostConfig, error) {
@@ -73,10 +94,13 @@ func initJumphostConfig(ctx context.Context, clusterId, subnetId string) (*jumph
 	}
 	defer ocm.Close()
 
-	//cluster, err := utils.GetClusterAnyStatus(ocm, clusterId)
-	//if err != nil {
-	//	return nil, fmt.Errorf("failed to get OCM cluster info for %s: %s", clusterId, err)
-	//}
+	var cluster *cmv1.Cluster
+	if clusterId != "" {
+		cluster, err = utils.GetClusterAnyStatus(ocm, clusterId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OCM cluster info for %s: %s", clusterId, err)
+		}
+	}
 
 	//if err := validateCluster(cluster); err != nil {
 	//	return nil, fmt.Errorf("cluster not supported yet - %s", err)
@@ -94,8 +118,17 @@ func initJumphostConfig(ctx context.Context, clusterId, subnetId string) (*jumph
 		return nil, err
 	}
 
+	if cluster != nil {
+		// The jumphosts of the cluster are in its region, and the credentials must be for its AWS account
+		cfg.Region = cluster.Region().ID()
+		if err := validateAccount(ctx, sts.NewFromConfig(cfg), cluster); err != nil {
+			return nil, err
+		}
+	}
+
 	return &jumphostConfig{
 		awsClient: ec2.NewFromConfig(cfg),
+		cluster:   cluster,
 		subnetId:  subnetId,
 		tags: []types.Tag{
 			//{
@@ -133,6 +166,37 @@ func validateCluster(cluster *cmv1.Cluster) error {
 	return errors.New("unexpected error, nil cluster provided")
 }
 
+// validateAccount checks that the AWS credentials are for the AWS account of the cluster
+func validateAccount(ctx context.Context, client callerIdentityClient, cluster *cmv1.Cluster) error {
+	accountId := cluster.AWS().AccountID()
+	if accountId == "" {
+		return fmt.Errorf("could not determine the AWS account of cluster %s", cluster.ID())
+	}
+
+	identity, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("failed to get the AWS caller identity: %w", err)
+	}
+	if aws.ToString(identity.Account) != accountId {
+		return fmt.Errorf("the AWS credentials are for account %s, but cluster %s is in account %s", aws.ToString(identity.Account), cluster.ID(), accountId)
+	}
+
+	return nil
+}
+
+// creationTags returns the tags of the created resources, the tags identifying the jumphost resources
+// plus who created them and when they expire
+func (j *jumphostConfig) creationTags(now time.Time) []types.Tag {
+	tags := append([]types.Tag{}, j.tags...)
+	if current, err := user.Current(); err == nil {
+		tags = append(tags, types.Tag{Key: aws.String(createdByTagKey), Value: aws.String(current.Username)})
+	}
+	if j.expiry > 0 {
+		tags = append(tags, types.Tag{Key: aws.String(expiryTagKey), Value: aws.String(now.Add(j.expiry).UTC().Format(time.RFC3339))})
+	}
+	return tags
+}
+
 // generateTagFilters converts a slice of expected tags to a slice of corresponding filters to search by.
 func generateTagFilters(tags []types.Tag) []types.Filter {
 	if len(tags) == 0 {
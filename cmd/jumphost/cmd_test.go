@@ -1,15 +1,28 @@
 package jumphost
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+type mockCallerIdentityClient struct {
+	mock.Mock
+}
+
+func (m *mockCallerIdentityClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(options *sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*sts.GetCallerIdentityOutput), args.Error(1)
+}
+
 func TestGenerateTagFilters(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -147,3 +160,51 @@ func TestValidateCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAccount(t *testing.T) {
+	tests := []struct {
+		name        string
+		accountId   string
+		callerId    string
+		callerErr   error
+		expectedErr string
+	}{
+		{
+			name:      "same_account",
+			accountId: "123456789012",
+			callerId:  "123456789012",
+		},
+		{
+			name:        "different_account",
+			accountId:   "123456789012",
+			callerId:    "210987654321",
+			expectedErr: "the AWS credentials are for account 210987654321, but cluster cluster-id is in account 123456789012",
+		},
+		{
+			name:        "unknown_account",
+			expectedErr: "could not determine the AWS account of cluster cluster-id",
+		},
+		{
+			name:        "caller_identity_error",
+			accountId:   "123456789012",
+			callerErr:   errors.New("expired token"),
+			expectedErr: "failed to get the AWS caller identity: expired token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := cmv1.NewCluster().ID("cluster-id").AWS(cmv1.NewAWS().AccountID(tt.accountId)).Build()
+			assert.NoError(t, err)
+			client := new(mockCallerIdentityClient)
+			client.On("GetCallerIdentity", mock.Anything, mock.Anything).Return(&sts.GetCallerIdentityOutput{Account: aws.String(tt.callerId)}, tt.callerErr)
+
+			err = validateAccount(context.Background(), client, cluster)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,451 @@
+package jumphost
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+const (
+	// jumphostUser is the default user of the Amazon Linux AMI of the jumphosts
+	jumphostUser = "ec2-user"
+	// hostKeysBegin and hostKeysEnd delimit the SSH host keys printed by cloud-init in the console output
+	hostKeysBegin = "-----BEGIN SSH HOST KEY KEYS-----"
+	hostKeysEnd   = "-----END SSH HOST KEY KEYS-----"
+	// apiLocalAddress is where --forward-api listens, the default port of the cluster API
+	apiLocalAddress = "localhost:6443"
+)
+
+var (
+	// hostKeysTimeout is how long to wait for cloud-init to print the host keys in the console output
+	hostKeysTimeout = 5 * time.Minute
+	// hostKeysInterval is how often the console output is read while waiting for the host keys
+	hostKeysInterval = 15 * time.Second
+)
+
+// connectOptions defines the struct for running the jumphost connect command
+type connectOptions struct {
+	instanceId string
+	keyFile    string
+	forwards   []string
+	forwardAPI bool
+	// insecureSkipHostKeyCheck accepts any host key, eg. when the console output can't be read
+	insecureSkipHostKeyCheck bool
+}
+
+// portForward forwards the connections to a local address to a remote address through the jumphost
+type portForward struct {
+	local  string
+	remote string
+}
+
+func newCmdConnectJumphost() *cobra.Command {
+	var clusterId string
+	ops := &connectOptions{}
+
+	connect := &cobra.Command{
+		Use:          "connect",
+		SilenceUsage: true,
+		Short:        "Open an SSH session or forward ports through a jumphost created by `osdctl jumphost create`",
+		Long: `Open an SSH session or forward ports through a jumphost created by "osdctl jumphost create"
+
+  This command finds the jumphost of the cluster, or the only jumphost of the AWS
+  account and region of the current AWS credentials, and opens an interactive SSH
+  session on the jumphost with the private key written by "osdctl jumphost create".
+  With --local-forward or --forward-api, the local ports are forwarded through the
+  jumphost instead, until interrupted. With --cluster-id, the AWS credentials must be
+  for the AWS account of the cluster.
+
+  The host key of the jumphost is verified against the keys printed in its console
+  output, which can take a few minutes to be available after its creation. The
+  command waits for them and fails if they can't be read, unless
+  --insecure-skip-host-key-check is set.
+
+  Requires these permissions:
+  {
+    "Version": "2012-10-17",
+    "Statement": [
+      {
+        "Action": [
+          "ec2:DescribeInstances",
+          "ec2:DescribeSubnets",
+          "ec2:GetConsoleOutput",
+          "sts:GetCallerIdentity"
+        ],
+        "Effect": "Allow",
+        "Resource": "*"
+      }
+    ]
+  }`,
+		Example: `
+  # Open an SSH session on the jumphost of a cluster
+  osdctl jumphost connect --cluster-id ${CLUSTER_ID} --key-file /tmp/jumphost_123.pem
+
+  # Forward localhost:6443 to the API of a private cluster, then use it with
+  # oc --server https://localhost:6443 --tls-server-name api.${CLUSTER_DOMAIN}
+  osdctl jumphost connect --cluster-id ${CLUSTER_ID} --key-file /tmp/jumphost_123.pem --forward-api
+
+  # Forward localhost:8443 to port 443 of a node
+  osdctl jumphost connect --cluster-id ${CLUSTER_ID} --key-file /tmp/jumphost_123.pem -L 8443:10.0.1.2:443`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			j, err := initJumphostConfig(context.TODO(), clusterId, "")
+			if err != nil {
+				return err
+			}
+
+			return j.runConnect(context.TODO(), ops)
+		},
+	}
+
+	connect.Flags().StringVarP(&clusterId, "cluster-id", "c", "", "OCM internal/external cluster id to connect to the jumphost of")
+	connect.Flags().StringVar(&ops.instanceId, "instance-id", "", "EC2 instance id of the jumphost, required when several jumphosts match")
+	connect.Flags().StringVar(&ops.keyFile, "key-file", "", "private key of the jumphost, written by \"osdctl jumphost create\"")
+	connect.MarkFlagRequired("key-file")
+	connect.Flags().StringArrayVarP(&ops.forwards, "local-forward", "L", nil, "forward a local port through the jumphost instead of opening a session: [bind_address:]port:host:hostport")
+	connect.Flags().BoolVar(&ops.forwardAPI, "forward-api", false, "forward "+apiLocalAddress+" to the API of the cluster through the jumphost instead of opening a session")
+	connect.Flags().BoolVar(&ops.insecureSkipHostKeyCheck, "insecure-skip-host-key-check", false, "accept any host key of the jumphost instead of verifying it against its console output")
+
+	return connect
+}
+
+func (j *jumphostConfig) runConnect(ctx context.Context, o *connectOptions) error {
+	forwards := []portForward{}
+	for _, spec := range o.forwards {
+		forward, err := parseForward(spec)
+		if err != nil {
+			return err
+		}
+		forwards = append(forwards, forward)
+	}
+	if o.forwardAPI {
+		if j.cluster == nil {
+			return errors.New("--forward-api requires --cluster-id")
+		}
+		forward, err := apiForward(j.cluster)
+		if err != nil {
+			return err
+		}
+		forwards = append(forwards, forward)
+	}
+
+	jumphosts, err := j.listJumphosts(ctx)
+	if err != nil {
+		return err
+	}
+	infraId := ""
+	if j.cluster != nil {
+		infraId = j.cluster.InfraID()
+	}
+	h, err := selectJumphost(jumphosts, infraId, o.instanceId)
+	if err != nil {
+		return err
+	}
+
+	key, err := os.ReadFile(o.keyFile) //#nosec G304 -- the file is given by the user
+	if err != nil {
+		return err
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to parse the private key %s: %w", o.keyFile, err)
+	}
+
+	// Tear down the session and the port forwarding on interrupt
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hostKeyCallback, err := j.hostKeyCallback(ctx, h.instanceId, o.insecureSkipHostKeyCheck)
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(h.publicIp, "22")
+	log.Printf("connecting to %s@%s (%s)", jumphostUser, address, h.instanceId)
+	client, err := ssh.Dial("tcp", address, &ssh.ClientConfig{
+		User:            jumphostUser,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to the jumphost %s: %w", h.instanceId, err)
+	}
+	defer client.Close()
+
+	if len(forwards) == 0 {
+		return runShell(ctx, client)
+	}
+
+	for _, forward := range forwards {
+		listener, err := net.Listen("tcp", forward.local)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", forward.local, err)
+		}
+		defer listener.Close()
+		log.Printf("forwarding %s to %s through the jumphost", forward.local, forward.remote)
+		go serveForward(listener, forward.remote, client.Dial)
+	}
+	log.Println("press Ctrl+C to stop forwarding")
+
+	closed := make(chan error, 1)
+	go func() { closed <- client.Wait() }()
+	select {
+	case <-ctx.Done():
+		log.Println("stopping the port forwarding")
+		return nil
+	case err := <-closed:
+		if err != nil {
+			return fmt.Errorf("the connection to the jumphost was closed: %w", err)
+		}
+		return errors.New("the connection to the jumphost was closed")
+	}
+}
+
+// parseForward parses a port forwarding in the format of ssh -L: [bind_address:]port:host:hostport
+func parseForward(spec string) (portForward, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) == 3 {
+		parts = append([]string{"localhost"}, parts...)
+	}
+	if len(parts) != 4 || parts[0] == "" || parts[2] == "" {
+		return portForward{}, fmt.Errorf("invalid forward %q, expected [bind_address:]port:host:hostport", spec)
+	}
+	for _, port := range []string{parts[1], parts[3]} {
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return portForward{}, fmt.Errorf("invalid forward %q, invalid port %q", spec, port)
+		}
+	}
+
+	return portForward{
+		local:  net.JoinHostPort(parts[0], parts[1]),
+		remote: net.JoinHostPort(parts[2], parts[3]),
+	}, nil
+}
+
+// apiForward returns the forwarding of apiLocalAddress to the API of the cluster
+func apiForward(cluster *cmv1.Cluster) (portForward, error) {
+	api, err := url.Parse(cluster.API().URL())
+	if err != nil || api.Hostname() == "" {
+		return portForward{}, fmt.Errorf("invalid API URL %q of cluster %s", cluster.API().URL(), cluster.ID())
+	}
+	port := api.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	log.Printf("use the forwarded API with: oc --server https://%s --tls-server-name %s", apiLocalAddress, api.Hostname())
+	return portForward{local: apiLocalAddress, remote: net.JoinHostPort(api.Hostname(), port)}, nil
+}
+
+// selectJumphost returns the running jumphost of the cluster with the infra ID and with the instance ID,
+// when not empty. It fails if there isn't exactly one.
+func selectJumphost(jumphosts []jumphost, infraId, instanceId string) (jumphost, error) {
+	matching := []jumphost{}
+	for _, h := range jumphosts {
+		if (infraId == "" || h.clusterInfraId == infraId) && (instanceId == "" || h.instanceId == instanceId) {
+			matching = append(matching, h)
+		}
+	}
+
+	switch len(matching) {
+	case 0:
+		if infraId != "" {
+			return jumphost{}, fmt.Errorf("no jumphost found for cluster %s, create one with \"osdctl jumphost create\"", infraId)
+		}
+		return jumphost{}, errors.New("no jumphost found, create one with \"osdctl jumphost create\"")
+	case 1:
+	default:
+		ids := make([]string, len(matching))
+		for i, h := range matching {
+			ids[i] = h.instanceId
+		}
+		return jumphost{}, fmt.Errorf("found %d jumphosts (%s), select one with --instance-id", len(matching), strings.Join(ids, ", "))
+	}
+
+	h := matching[0]
+	if h.state != string(types.InstanceStateNameRunning) {
+		return jumphost{}, fmt.Errorf("jumphost %s is %s", h.instanceId, h.state)
+	}
+	if h.publicIp == "" {
+		return jumphost{}, fmt.Errorf("jumphost %s has no public IP", h.instanceId)
+	}
+	return h, nil
+}
+
+// hostKeyCallback verifies the host key of the jumphost against the keys printed by cloud-init in its console
+// output, waiting for them to be printed. Any host key is accepted if insecure.
+func (j *jumphostConfig) hostKeyCallback(ctx context.Context, instanceId string, insecure bool) (ssh.HostKeyCallback, error) {
+	if insecure {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			log.Printf("warning: accepting the host key %s %s of %s without verification", key.Type(), ssh.FingerprintSHA256(key), instanceId)
+			return nil
+		}, nil
+	}
+
+	hostKeys, err := j.waitForHostKeys(ctx, instanceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the host key of %s, use --insecure-skip-host-key-check to connect anyway: %w", instanceId, err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		for _, hostKey := range hostKeys {
+			if bytes.Equal(hostKey.Marshal(), key.Marshal()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key %s %s doesn't match the console output of %s", key.Type(), ssh.FingerprintSHA256(key), instanceId)
+	}, nil
+}
+
+// waitForHostKeys reads the console output of the instance until it contains the host keys printed by cloud-init
+func (j *jumphostConfig) waitForHostKeys(ctx context.Context, instanceId string) ([]ssh.PublicKey, error) {
+	timeout := time.After(hostKeysTimeout)
+	for {
+		resp, err := j.awsClient.GetConsoleOutput(ctx, &ec2.GetConsoleOutputInput{InstanceId: aws.String(instanceId)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the console output: %w", err)
+		}
+		hostKeys, err := parseHostKeys(aws.ToString(resp.Output))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the console output: %w", err)
+		}
+		if len(hostKeys) > 0 {
+			return hostKeys, nil
+		}
+
+		log.Println("waiting for the host keys to be in the console output")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, fmt.Errorf("the host keys weren't in the console output after %s", hostKeysTimeout)
+		case <-time.After(hostKeysInterval):
+		}
+	}
+}
+
+// parseHostKeys returns the SSH host keys in the base64 encoded console output of an instance
+func parseHostKeys(output string) ([]ssh.PublicKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(output)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeys := []ssh.PublicKey{}
+	inHostKeys := false
+	for _, line := range strings.Split(string(decoded), "\n") {
+		switch {
+		case strings.Contains(line, hostKeysBegin):
+			inHostKeys = true
+		case strings.Contains(line, hostKeysEnd):
+			inHostKeys = false
+		case inHostKeys:
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(line)))
+			if err != nil {
+				continue
+			}
+			hostKeys = append(hostKeys, key)
+		}
+	}
+
+	return hostKeys, nil
+}
+
+// runShell opens an interactive shell on the jumphost until it exits or ctx is done
+func runShell(ctx context.Context, client *ssh.Client) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open a session: %w", err)
+	}
+	defer session.Close()
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd()) //#nosec G115 -- file descriptors fit in an int
+	if term.IsTerminal(fd) {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+		if err := session.RequestPty(termType, height, width, ssh.TerminalModes{ssh.ECHO: 1}); err != nil {
+			return fmt.Errorf("failed to request a pseudo terminal: %w", err)
+		}
+
+		// Send the keys as they're typed, eg. Ctrl+C to the remote shell
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = term.Restore(fd, state) }()
+	}
+
+	if err := session.Shell(); err != nil {
+		return fmt.Errorf("failed to start a shell: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-done:
+		// The exit status of the shell is the one of its last command
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return nil
+		}
+		return err
+	}
+}
+
+// serveForward forwards the connections accepted by the listener to the remote address until the listener is closed
+func serveForward(listener net.Listener, remote string, dial func(network, address string) (net.Conn, error)) {
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer local.Close()
+			conn, err := dial("tcp", remote)
+			if err != nil {
+				log.Printf("failed to forward a connection to %s: %s", remote, err)
+				return
+			}
+			defer conn.Close()
+
+			// Closing both connections when either direction ends stops the other copy
+			done := make(chan struct{}, 2)
+			go func() { _, _ = io.Copy(conn, local); done <- struct{}{} }()
+			go func() { _, _ = io.Copy(local, conn); done <- struct{}{} }()
+			<-done
+		}()
+	}
+}
@@ -0,0 +1,272 @@
+package jumphost
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func (m *mockAWSClient) GetConsoleOutput(ctx context.Context, params *ec2.GetConsoleOutputInput, optFns ...func(options *ec2.Options)) (*ec2.GetConsoleOutputOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*ec2.GetConsoleOutputOutput), args.Error(1)
+}
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := ssh.NewPublicKey(public)
+	require.NoError(t, err)
+	return key
+}
+
+// consoleOutput returns a base64 encoded console output printing the host keys like cloud-init
+func consoleOutput(keys ...ssh.PublicKey) string {
+	output := "[    5.123456] cloud-init[1234]: Cloud-init v. 22.2.2 running 'modules:final'\r\n" + hostKeysBegin + "\r\n"
+	for _, key := range keys {
+		output += string(ssh.MarshalAuthorizedKey(key))
+	}
+	output += hostKeysEnd + "\r\n"
+	return base64.StdEncoding.EncodeToString([]byte(output))
+}
+
+func TestParseForward(t *testing.T) {
+	tests := []struct {
+		spec      string
+		expected  portForward
+		expectErr bool
+	}{
+		{
+			spec:     "8443:10.0.1.2:443",
+			expected: portForward{local: "localhost:8443", remote: "10.0.1.2:443"},
+		},
+		{
+			spec:     "0.0.0.0:6443:api.example.com:6443",
+			expected: portForward{local: "0.0.0.0:6443", remote: "api.example.com:6443"},
+		},
+		{
+			spec:      "8443:10.0.1.2",
+			expectErr: true,
+		},
+		{
+			spec:      "8443::443",
+			expectErr: true,
+		},
+		{
+			spec:      "http:10.0.1.2:443",
+			expectErr: true,
+		},
+		{
+			spec:      "8443:10.0.1.2:70000",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			forward, err := parseForward(tt.spec)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, forward)
+		})
+	}
+}
+
+func TestSelectJumphost(t *testing.T) {
+	running := string(types.InstanceStateNameRunning)
+	jumphosts := []jumphost{
+		{instanceId: "i-a", state: running, publicIp: "1.2.3.4", clusterInfraId: "cluster-a"},
+		{instanceId: "i-b", state: running, publicIp: "1.2.3.5", clusterInfraId: "cluster-b"},
+		{instanceId: "i-c", state: string(types.InstanceStateNameStopped), clusterInfraId: "cluster-b"},
+	}
+
+	tests := []struct {
+		name       string
+		jumphosts  []jumphost
+		infraId    string
+		instanceId string
+		expected   string
+		expectErr  string
+	}{
+		{
+			name:      "by_cluster",
+			jumphosts: jumphosts,
+			infraId:   "cluster-a",
+			expected:  "i-a",
+		},
+		{
+			name:       "by_instance",
+			jumphosts:  jumphosts,
+			infraId:    "cluster-b",
+			instanceId: "i-b",
+			expected:   "i-b",
+		},
+		{
+			name:      "only_jumphost",
+			jumphosts: jumphosts[:1],
+			expected:  "i-a",
+		},
+		{
+			name:      "several_jumphosts",
+			jumphosts: jumphosts,
+			infraId:   "cluster-b",
+			expectErr: "found 2 jumphosts (i-b, i-c), select one with --instance-id",
+		},
+		{
+			name:      "no_jumphost",
+			jumphosts: jumphosts,
+			infraId:   "cluster-c",
+			expectErr: "no jumphost found for cluster cluster-c",
+		},
+		{
+			name:       "stopped",
+			jumphosts:  jumphosts,
+			instanceId: "i-c",
+			expectErr:  "jumphost i-c is stopped",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := selectJumphost(tt.jumphosts, tt.infraId, tt.instanceId)
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, h.instanceId)
+		})
+	}
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	hostKey, otherKey := newTestHostKey(t), newTestHostKey(t)
+	remote := &net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 22}
+	hostKeysTimeout, hostKeysInterval = 50*time.Millisecond, 10*time.Millisecond
+	defer func() { hostKeysTimeout, hostKeysInterval = 5*time.Minute, 15*time.Second }()
+
+	tests := []struct {
+		name      string
+		outputs   []string
+		outputErr error
+		insecure  bool
+		key       ssh.PublicKey
+		expectErr bool
+	}{
+		{
+			name:    "matching_host_key",
+			outputs: []string{consoleOutput(otherKey, hostKey)},
+			key:     hostKey,
+		},
+		{
+			name:    "host_keys_printed_later",
+			outputs: []string{"", consoleOutput(hostKey)},
+			key:     hostKey,
+		},
+		{
+			name:      "different_host_key",
+			outputs:   []string{consoleOutput(otherKey)},
+			key:       hostKey,
+			expectErr: true,
+		},
+		{
+			name:      "console_output_not_available",
+			outputs:   []string{""},
+			key:       hostKey,
+			expectErr: true,
+		},
+		{
+			name:      "console_output_error",
+			outputs:   []string{""},
+			outputErr: errors.New("unauthorized"),
+			key:       hostKey,
+			expectErr: true,
+		},
+		{
+			name:     "insecure",
+			insecure: true,
+			key:      hostKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAws := new(mockAWSClient)
+			j := &jumphostConfig{awsClient: mockAws}
+			for i, output := range tt.outputs {
+				call := mockAws.On("GetConsoleOutput", mock.Anything, &ec2.GetConsoleOutputInput{InstanceId: aws.String("i-12345")}).
+					Return(&ec2.GetConsoleOutputOutput{Output: aws.String(output)}, tt.outputErr)
+				if i < len(tt.outputs)-1 {
+					call.Once()
+				}
+			}
+
+			callback, err := j.hostKeyCallback(context.Background(), "i-12345", tt.insecure)
+			if err == nil {
+				err = callback("1.2.3.4:22", remote, tt.key)
+			}
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.insecure {
+				mockAws.AssertNotCalled(t, "GetConsoleOutput", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestServeForward(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	dialed := make(chan string, 1)
+	go serveForward(listener, "api.example.com:6443", func(network, address string) (net.Conn, error) {
+		dialed <- address
+		return net.Dial(network, echo.Addr().String())
+	})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	response := make([]byte, 4)
+	_, err = io.ReadFull(conn, response)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(response))
+	assert.Equal(t, "api.example.com:6443", <-dialed)
+}
@@ -22,6 +22,7 @@ func newCmdCreateJumphost() *cobra.Command {
 	var (
 		clusterId string
 		subnetId  string
+		expiry    time.Duration
 	)
 
 	create := &cobra.Command{
@@ -73,6 +74,7 @@ func newCmdCreateJumphost() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			j.expiry = expiry
 
 			return j.runCreate(context.TODO())
 		},
@@ -81,11 +83,14 @@ func newCmdCreateJumphost() *cobra.Command {
 	// create.Flags().StringVarP(&clusterId, "cluster-id", "c", "", "OCM internal/external cluster id trying to access via a jumphost")
 	create.Flags().StringVar(&subnetId, "subnet-id", "", "public subnet id to create a jumphost in")
 	create.MarkFlagRequired("subnet-id")
+	create.Flags().DurationVar(&expiry, "expiry", defaultExpiry, "how long to keep the jumphost before \"osdctl jumphost gc\" deletes it, 0 to keep it until deleted")
 
 	return create
 }
 
 func (j *jumphostConfig) runCreate(ctx context.Context) error {
+	// Tag all the resources with the same expiry
+	j.tags = j.creationTags(time.Now())
 	if err := j.createKeyPair(ctx); err != nil {
 		return err
 	}
@@ -0,0 +1,170 @@
+package jumphost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newCmdGCJumphosts() *cobra.Command {
+	var (
+		dryRun      bool
+		skipPrompts bool
+	)
+
+	gc := &cobra.Command{
+		Use:          "gc",
+		SilenceUsage: true,
+		Short:        "Delete the expired jumphosts created by `osdctl jumphost create`",
+		Long: `Delete the expired jumphosts created by "osdctl jumphost create"
+
+  This command terminates the jumphosts of the AWS account and region of the current
+  AWS credentials whose expiry, set by "osdctl jumphost create --expiry", is past and
+  deletes their key pairs and security groups. Jumphosts created without an expiry
+  are kept, delete them with "osdctl jumphost delete".
+
+  Requires these permissions:
+  {
+    "Version": "2012-10-17",
+    "Statement": [
+      {
+        "Action": [
+          "ec2:DeleteKeyPair",
+          "ec2:DeleteSecurityGroup",
+          "ec2:DescribeInstances",
+          "ec2:DescribeKeyPairs",
+          "ec2:DescribeSecurityGroups",
+          "ec2:DescribeSubnets",
+          "ec2:TerminateInstances"
+        ],
+        "Effect": "Allow",
+        "Resource": "*"
+      }
+    ]
+  }`,
+		Example: `
+  # List the expired jumphosts without deleting them
+  osdctl jumphost gc --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			j, err := initJumphostConfig(context.TODO(), "", "")
+			if err != nil {
+				return err
+			}
+
+			return j.runGC(context.TODO(), time.Now(), dryRun, skipPrompts)
+		},
+	}
+
+	gc.Flags().BoolVar(&dryRun, "dry-run", false, "list the expired jumphosts but don't delete them")
+	gc.Flags().BoolVarP(&skipPrompts, "yes", "y", false, "skip the confirmation prompt")
+
+	return gc
+}
+
+func (j *jumphostConfig) runGC(ctx context.Context, now time.Time, dryRun bool, skipPrompts bool) error {
+	jumphosts, err := j.listJumphosts(ctx)
+	if err != nil {
+		return err
+	}
+
+	var expired, remaining []jumphost
+	for _, h := range jumphosts {
+		if h.expired(now) {
+			expired = append(expired, h)
+		} else {
+			remaining = append(remaining, h)
+		}
+	}
+	if len(expired) == 0 {
+		log.Println("no expired jumphosts found")
+		return nil
+	}
+
+	if err := printJumphosts(os.Stdout, expired, now); err != nil {
+		return err
+	}
+	if dryRun || (!skipPrompts && !utils.ConfirmPrompt()) {
+		return nil
+	}
+
+	var errs []error
+	for _, h := range expired {
+		// The key pair has a fixed name, it is shared with the jumphosts of the region which aren't expired
+		keepKeyPair := false
+		for _, other := range remaining {
+			if other.keyName == h.keyName {
+				keepKeyPair = true
+			}
+		}
+		if err := j.deleteJumphost(ctx, h, keepKeyPair); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete jumphost %s: %w", h.instanceId, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteJumphost terminates the EC2 instance of the jumphost, then deletes its security groups and key pair
+// which have the expected tags
+func (j *jumphostConfig) deleteJumphost(ctx context.Context, h jumphost, keepKeyPair bool) error {
+	log.Printf("terminating EC2 instance: %s", h.instanceId)
+	if _, err := j.awsClient.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: []string{h.instanceId},
+	}); err != nil {
+		return err
+	}
+
+	// The security groups can't be deleted while the instance uses them
+	log.Println("waiting for the EC2 instance to be in a terminated state")
+	waiter := ec2.NewInstanceTerminatedWaiter(j.awsClient)
+	if err := waiter.Wait(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{h.instanceId}}, 5*time.Minute); err != nil {
+		return err
+	}
+
+	if len(h.securityGroupIds) > 0 {
+		resp, err := j.awsClient.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+			Filters: append(generateTagFilters(j.tags), types.Filter{
+				Name:   aws.String("group-id"),
+				Values: h.securityGroupIds,
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe security groups: %w", err)
+		}
+		for _, group := range resp.SecurityGroups {
+			log.Printf("deleting security group: %s (%s)", aws.ToString(group.GroupName), aws.ToString(group.GroupId))
+			if _, err := j.awsClient.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: group.GroupId}); err != nil {
+				return fmt.Errorf("failed to delete security group: %w", err)
+			}
+		}
+	}
+
+	if keepKeyPair || h.keyName == "" {
+		return nil
+	}
+	resp, err := j.awsClient.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+		Filters: append(generateTagFilters(j.tags), types.Filter{
+			Name:   aws.String("key-name"),
+			Values: []string{h.keyName},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe key pair: %w", err)
+	}
+	for _, keyPair := range resp.KeyPairs {
+		log.Printf("deleting key pair: %s (%s)", aws.ToString(keyPair.KeyName), aws.ToString(keyPair.KeyPairId))
+		if _, err := j.awsClient.DeleteKeyPair(ctx, &ec2.DeleteKeyPairInput{KeyPairId: keyPair.KeyPairId}); err != nil {
+			return fmt.Errorf("failed to delete keypair: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package jumphost
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunGC(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	expired := newTestInstance("i-expired", now.Add(-9*time.Hour), "2024-01-01T11:00:00Z")
+	running := newTestInstance("i-running", now.Add(-time.Hour), "2024-01-01T19:00:00Z")
+	noExpiry := newTestInstance("i-no-expiry", now.Add(-24*time.Hour), "")
+	isListing := func(input *ec2.DescribeInstancesInput) bool { return len(input.InstanceIds) == 0 }
+	isWaiting := func(input *ec2.DescribeInstancesInput) bool { return len(input.InstanceIds) == 1 }
+
+	tests := []struct {
+		name          string
+		instances     []types.Instance
+		dryRun        bool
+		expectDelete  bool
+		expectKeyPair bool
+		terminateErr  error
+		expectErr     bool
+	}{
+		{
+			name:      "nothing_expired",
+			instances: []types.Instance{running, noExpiry},
+		},
+		{
+			name:      "dry_run",
+			instances: []types.Instance{expired},
+			dryRun:    true,
+		},
+		{
+			name:          "delete_expired",
+			instances:     []types.Instance{expired},
+			expectDelete:  true,
+			expectKeyPair: true,
+		},
+		{
+			name:         "key_pair_in_use",
+			instances:    []types.Instance{expired, running},
+			expectDelete: true,
+		},
+		{
+			name:         "terminate_error",
+			instances:    []types.Instance{expired},
+			terminateErr: errors.New("unauthorized"),
+			expectErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAws := new(mockAWSClient)
+			j := &jumphostConfig{awsClient: mockAws, tags: []types.Tag{{Key: aws.String("Name"), Value: aws.String(awsResourceName)}}}
+			mockAws.On("DescribeInstances", mock.Anything, mock.MatchedBy(isListing)).
+				Return(&ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: tt.instances}}}, nil)
+			mockAws.On("DescribeSubnets", mock.Anything, mock.Anything).Return(&ec2.DescribeSubnetsOutput{}, nil)
+
+			if tt.expectDelete || tt.terminateErr != nil {
+				mockAws.On("TerminateInstances", mock.Anything, &ec2.TerminateInstancesInput{InstanceIds: []string{"i-expired"}}).
+					Return(&ec2.TerminateInstancesOutput{}, tt.terminateErr)
+			}
+			if tt.expectDelete {
+				mockAws.On("DescribeInstances", mock.Anything, mock.MatchedBy(isWaiting)).Return(&ec2.DescribeInstancesOutput{
+					Reservations: []types.Reservation{{Instances: []types.Instance{{InstanceId: aws.String("i-expired"), State: &types.InstanceState{Name: types.InstanceStateNameTerminated}}}}},
+				}, nil)
+				mockAws.On("DescribeSecurityGroups", mock.Anything, mock.Anything).Return(&ec2.DescribeSecurityGroupsOutput{
+					SecurityGroups: []types.SecurityGroup{{GroupId: aws.String("sg-i-expired"), GroupName: aws.String(awsResourceName)}},
+				}, nil)
+				mockAws.On("DeleteSecurityGroup", mock.Anything, &ec2.DeleteSecurityGroupInput{GroupId: aws.String("sg-i-expired")}).Return(&ec2.DeleteSecurityGroupOutput{}, nil)
+			}
+			if tt.expectKeyPair {
+				mockAws.On("DescribeKeyPairs", mock.Anything, mock.Anything).Return(&ec2.DescribeKeyPairsOutput{
+					KeyPairs: []types.KeyPairInfo{{KeyName: aws.String(awsResourceName), KeyPairId: aws.String("key-12345")}},
+				}, nil)
+				mockAws.On("DeleteKeyPair", mock.Anything, &ec2.DeleteKeyPairInput{KeyPairId: aws.String("key-12345")}).Return(&ec2.DeleteKeyPairOutput{}, nil)
+			}
+
+			err := j.runGC(context.Background(), now, tt.dryRun, true)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockAws.AssertExpectations(t)
+			if !tt.expectKeyPair {
+				mockAws.AssertNotCalled(t, "DeleteKeyPair", mock.Anything, mock.Anything)
+			}
+			if !tt.expectDelete {
+				mockAws.AssertNotCalled(t, "DeleteSecurityGroup", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
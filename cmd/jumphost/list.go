@@ -0,0 +1,205 @@
+package jumphost
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// jumphost is an EC2 instance created by "osdctl jumphost create"
+type jumphost struct {
+	instanceId       string
+	state            string
+	publicIp         string
+	vpcId            string
+	subnetId         string
+	keyName          string
+	securityGroupIds []string
+	launchTime       time.Time
+	createdBy        string
+	// expiry is zero for the jumphosts created without an expiry
+	expiry time.Time
+	// clusterInfraId is found in the tags of the subnet of the jumphost
+	clusterInfraId string
+}
+
+// expired returns true if the jumphost has an expiry in the past
+func (h jumphost) expired(now time.Time) bool {
+	return !h.expiry.IsZero() && now.After(h.expiry)
+}
+
+func newCmdListJumphosts() *cobra.Command {
+	list := &cobra.Command{
+		Use:          "list",
+		SilenceUsage: true,
+		Short:        "List the jumphosts created by `osdctl jumphost create`",
+		Long: `List the jumphosts created by "osdctl jumphost create"
+
+  This command lists the jumphosts of the AWS account and region of the current AWS
+  credentials, with the cluster of their subnet, who created them, their age and
+  their public IP. Jumphosts past their expiry are deleted by "osdctl jumphost gc".
+
+  Requires these permissions:
+  {
+    "Version": "2012-10-17",
+    "Statement": [
+      {
+        "Action": [
+          "ec2:DescribeInstances",
+          "ec2:DescribeSubnets"
+        ],
+        "Effect": "Allow",
+        "Resource": "*"
+      }
+    ]
+  }`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			j, err := initJumphostConfig(context.TODO(), "", "")
+			if err != nil {
+				return err
+			}
+
+			jumphosts, err := j.listJumphosts(context.TODO())
+			if err != nil {
+				return err
+			}
+			if len(jumphosts) == 0 {
+				log.Println("no jumphosts found")
+				return nil
+			}
+			return printJumphosts(os.Stdout, jumphosts, time.Now())
+		},
+	}
+
+	return list
+}
+
+// listJumphosts searches for the EC2 instances with the expected tags which aren't terminated, oldest first
+func (j *jumphostConfig) listJumphosts(ctx context.Context) ([]jumphost, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: append(generateTagFilters(j.tags), types.Filter{
+			Name: aws.String("instance-state-name"),
+			Values: []string{
+				string(types.InstanceStateNamePending),
+				string(types.InstanceStateNameRunning),
+				string(types.InstanceStateNameStopping),
+				string(types.InstanceStateNameStopped),
+			},
+		}),
+	}
+
+	jumphosts := []jumphost{}
+	for {
+		resp, err := j.awsClient.DescribeInstances(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances: %w", err)
+		}
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				jumphosts = append(jumphosts, newJumphost(instance))
+			}
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		input.NextToken = resp.NextToken
+	}
+	sort.Slice(jumphosts, func(i, k int) bool { return jumphosts[i].launchTime.Before(jumphosts[k].launchTime) })
+
+	if err := j.findClusterInfraIds(ctx, jumphosts); err != nil {
+		log.Printf("failed to determine the clusters of the jumphosts: %s", err)
+	}
+	return jumphosts, nil
+}
+
+func newJumphost(instance types.Instance) jumphost {
+	h := jumphost{
+		instanceId: aws.ToString(instance.InstanceId),
+		publicIp:   aws.ToString(instance.PublicIpAddress),
+		vpcId:      aws.ToString(instance.VpcId),
+		subnetId:   aws.ToString(instance.SubnetId),
+		keyName:    aws.ToString(instance.KeyName),
+		launchTime: aws.ToTime(instance.LaunchTime),
+	}
+	if instance.State != nil {
+		h.state = string(instance.State.Name)
+	}
+	for _, group := range instance.SecurityGroups {
+		h.securityGroupIds = append(h.securityGroupIds, aws.ToString(group.GroupId))
+	}
+	for _, tag := range instance.Tags {
+		switch aws.ToString(tag.Key) {
+		case createdByTagKey:
+			h.createdBy = aws.ToString(tag.Value)
+		case expiryTagKey:
+			expiry, err := time.Parse(time.RFC3339, aws.ToString(tag.Value))
+			if err != nil {
+				log.Printf("ignoring invalid expiry %q of %s", aws.ToString(tag.Value), h.instanceId)
+				continue
+			}
+			h.expiry = expiry
+		}
+	}
+	return h
+}
+
+// findClusterInfraIds sets the infra ID of the cluster owning the subnet of every jumphost
+func (j *jumphostConfig) findClusterInfraIds(ctx context.Context, jumphosts []jumphost) error {
+	subnetIds := []string{}
+	for _, h := range jumphosts {
+		if h.subnetId != "" && !slices.Contains(subnetIds, h.subnetId) {
+			subnetIds = append(subnetIds, h.subnetId)
+		}
+	}
+	if len(subnetIds) == 0 {
+		return nil
+	}
+
+	resp, err := j.awsClient.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: subnetIds})
+	if err != nil {
+		return err
+	}
+	infraIds := map[string]string{}
+	for _, subnet := range resp.Subnets {
+		for _, tag := range subnet.Tags {
+			if key := aws.ToString(tag.Key); strings.HasPrefix(key, clusterTagKeyPrefix) {
+				infraIds[aws.ToString(subnet.SubnetId)] = strings.TrimPrefix(key, clusterTagKeyPrefix)
+			}
+		}
+	}
+	for i := range jumphosts {
+		jumphosts[i].clusterInfraId = infraIds[jumphosts[i].subnetId]
+	}
+	return nil
+}
+
+// printJumphosts prints a table of the jumphosts
+func printJumphosts(w io.Writer, jumphosts []jumphost, now time.Time) error {
+	p := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	p.AddRow([]string{"INSTANCE ID", "CLUSTER", "STATE", "PUBLIC IP", "CREATED BY", "AGE", "EXPIRY"})
+	for _, h := range jumphosts {
+		expiry := "-"
+		if !h.expiry.IsZero() {
+			expiry = h.expiry.Format(time.RFC3339)
+			if h.expired(now) {
+				expiry += " (expired)"
+			}
+		}
+		p.AddRow([]string{h.instanceId, h.clusterInfraId, h.state, h.publicIp, h.createdBy, duration.HumanDuration(now.Sub(h.launchTime)), expiry})
+	}
+	return p.Flush()
+}
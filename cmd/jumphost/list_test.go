@@ -0,0 +1,84 @@
+package jumphost
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestInstance(id string, launchTime time.Time, expiry string) types.Instance {
+	instance := types.Instance{
+		InstanceId:      aws.String(id),
+		PublicIpAddress: aws.String("203.0.113.10"),
+		SubnetId:        aws.String("subnet-" + id),
+		KeyName:         aws.String(awsResourceName),
+		LaunchTime:      aws.Time(launchTime),
+		State:           &types.InstanceState{Name: types.InstanceStateNameRunning},
+		SecurityGroups:  []types.GroupIdentifier{{GroupId: aws.String("sg-" + id)}},
+		Tags:            []types.Tag{{Key: aws.String(createdByTagKey), Value: aws.String("jdoe")}},
+	}
+	if expiry != "" {
+		instance.Tags = append(instance.Tags, types.Tag{Key: aws.String(expiryTagKey), Value: aws.String(expiry)})
+	}
+	return instance
+}
+
+func TestListJumphosts(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockAws := new(mockAWSClient)
+	j := &jumphostConfig{awsClient: mockAws, tags: []types.Tag{{Key: aws.String("Name"), Value: aws.String(awsResourceName)}}}
+
+	mockAws.On("DescribeInstances", mock.Anything, mock.MatchedBy(func(input *ec2.DescribeInstancesInput) bool { return input.NextToken == nil })).
+		Return(&ec2.DescribeInstancesOutput{
+			Reservations: []types.Reservation{{Instances: []types.Instance{newTestInstance("i-new", now.Add(-time.Hour), "2024-01-01T19:00:00Z")}}},
+			NextToken:    aws.String("page-2"),
+		}, nil).Once()
+	mockAws.On("DescribeInstances", mock.Anything, mock.MatchedBy(func(input *ec2.DescribeInstancesInput) bool { return aws.ToString(input.NextToken) == "page-2" })).
+		Return(&ec2.DescribeInstancesOutput{
+			Reservations: []types.Reservation{{Instances: []types.Instance{newTestInstance("i-old", now.Add(-10*time.Hour), "")}}},
+		}, nil).Once()
+	mockAws.On("DescribeSubnets", mock.Anything, mock.Anything).Return(&ec2.DescribeSubnetsOutput{
+		Subnets: []types.Subnet{{SubnetId: aws.String("subnet-i-new"), Tags: []types.Tag{{Key: aws.String("kubernetes.io/cluster/mycluster-abcde"), Value: aws.String("owned")}}}},
+	}, nil)
+
+	jumphosts, err := j.listJumphosts(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, jumphosts, 2) {
+		assert.Equal(t, "i-old", jumphosts[0].instanceId)
+		assert.True(t, jumphosts[0].expiry.IsZero())
+		assert.Equal(t, "i-new", jumphosts[1].instanceId)
+		assert.Equal(t, "mycluster-abcde", jumphosts[1].clusterInfraId)
+		assert.Equal(t, "jdoe", jumphosts[1].createdBy)
+		assert.Equal(t, []string{"sg-i-new"}, jumphosts[1].securityGroupIds)
+		assert.False(t, jumphosts[1].expired(now))
+		assert.True(t, jumphosts[1].expired(now.Add(8*time.Hour)))
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, printJumphosts(&out, jumphosts, now.Add(8*time.Hour)))
+	assert.Contains(t, out.String(), "mycluster-abcde")
+	assert.Contains(t, out.String(), "2024-01-01T19:00:00Z (expired)")
+	assert.Contains(t, out.String(), "18h")
+	mockAws.AssertExpectations(t)
+}
+
+func TestCreationTags(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	j := &jumphostConfig{tags: []types.Tag{{Key: aws.String("Name"), Value: aws.String(awsResourceName)}}, expiry: 8 * time.Hour}
+
+	tags := j.creationTags(now)
+	assert.Equal(t, "2024-01-01T20:00:00Z", aws.ToString(tags[len(tags)-1].Value))
+	assert.Len(t, j.tags, 1)
+
+	j.expiry = 0
+	for _, tag := range j.creationTags(now) {
+		assert.NotEqual(t, expiryTagKey, aws.ToString(tag.Key))
+	}
+}
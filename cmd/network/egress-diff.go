@@ -0,0 +1,277 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	endpointPassed   = "passed"
+	endpointFailed   = "failed"
+	endpointUntested = "untested"
+
+	changeFixed          = "fixed"
+	changeBroken         = "broken"
+	changeNewFailure     = "new-failure"
+	changeNoLongerTested = "no-longer-tested"
+)
+
+var egressDiffOutputFormats = []string{outputText, outputJSON, outputYAML}
+
+type egressDiffOptions struct {
+	output string
+}
+
+// EgressDiff lists the endpoints whose egress changed between two saved verifications
+type EgressDiff struct {
+	Changes []EndpointChange `json:"changes"`
+	// OnlyBefore and OnlyAfter are the <cluster>/<subnet> verified in only one of the results
+	OnlyBefore []string `json:"onlyBefore,omitempty"`
+	OnlyAfter  []string `json:"onlyAfter,omitempty"`
+}
+
+// EndpointChange is an endpoint of a subnet which went from failing to passing, or vice versa
+type EndpointChange struct {
+	ClusterID string `json:"clusterId,omitempty"`
+	SubnetID  string `json:"subnetId"`
+	URL       string `json:"url"`
+	Before    string `json:"before"`
+	After     string `json:"after"`
+	Change    string `json:"change"`
+	// Error is the error of the failing side
+	Error string `json:"error,omitempty"`
+}
+
+func newCmdEgressDiff() *cobra.Command {
+	opts := &egressDiffOptions{}
+	egressDiffCmd := &cobra.Command{
+		Use:   "egress-diff <before> <after>",
+		Short: "Compare the results of two egress verifications saved with 'osdctl network verify-egress --save'",
+		Long: `Compare the results of two egress verifications saved with 'osdctl network verify-egress --save', eg. to prove a
+customer firewall change fixed or broke specific egress URLs.
+
+For every subnet verified in both results, the endpoints which went from failing to passing (fixed) or from
+passing to failing (broken) are listed. Failing endpoints which were only tested in one of the results are
+listed as well. Endpoints which weren't tested because of verifier errors are ignored. Results saved for several
+clusters are compared cluster by cluster.`,
+		Example: `  osdctl network verify-egress --cluster-id my-rosa-cluster --save before.json
+  osdctl network verify-egress --cluster-id my-rosa-cluster --save after.json
+  osdctl network egress-diff before.json after.json`,
+		Args:              cobra.ExactArgs(2),
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.run(args[0], args[1])
+		},
+	}
+	egressDiffCmd.Flags().StringVarP(&opts.output, "output", "o", outputText, fmt.Sprintf("Output format, one of %s", strings.Join(egressDiffOutputFormats, ", ")))
+	return egressDiffCmd
+}
+
+func (o *egressDiffOptions) run(beforeFile string, afterFile string) error {
+	if !slices.Contains(egressDiffOutputFormats, o.output) {
+		return fmt.Errorf("invalid output format %q, valid formats are %s", o.output, strings.Join(egressDiffOutputFormats, ", "))
+	}
+	before, err := loadResults(beforeFile)
+	if err != nil {
+		return err
+	}
+	after, err := loadResults(afterFile)
+	if err != nil {
+		return err
+	}
+	return printEgressDiff(os.Stdout, diffResults(before, after), o.output)
+}
+
+// saveResults writes a VerificationResult or a FleetResult as JSON
+func saveResults(path string, results any) error {
+	content, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0600)
+}
+
+// loadResults reads the verification results saved by verify-egress, for one or several clusters
+func loadResults(path string) ([]VerificationResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse %s, expected results saved by 'osdctl network verify-egress --save': %w", path, err)
+	}
+	if _, ok := fields["clusters"]; ok {
+		fleet := FleetResult{}
+		if err := json.Unmarshal(content, &fleet); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		results := []VerificationResult{}
+		for _, cluster := range fleet.Clusters {
+			if cluster.Result != nil {
+				results = append(results, *cluster.Result)
+			}
+		}
+		return results, nil
+	}
+	if _, ok := fields["subnets"]; !ok {
+		return nil, fmt.Errorf("failed to parse %s, expected results saved by 'osdctl network verify-egress --save'", path)
+	}
+	result := VerificationResult{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return []VerificationResult{result}, nil
+}
+
+// diffResults compares the endpoints of every subnet verified in both results
+func diffResults(before []VerificationResult, after []VerificationResult) EgressDiff {
+	subnetKey := func(clusterID string, subnetID string) string {
+		if clusterID == "" {
+			return subnetID
+		}
+		return clusterID + "/" + subnetID
+	}
+	beforeSubnets := map[string]SubnetResult{}
+	for _, result := range before {
+		for _, subnet := range result.Subnets {
+			beforeSubnets[subnetKey(result.ClusterID, subnet.SubnetID)] = subnet
+		}
+	}
+
+	diff := EgressDiff{Changes: []EndpointChange{}}
+	seen := map[string]bool{}
+	for _, result := range after {
+		for _, afterSubnet := range result.Subnets {
+			key := subnetKey(result.ClusterID, afterSubnet.SubnetID)
+			beforeSubnet, ok := beforeSubnets[key]
+			if !ok {
+				diff.OnlyAfter = append(diff.OnlyAfter, key)
+				continue
+			}
+			seen[key] = true
+			diff.Changes = append(diff.Changes, diffSubnet(result.ClusterID, beforeSubnet, afterSubnet)...)
+		}
+	}
+	for key := range beforeSubnets {
+		if !seen[key] {
+			diff.OnlyBefore = append(diff.OnlyBefore, key)
+		}
+	}
+	sort.Strings(diff.OnlyBefore)
+	return diff
+}
+
+// diffSubnet returns the endpoints of the subnet whose status changed, passing endpoints
+// which were only tested in one of the results and endpoints untested because of verifier
+// errors are ignored
+func diffSubnet(clusterID string, before SubnetResult, after SubnetResult) []EndpointChange {
+	beforeEndpoints := map[string]EndpointResult{}
+	for _, endpoint := range before.Endpoints {
+		beforeEndpoints[endpoint.URL] = endpoint
+	}
+	afterEndpoints := map[string]EndpointResult{}
+	urls := []string{}
+	for _, endpoint := range after.Endpoints {
+		afterEndpoints[endpoint.URL] = endpoint
+		urls = append(urls, endpoint.URL)
+	}
+	for _, endpoint := range before.Endpoints {
+		if _, ok := afterEndpoints[endpoint.URL]; !ok {
+			urls = append(urls, endpoint.URL)
+		}
+	}
+
+	changes := []EndpointChange{}
+	for _, url := range urls {
+		beforeEndpoint, beforeOk := beforeEndpoints[url]
+		afterEndpoint, afterOk := afterEndpoints[url]
+		if beforeEndpoint.Untested || afterEndpoint.Untested {
+			continue
+		}
+		change := EndpointChange{
+			ClusterID: clusterID,
+			SubnetID:  after.SubnetID,
+			URL:       url,
+			Before:    endpointStatus(beforeEndpoint, beforeOk),
+			After:     endpointStatus(afterEndpoint, afterOk),
+		}
+		switch {
+		case change.Before == endpointFailed && change.After == endpointPassed:
+			change.Change = changeFixed
+			change.Error = beforeEndpoint.Error
+		case change.Before == endpointPassed && change.After == endpointFailed:
+			change.Change = changeBroken
+			change.Error = afterEndpoint.Error
+		case change.Before == endpointUntested && change.After == endpointFailed:
+			change.Change = changeNewFailure
+			change.Error = afterEndpoint.Error
+		case change.Before == endpointFailed && change.After == endpointUntested:
+			change.Change = changeNoLongerTested
+			change.Error = beforeEndpoint.Error
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func endpointStatus(endpoint EndpointResult, tested bool) string {
+	switch {
+	case !tested:
+		return endpointUntested
+	case endpoint.Passed:
+		return endpointPassed
+	default:
+		return endpointFailed
+	}
+}
+
+func printEgressDiff(w io.Writer, diff EgressDiff, format string) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	case outputYAML:
+		out, err := yaml.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+
+	for _, key := range diff.OnlyBefore {
+		fmt.Fprintf(w, "Subnet %s was only verified before\n", key)
+	}
+	for _, key := range diff.OnlyAfter {
+		fmt.Fprintf(w, "Subnet %s was only verified after\n", key)
+	}
+	if len(diff.Changes) == 0 {
+		fmt.Fprintln(w, "No egress changes")
+		return nil
+	}
+
+	p := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	p.AddRow([]string{"SUBNET", "ENDPOINT", "BEFORE", "AFTER", "CHANGE", "ERROR"})
+	for _, change := range diff.Changes {
+		subnet := change.SubnetID
+		if change.ClusterID != "" {
+			subnet = change.ClusterID + "/" + subnet
+		}
+		p.AddRow([]string{subnet, change.URL, change.Before, change.After, change.Change, change.Error})
+	}
+	return p.Flush()
+}
@@ -0,0 +1,78 @@
+package network
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffResults(t *testing.T) {
+	before := []VerificationResult{{
+		ClusterID: "cluster-id",
+		Subnets: []SubnetResult{
+			{SubnetID: "subnet-a", Endpoints: []EndpointResult{
+				{URL: "https://quay.io:443", Category: errorCategoryTimeout, Error: "Connection timed out"},
+				{URL: "https://api.openshift.com:443", Passed: true},
+				{URL: "https://sso.redhat.com:443", Passed: true},
+				{URL: "https://removed.example.com:443", Error: "Could not resolve host"},
+				{URL: "https://untested-before.example.com:443", Untested: true},
+				{URL: "https://untested-after.example.com:443", Error: "Connection refused"},
+			}},
+			{SubnetID: "subnet-b"},
+		},
+	}}
+	after := []VerificationResult{{
+		ClusterID: "cluster-id",
+		Subnets: []SubnetResult{
+			{SubnetID: "subnet-a", Endpoints: []EndpointResult{
+				{URL: "https://quay.io:443", Passed: true},
+				{URL: "https://api.openshift.com:443", Error: "Connection refused"},
+				{URL: "https://sso.redhat.com:443", Passed: true},
+				{URL: "https://added.example.com:443", Error: "Connection timed out"},
+				{URL: "https://added-passing.example.com:443", Passed: true},
+				{URL: "https://untested-before.example.com:443", Error: "Connection refused"},
+				{URL: "https://untested-after.example.com:443", Untested: true},
+			}},
+			{SubnetID: "subnet-c"},
+		},
+	}}
+
+	diff := diffResults(before, after)
+	assert.Equal(t, []EndpointChange{
+		{ClusterID: "cluster-id", SubnetID: "subnet-a", URL: "https://quay.io:443", Before: endpointFailed, After: endpointPassed, Change: changeFixed, Error: "Connection timed out"},
+		{ClusterID: "cluster-id", SubnetID: "subnet-a", URL: "https://api.openshift.com:443", Before: endpointPassed, After: endpointFailed, Change: changeBroken, Error: "Connection refused"},
+		{ClusterID: "cluster-id", SubnetID: "subnet-a", URL: "https://added.example.com:443", Before: endpointUntested, After: endpointFailed, Change: changeNewFailure, Error: "Connection timed out"},
+		{ClusterID: "cluster-id", SubnetID: "subnet-a", URL: "https://removed.example.com:443", Before: endpointFailed, After: endpointUntested, Change: changeNoLongerTested, Error: "Could not resolve host"},
+	}, diff.Changes)
+	assert.Equal(t, []string{"cluster-id/subnet-b"}, diff.OnlyBefore)
+	assert.Equal(t, []string{"cluster-id/subnet-c"}, diff.OnlyAfter)
+
+	var text bytes.Buffer
+	assert.NoError(t, printEgressDiff(&text, diff, outputText))
+	assert.Contains(t, text.String(), "Subnet cluster-id/subnet-b was only verified before")
+	assert.Contains(t, text.String(), "fixed")
+}
+
+func TestSaveAndLoadResults(t *testing.T) {
+	dir := t.TempDir()
+	result := VerificationResult{ClusterID: "a", Subnets: []SubnetResult{{SubnetID: "subnet-a", Endpoints: []EndpointResult{{URL: "https://quay.io:443", Passed: true}}}}}
+
+	single := filepath.Join(dir, "single.json")
+	assert.NoError(t, saveResults(single, &result))
+	loaded, err := loadResults(single)
+	assert.NoError(t, err)
+	assert.Equal(t, []VerificationResult{result}, loaded)
+
+	fleet := filepath.Join(dir, "fleet.json")
+	assert.NoError(t, saveResults(fleet, &FleetResult{Clusters: []FleetClusterResult{{ClusterID: "a", Result: &result}, {ClusterID: "b", Error: "failed"}}}))
+	loaded, err = loadResults(fleet)
+	assert.NoError(t, err)
+	assert.Equal(t, []VerificationResult{result}, loaded)
+
+	other := filepath.Join(dir, "other.json")
+	assert.NoError(t, saveResults(other, map[string]string{"foo": "bar"}))
+	_, err = loadResults(other)
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openshift/osdctl/pkg/k8s"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// packetCaptureCleanupOptions defines the struct for running the packet-capture cleanup command
+type packetCaptureCleanupOptions struct {
+	all         bool
+	dryRun      bool
+	skipPrompts bool
+	reason      string
+
+	genericclioptions.IOStreams
+	kubeCli *k8s.LazyClient
+}
+
+// captureResource is a daemonset or pod left over by a packet capture
+type captureResource struct {
+	kind   string
+	object client.Object
+}
+
+// newCmdPacketCaptureCleanup implements the packet-capture cleanup command removing leftover capture resources
+func newCmdPacketCaptureCleanup(streams genericclioptions.IOStreams, client *k8s.LazyClient) *cobra.Command {
+	ops := &packetCaptureCleanupOptions{IOStreams: streams, kubeCli: client}
+	cleanupCmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete the daemonsets and pods left over by packet captures",
+		Long: `Delete the daemonsets and pods left over by packet captures, eg. when osdctl was killed during a capture.
+
+The capture resources of all the namespaces are listed, by default only the ones past their expiry are deleted
+so captures still running aren't interrupted. Capture resources created by older osdctl versions have no expiry
+and are always deleted.`,
+		Example: `  # List the leftover capture resources without deleting them
+  osdctl network packet-capture cleanup --dry-run
+
+  # Delete all the capture resources, including the ones of running captures
+  osdctl network packet-capture cleanup --all --reason OHSS-1234`,
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete())
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	cleanupCmd.Flags().BoolVar(&ops.all, "all", false, "Delete the capture resources which haven't expired yet as well")
+	cleanupCmd.Flags().BoolVar(&ops.dryRun, "dry-run", false, "Dry-run - list the capture resources to delete but don't delete them")
+	cleanupCmd.Flags().BoolVarP(&ops.skipPrompts, "yes", "y", false, "Skips all prompts.")
+	cleanupCmd.Flags().StringVar(&ops.reason, "reason", "", "The reason for this command, which requires elevation, to be run (usualy an OHSS or PD ticket)")
+	return cleanupCmd
+}
+
+func (o *packetCaptureCleanupOptions) complete() error {
+	if len(o.reason) > 0 {
+		// This action requires elevation
+		o.kubeCli.Impersonate("backplane-cluster-admin", o.reason, "Elevation required to clean up network captures")
+	}
+	return nil
+}
+
+func (o *packetCaptureCleanupOptions) run() error {
+	resources, err := findCaptureResources(o.kubeCli, o.all, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(resources) == 0 {
+		fmt.Fprintln(o.Out, "No packet capture resources to delete")
+		return nil
+	}
+
+	p := printer.NewTablePrinter(o.Out, 20, 1, 3, ' ')
+	p.AddRow([]string{"KIND", "NAMESPACE", "NAME", "OWNER", "REASON", "EXPIRY"})
+	for _, resource := range resources {
+		objLabels := resource.object.GetLabels()
+		expiry := "-"
+		if at, ok := captureExpiry(objLabels); ok {
+			expiry = at.UTC().Format(time.RFC3339)
+		}
+		p.AddRow([]string{resource.kind, resource.object.GetNamespace(), resource.object.GetName(), objLabels[packetCaptureOwnerLabel], objLabels[packetCaptureReasonLabel], expiry})
+	}
+	if err := p.Flush(); err != nil {
+		return err
+	}
+
+	if o.dryRun {
+		return nil
+	}
+	if !o.skipPrompts && !utils.ConfirmPrompt() {
+		return nil
+	}
+
+	var errs []error
+	for _, resource := range resources {
+		if err := o.kubeCli.Delete(context.TODO(), resource.object); err != nil && !k8serr.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s/%s: %v", resource.kind, resource.object.GetNamespace(), resource.object.GetName(), err))
+			continue
+		}
+		fmt.Fprintf(o.Out, "Deleted %s %s/%s\n", resource.kind, resource.object.GetNamespace(), resource.object.GetName())
+	}
+	return errors.Join(errs...)
+}
+
+// findCaptureResources returns the capture daemonsets and pods of all the namespaces, only the expired
+// ones unless all is true. The pods of the capture daemonsets are left to the daemonsets.
+func findCaptureResources(kubeCli client.Client, all bool, now time.Time) ([]captureResource, error) {
+	// Resources created before they were labeled only have the app label of the default name
+	selectors := []labels.Selector{
+		labels.SelectorFromSet(labels.Set{packetCaptureLabel: "true"}),
+		labels.SelectorFromSet(labels.Set{"app": packetCaptureName}),
+	}
+
+	resources := []captureResource{}
+	seen := map[string]bool{}
+	add := func(kind string, obj client.Object) {
+		key := kind + "/" + obj.GetNamespace() + "/" + obj.GetName()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		if expiry, ok := captureExpiry(obj.GetLabels()); !all && ok && now.Before(expiry) {
+			return
+		}
+		resources = append(resources, captureResource{kind: kind, object: obj})
+	}
+
+	for _, selector := range selectors {
+		var daemonSets appsv1.DaemonSetList
+		if err := kubeCli.List(context.TODO(), &daemonSets, &client.ListOptions{LabelSelector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to list packet capture daemonsets: %w", err)
+		}
+		for i := range daemonSets.Items {
+			add("DaemonSet", &daemonSets.Items[i])
+		}
+
+		var pods corev1.PodList
+		if err := kubeCli.List(context.TODO(), &pods, &client.ListOptions{LabelSelector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to list packet capture pods: %w", err)
+		}
+		for i := range pods.Items {
+			if len(pods.Items[i].OwnerReferences) > 0 {
+				continue
+			}
+			add("Pod", &pods.Items[i])
+		}
+	}
+	return resources, nil
+}
+
+// captureExpiry returns the expiry of a capture resource, false if it has none
+func captureExpiry(objLabels map[string]string) (time.Time, bool) {
+	expiry, err := strconv.ParseInt(objLabels[packetCaptureExpiryLabel], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(expiry, 0), true
+}
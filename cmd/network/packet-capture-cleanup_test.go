@@ -0,0 +1,91 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/openshift/osdctl/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newCaptureCleanupObjects(now time.Time) []runtime.Object {
+	expired := strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)
+	running := strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+	return []runtime.Object{
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "expired", Namespace: "default", Labels: map[string]string{packetCaptureLabel: "true", packetCaptureExpiryLabel: expired}}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "other", Labels: map[string]string{packetCaptureLabel: "true", packetCaptureExpiryLabel: running}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default", Labels: map[string]string{"app": packetCaptureName}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:            "expired-abcde",
+			Namespace:       "default",
+			Labels:          map[string]string{packetCaptureLabel: "true", packetCaptureExpiryLabel: expired},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "expired"}},
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default", Labels: map[string]string{"app": "unrelated"}}},
+	}
+}
+
+func TestFindCaptureResources(t *testing.T) {
+	now := time.Now()
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(newCaptureCleanupObjects(now)...).Build()
+
+	names := func(resources []captureResource) []string {
+		result := []string{}
+		for _, resource := range resources {
+			result = append(result, resource.kind+"/"+resource.object.GetNamespace()+"/"+resource.object.GetName())
+		}
+		return result
+	}
+
+	resources, err := findCaptureResources(fakeClient, false, now)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"DaemonSet/default/expired", "Pod/default/legacy"}, names(resources))
+
+	resources, err = findCaptureResources(fakeClient, true, now)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"DaemonSet/default/expired", "DaemonSet/other/running", "Pod/default/legacy"}, names(resources))
+}
+
+func TestPacketCaptureCleanupRun(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(newCaptureCleanupObjects(time.Now())...).Build()
+	out := &bytes.Buffer{}
+	ops := &packetCaptureCleanupOptions{
+		skipPrompts: true,
+		IOStreams:   genericclioptions.IOStreams{Out: out},
+		kubeCli:     k8s.LazyClientInit(fakeClient),
+	}
+
+	assert.NoError(t, ops.run())
+	assert.Contains(t, out.String(), "Deleted DaemonSet default/expired")
+	assert.Contains(t, out.String(), "Deleted Pod default/legacy")
+
+	err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: "expired", Namespace: "default"}, &appsv1.DaemonSet{})
+	assert.True(t, k8serr.IsNotFound(err))
+	assert.NoError(t, fakeClient.Get(context.TODO(), types.NamespacedName{Name: "running", Namespace: "other"}, &appsv1.DaemonSet{}))
+}
+
+func TestPacketCaptureCleanupDryRun(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(newCaptureCleanupObjects(time.Now())...).Build()
+	out := &bytes.Buffer{}
+	ops := &packetCaptureCleanupOptions{
+		dryRun:    true,
+		IOStreams: genericclioptions.IOStreams{Out: out},
+		kubeCli:   k8s.LazyClientInit(fakeClient),
+	}
+
+	assert.NoError(t, ops.run())
+	assert.Contains(t, out.String(), "expired")
+	assert.NotContains(t, out.String(), "Deleted")
+	assert.NoError(t, fakeClient.Get(context.TODO(), types.NamespacedName{Name: "expired", Namespace: "default"}, &appsv1.DaemonSet{}))
+}
@@ -0,0 +1,279 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift/osdctl/pkg/pcap"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const captureSummaryTop = 10
+
+var (
+	captureSummaryOutputFormats = []string{outputText, outputJSON}
+	// pcapFileRegex matches the capture files and the rotated capture files, eg. capture.pcap3
+	pcapFileRegex = regexp.MustCompile(`\.pcap\d*$`)
+)
+
+// packetCaptureSummaryOptions defines the struct for running the packet-capture summary command
+type packetCaptureSummaryOptions struct {
+	top    int
+	merge  string
+	output string
+
+	genericclioptions.IOStreams
+}
+
+// CaptureSummary is the summary of the files captured on a node
+type CaptureSummary struct {
+	// Capture is the name of the capture file, or of the directory of the rotated files, eg. <node>-<time>
+	Capture string   `json:"capture"`
+	Files   []string `json:"files"`
+	*pcap.Summary
+}
+
+// captureFiles are the pcap files captured on a node
+type captureFiles struct {
+	name  string
+	paths []string
+}
+
+// newCmdPacketCaptureSummary implements the packet-capture summary command summarizing downloaded captures
+func newCmdPacketCaptureSummary(streams genericclioptions.IOStreams) *cobra.Command {
+	ops := &packetCaptureSummaryOptions{IOStreams: streams}
+	summaryCmd := &cobra.Command{
+		Use:   "summary [path...]",
+		Short: "Summarize the captured packets downloaded by packet-capture",
+		Long: `Summarize the captured packets downloaded by packet-capture, without Wireshark.
+
+The pcap files in the given files and directories, capture-output by default, are summarized per node with the top
+talkers, the TCP resets and retransmissions, the DNS failures and the TLS handshake failures. The packets of the
+rotated files of a capture are summarized together, ordered by time. TCP streams aren't reassembled, so the TLS failures are only found in the
+first bytes of the TCP segments.
+
+With --merge, the packets of all the files are written to a single pcap file, ordered by time.`,
+		Example: `  # Summarize all the downloaded captures
+  osdctl network packet-capture summary
+
+  # Summarize a capture and merge the files of all the nodes
+  osdctl network packet-capture summary capture-output/*-20240101T120000* --merge merged.pcap`,
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.run(args))
+		},
+	}
+
+	summaryCmd.Flags().IntVar(&ops.top, "top", captureSummaryTop, "Number of entries of each list of the summary")
+	summaryCmd.Flags().StringVar(&ops.merge, "merge", "", "Merge the packets of all the files into this pcap file, ordered by time")
+	summaryCmd.Flags().StringVarP(&ops.output, "output", "o", outputText, fmt.Sprintf("Output format, one of %s", strings.Join(captureSummaryOutputFormats, ", ")))
+	return summaryCmd
+}
+
+func (o *packetCaptureSummaryOptions) run(paths []string) error {
+	if !slices.Contains(captureSummaryOutputFormats, o.output) {
+		return fmt.Errorf("invalid output format %q, valid formats are %s", o.output, strings.Join(captureSummaryOutputFormats, ", "))
+	}
+	if len(paths) == 0 {
+		paths = []string{outputDir}
+	}
+	captures, err := findCaptureFiles(paths, o.merge)
+	if err != nil {
+		return err
+	}
+	if len(captures) == 0 {
+		return fmt.Errorf("no pcap files found in %s", strings.Join(paths, ", "))
+	}
+
+	if o.merge != "" {
+		if err := mergeCaptureFiles(o.merge, captures); err != nil {
+			return err
+		}
+	}
+	return summarizeCaptureFiles(o.Out, captures, o.top, o.output)
+}
+
+// findCaptureFiles returns the pcap files of the paths grouped by capture, the rotated files of a
+// capture are in a directory. The excluded file, eg. the merged file, is ignored.
+func findCaptureFiles(paths []string, exclude string) ([]captureFiles, error) {
+	excluded := ""
+	if exclude != "" {
+		excluded, _ = filepath.Abs(exclude)
+	}
+
+	byName := map[string][]string{}
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || !pcapFileRegex.MatchString(entry.Name()) {
+				return nil
+			}
+			if abs, _ := filepath.Abs(path); abs == excluded {
+				return nil
+			}
+			name := strings.TrimSuffix(entry.Name(), ".pcap")
+			if strings.HasPrefix(entry.Name(), "capture.pcap") {
+				name = filepath.Base(filepath.Dir(path))
+			}
+			if !slices.Contains(byName[name], path) {
+				byName[name] = append(byName[name], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	captures := []captureFiles{}
+	for name, files := range byName {
+		sort.Slice(files, func(i, j int) bool { return lessCaptureFile(files[i], files[j]) })
+		captures = append(captures, captureFiles{name: name, paths: files})
+	}
+	sort.Slice(captures, func(i, j int) bool { return captures[i].name < captures[j].name })
+	return captures, nil
+}
+
+// lessCaptureFile orders the rotated files by their number, eg. capture.pcap2 before capture.pcap10.
+// The files are only listed in this order, their packets are summarized and merged by timestamp as the
+// ring of files can wrap around.
+func lessCaptureFile(a string, b string) bool {
+	aBase, aNumber := splitRotatedFile(a)
+	bBase, bNumber := splitRotatedFile(b)
+	if aBase != bBase {
+		return aBase < bBase
+	}
+	return aNumber < bNumber
+}
+
+// splitRotatedFile splits the path of a rotated file into the path without the number and the number,
+// which is -1 if the file isn't rotated
+func splitRotatedFile(path string) (string, int) {
+	base := strings.TrimRight(path, "0123456789")
+	number, err := strconv.Atoi(path[len(base):])
+	if err != nil {
+		return path, -1
+	}
+	return base, number
+}
+
+// mergeCaptureFiles writes the packets of all the captures to a single pcap file ordered by time
+func mergeCaptureFiles(path string, captures []captureFiles) error {
+	paths := []string{}
+	for _, capture := range captures {
+		paths = append(paths, capture.paths...)
+	}
+
+	file, err := os.Create(path) //#nosec G304 -- the file is given by the user
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	count, err := pcap.Merge(file, paths)
+	if err != nil {
+		return fmt.Errorf("failed to merge the capture files: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Merged %d packets of %d files into %s\n", count, len(paths), path)
+	return nil
+}
+
+// summarizeCaptureFiles prints the summary of every capture
+func summarizeCaptureFiles(w io.Writer, captures []captureFiles, top int, format string) error {
+	summaries := []CaptureSummary{}
+	for _, capture := range captures {
+		summary, err := pcap.SummarizeFiles(capture.paths, top)
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, CaptureSummary{Capture: capture.name, Files: capture.paths, Summary: summary})
+	}
+
+	if format == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summaries)
+	}
+	for i, summary := range summaries {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := printCaptureSummary(w, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printCaptureSummary(w io.Writer, summary CaptureSummary) error {
+	fmt.Fprintf(w, "=== %s ===\n", summary.Capture)
+	if summary.Packets == 0 {
+		fmt.Fprintln(w, "No packets captured")
+		return nil
+	}
+	fmt.Fprintf(w, "%d packets, %d bytes from %s to %s\n", summary.Packets, summary.Bytes, summary.Start.Format(time.RFC3339), summary.End.Format(time.RFC3339))
+	if summary.Truncated {
+		fmt.Fprintln(w, "The last packet of a file was truncated")
+	}
+	fmt.Fprintf(w, "%d TCP resets, %d TCP retransmissions\n\n", summary.TCPResets, summary.TCPRetransmissions)
+
+	p := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	p.AddRow([]string{"TOP TALKERS", "", "PACKETS", "BYTES"})
+	for _, conversation := range summary.TopTalkers {
+		p.AddRow([]string{conversation.A, conversation.B, strconv.Itoa(conversation.Packets), strconv.Itoa(conversation.Bytes)})
+	}
+	if err := p.Flush(); err != nil {
+		return err
+	}
+
+	if len(summary.TCPIssues) > 0 {
+		fmt.Fprintln(w)
+		p = printer.NewTablePrinter(w, 20, 1, 3, ' ')
+		p.AddRow([]string{"TCP CONNECTION", "", "RESETS", "RETRANSMISSIONS"})
+		for _, issue := range summary.TCPIssues {
+			p.AddRow([]string{issue.A, issue.B, strconv.Itoa(issue.Resets), strconv.Itoa(issue.Retransmissions)})
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(summary.DNSFailures) > 0 {
+		fmt.Fprintln(w)
+		p = printer.NewTablePrinter(w, 20, 1, 3, ' ')
+		p.AddRow([]string{"DNS FAILURE", "SERVER", "RESULT", "COUNT"})
+		for _, failure := range summary.DNSFailures {
+			p.AddRow([]string{failure.Name, failure.Server, failure.Result, strconv.Itoa(failure.Count)})
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(summary.TLSFailures) > 0 {
+		fmt.Fprintln(w)
+		p = printer.NewTablePrinter(w, 20, 1, 3, ' ')
+		p.AddRow([]string{"TLS FAILURE", "SERVER NAME", "REASON", "COUNT"})
+		for _, failure := range summary.TLSFailures {
+			p.AddRow([]string{failure.Server, failure.ServerName, failure.Reason, strconv.Itoa(failure.Count)})
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
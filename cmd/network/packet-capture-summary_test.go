@@ -0,0 +1,73 @@
+package network
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/osdctl/pkg/pcap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func writeTestCapture(t *testing.T, path string, timestamps ...time.Time) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	var buffer bytes.Buffer
+	writer, err := pcap.NewWriter(&buffer, pcap.LinkTypeEthernet, 65535)
+	require.NoError(t, err)
+	for _, timestamp := range timestamps {
+		require.NoError(t, writer.WritePacket(pcap.Packet{Timestamp: timestamp, Data: make([]byte, 60), Length: 60}))
+	}
+	require.NoError(t, os.WriteFile(path, buffer.Bytes(), 0600))
+}
+
+func TestFindCaptureFiles(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeTestCapture(t, filepath.Join(dir, "node-a-20240101T000000.pcap"), start)
+	writeTestCapture(t, filepath.Join(dir, "node-b-20240101T000000", "capture.pcap0"), start)
+	writeTestCapture(t, filepath.Join(dir, "node-b-20240101T000000", "capture.pcap1"), start)
+	writeTestCapture(t, filepath.Join(dir, "node-b-20240101T000000", "capture.pcap10"), start)
+	writeTestCapture(t, filepath.Join(dir, "node-b-20240101T000000", "capture.pcap2"), start)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0600))
+	writeTestCapture(t, filepath.Join(dir, "merged.pcap"), start)
+
+	captures, err := findCaptureFiles([]string{dir}, filepath.Join(dir, "merged.pcap"))
+	require.NoError(t, err)
+	assert.Equal(t, []captureFiles{
+		{name: "node-a-20240101T000000", paths: []string{filepath.Join(dir, "node-a-20240101T000000.pcap")}},
+		{name: "node-b-20240101T000000", paths: []string{
+			filepath.Join(dir, "node-b-20240101T000000", "capture.pcap0"),
+			filepath.Join(dir, "node-b-20240101T000000", "capture.pcap1"),
+			filepath.Join(dir, "node-b-20240101T000000", "capture.pcap2"),
+			filepath.Join(dir, "node-b-20240101T000000", "capture.pcap10"),
+		}},
+	}, captures)
+}
+
+func TestPacketCaptureSummaryRun(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeTestCapture(t, filepath.Join(dir, "node-a-20240101T000000.pcap"), start, start.Add(2*time.Second))
+	writeTestCapture(t, filepath.Join(dir, "node-b-20240101T000000.pcap"), start.Add(time.Second))
+
+	out := &bytes.Buffer{}
+	merged := filepath.Join(dir, "merged.pcap")
+	ops := &packetCaptureSummaryOptions{top: 10, merge: merged, output: outputText, IOStreams: genericclioptions.IOStreams{Out: out}}
+	require.NoError(t, ops.run([]string{dir}))
+	assert.Contains(t, out.String(), "=== node-a-20240101T000000 ===")
+	assert.Contains(t, out.String(), "2 packets, 120 bytes")
+	assert.Contains(t, out.String(), "=== node-b-20240101T000000 ===")
+
+	summary, err := pcap.SummarizeFiles([]string{merged}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Packets)
+
+	ops.output = "yaml"
+	assert.Error(t, ops.run([]string{dir}))
+	ops.output = outputText
+	assert.Error(t, ops.run([]string{t.TempDir()}))
+}
@@ -8,7 +8,13 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
+	"os/user"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
@@ -20,6 +26,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -36,15 +43,45 @@ const (
 	nodeLabelValue           = ""
 	packetCaptureDurationSec = 60
 	singlePod                = false
+	captureFileCount         = 5
+	// netnsDir is where CRI-O pins the network namespaces of the pods on the node
+	netnsDir = "/var/run/netns"
+
+	// Labels of the capture resources, used by 'packet-capture cleanup' to find the leftovers
+	packetCaptureLabel       = "osdctl.openshift.io/packet-capture"
+	packetCaptureOwnerLabel  = "osdctl.openshift.io/packet-capture-owner"
+	packetCaptureReasonLabel = "osdctl.openshift.io/packet-capture-reason"
+	// packetCaptureExpiryLabel is the unix time after which the resources are considered leftovers
+	packetCaptureExpiryLabel = "osdctl.openshift.io/packet-capture-expiry"
+	// packetCaptureExpiryMargin is added to the capture duration for the pods to start and the files to be copied
+	packetCaptureExpiryMargin = time.Hour
+	// packetCaptureWaitMargin is added to the capture duration when waiting for the pods, which capture in their init container
+	packetCaptureWaitMargin = 10 * time.Minute
 )
 
 // newCmdPacketCapture implements the packet-capture command to run a packet capture
 func newCmdPacketCapture(streams genericclioptions.IOStreams, client *k8s.LazyClient) *cobra.Command {
 	ops := newPacketCaptureOptions(streams, client)
 	packetCaptureCmd := &cobra.Command{
-		Use:               "packet-capture",
-		Aliases:           []string{"pcap"},
-		Short:             "Start packet capture",
+		Use:     "packet-capture",
+		Aliases: []string{"pcap"},
+		Short:   "Start packet capture",
+		Long: `Start packet capture
+
+By default tcpdump captures the overlay interface of the nodes matching --node-label-key and --node-label-value.
+With --pod, the capture pod is scheduled on the node of the given pod and tcpdump runs in the network namespace
+of that pod, capturing only its traffic.
+
+With --file-size, tcpdump writes a ring buffer of --file-count files of at most --file-size MB each, overwriting
+the oldest file once the last one is full, so long captures don't fill the disk of the nodes.
+
+The capture resources are deleted when the capture ends, fails or is interrupted. Resources left over, eg. when
+osdctl was killed, are labeled with their owner, reason and expiry and removed by 'packet-capture cleanup'.`,
+		Example: `  # Capture the DNS traffic of all the worker nodes for 5 minutes
+  osdctl network packet-capture --duration 300 --filter "port 53"
+
+  # Capture the traffic of a pod, keeping at most 5 files of 100MB
+  osdctl network packet-capture --pod openshift-ingress/router-default-abc123 --duration 3600 --file-size 100`,
 		Args:              cobra.NoArgs,
 		DisableAutoGenTag: true,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -60,6 +97,15 @@ func newCmdPacketCapture(streams genericclioptions.IOStreams, client *k8s.LazyCl
 	packetCaptureCmd.Flags().StringVarP(&ops.nodeLabelValue, "node-label-value", "", nodeLabelValue, "Node label value")
 	packetCaptureCmd.Flags().BoolVarP(&ops.singlePod, "single-pod", "", singlePod, "toggle deployment as single pod (default: deploy a daemonset)")
 	packetCaptureCmd.Flags().StringVar(&ops.reason, "reason", "", "The reason for this command, which requires elevation, to be run (usualy an OHSS or PD ticket)")
+	packetCaptureCmd.Flags().StringVar(&ops.filter, "filter", "", "BPF filter expression of the packets to capture, eg. \"tcp port 443\"")
+	packetCaptureCmd.Flags().StringVar(&ops.pod, "pod", "", "Capture only the traffic of the given pod, as <namespace>/<name>. Implies --single-pod on the node of the pod")
+	packetCaptureCmd.Flags().IntVar(&ops.snaplen, "snaplen", 0, "Bytes of each packet to capture, 0 captures the whole packets")
+	packetCaptureCmd.Flags().IntVar(&ops.fileSize, "file-size", 0, "Rotate the capture file once it reaches this size in MB, 0 disables the rotation")
+	packetCaptureCmd.Flags().IntVar(&ops.fileCount, "file-count", captureFileCount, "Number of rotated capture files to keep per node when --file-size is set, the oldest file is overwritten")
+
+	packetCaptureCmd.Flags().BoolVar(&ops.summarize, "summarize", false, "Summarize the downloaded capture files, see 'packet-capture summary'")
+	packetCaptureCmd.AddCommand(newCmdPacketCaptureCleanup(streams, client))
+	packetCaptureCmd.AddCommand(newCmdPacketCaptureSummary(streams))
 
 	ops.startTime = time.Now()
 	return packetCaptureCmd
@@ -75,6 +121,16 @@ type packetCaptureOptions struct {
 	singlePod        bool
 	captureInterface string
 	reason           string
+	filter           string
+	pod              string
+	snaplen          int
+	fileSize         int
+	fileCount        int
+	summarize        bool
+
+	// targetNode and targetPodIP are the node and IP of the pod given with --pod
+	targetNode  string
+	targetPodIP string
 
 	genericclioptions.IOStreams
 	kubeCli   *k8s.LazyClient
@@ -96,91 +152,188 @@ func (o *packetCaptureOptions) complete(cmd *cobra.Command, _ []string) error {
 	return nil
 }
 
+func (o *packetCaptureOptions) validate() error {
+	if o.duration < 1 {
+		return fmt.Errorf("--duration must be at least 1 second")
+	}
+	if o.snaplen < 0 {
+		return fmt.Errorf("--snaplen can't be negative")
+	}
+	if o.fileSize < 0 {
+		return fmt.Errorf("--file-size can't be negative")
+	}
+	if o.fileSize > 0 && o.fileCount < 1 {
+		return fmt.Errorf("--file-count must be at least 1")
+	}
+	if o.pod != "" {
+		if _, _, err := parsePodName(o.pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (o *packetCaptureOptions) run() error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	if o.pod != "" {
+		log.Printf("Resolving the node of pod %s\n", o.pod)
+		if err := setTargetPod(o); err != nil {
+			return err
+		}
+	}
+
+	var err error
 	if o.singlePod {
-		return o.runPod()
+		err = o.runPod()
+	} else {
+		err = o.runDaemonSet()
+	}
+	if err != nil || !o.summarize {
+		return err
 	}
-	return o.runDaemonSet()
+	return o.summarizeCapture()
 }
 
-func (o *packetCaptureOptions) runDaemonSet() error {
-	log.Println("Confirming the interface for capturing")
-	err := setCaptureInterface(o)
+// summarizeCapture prints the summary of the files downloaded by this capture
+func (o *packetCaptureOptions) summarizeCapture() error {
+	paths, err := filepath.Glob(filepath.Join(outputDir, "*-"+o.startTime.UTC().Format("20060102T150405")+"*"))
 	if err != nil {
-		log.Fatalf("Error setting the interface for capture")
 		return err
 	}
+	captures, err := findCaptureFiles(paths, "")
+	if err != nil {
+		return err
+	}
+	return summarizeCaptureFiles(o.Out, captures, captureSummaryTop, outputText)
+}
+
+func (o *packetCaptureOptions) runDaemonSet() error {
+	log.Println("Confirming the interface for capturing")
+	if err := setCaptureInterface(o); err != nil {
+		return fmt.Errorf("error setting the interface for capture: %w", err)
+	}
 
 	log.Println("Ensuring Packet Capture Daemonset")
 	ds, err := ensurePacketCaptureDaemonSet(o)
 	if err != nil {
-		log.Fatalf("Error ensuring packet capture daemonset %v", err)
-		return err
+		return fmt.Errorf("error ensuring packet capture daemonset: %w", err)
 	}
+	cleanup := deleteOnExit(o, ds)
+	defer cleanup()
+
 	log.Println("Waiting For Packet Capture Daemonset")
-	err = waitForPacketCaptureDaemonset(o, ds)
-	if err != nil {
-		log.Fatalf("Error Waiting for daemonset %v", err)
-		return err
+	if err := waitForPacketCaptureDaemonset(o, ds); err != nil {
+		return fmt.Errorf("error waiting for daemonset: %w", err)
 	}
 	log.Println("Copying Files From Packet Capture Pods")
-	err = copyFilesFromPacketCapturePods(o)
-	if err != nil {
-		log.Fatalf("Error copying files %v", err)
-		return err
-	}
-	log.Println("Deleting Packet Capture Daemonset")
-	err = deletePacketCaptureDaemonSet(o, ds)
-	if err != nil {
-		log.Fatalf("Error deleting packet capture daemonset %v", err)
-		return err
+	if err := copyFilesFromPacketCapturePods(o); err != nil {
+		return fmt.Errorf("error copying files: %w", err)
 	}
 	return nil
 }
 
 func (o *packetCaptureOptions) runPod() error {
 	log.Println("Confirming the interface for capturing")
-	err := setCaptureInterface(o)
-	if err != nil {
-		log.Fatalf("Error setting the interface for capture")
-		return err
+	if err := setCaptureInterface(o); err != nil {
+		return fmt.Errorf("error setting the interface for capture: %w", err)
 	}
 
-	log.Println("Ensuring Packet Capture Daemonset")
+	log.Println("Ensuring Packet Capture Pod")
 	capturePod, err := ensurePacketCapturePod(o)
 	if err != nil {
-		log.Fatalf("Error ensuring packet capture Pod %v", err)
-		return err
+		return fmt.Errorf("error ensuring packet capture pod: %w", err)
 	}
+	cleanup := deleteOnExit(o, capturePod)
+	defer cleanup()
+
 	log.Println("Waiting For Packet Capture Pod")
-	err = waitForPacketCapturePod(o, capturePod)
-	if err != nil {
-		log.Fatalf("Error Waiting for daemonset %v", err)
-		return err
+	if err := waitForPacketCapturePod(o, capturePod); err != nil {
+		return fmt.Errorf("error waiting for pod: %w", err)
 	}
 	log.Println("Copying Files From Packet Capture Pods")
-	err = copyFilesFromPacketCapturePods(o)
-	if err != nil {
-		log.Fatalf("Error copying files %v", err)
-		return err
-	}
-	log.Println("Deleting Packet Capture Pod")
-	err = deletePacketCapturePod(o, capturePod)
-	if err != nil {
-		log.Fatalf("Error deleting packet capture daemonset %v", err)
-		return err
+	if err := copyFilesFromPacketCapturePods(o); err != nil {
+		return fmt.Errorf("error copying files: %w", err)
 	}
 	return nil
 }
 
+// deleteOnExit deletes the capture resource when the returned function is called or when the
+// command is interrupted, whichever happens first
+func deleteOnExit(o *packetCaptureOptions, obj client.Object) func() {
+	var once sync.Once
+	deleteObj := func() {
+		once.Do(func() {
+			log.Printf("Deleting packet capture %T %s/%s\n", obj, obj.GetNamespace(), obj.GetName())
+			if err := o.kubeCli.Delete(context.Background(), obj); err != nil && !k8serr.IsNotFound(err) {
+				log.Printf("Failed to delete %s/%s, remove it with 'osdctl network packet-capture cleanup': %v\n", obj.GetNamespace(), obj.GetName(), err)
+			}
+		})
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %v, cleaning up\n", sig)
+			deleteObj()
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		deleteObj()
+	}
+}
+
+// captureLabels returns the labels identifying the capture resources, who created them, why and
+// until when they are needed
+func captureLabels(o *packetCaptureOptions) map[string]string {
+	labels := map[string]string{
+		"app":                    o.name,
+		packetCaptureLabel:       "true",
+		packetCaptureExpiryLabel: strconv.FormatInt(o.startTime.Add(time.Duration(o.duration)*time.Second+packetCaptureExpiryMargin).Unix(), 10),
+	}
+	if current, err := user.Current(); err == nil {
+		if owner := sanitizeLabelValue(current.Username); owner != "" {
+			labels[packetCaptureOwnerLabel] = owner
+		}
+	}
+	if reason := sanitizeLabelValue(o.reason); reason != "" {
+		labels[packetCaptureReasonLabel] = reason
+	}
+	return labels
+}
+
+// sanitizeLabelValue replaces the characters not allowed in a label value and truncates it
+func sanitizeLabelValue(value string) string {
+	sanitized := []rune{}
+	for _, r := range value {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			sanitized = append(sanitized, r)
+		} else {
+			sanitized = append(sanitized, '_')
+		}
+	}
+	if len(sanitized) > validation.LabelValueMaxLength {
+		sanitized = sanitized[:validation.LabelValueMaxLength]
+	}
+	return strings.Trim(string(sanitized), "-_.")
+}
+
 // ensurePacketCaptureDaemonSet ensures the daemonset exists
 func ensurePacketCaptureDaemonSet(o *packetCaptureOptions) (*appsv1.DaemonSet, error) {
 	key := types.NamespacedName{Name: o.name, Namespace: o.namespace}
 	desired := desiredPacketCaptureDaemonSet(o, key)
 	haveDs, err := hasPacketCaptureDaemonSet(o, key)
 	if err != nil {
-		log.Fatalf("Error getting current daemonset %v", err)
-		return nil, err
+		return nil, fmt.Errorf("error getting current daemonset: %w", err)
 	}
 
 	if haveDs {
@@ -190,7 +343,6 @@ func ensurePacketCaptureDaemonSet(o *packetCaptureOptions) (*appsv1.DaemonSet, e
 
 	err = createPacketCaptureDaemonSet(o, desired)
 	if err != nil {
-		log.Fatalf("Error creating packet capture daemonset %v", err)
 		return nil, err
 	}
 
@@ -238,12 +390,13 @@ func desiredPacketCaptureDaemonSet(o *packetCaptureOptions, key types.Namespaced
 	}
 	ds.Name = key.Name
 	ds.Namespace = key.Namespace
+	ds.Labels = captureLabels(o)
 
 	ds.Spec.Selector = ls
 	ds.Spec.Template.Spec.NodeSelector = map[string]string{
 		o.nodeLabelKey: o.nodeLabelValue,
 	}
-	ds.Spec.Template.Labels = ls.MatchLabels
+	ds.Spec.Template.Labels = captureLabels(o)
 	ds.Spec.Template.Spec.Tolerations = []corev1.Toleration{
 		{
 			Effect:   "NoSchedule",
@@ -265,7 +418,7 @@ func desiredPacketCaptureDaemonSet(o *packetCaptureOptions, key types.Namespaced
 			Name:            "init-capture",
 			Image:           packetCaptureImage,
 			ImagePullPolicy: corev1.PullIfNotPresent,
-			Command:         []string{"/bin/bash", "-c", "tcpdump -G " + strconv.Itoa(o.duration) + " -W 1 -w /tmp/capture-output/capture.pcap -i " + o.captureInterface + " -nn -s0; sync"},
+			Command:         []string{"/bin/bash", "-c", captureCommand(o)},
 			SecurityContext: &corev1.SecurityContext{Privileged: &t},
 			VolumeMounts: []corev1.VolumeMount{
 				{
@@ -296,13 +449,50 @@ func desiredPacketCaptureDaemonSet(o *packetCaptureOptions, key types.Namespaced
 	return ds
 }
 
+// captureCommand returns the shell command running tcpdump for the duration of the capture
+func captureCommand(o *packetCaptureOptions) string {
+	var tcpdump string
+	if o.fileSize > 0 {
+		// tcpdump can't both rotate by size and stop after a duration, the files are suffixed with their
+		// index in the ring buffer, eg. capture.pcap0
+		tcpdump = fmt.Sprintf("timeout -s INT %d tcpdump -C %d -W %d", o.duration, o.fileSize, o.fileCount)
+	} else {
+		tcpdump = fmt.Sprintf("tcpdump -G %d -W 1", o.duration)
+	}
+	tcpdump += " -w /tmp/capture-output/capture.pcap -i " + o.captureInterface + " -nn -s" + strconv.Itoa(o.snaplen)
+	if o.filter != "" {
+		tcpdump += " " + shellQuote(o.filter)
+	}
+
+	if o.targetPodIP == "" {
+		return tcpdump + "; sync"
+	}
+	// Run tcpdump in the network namespace holding the IP of the target pod
+	return fmt.Sprintf(`for ns in %[1]s/*; do
+  if nsenter --net="$ns" ip -o -4 addr show | grep -qF " %[2]s/"; then netns="$ns"; break; fi
+done
+if [ -z "$netns" ]; then echo "network namespace of pod IP %[2]s not found in %[1]s"; exit 1; fi
+nsenter --net="$netns" %[3]s; sync`, netnsDir, o.targetPodIP, tcpdump)
+}
+
+// shellQuote quotes s as a single word for bash
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 func copyFilesFromPod(o *packetCaptureOptions, pod *corev1.Pod) error {
 	err := os.MkdirAll(outputDir, 0750)
 	if err != nil {
 		return err
 	}
+	source := "/tmp/capture-output/capture.pcap"
 	fileName := fmt.Sprintf("%s-%s.pcap", pod.Spec.NodeName, o.startTime.UTC().Format("20060102T150405"))
-	cmd := exec.Command("oc", "cp", pod.Namespace+"/"+pod.Name+":/tmp/capture-output/capture.pcap", outputDir+"/"+fileName, "--as", "backplane-cluster-admin") //#nosec G204 -- Subprocess launched with a potential tainted input or cmd arguments
+	if o.fileSize > 0 {
+		// Copy the rotated files in a directory per node
+		source = "/tmp/capture-output"
+		fileName = fmt.Sprintf("%s-%s", pod.Spec.NodeName, o.startTime.UTC().Format("20060102T150405"))
+	}
+	cmd := exec.Command("oc", "cp", pod.Namespace+"/"+pod.Name+":"+source, outputDir+"/"+fileName, "--as", "backplane-cluster-admin") //#nosec G204 -- Subprocess launched with a potential tainted input or cmd arguments
 	var stdBuffer bytes.Buffer
 	mw := io.MultiWriter(os.Stdout, &stdBuffer)
 
@@ -318,8 +508,13 @@ func copyFilesFromPod(o *packetCaptureOptions, pod *corev1.Pod) error {
 	return err
 }
 
+// waitTimeout returns how long to wait for the capture pods, they only run once the capture is done
+func (o *packetCaptureOptions) waitTimeout() time.Duration {
+	return time.Duration(o.duration)*time.Second + packetCaptureWaitMargin
+}
+
 func waitForPacketCaptureDaemonset(o *packetCaptureOptions, ds *appsv1.DaemonSet) error {
-	pollErr := wait.PollImmediate(10*time.Second, time.Duration(600)*time.Second, func() (bool, error) {
+	pollErr := wait.PollImmediate(10*time.Second, o.waitTimeout(), func() (bool, error) {
 		var err error
 		tmp := &appsv1.DaemonSet{}
 		key := types.NamespacedName{Name: ds.Name, Namespace: ds.Namespace}
@@ -335,7 +530,7 @@ func waitForPacketCaptureDaemonset(o *packetCaptureOptions, ds *appsv1.DaemonSet
 }
 
 func waitForPacketCaptureContainerRunning(o *packetCaptureOptions, pod *corev1.Pod) error {
-	pollErr := wait.PollImmediate(10*time.Second, time.Duration(600)*time.Second, func() (bool, error) {
+	pollErr := wait.PollImmediate(10*time.Second, o.waitTimeout(), func() (bool, error) {
 		var err error
 		tmp := &corev1.Pod{}
 		key := types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}
@@ -367,14 +562,12 @@ func copyFilesFromPacketCapturePods(o *packetCaptureOptions) error {
 		}
 		err := waitForPacketCaptureContainerRunning(o, &pods.Items[i])
 		if err != nil {
-			log.Fatalf("Error waiting for pods %v", err)
-			return err
+			return fmt.Errorf("error waiting for pod %s: %w", pod.Name, err)
 		}
 		log.Printf("Copying files from %s\n", pod.Name)
 		err = copyFilesFromPod(o, &pods.Items[i])
 		if err != nil {
-			log.Fatalf("error copying files %v", err)
-			return err
+			return fmt.Errorf("error copying files from pod %s: %w", pod.Name, err)
 		}
 	}
 
@@ -385,14 +578,9 @@ func copyFilesFromPacketCapturePods(o *packetCaptureOptions) error {
 func desiredPacketCapturePod(o *packetCaptureOptions, key types.NamespacedName) *corev1.Pod {
 	capturePod := &corev1.Pod{}
 	t := true
-	ls := &metav1.LabelSelector{
-		MatchLabels: map[string]string{
-			"app": key.Name,
-		},
-	}
 	capturePod.Name = key.Name
 	capturePod.Namespace = key.Namespace
-	capturePod.Labels = ls.MatchLabels
+	capturePod.Labels = captureLabels(o)
 	capturePod.Spec.NodeSelector = map[string]string{
 		o.nodeLabelKey: o.nodeLabelValue,
 	}
@@ -412,12 +600,23 @@ func desiredPacketCapturePod(o *packetCaptureOptions, key types.NamespacedName)
 		},
 	}
 	capturePod.Spec.HostNetwork = true
+	if o.targetNode != "" {
+		// Bypass the scheduler to run next to the target pod, whatever the node labels and taints
+		capturePod.Spec.NodeSelector = nil
+		capturePod.Spec.NodeName = o.targetNode
+		capturePod.Spec.Volumes = append(capturePod.Spec.Volumes, corev1.Volume{
+			Name: "netns",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: netnsDir},
+			},
+		})
+	}
 	capturePod.Spec.InitContainers = []corev1.Container{
 		{
 			Name:            "init-capture",
 			Image:           packetCaptureImage,
 			ImagePullPolicy: corev1.PullIfNotPresent,
-			Command:         []string{"/bin/bash", "-c", "tcpdump -G " + strconv.Itoa(o.duration) + " -W 1 -w /tmp/capture-output/capture.pcap -i " + o.captureInterface + " -nn -s0; sync"},
+			Command:         []string{"/bin/bash", "-c", captureCommand(o)},
 			SecurityContext: &corev1.SecurityContext{Privileged: &t},
 			VolumeMounts: []corev1.VolumeMount{
 				{
@@ -444,6 +643,15 @@ func desiredPacketCapturePod(o *packetCaptureOptions, key types.NamespacedName)
 			},
 		},
 	}
+	if o.targetNode != "" {
+		propagation := corev1.MountPropagationHostToContainer
+		capturePod.Spec.InitContainers[0].VolumeMounts = append(capturePod.Spec.InitContainers[0].VolumeMounts, corev1.VolumeMount{
+			Name:             "netns",
+			MountPath:        netnsDir,
+			ReadOnly:         true,
+			MountPropagation: &propagation,
+		})
+	}
 	return capturePod
 }
 
@@ -474,8 +682,7 @@ func ensurePacketCapturePod(o *packetCaptureOptions) (*corev1.Pod, error) {
 	desired := desiredPacketCapturePod(o, key)
 	havePod, err := hasPacketCapturePod(o, key)
 	if err != nil {
-		log.Fatalf("Error getting current Pod %v", err)
-		return nil, err
+		return nil, fmt.Errorf("error getting current Pod: %w", err)
 	}
 
 	if havePod {
@@ -485,7 +692,6 @@ func ensurePacketCapturePod(o *packetCaptureOptions) (*corev1.Pod, error) {
 
 	err = createPacketCapturePod(o, desired)
 	if err != nil {
-		log.Fatalf("Error creating packet capture Pod %v", err)
 		return nil, err
 	}
 
@@ -503,7 +709,7 @@ func createPacketCapturePod(o *packetCaptureOptions, capturePod *corev1.Pod) err
 
 // waitForPacketCapturePod creates the given Pod resource
 func waitForPacketCapturePod(o *packetCaptureOptions, capturePod *corev1.Pod) error {
-	pollErr := wait.PollImmediate(10*time.Second, time.Duration(600)*time.Second, func() (bool, error) {
+	pollErr := wait.PollImmediate(10*time.Second, o.waitTimeout(), func() (bool, error) {
 		var err error
 		tmp := &corev1.Pod{}
 		key := types.NamespacedName{Name: capturePod.Name, Namespace: capturePod.Namespace}
@@ -516,7 +722,44 @@ func waitForPacketCapturePod(o *packetCaptureOptions, capturePod *corev1.Pod) er
 	return pollErr
 }
 
+// parsePodName splits a pod given as <namespace>/<name>
+func parsePodName(pod string) (string, string, error) {
+	namespace, name, ok := strings.Cut(pod, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid pod %q, expected <namespace>/<name>", pod)
+	}
+	return namespace, name, nil
+}
+
+// setTargetPod resolves the node and IP of the pod given with --pod
+func setTargetPod(o *packetCaptureOptions) error {
+	namespace, name, err := parsePodName(o.pod)
+	if err != nil {
+		return err
+	}
+	pod := &corev1.Pod{}
+	if err := o.kubeCli.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, pod); err != nil {
+		return fmt.Errorf("failed to get pod %s: %v", o.pod, err)
+	}
+	if pod.Spec.HostNetwork {
+		return fmt.Errorf("pod %s uses the host network, capture its node with --filter instead", o.pod)
+	}
+	if pod.Spec.NodeName == "" || pod.Status.PodIP == "" {
+		return fmt.Errorf("pod %s isn't running on a node yet", o.pod)
+	}
+	o.targetNode = pod.Spec.NodeName
+	o.targetPodIP = pod.Status.PodIP
+	o.singlePod = true
+	return nil
+}
+
 func setCaptureInterface(o *packetCaptureOptions) error {
+	if o.targetPodIP != "" {
+		// The interface of the target pod in its own network namespace
+		o.captureInterface = "eth0"
+		return nil
+	}
+
 	networkConfig := &configv1.Network{}
 	if err := o.kubeCli.Get(context.Background(), client.ObjectKey{Name: "cluster"}, networkConfig); err != nil {
 		return fmt.Errorf("failed to determine the network type: %s", err)
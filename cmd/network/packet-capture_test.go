@@ -127,11 +127,136 @@ func TestDesiredPacketCapturePod(t *testing.T) {
 
 	assert.Equal(t, ops.name, pod.Name)
 	assert.Equal(t, ops.namespace, pod.Namespace)
-	assert.Equal(t, map[string]string{"app": ops.name}, pod.Labels)
+	assert.Equal(t, ops.name, pod.Labels["app"])
+	assert.Equal(t, "true", pod.Labels[packetCaptureLabel])
 	assert.Equal(t, map[string]string{ops.nodeLabelKey: ops.nodeLabelValue}, pod.Spec.NodeSelector)
 	assert.True(t, pod.Spec.HostNetwork)
 }
 
+func TestDesiredPacketCapturePodForTargetPod(t *testing.T) {
+	ops := &packetCaptureOptions{
+		name:             "test-capture",
+		namespace:        "test-ns",
+		nodeLabelKey:     "test-key",
+		nodeLabelValue:   "test-value",
+		duration:         60,
+		captureInterface: "eth0",
+		targetNode:       "worker-1",
+		targetPodIP:      "10.128.2.15",
+	}
+
+	pod := desiredPacketCapturePod(ops, types.NamespacedName{Name: ops.name, Namespace: ops.namespace})
+
+	assert.Equal(t, "worker-1", pod.Spec.NodeName)
+	assert.Nil(t, pod.Spec.NodeSelector)
+	assert.Equal(t, netnsDir, pod.Spec.Volumes[1].HostPath.Path)
+	assert.Equal(t, netnsDir, pod.Spec.InitContainers[0].VolumeMounts[1].MountPath)
+	assert.Contains(t, pod.Spec.InitContainers[0].Command[2], `grep -qF " 10.128.2.15/"`)
+}
+
+func TestCaptureCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *packetCaptureOptions
+		want string
+	}{
+		{
+			name: "default",
+			opts: &packetCaptureOptions{duration: 60, captureInterface: "genev_sys_6081"},
+			want: "tcpdump -G 60 -W 1 -w /tmp/capture-output/capture.pcap -i genev_sys_6081 -nn -s0; sync",
+		},
+		{
+			name: "filter_and_snaplen",
+			opts: &packetCaptureOptions{duration: 60, captureInterface: "genev_sys_6081", snaplen: 96, filter: "host 10.0.0.1 and port 53"},
+			want: "tcpdump -G 60 -W 1 -w /tmp/capture-output/capture.pcap -i genev_sys_6081 -nn -s96 'host 10.0.0.1 and port 53'; sync",
+		},
+		{
+			name: "ring_buffer",
+			opts: &packetCaptureOptions{duration: 3600, captureInterface: "genev_sys_6081", fileSize: 100, fileCount: 5},
+			want: "timeout -s INT 3600 tcpdump -C 100 -W 5 -w /tmp/capture-output/capture.pcap -i genev_sys_6081 -nn -s0; sync",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, captureCommand(tt.opts))
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'port 53'`, shellQuote("port 53"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
+
+func TestPacketCaptureWaitTimeout(t *testing.T) {
+	assert.Equal(t, 10*time.Minute+time.Minute, (&packetCaptureOptions{duration: 60}).waitTimeout())
+	assert.Equal(t, time.Hour+10*time.Minute, (&packetCaptureOptions{duration: 3600}).waitTimeout())
+}
+
+func TestPacketCaptureValidate(t *testing.T) {
+	assert.NoError(t, (&packetCaptureOptions{duration: 60}).validate())
+	assert.NoError(t, (&packetCaptureOptions{duration: 60, pod: "ns/name", fileSize: 10, fileCount: 1}).validate())
+	assert.Error(t, (&packetCaptureOptions{duration: 0}).validate())
+	assert.Error(t, (&packetCaptureOptions{duration: 60, snaplen: -1}).validate())
+	assert.Error(t, (&packetCaptureOptions{duration: 60, fileSize: 10}).validate())
+	assert.Error(t, (&packetCaptureOptions{duration: 60, pod: "name"}).validate())
+	assert.Error(t, (&packetCaptureOptions{duration: 60, pod: "ns/name/extra"}).validate())
+}
+
+func TestSetTargetPod(t *testing.T) {
+	tests := []struct {
+		name    string
+		pod     *corev1.Pod
+		wantErr bool
+	}{
+		{
+			name: "running_pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test-ns"},
+				Spec:       corev1.PodSpec{NodeName: "worker-1"},
+				Status:     corev1.PodStatus{PodIP: "10.128.2.15"},
+			},
+		},
+		{
+			name: "host_network_pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test-ns"},
+				Spec:       corev1.PodSpec{NodeName: "worker-1", HostNetwork: true},
+				Status:     corev1.PodStatus{PodIP: "10.0.0.5"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pending_pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test-ns"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().WithRuntimeObjects(tt.pod).Build()
+			ops := &packetCaptureOptions{pod: "test-ns/app", kubeCli: k8s.LazyClientInit(fakeClient)}
+
+			err := setTargetPod(ops)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "worker-1", ops.targetNode)
+			assert.Equal(t, "10.128.2.15", ops.targetPodIP)
+			assert.True(t, ops.singlePod)
+
+			assert.NoError(t, setCaptureInterface(ops))
+			assert.Equal(t, "eth0", ops.captureInterface)
+		})
+	}
+}
+
 func TestDeletePacketCapturePod(t *testing.T) {
 	testPod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -842,3 +967,39 @@ type LazyClient struct {
 func (m *MockKubeClient) ToLazyClient() *k8s.LazyClient {
 	return k8s.LazyClientMock(m)
 }
+
+func TestSanitizeLabelValue(t *testing.T) {
+	assert.Equal(t, "OHSS-1234", sanitizeLabelValue("OHSS-1234"))
+	assert.Equal(t, "jdoe_redhat.com", sanitizeLabelValue("jdoe@redhat.com"))
+	assert.Equal(t, "investigate_dns", sanitizeLabelValue("-investigate dns!"))
+	assert.Len(t, sanitizeLabelValue(strings.Repeat("a", 100)), 63)
+}
+
+func TestDeleteOnExit(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "capture", Namespace: "default"}}
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(pod).Build()
+	ops := &packetCaptureOptions{kubeCli: k8s.LazyClientInit(fakeClient)}
+
+	cleanup := deleteOnExit(ops, pod)
+	cleanup()
+
+	err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: "capture", Namespace: "default"}, &corev1.Pod{})
+	assert.Error(t, err)
+}
+
+func TestCaptureLabels(t *testing.T) {
+	ops := &packetCaptureOptions{
+		name:      "test-capture",
+		duration:  60,
+		reason:    "OHSS-1234",
+		startTime: time.Unix(1700000000, 0),
+	}
+
+	labels := captureLabels(ops)
+	assert.Equal(t, "test-capture", labels["app"])
+	assert.Equal(t, "true", labels[packetCaptureLabel])
+	assert.Equal(t, "OHSS-1234", labels[packetCaptureReasonLabel])
+	expiry, ok := captureExpiry(labels)
+	assert.True(t, ok)
+	assert.Equal(t, ops.startTime.Add(time.Minute+packetCaptureExpiryMargin), expiry)
+}
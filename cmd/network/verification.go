@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"strings"
 	"time"
@@ -92,6 +93,19 @@ type EgressVerification struct {
 	GcpProjectID string
 	// VpcName is the VPC where the verifier will run
 	VpcName string
+	// Output is the format of the results, text prints the verifier summary while json, yaml and junit
+	// print per-subnet, per-endpoint results
+	Output string
+	// Queries are OCM search queries selecting the clusters to verify, instead of ClusterId
+	Queries []string
+	// ClustersFile is a file listing the clusters to verify, instead of ClusterId
+	ClustersFile string
+	// Concurrency is the number of clusters verified at the same time with Queries or ClustersFile
+	Concurrency int
+	// SendServiceLogs sends the drafted service logs of the clusters with blocked egresses, after confirmation
+	SendServiceLogs bool
+	// Save is an optional file the structured results are saved to, to be compared later with egress-diff
+	Save string
 }
 
 func NewCmdValidateEgress() *cobra.Command {
@@ -128,6 +142,20 @@ func NewCmdValidateEgress() *cobra.Command {
   # Override automatic selection of the list of endpoints to check
   osdctl network verify-egress --cluster-id my-rosa-cluster --platform hostedcluster
 
+  # Archive per-subnet, per-endpoint results on a ticket
+  osdctl network verify-egress --cluster-id my-rosa-cluster -o json > egress.json
+
+  # Save the results, then compare them with the results after a customer firewall change
+  osdctl network verify-egress --cluster-id my-rosa-cluster --save before.json
+  osdctl network verify-egress --cluster-id my-rosa-cluster --save after.json
+  osdctl network egress-diff before.json after.json
+
+  # Verify all the ready AWS clusters behind a proxy, 10 at a time, and report which endpoints fail on which clusters
+  osdctl network verify-egress -q "cloud_provider.id = 'aws' and state = 'ready' and proxy.http_proxy != ''" --concurrency 10
+
+  # Verify the clusters of a file, then send the drafted service logs after confirming each of them
+  osdctl network verify-egress --clusters-file clusters.json --send-service-logs
+
   # (Not recommended) Run against a specific VPC, without specifying cluster-id
   <export environment variables like AWS_ACCESS_KEY_ID or use aws configure>
   osdctl network verify-egress --subnet-id subnet-abcdefg123 --security-group sg-abcdefgh123 --region us-east-1`,
@@ -135,6 +163,10 @@ func NewCmdValidateEgress() *cobra.Command {
 			if e.Version {
 				printVersion()
 			}
+			if e.isFleet() {
+				e.RunFleet(context.Background())
+				return
+			}
 			e.Run(context.Background())
 		},
 	}
@@ -154,9 +186,18 @@ func NewCmdValidateEgress() *cobra.Command {
 	validateEgressCmd.Flags().StringVar(&e.CpuArchName, "cpu-arch", "x86", "(optional) compute instance CPU architecture. E.g., 'x86' or 'arm'")
 	validateEgressCmd.Flags().StringVar(&e.GcpProjectID, "gcp-project-id", "", "(optional) the GCP project ID to run verification for")
 	validateEgressCmd.Flags().StringVar(&e.VpcName, "vpc", "", "(optional) VPC name for cases where it can't be fetched from OCM")
+	validateEgressCmd.Flags().StringVarP(&e.Output, "output", "o", outputText, fmt.Sprintf("(optional) output format, one of %s. Structured formats report every endpoint of every subnet and don't send service logs", strings.Join(verificationOutputFormats, ", ")))
+
+	validateEgressCmd.Flags().StringArrayVarP(&e.Queries, "query", "q", []string{}, "(optional) OCM search query selecting the clusters to verify, eg. \"cloud_provider.id = 'aws' and state = 'ready'\". Can be repeated, queries are combined with a logical AND")
+	validateEgressCmd.Flags().StringVar(&e.ClustersFile, "clusters-file", "", `(optional) file listing the clusters to verify, the format of the file is: {"clusters":["$CLUSTERID"]}`)
+	validateEgressCmd.Flags().IntVar(&e.Concurrency, "concurrency", 5, "(optional) number of clusters verified at the same time with --query or --clusters-file")
+	validateEgressCmd.Flags().BoolVar(&e.SendServiceLogs, "send-service-logs", false, "(optional) with --query or --clusters-file, send the drafted service logs of the clusters with blocked egresses, each one after confirmation")
+
+	validateEgressCmd.Flags().StringVar(&e.Save, "save", "", "(optional) save the per-subnet, per-endpoint results as JSON to the given file, to be compared later with 'osdctl network egress-diff'")
 
 	// If a cluster-id is specified, don't allow the foot-gun of overriding region
 	validateEgressCmd.MarkFlagsMutuallyExclusive("cluster-id", "region")
+	validateEgressCmd.MarkFlagsMutuallyExclusive("cluster-id", "query", "clusters-file")
 
 	return validateEgressCmd
 }
@@ -182,87 +223,209 @@ func (e *EgressVerification) Run(ctx context.Context) {
 		log.Fatalf("network verification failed to validate input: %s", err)
 	}
 
+	target, err := e.prepare(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Structured results list the passing endpoints as well, which the verifier output doesn't
+	if e.Output != outputText || e.Save != "" {
+		result, err := e.collectResult(ctx, target)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if e.Save != "" {
+			if err := saveResults(e.Save, result); err != nil {
+				log.Fatalf("failed to save the results: %s", err)
+			}
+		}
+
+		if e.Output == outputText {
+			// Like without --save, only the first subnet with blocked egresses is handled
+			handled := false
+			for _, subnet := range result.Subnets {
+				subnet.out.Summary(e.Debug)
+				if !handled {
+					handled = e.handleBlockedEgresses(subnet.out)
+				}
+			}
+		} else {
+			for _, subnet := range result.Subnets {
+				if len(subnet.out.GetEgressURLFailures()) > 0 && e.ClusterId != "" {
+					postCmd := generateServiceLog(subnet.out, e.ClusterId)
+					fmt.Fprintf(os.Stderr, "Blocked egresses found for subnet %s, no service log was sent. Send one with:\nosdctl servicelog post %v -t %v -p %v\n",
+						subnet.SubnetID, e.ClusterId, blockedEgressTemplateUrl, strings.Join(postCmd.TemplateParams, " -p "))
+				}
+			}
+			if err := printVerificationResult(os.Stdout, result, e.Output); err != nil {
+				log.Fatalf("failed to print the results: %s", err)
+			}
+		}
+		if !result.Passed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	inputs := target.inputs
+	e.log.Info(ctx, "Preparing to check %+v subnet(s) with network verifier.", len(inputs))
+	for i := range inputs {
+		e.log.Info(ctx, "running network verifier for subnet  %+v, security group %+v", inputs[i].SubnetID, inputs[i].AWS.SecurityGroupIDs)
+		out := onv.ValidateEgress(target.verifier, *inputs[i])
+		out.Summary(e.Debug)
+		if e.handleBlockedEgresses(out) {
+			os.Exit(1)
+		}
+	}
+}
+
+// handleBlockedEgresses prompts putting the cluster into LS if egresses crucial for monitoring (PagerDuty/DMS)
+// are blocked, and prompts sending a service log instead for other blocked egresses.
+// It returns true if egresses are blocked.
+func (e *EgressVerification) handleBlockedEgresses(out *output.Output) bool {
+	if out.IsSuccessful() || len(out.GetEgressURLFailures()) == 0 {
+		return false
+	}
+	postCmd := generateServiceLog(out, e.ClusterId)
+	blockedUrl := strings.Join(postCmd.TemplateParams, ",")
+	if (strings.Contains(blockedUrl, "deadmanssnitch") || strings.Contains(blockedUrl, "pagerduty")) && e.cluster.State() == "ready" {
+		fmt.Println("PagerDuty and/or DMS outgoing traffic is blocked, resulting in a loss of observability. As a result, Red Hat can no longer guarantee SLAs and the cluster should be put in limited support")
+		pCmd := lsupport.Post{Template: LimitedSupportTemplate}
+		if err := pCmd.Run(e.ClusterId); err != nil {
+			fmt.Printf("failed to post limited support reason: %v", err)
+		}
+	} else if err := postCmd.Run(); err != nil {
+		fmt.Println("Failed to generate service log. Please manually send a service log to the customer for the blocked egresses with:")
+		fmt.Printf("osdctl servicelog post %v -t %v -p %v\n", e.ClusterId, blockedEgressTemplateUrl, strings.Join(postCmd.TemplateParams, " -p "))
+	}
+	return true
+}
+
+// RunFleet verifies the egress of the clusters selected by the OCM search queries or the clusters file
+// in parallel and reports which endpoints fail on which clusters
+func (e *EgressVerification) RunFleet(ctx context.Context) {
+	builder := logging.NewGoLoggerBuilder().Debug(e.Debug)
+	logger, err := builder.Build()
+	if err != nil {
+		log.Fatalf("network verification failed to build logger: %s", err)
+	}
+	e.log = logger
+
+	if err := e.validateInput(); err != nil {
+		log.Fatalf("network verification failed to validate input: %s", err)
+	}
+	if err := e.validateFleetInput(); err != nil {
+		log.Fatalf("network verification failed to validate input: %s", err)
+	}
+	if err := e.runFleet(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// verificationTarget is what the verifier needs to run against a cluster or a set of subnets
+type verificationTarget struct {
+	platform cloud.Platform
+	verifier networkVerifier
+	inputs   []*onv.ValidateEgressInput
+	// variables are the ones the verifier expands in the egress list
+	variables map[string]string
+}
+
+// prepare fetches the cluster, if any, and assembles the verifier and its inputs for the platform
+func (e *EgressVerification) prepare(ctx context.Context) (*verificationTarget, error) {
 	e.cpuArch = cpu.ArchitectureByName(e.CpuArchName)
 	if e.CpuArchName != "" && !e.cpuArch.IsValid() {
-		log.Fatalf("%s is not a valid CPU architecture", e.CpuArchName)
+		return nil, fmt.Errorf("%s is not a valid CPU architecture", e.CpuArchName)
 	}
 
 	// If no ClusterId is provided, fetch from OCM
-	err = e.fetchCluster(ctx)
-	if err != nil {
-		log.Fatal(err)
+	if err := e.fetchCluster(ctx); err != nil {
+		return nil, err
 	}
 
 	platform, err := e.getPlatform()
 	if err != nil {
-		log.Fatalf("error getting platform: %s", err)
+		return nil, fmt.Errorf("error getting platform: %s", err)
 	}
 
-	var inputs []*onv.ValidateEgressInput
-	var verifier networkVerifier
-
+	target := &verificationTarget{platform: platform, variables: map[string]string{}}
 	switch platform {
 	case cloud.AWSHCP, cloud.AWSHCPZeroEgress, cloud.AWSClassic:
 		cfg, err := e.setupForAws(ctx)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
-		verifier, err = onvAwsClient.NewAwsVerifierFromConfig(*cfg, e.log)
+		target.variables["AWS_REGION"] = cfg.Region
+		target.verifier, err = onvAwsClient.NewAwsVerifierFromConfig(*cfg, e.log)
 		if err != nil {
-			log.Fatalf("failed to assemble osd-network-verifier client: %s", err)
+			return nil, fmt.Errorf("failed to assemble osd-network-verifier client: %s", err)
 		}
 
-		inputs, err = e.generateAWSValidateEgressInput(ctx, platform)
+		target.inputs, err = e.generateAWSValidateEgressInput(ctx, platform)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	case cloud.GCPClassic:
 		credentials, err := e.setupForGcp(ctx)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
-		verifier, err = onvGcpClient.NewGcpVerifier(credentials, e.Debug)
+		target.verifier, err = onvGcpClient.NewGcpVerifier(credentials, e.Debug)
 		if err != nil {
-			log.Fatalf("failed to assemble osd-network-verifier client: %s", err)
+			return nil, fmt.Errorf("failed to assemble osd-network-verifier client: %s", err)
 		}
 
-		inputs, err = e.generateGcpValidateEgressInput(ctx, platform)
+		target.inputs, err = e.generateGcpValidateEgressInput(ctx, platform)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	default:
-		log.Fatalf("unsupported platform: %s", platform)
+		return nil, fmt.Errorf("unsupported platform: %s", platform)
 	}
+	return target, nil
+}
 
-	e.log.Info(ctx, "Preparing to check %+v subnet(s) with network verifier.", len(inputs))
-	var failures int
-	for i := range inputs {
-		e.log.Info(ctx, "running network verifier for subnet  %+v, security group %+v", inputs[i].SubnetID, inputs[i].AWS.SecurityGroupIDs)
-		out := onv.ValidateEgress(verifier, *inputs[i])
-		out.Summary(e.Debug)
-		// Prompt putting the cluster into LS if egresses crucial for monitoring (PagerDuty/DMS) are blocked.
-		// Prompt sending a service log instead for other blocked egresses.
-		if !out.IsSuccessful() && len(out.GetEgressURLFailures()) > 0 {
-			failures++
-			postCmd := generateServiceLog(out, e.ClusterId)
-			blockedUrl := strings.Join(postCmd.TemplateParams, ",")
-			if (strings.Contains(blockedUrl, "deadmanssnitch") || strings.Contains(blockedUrl, "pagerduty")) && e.cluster.State() == "ready" {
-				fmt.Println("PagerDuty and/or DMS outgoing traffic is blocked, resulting in a loss of observability. As a result, Red Hat can no longer guarantee SLAs and the cluster should be put in limited support")
-				pCmd := lsupport.Post{Template: LimitedSupportTemplate}
-				if err := pCmd.Run(e.ClusterId); err != nil {
-					fmt.Printf("failed to post limited support reason: %v", err)
-				}
-			} else if err := postCmd.Run(); err != nil {
-				fmt.Println("Failed to generate service log. Please manually send a service log to the customer for the blocked egresses with:")
-				fmt.Printf("osdctl servicelog post %v -t %v -p %v\n", e.ClusterId, blockedEgressTemplateUrl, strings.Join(postCmd.TemplateParams, " -p "))
-			}
+// collectResult runs the verifier against every subnet of the target and returns per-subnet,
+// per-endpoint results. The egress list is fetched upfront and passed to the verifier so the
+// passing endpoints can be reported.
+func (e *EgressVerification) collectResult(ctx context.Context, target *verificationTarget) (*VerificationResult, error) {
+	var endpoints []string
+	if strings.ToLower(e.Probe) != "legacy" {
+		egressListYaml, urls, err := getEgressList(ctx, target.platform, target.variables, e.log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the egress list: %s", err)
 		}
-		if failures > 0 {
-			os.Exit(1)
+		endpoints = urls
+		for i := range target.inputs {
+			target.inputs[i].EgressListYaml = egressListYaml
 		}
 	}
+
+	// Save the internal ID like the fleet results, the cluster ID given by the user can be its name or external ID
+	clusterID := e.ClusterId
+	if e.cluster != nil {
+		clusterID = e.cluster.ID()
+	}
+	result := &VerificationResult{
+		ClusterID: clusterID,
+		Platform:  target.platform.String(),
+		Probe:     strings.ToLower(e.Probe),
+		StartTime: time.Now().UTC(),
+		Passed:    true,
+	}
+	e.log.Info(ctx, "Preparing to check %+v subnet(s) with network verifier.", len(target.inputs))
+	for _, input := range target.inputs {
+		e.log.Info(ctx, "running network verifier for subnet  %+v, security group %+v", input.SubnetID, input.AWS.SecurityGroupIDs)
+		start := time.Now()
+		out := onv.ValidateEgress(target.verifier, *input)
+		subnet := newSubnetResult(input.SubnetID, input.AWS.SecurityGroupIDs, endpoints, out, time.Since(start))
+		result.Subnets = append(result.Subnets, subnet)
+		result.Passed = result.Passed && subnet.Passed
+	}
+	result.Duration = time.Since(result.StartTime).Seconds()
+	return result, nil
 }
 
 func generateServiceLog(out *output.Output, clusterId string) servicelog.PostCmdOptions {
@@ -461,7 +624,8 @@ func (e *EgressVerification) defaultValidateEgressInput(ctx context.Context, pla
 			NoTls: e.NoTls,
 		},
 		Timeout: e.EgressTimeout,
-		Tags:    networkVerifierDefaultTags,
+		// Cluster tags are added to the input, don't share the defaults between inputs
+		Tags: maps.Clone(networkVerifierDefaultTags),
 	}
 
 	switch strings.ToLower(e.Probe) {
@@ -508,7 +672,7 @@ func (e *EgressVerification) fetchCluster(ctx context.Context) error {
 	if e.ClusterId != "" {
 		ocmClient, err := utils.CreateConnection()
 		if err != nil {
-			log.Fatalf("error creating OCM connection: %s", err)
+			return fmt.Errorf("error creating OCM connection: %s", err)
 		}
 		defer ocmClient.Close()
 
@@ -523,7 +687,7 @@ func (e *EgressVerification) fetchCluster(ctx context.Context) error {
 		case "rosa", "osd", "osdtrial":
 			break
 		default:
-			log.Fatalf("only supports rosa, osd, and osdtrial, got %s", e.cluster.Product().ID())
+			return fmt.Errorf("only supports rosa, osd, and osdtrial, got %s", e.cluster.Product().ID())
 		}
 	}
 
@@ -531,6 +695,13 @@ func (e *EgressVerification) fetchCluster(ctx context.Context) error {
 }
 
 func (e *EgressVerification) validateInput() error {
+	if e.Output == "" {
+		e.Output = outputText
+	}
+	if err := validateOutputFormat(e.Output); err != nil {
+		return err
+	}
+
 	// Validate proper usage of --subnet-id flag
 	if len(e.SubnetIds) == 1 && len(strings.Split(e.SubnetIds[0], ",")) > 1 {
 		return fmt.Errorf("multiple subnets passed to a single --subnet-id flag, you must pass the flag per subnet, eg " +
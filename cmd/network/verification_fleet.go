@@ -0,0 +1,339 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/osdctl/cmd/servicelog"
+	internalservicelog "github.com/openshift/osdctl/internal/servicelog"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/openshift/osdctl/pkg/utils"
+)
+
+// FleetResult is the result of an egress verification of several clusters
+type FleetResult struct {
+	StartTime time.Time            `json:"startTime"`
+	Duration  float64              `json:"durationSeconds"`
+	Clusters  []FleetClusterResult `json:"clusters"`
+	// FailingEndpoints are the endpoints failing on at least one cluster, failing on the most clusters first
+	FailingEndpoints []FailingEndpoint `json:"failingEndpoints"`
+}
+
+// FleetClusterResult is the verification result of a cluster of the fleet, or the error which prevented it
+type FleetClusterResult struct {
+	ClusterID string              `json:"clusterId"`
+	Name      string              `json:"name,omitempty"`
+	Result    *VerificationResult `json:"result,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	// ServiceLog is the drafted command sending the blocked egresses service log, it isn't sent
+	// unless --send-service-logs is passed
+	ServiceLog string `json:"serviceLog,omitempty"`
+
+	postCmd *servicelog.PostCmdOptions
+}
+
+// FailingEndpoint is an endpoint failing on some clusters of the fleet
+type FailingEndpoint struct {
+	URL        string   `json:"url"`
+	Categories []string `json:"categories"`
+	Clusters   []string `json:"clusters"`
+}
+
+// isFleet returns true if several clusters are verified, selected by an OCM search query or a clusters file
+func (e *EgressVerification) isFleet() bool {
+	return len(e.Queries) > 0 || e.ClustersFile != ""
+}
+
+func (e *EgressVerification) validateFleetInput() error {
+	if e.ClusterId != "" {
+		return fmt.Errorf("--cluster-id can't be used with --query or --clusters-file")
+	}
+	if len(e.SubnetIds) > 0 || e.SecurityGroupId != "" || e.Region != "" || e.CaCert != "" {
+		return fmt.Errorf("--subnet-id, --security-group, --region and --cacert are per cluster and can't be used with --query or --clusters-file")
+	}
+	if e.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	return nil
+}
+
+// getFleetClusterIDs returns the IDs of the clusters matching the search queries or listed in the clusters file
+func (e *EgressVerification) getFleetClusterIDs() ([]string, error) {
+	if e.ClustersFile != "" {
+		content, err := os.ReadFile(e.ClustersFile)
+		if err != nil {
+			return nil, err
+		}
+		clustersFile := internalservicelog.ClustersFile{}
+		if err := json.Unmarshal(content, &clustersFile); err != nil {
+			return nil, fmt.Errorf("failed to parse %s, the expected format is {\"clusters\":[\"$CLUSTERID\"]}: %w", e.ClustersFile, err)
+		}
+		return clustersFile.Clusters, nil
+	}
+
+	ocmClient, err := utils.CreateConnection()
+	if err != nil {
+		return nil, fmt.Errorf("error creating OCM connection: %s", err)
+	}
+	defer ocmClient.Close()
+
+	clusters, err := utils.ApplyFilters(ocmClient, append([]string{}, e.Queries...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for clusters with provided filters (%v): %v", e.Queries, err)
+	}
+	ids := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		ids = append(ids, cluster.ID())
+	}
+	return ids, nil
+}
+
+// forCluster returns the verification of a cluster of the fleet with the same settings
+func (e *EgressVerification) forCluster(clusterID string) *EgressVerification {
+	return &EgressVerification{
+		log:           e.log,
+		ClusterId:     clusterID,
+		platformName:  e.platformName,
+		Debug:         e.Debug,
+		NoTls:         e.NoTls,
+		AllSubnets:    e.AllSubnets,
+		EgressTimeout: e.EgressTimeout,
+		Probe:         e.Probe,
+		CpuArchName:   e.CpuArchName,
+		Output:        e.Output,
+	}
+}
+
+// runFleet verifies the clusters in parallel, prints the aggregated results and drafts
+// the service logs of the clusters with blocked egresses
+func (e *EgressVerification) runFleet(ctx context.Context) error {
+	clusterIDs, err := e.getFleetClusterIDs()
+	if err != nil {
+		return err
+	}
+	if len(clusterIDs) == 0 {
+		return fmt.Errorf("no clusters match the given filters")
+	}
+	e.log.Info(ctx, "verifying egress of %d clusters, %d at a time", len(clusterIDs), e.Concurrency)
+
+	fleet := &FleetResult{StartTime: time.Now().UTC(), Clusters: make([]FleetClusterResult, len(clusterIDs))}
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, e.Concurrency)
+	for i, clusterID := range clusterIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			fleet.Clusters[i] = e.verifyFleetCluster(ctx, clusterID)
+		}()
+	}
+	wg.Wait()
+	fleet.Duration = time.Since(fleet.StartTime).Seconds()
+	fleet.FailingEndpoints = aggregateFailingEndpoints(fleet.Clusters)
+
+	if e.Save != "" {
+		if err := saveResults(e.Save, fleet); err != nil {
+			return fmt.Errorf("failed to save the results: %w", err)
+		}
+	}
+	if err := printFleetResult(os.Stdout, fleet, e.Output); err != nil {
+		return err
+	}
+
+	if e.SendServiceLogs {
+		for _, cluster := range fleet.Clusters {
+			if cluster.postCmd == nil {
+				continue
+			}
+			// The post command prints the service log and asks for confirmation before sending it
+			fmt.Fprintf(os.Stderr, "Service log for the blocked egresses of %s (%s):\n", cluster.ClusterID, cluster.Name)
+			if err := cluster.postCmd.Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to send the service log to %s: %v\n", cluster.ClusterID, err)
+			}
+		}
+	}
+
+	for _, cluster := range fleet.Clusters {
+		if cluster.Result == nil || !cluster.Result.Passed {
+			os.Exit(1)
+		}
+	}
+	return nil
+}
+
+// verifyFleetCluster verifies a single cluster of the fleet, errors are recorded in the result
+func (e *EgressVerification) verifyFleetCluster(ctx context.Context, clusterID string) FleetClusterResult {
+	c := e.forCluster(clusterID)
+	result := FleetClusterResult{ClusterID: clusterID}
+	target, err := c.prepare(ctx)
+	if c.cluster != nil {
+		result.ClusterID = c.cluster.ID()
+		result.Name = c.cluster.Name()
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if result.Result, err = c.collectResult(ctx, target); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.postCmd = draftServiceLog(result.Result, result.ClusterID)
+	if result.postCmd != nil {
+		result.ServiceLog = fmt.Sprintf("osdctl servicelog post %v -t %v -p %v", result.ClusterID, result.postCmd.Template, strings.Join(result.postCmd.TemplateParams, " -p "))
+	}
+	return result
+}
+
+// draftServiceLog returns the blocked egresses service log of the failures of all the subnets,
+// or nil if no egress is blocked
+func draftServiceLog(result *VerificationResult, clusterID string) *servicelog.PostCmdOptions {
+	combined := &output.Output{}
+	seen := map[string]bool{}
+	for _, subnet := range result.Subnets {
+		if subnet.out == nil {
+			continue
+		}
+		for _, failure := range subnet.out.GetEgressURLFailures() {
+			if !seen[failure.EgressURL()] {
+				seen[failure.EgressURL()] = true
+				combined.SetEgressFailures([]string{failure.EgressURL()})
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	postCmd := generateServiceLog(combined, clusterID)
+	return &postCmd
+}
+
+// aggregateFailingEndpoints lists for every failing endpoint the clusters it fails on
+func aggregateFailingEndpoints(clusters []FleetClusterResult) []FailingEndpoint {
+	byURL := map[string]*FailingEndpoint{}
+	for _, cluster := range clusters {
+		if cluster.Result == nil {
+			continue
+		}
+		for _, subnet := range cluster.Result.Subnets {
+			for _, endpoint := range subnet.Failures() {
+				failing, ok := byURL[endpoint.URL]
+				if !ok {
+					failing = &FailingEndpoint{URL: endpoint.URL, Categories: []string{}, Clusters: []string{}}
+					byURL[endpoint.URL] = failing
+				}
+				if !slices.Contains(failing.Categories, endpoint.Category) {
+					failing.Categories = append(failing.Categories, endpoint.Category)
+				}
+				if !slices.Contains(failing.Clusters, cluster.ClusterID) {
+					failing.Clusters = append(failing.Clusters, cluster.ClusterID)
+				}
+			}
+		}
+	}
+
+	failing := make([]FailingEndpoint, 0, len(byURL))
+	for _, endpoint := range byURL {
+		failing = append(failing, *endpoint)
+	}
+	sort.Slice(failing, func(i, j int) bool {
+		if len(failing[i].Clusters) != len(failing[j].Clusters) {
+			return len(failing[i].Clusters) > len(failing[j].Clusters)
+		}
+		return failing[i].URL < failing[j].URL
+	})
+	return failing
+}
+
+func printFleetResult(w io.Writer, fleet *FleetResult, format string) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(fleet)
+	case outputYAML:
+		out, err := yaml.Marshal(fleet)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case outputJUnit:
+		suites := []junitTestSuite{}
+		for _, cluster := range fleet.Clusters {
+			if cluster.Result == nil {
+				suites = append(suites, junitTestSuite{
+					Name:      cluster.ClusterID,
+					Tests:     1,
+					Errors:    1,
+					Timestamp: fleet.StartTime.Format(time.RFC3339),
+					Cases: []junitTestCase{{
+						Name:      "verifier",
+						ClassName: cluster.ClusterID,
+						Error:     &junitProblem{Type: errorCategoryError, Message: cluster.Error},
+					}},
+				})
+				continue
+			}
+			suites = append(suites, junitSuites(cluster.Result, cluster.ClusterID+"/")...)
+		}
+		return writeJUnit(w, "osd-network-verifier", fleet.Duration, suites)
+	}
+
+	p := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	p.AddRow([]string{"CLUSTER ID", "NAME", "RESULT", "FAILED ENDPOINTS", "ERROR"})
+	for _, cluster := range fleet.Clusters {
+		status, failed := "error", 0
+		if cluster.Result != nil {
+			status = "passed"
+			if !cluster.Result.Passed {
+				status = "failed"
+			}
+			for _, subnet := range cluster.Result.Subnets {
+				failed += len(subnet.Failures())
+			}
+		}
+		p.AddRow([]string{cluster.ClusterID, cluster.Name, status, strconv.Itoa(failed), cluster.Error})
+	}
+	if err := p.Flush(); err != nil {
+		return err
+	}
+
+	if len(fleet.FailingEndpoints) > 0 {
+		fmt.Fprintln(w)
+		p = printer.NewTablePrinter(w, 20, 1, 3, ' ')
+		p.AddRow([]string{"FAILING ENDPOINT", "CLUSTERS", "CATEGORIES", "CLUSTER IDS"})
+		for _, endpoint := range fleet.FailingEndpoints {
+			p.AddRow([]string{endpoint.URL, strconv.Itoa(len(endpoint.Clusters)), strings.Join(endpoint.Categories, ","), strings.Join(endpoint.Clusters, ",")})
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+	}
+
+	drafted := false
+	for _, cluster := range fleet.Clusters {
+		if cluster.ServiceLog == "" {
+			continue
+		}
+		if !drafted {
+			fmt.Fprintln(w, "\nDrafted service logs, not sent unless --send-service-logs is passed:")
+			drafted = true
+		}
+		fmt.Fprintln(w, cluster.ServiceLog)
+	}
+	return nil
+}
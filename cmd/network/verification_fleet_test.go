@@ -0,0 +1,92 @@
+package network
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFleetCluster(clusterID string, failures ...string) FleetClusterResult {
+	out := &output.Output{}
+	out.SetEgressFailures(failures)
+	endpoints := []string{"https://quay.io:443", "https://api.openshift.com:443"}
+	subnet := newSubnetResult("subnet-"+clusterID, nil, endpoints, out, time.Second)
+	return FleetClusterResult{
+		ClusterID: clusterID,
+		Result:    &VerificationResult{ClusterID: clusterID, Passed: subnet.Passed, Subnets: []SubnetResult{subnet}},
+	}
+}
+
+func TestAggregateFailingEndpoints(t *testing.T) {
+	clusters := []FleetClusterResult{
+		newTestFleetCluster("a", "https://quay.io:443 (Connection timed out)"),
+		newTestFleetCluster("b", "https://quay.io:443 (Could not resolve host: quay.io)", "https://api.openshift.com:443 (Connection timed out)"),
+		newTestFleetCluster("c"),
+		{ClusterID: "d", Error: "failed to get credentials"},
+	}
+
+	assert.Equal(t, []FailingEndpoint{
+		{URL: "https://quay.io:443", Categories: []string{errorCategoryTimeout, errorCategoryDNS}, Clusters: []string{"a", "b"}},
+		{URL: "https://api.openshift.com:443", Categories: []string{errorCategoryTimeout}, Clusters: []string{"b"}},
+	}, aggregateFailingEndpoints(clusters))
+}
+
+func TestDraftServiceLog(t *testing.T) {
+	failing := newTestFleetCluster("a", "https://quay.io:443 (Connection timed out)")
+	failing.Result.Subnets = append(failing.Result.Subnets, failing.Result.Subnets[0])
+	postCmd := draftServiceLog(failing.Result, "a")
+	if assert.NotNil(t, postCmd) {
+		assert.Equal(t, "a", postCmd.ClusterId)
+		assert.Equal(t, []string{"URLS=https://quay.io:443 (Connection timed out)"}, postCmd.TemplateParams)
+	}
+
+	assert.Nil(t, draftServiceLog(newTestFleetCluster("b").Result, "b"))
+}
+
+func TestPrintFleetResult(t *testing.T) {
+	fleet := &FleetResult{
+		Clusters: []FleetClusterResult{
+			newTestFleetCluster("a", "https://quay.io:443 (Connection timed out)"),
+			{ClusterID: "b", Error: "failed to get credentials"},
+		},
+	}
+	fleet.Clusters[0].ServiceLog = "osdctl servicelog post a"
+	fleet.FailingEndpoints = aggregateFailingEndpoints(fleet.Clusters)
+
+	var text bytes.Buffer
+	assert.NoError(t, printFleetResult(&text, fleet, outputText))
+	assert.Contains(t, text.String(), "failed to get credentials")
+	assert.Contains(t, text.String(), "https://quay.io:443")
+	assert.Contains(t, text.String(), "osdctl servicelog post a")
+
+	var junit bytes.Buffer
+	assert.NoError(t, printFleetResult(&junit, fleet, outputJUnit))
+	assert.Contains(t, junit.String(), `tests="3" failures="1" errors="1"`)
+	assert.Contains(t, junit.String(), `<testsuite name="a/subnet-a"`)
+}
+
+func TestGetFleetClusterIDsFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "clusters.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"clusters":["a","b"]}`), 0600))
+
+	e := &EgressVerification{ClustersFile: file}
+	ids, err := e.getFleetClusterIDs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids)
+
+	assert.NoError(t, os.WriteFile(file, []byte(`["a"]`), 0600))
+	_, err = e.getFleetClusterIDs()
+	assert.Error(t, err)
+}
+
+func TestValidateFleetInput(t *testing.T) {
+	assert.NoError(t, (&EgressVerification{Queries: []string{"state = 'ready'"}, Concurrency: 5}).validateFleetInput())
+	assert.Error(t, (&EgressVerification{Queries: []string{"state = 'ready'"}, ClusterId: "a", Concurrency: 5}).validateFleetInput())
+	assert.Error(t, (&EgressVerification{Queries: []string{"state = 'ready'"}, SubnetIds: []string{"subnet-a"}, Concurrency: 5}).validateFleetInput())
+	assert.Error(t, (&EgressVerification{ClustersFile: "clusters.json"}).validateFleetInput())
+}
@@ -0,0 +1,337 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/data/cloud"
+	"github.com/openshift/osd-network-verifier/pkg/data/egress_lists"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputJUnit = "junit"
+
+	// Categories of the failed endpoints and of the verifier errors
+	errorCategoryDNS        = "dns"
+	errorCategoryTimeout    = "timeout"
+	errorCategoryTLS        = "tls"
+	errorCategoryConnection = "connection"
+	errorCategoryNonPrivate = "non-private"
+	errorCategoryEgress     = "egress"
+	errorCategoryException  = "exception"
+	errorCategoryError      = "error"
+)
+
+var verificationOutputFormats = []string{outputText, outputJSON, outputYAML, outputJUnit}
+
+// VerificationResult is the structured result of an egress verification of a cluster
+type VerificationResult struct {
+	ClusterID string         `json:"clusterId,omitempty"`
+	Platform  string         `json:"platform"`
+	Probe     string         `json:"probe"`
+	StartTime time.Time      `json:"startTime"`
+	Duration  float64        `json:"durationSeconds"`
+	Passed    bool           `json:"passed"`
+	Subnets   []SubnetResult `json:"subnets"`
+}
+
+// SubnetResult is the result of the egress verification of a single subnet
+type SubnetResult struct {
+	SubnetID         string           `json:"subnetId"`
+	SecurityGroupIDs []string         `json:"securityGroupIds,omitempty"`
+	Passed           bool             `json:"passed"`
+	Duration         float64          `json:"durationSeconds"`
+	Endpoints        []EndpointResult `json:"endpoints"`
+	// Errors are the exceptions and errors which prevented the verifier from testing some egresses
+	Errors []VerifierError `json:"errors,omitempty"`
+
+	// out is the verifier output the result was built from, eg. to draft a service log
+	out *output.Output
+}
+
+// EndpointResult is the result of the egress check of a single endpoint, eg. https://quay.io:443
+type EndpointResult struct {
+	URL    string `json:"url"`
+	Passed bool   `json:"passed"`
+	// Untested is true when the endpoint has no failure but the verifier had errors, so it may not have been tested
+	Untested bool   `json:"untested,omitempty"`
+	Category string `json:"category,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// VerifierError is an exception or error reported by the verifier
+type VerifierError struct {
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+// Failures returns the failed endpoints of the subnet
+func (s SubnetResult) Failures() []EndpointResult {
+	failures := []EndpointResult{}
+	for _, endpoint := range s.Endpoints {
+		if !endpoint.Passed && !endpoint.Untested {
+			failures = append(failures, endpoint)
+		}
+	}
+	return failures
+}
+
+// newSubnetResult converts the output of the verifier into a SubnetResult. The endpoints are the
+// URLs the probe was expected to test, they are reported as passed unless the output has a failure
+// for them, or as untested if the verifier had exceptions or errors. Failures for URLs not in the list,
+// eg. with the legacy probe, are reported as well.
+func newSubnetResult(subnetID string, securityGroupIDs []string, endpoints []string, out *output.Output, duration time.Duration) SubnetResult {
+	result := SubnetResult{
+		SubnetID:         subnetID,
+		SecurityGroupIDs: securityGroupIDs,
+		Passed:           out.IsSuccessful(),
+		Duration:         duration.Seconds(),
+		Endpoints:        []EndpointResult{},
+		out:              out,
+	}
+
+	failures, exceptions, errs := out.Parse()
+	failed := map[string]EndpointResult{}
+	failedURLs := []string{}
+	for _, failure := range failures {
+		var egressErr *handledErrors.GenericError
+		if !errors.As(failure, &egressErr) || egressErr.EgressURL() == "" {
+			result.Errors = append(result.Errors, VerifierError{Category: errorCategoryError, Message: failure.Error()})
+			continue
+		}
+		endpoint := parseEgressFailure(egressErr.EgressURL())
+		if _, ok := failed[endpoint.URL]; !ok {
+			failedURLs = append(failedURLs, endpoint.URL)
+		}
+		failed[endpoint.URL] = endpoint
+	}
+	for _, exception := range exceptions {
+		result.Errors = append(result.Errors, VerifierError{Category: errorCategoryException, Message: exception.Error()})
+	}
+	for _, err := range errs {
+		result.Errors = append(result.Errors, VerifierError{Category: errorCategoryError, Message: err.Error()})
+	}
+
+	// The verifier errors can prevent the probe from testing some or all of the endpoints
+	untested := len(result.Errors) > 0
+	for _, url := range endpoints {
+		if endpoint, ok := failed[url]; ok {
+			result.Endpoints = append(result.Endpoints, endpoint)
+			delete(failed, url)
+			continue
+		}
+		result.Endpoints = append(result.Endpoints, EndpointResult{URL: url, Passed: !untested, Untested: untested})
+	}
+	for _, url := range failedURLs {
+		if endpoint, ok := failed[url]; ok {
+			result.Endpoints = append(result.Endpoints, endpoint)
+		}
+	}
+	return result
+}
+
+// parseEgressFailure splits a failure reported by the curl probe, eg.
+// "https://quay.io:443 (Connection timed out after 5000 milliseconds)", into its URL and error
+func parseEgressFailure(failure string) EndpointResult {
+	endpoint := EndpointResult{URL: failure, Category: errorCategoryEgress}
+	if i := strings.Index(failure, " ("); i > 0 && strings.HasSuffix(failure, ")") {
+		endpoint.URL = failure[:i]
+		endpoint.Error = failure[i+2 : len(failure)-1]
+		endpoint.Category = categorizeEgressError(endpoint.Error)
+	}
+	return endpoint
+}
+
+// categorizeEgressError maps the curl error of a failed endpoint to a coarse category
+func categorizeEgressError(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "resolve"):
+		return errorCategoryDNS
+	case strings.Contains(lower, "timed out") || strings.Contains(lower, "timeout"):
+		return errorCategoryTimeout
+	case strings.Contains(lower, "ssl") || strings.Contains(lower, "tls") || strings.Contains(lower, "certificate"):
+		return errorCategoryTLS
+	case strings.Contains(lower, "non private"):
+		return errorCategoryNonPrivate
+	case strings.Contains(lower, "connect") || strings.Contains(lower, "refused") || strings.Contains(lower, "reset"):
+		return errorCategoryConnection
+	default:
+		return errorCategoryEgress
+	}
+}
+
+// getEgressList returns the egress list YAML of the platform, from GitHub like the verifier does or
+// else the list embedded in the verifier, and the endpoint URLs it contains. Passing the returned YAML
+// to the verifier guarantees the endpoints are the ones it tests.
+func getEgressList(ctx context.Context, platform cloud.Platform, variables map[string]string, logger logging.Logger) (string, []string, error) {
+	generator := egress_lists.NewGenerator(platform, variables, logger)
+	var egressListYaml string
+	content, err := generator.GetGithubEgressList(ctx)
+	if err == nil {
+		egressListYaml, err = content.GetContent()
+	}
+	if err != nil {
+		logger.Info(ctx, "failed to get egress list from GitHub, falling back to the local list: %v", err)
+		if egressListYaml, err = generator.GetLocalEgressList(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	urls, tlsDisabledUrls, err := generator.EgressListToString(egressListYaml, variables)
+	if err != nil {
+		return "", nil, err
+	}
+	endpoints := []string{}
+	for _, url := range strings.Fields(urls + " " + tlsDisabledUrls) {
+		// The probe reports telnet URLs as tcp
+		endpoints = append(endpoints, strings.Replace(url, "telnet", "tcp", 1))
+	}
+	return egressListYaml, endpoints, nil
+}
+
+// printVerificationResult prints the result in the json, yaml or junit format
+func printVerificationResult(w io.Writer, result *VerificationResult, format string) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case outputYAML:
+		out, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case outputJUnit:
+		return printJUnit(w, result)
+	}
+	return fmt.Errorf("invalid output format %q, valid formats are %s", format, strings.Join(verificationOutputFormats, ", "))
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr,omitempty"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr,omitempty"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitProblem struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+}
+
+// printJUnit prints a test suite per subnet with a test case per endpoint
+func printJUnit(w io.Writer, result *VerificationResult) error {
+	name := "osd-network-verifier"
+	if result.ClusterID != "" {
+		name += " " + result.ClusterID
+	}
+	return writeJUnit(w, name, result.Duration, junitSuites(result, ""))
+}
+
+// junitSuites returns a test suite per subnet with a test case per endpoint, verifier errors
+// are reported as test cases with an error. The suite names are the subnet IDs after the prefix.
+func junitSuites(result *VerificationResult, prefix string) []junitTestSuite {
+	suites := []junitTestSuite{}
+	for _, subnet := range result.Subnets {
+		suite := junitTestSuite{
+			Name:      prefix + subnet.SubnetID,
+			Time:      subnet.Duration,
+			Timestamp: result.StartTime.UTC().Format(time.RFC3339),
+		}
+		for _, endpoint := range subnet.Endpoints {
+			testCase := junitTestCase{Name: endpoint.URL, ClassName: suite.Name}
+			switch {
+			case endpoint.Untested:
+				testCase.Skipped = &junitSkipped{Message: "not tested because of the verifier errors"}
+				suite.Skipped++
+			case !endpoint.Passed:
+				testCase.Failure = &junitProblem{Type: endpoint.Category, Message: endpoint.Error}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		for i, verifierErr := range subnet.Errors {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("verifier %s %d", verifierErr.Category, i+1),
+				ClassName: suite.Name,
+				Error:     &junitProblem{Type: verifierErr.Category, Message: verifierErr.Message},
+			})
+			suite.Errors++
+		}
+		suite.Tests = len(suite.Cases)
+		suites = append(suites, suite)
+	}
+	return suites
+}
+
+func writeJUnit(w io.Writer, name string, duration float64, suites []junitTestSuite) error {
+	testSuites := junitTestSuites{Name: name, Time: duration, Suites: suites}
+	for _, suite := range suites {
+		testSuites.Tests += suite.Tests
+		testSuites.Failures += suite.Failures
+		testSuites.Errors += suite.Errors
+		testSuites.Skipped += suite.Skipped
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(testSuites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func validateOutputFormat(format string) error {
+	if !slices.Contains(verificationOutputFormats, format) {
+		return fmt.Errorf("invalid output format %q, valid formats are %s", format, strings.Join(verificationOutputFormats, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSubnetResult(t *testing.T) {
+	out := &output.Output{}
+	out.SetEgressFailures([]string{
+		"https://quay.io:443 (Connection timed out after 5000 milliseconds)",
+		"tcp://api.openshift.com:9997 (Could not resolve host: api.openshift.com)",
+		"https://unlisted.example.com:443 (SSL certificate problem: self signed certificate)",
+	})
+	out.AddException(errors.New("instance failed to start"))
+
+	endpoints := []string{"https://registry.redhat.io:443", "https://quay.io:443", "tcp://api.openshift.com:9997"}
+	result := newSubnetResult("subnet-a", []string{"sg-a"}, endpoints, out, 90*time.Second)
+
+	assert.False(t, result.Passed)
+	assert.Equal(t, 90.0, result.Duration)
+	assert.Equal(t, []EndpointResult{
+		{URL: "https://registry.redhat.io:443", Untested: true},
+		{URL: "https://quay.io:443", Category: errorCategoryTimeout, Error: "Connection timed out after 5000 milliseconds"},
+		{URL: "tcp://api.openshift.com:9997", Category: errorCategoryDNS, Error: "Could not resolve host: api.openshift.com"},
+		{URL: "https://unlisted.example.com:443", Category: errorCategoryTLS, Error: "SSL certificate problem: self signed certificate"},
+	}, result.Endpoints)
+	assert.Len(t, result.Failures(), 3)
+	assert.Equal(t, []VerifierError{{Category: errorCategoryException, Message: "instance failed to start"}}, result.Errors)
+
+	passed := newSubnetResult("subnet-b", nil, endpoints, &output.Output{}, time.Second)
+	assert.True(t, passed.Passed)
+	assert.Empty(t, passed.Failures())
+	assert.Equal(t, EndpointResult{URL: "https://registry.redhat.io:443", Passed: true}, passed.Endpoints[0])
+
+	errored := &output.Output{}
+	errored.AddError(errors.New("failed to create instance"))
+	untested := newSubnetResult("subnet-c", nil, endpoints, errored, time.Second)
+	assert.False(t, untested.Passed)
+	assert.Empty(t, untested.Failures())
+	for _, endpoint := range untested.Endpoints {
+		assert.True(t, endpoint.Untested, endpoint.URL)
+		assert.False(t, endpoint.Passed, endpoint.URL)
+	}
+}
+
+func TestPrintVerificationResult(t *testing.T) {
+	result := &VerificationResult{
+		ClusterID: "cluster-id",
+		Platform:  "aws-classic",
+		Probe:     "curl",
+		StartTime: time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+		Duration:  60,
+		Subnets: []SubnetResult{{
+			SubnetID: "subnet-a",
+			Duration: 60,
+			Endpoints: []EndpointResult{
+				{URL: "https://registry.redhat.io:443", Passed: true},
+				{URL: "https://quay.io:443", Category: errorCategoryTimeout, Error: "Connection timed out"},
+				{URL: "https://sso.redhat.com:443", Untested: true},
+			},
+			Errors: []VerifierError{{Category: errorCategoryError, Message: "failed to terminate instance"}},
+		}},
+	}
+
+	var junit bytes.Buffer
+	assert.NoError(t, printVerificationResult(&junit, result, outputJUnit))
+	assert.Contains(t, junit.String(), `<testsuites name="osd-network-verifier cluster-id" tests="4" failures="1" errors="1" skipped="1" time="60">`)
+	assert.Contains(t, junit.String(), `<skipped message="not tested because of the verifier errors"></skipped>`)
+	assert.Contains(t, junit.String(), `<failure type="timeout" message="Connection timed out"></failure>`)
+
+	var yamlOut bytes.Buffer
+	assert.NoError(t, printVerificationResult(&yamlOut, result, outputYAML))
+	assert.Contains(t, yamlOut.String(), "  subnetId: subnet-a")
+
+	var jsonOut bytes.Buffer
+	assert.NoError(t, printVerificationResult(&jsonOut, result, outputJSON))
+	assert.Contains(t, jsonOut.String(), `"url": "https://quay.io:443"`)
+
+	assert.Error(t, printVerificationResult(&jsonOut, result, "xml"))
+}
+
+func TestCategorizeEgressError(t *testing.T) {
+	tests := map[string]string{
+		"Could not resolve host: quay.io":              errorCategoryDNS,
+		"Connection timed out after 5000 milliseconds": errorCategoryTimeout,
+		"OpenSSL SSL_connect: SSL_ERROR_SYSCALL":       errorCategoryTLS,
+		"The endpoint is non private":                  errorCategoryNonPrivate,
+		"Failed to connect to quay.io port 443":        errorCategoryConnection,
+		"unknown":                                      errorCategoryEgress,
+	}
+	for message, want := range tests {
+		assert.Equal(t, want, categorizeEgressError(message), message)
+	}
+}
@@ -1,6 +1,7 @@
 package servicelog
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -66,28 +67,56 @@ func validateBadResponse(body []byte) (badReply *servicelog.BadReply, err error)
 // of the service logs from the given time period, while the second return value
 // indicates if an error has happened.
 func GetServiceLogsSince(clusterID string, timeSince time.Time, allMessages bool, internalOnly bool) ([]*v1.LogEntry, error) {
-	earliestTime := timeSince
-
-	slResponse, err := FetchServiceLogs(clusterID, allMessages, internalOnly)
+	var serviceLogs []*v1.LogEntry
+	err := withClusterConnection(clusterID, func(ocmClient *sdk.Connection, cluster *cmv1.Cluster) (err error) {
+		serviceLogs, err = GetClusterServiceLogsSince(context.Background(), ocmClient, cluster, timeSince, allMessages, internalOnly)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var errorServiceLogs []*v1.LogEntry
-	for _, serviceLog := range slResponse.Items().Slice() {
-		if serviceLog.CreatedAt().After(earliestTime) {
-			errorServiceLogs = append(errorServiceLogs, serviceLog)
-		}
-	}
+	return serviceLogs, nil
+}
 
-	return errorServiceLogs, nil
+// GetClusterServiceLogsSince is GetServiceLogsSince with an existing OCM connection and cluster,
+// cancelling the requests once ctx is done.
+func GetClusterServiceLogsSince(ctx context.Context, ocmClient *sdk.Connection, cluster *cmv1.Cluster, timeSince time.Time, allMessages bool, internalOnly bool) ([]*v1.LogEntry, error) {
+	return listAllClusterLogs(ctx, ocmClient, cluster, timeSince, allMessages, internalOnly)
 }
 
 func FetchServiceLogs(clusterID string, allMessages bool, internalOnly bool) (*v1.ClustersClusterLogsListResponse, error) {
+	var clusterLogsListResponse *v1.ClustersClusterLogsListResponse
+	err := withClusterConnection(clusterID, func(ocmClient *sdk.Connection, cluster *cmv1.Cluster) (err error) {
+		clusterLogsListResponse, err = sendClusterLogsListRequest(ocmClient, cluster, allMessages, internalOnly)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clusterLogsListResponse, nil
+}
+
+// FetchAllServiceLogs returns all service logs of the cluster, newest first. Unlike
+// FetchServiceLogs it reads every page instead of only the first one.
+func FetchAllServiceLogs(clusterID string, allMessages bool, internalOnly bool) ([]*v1.LogEntry, error) {
+	var entries []*v1.LogEntry
+	err := withClusterConnection(clusterID, func(ocmClient *sdk.Connection, cluster *cmv1.Cluster) (err error) {
+		entries, err = listAllClusterLogs(context.Background(), ocmClient, cluster, time.Time{}, allMessages, internalOnly)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// withClusterConnection looks up the cluster with a new OCM connection and calls fetch with both
+func withClusterConnection(clusterID string, fetch func(ocmClient *sdk.Connection, cluster *cmv1.Cluster) error) error {
 	// Create OCM client to talk to cluster API
 	ocmClient, err := utils.CreateConnection()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() {
 		if err := ocmClient.Close(); err != nil {
@@ -98,19 +127,59 @@ func FetchServiceLogs(clusterID string, allMessages bool, internalOnly bool) (*v
 	// Use the OCM client to retrieve clusters
 	clusters := utils.GetClusters(ocmClient, []string{clusterID})
 	if len(clusters) != 1 {
-		return nil, fmt.Errorf("GetClusters expected to return 1 cluster, got: %d", len(clusters))
+		return fmt.Errorf("GetClusters expected to return 1 cluster, got: %d", len(clusters))
 	}
-	cluster := clusters[0]
 
 	// Now get the SLs for the cluster
-	clusterLogsListResponse, err := sendClusterLogsListRequest(ocmClient, cluster, allMessages, internalOnly)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch service logs for cluster %v: %w", clusterID, err)
+	if err := fetch(ocmClient, clusters[0]); err != nil {
+		return fmt.Errorf("failed to fetch service logs for cluster %v: %w", clusterID, err)
 	}
-	return clusterLogsListResponse, nil
+	return nil
 }
 
 func sendClusterLogsListRequest(ocmClient *sdk.Connection, cluster *cmv1.Cluster, allMessages bool, internalMessages bool) (*v1.ClustersClusterLogsListResponse, error) {
+	response, err := newClusterLogsListRequest(ocmClient, cluster, allMessages, internalMessages).Send()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch service logs: %w", err)
+	}
+	return response, nil
+}
+
+// listAllClusterLogs returns the service logs of the cluster created after since, newest first,
+// reading every page until one reaches back before since. A zero since reads all of them.
+func listAllClusterLogs(ctx context.Context, ocmClient *sdk.Connection, cluster *cmv1.Cluster, since time.Time, allMessages bool, internalMessages bool) ([]*v1.LogEntry, error) {
+	requestSize := 100
+	request := newClusterLogsListRequest(ocmClient, cluster, allMessages, internalMessages).Size(requestSize)
+	response, err := request.SendContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch service logs: %w", err)
+	}
+
+	items := response.Items().Slice()
+	for response.Size() >= requestSize && !reachedBefore(items, since) {
+		request.Page(response.Page() + 1)
+		response, err = request.SendContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch service logs: %w", err)
+		}
+		items = append(items, response.Items().Slice()...)
+	}
+
+	var entries []*v1.LogEntry
+	for _, item := range items {
+		if item.CreatedAt().After(since) {
+			entries = append(entries, item)
+		}
+	}
+	return entries, nil
+}
+
+// reachedBefore returns true if the oldest of the entries, sorted newest first, was created before since
+func reachedBefore(entries []*v1.LogEntry, since time.Time) bool {
+	return !since.IsZero() && len(entries) > 0 && entries[len(entries)-1].CreatedAt().Before(since)
+}
+
+func newClusterLogsListRequest(ocmClient *sdk.Connection, cluster *cmv1.Cluster, allMessages bool, internalMessages bool) *v1.ClustersClusterLogsListRequest {
 	request := ocmClient.ServiceLogs().V1().Clusters().ClusterLogs().List().
 		Parameter("cluster_id", cluster.ID()).
 		Parameter("cluster_uuid", cluster.ExternalID()).
@@ -126,11 +195,5 @@ func sendClusterLogsListRequest(ocmClient *sdk.Connection, cluster *cmv1.Cluster
 		}
 		searchQuery += "internal_only='true'"
 	}
-	request.Search(searchQuery)
-
-	response, err := request.Send()
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch service logs: %w", err)
-	}
-	return response, nil
+	return request.Search(searchQuery)
 }
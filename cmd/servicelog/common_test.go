@@ -2,7 +2,9 @@ package servicelog
 
 import (
 	"testing"
+	"time"
 
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
 	"github.com/openshift/osdctl/internal/servicelog"
 	"github.com/stretchr/testify/assert"
 )
@@ -224,3 +226,18 @@ func TestValidateBadResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestReachedBefore(t *testing.T) {
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	var entries []*slv1.LogEntry
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour} {
+		entry, err := slv1.NewLogEntry().CreatedAt(now.Add(-age)).Build()
+		assert.NoError(t, err)
+		entries = append(entries, entry)
+	}
+
+	assert.False(t, reachedBefore(entries, time.Time{}), "zero since reads all pages")
+	assert.False(t, reachedBefore(nil, now), "no entries yet")
+	assert.False(t, reachedBefore(entries, now.Add(-3*time.Hour)))
+	assert.True(t, reachedBefore(entries, now.Add(-90*time.Minute)))
+}
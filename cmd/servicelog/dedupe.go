@@ -0,0 +1,122 @@
+package servicelog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	"github.com/openshift/osdctl/internal/servicelog"
+	ocmutils "github.com/openshift/osdctl/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultDedupeWindow = 24 * time.Hour
+
+// duplicateCheck is the outcome of looking for an identical service log on one cluster
+type duplicateCheck struct {
+	cluster   *cmv1.Cluster
+	duplicate *slv1.LogEntry
+	err       error
+}
+
+// isDuplicateServiceLog returns true if the entry has the same severity,
+// summary and description as the message about to be sent
+func isDuplicateServiceLog(message servicelog.Message, entry *slv1.LogEntry) bool {
+	return string(entry.Severity()) == message.Severity &&
+		strings.TrimSpace(entry.Summary()) == strings.TrimSpace(message.Summary) &&
+		strings.TrimSpace(entry.Description()) == strings.TrimSpace(message.Description)
+}
+
+// findDuplicateServiceLog returns the most recent service log on the cluster
+// sent after since that is identical to message, or nil if there isn't one
+func findDuplicateServiceLog(ocmClient *sdk.Connection, cluster *cmv1.Cluster, message servicelog.Message, since time.Time) (*slv1.LogEntry, error) {
+	entries, err := listAllClusterLogs(context.Background(), ocmClient, cluster, since, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if isDuplicateServiceLog(message, entry) {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkDuplicates looks for identical service logs sent within the dedupe
+// window on all clusters, in parallel. The order of the clusters is kept.
+func (o *PostCmdOptions) checkDuplicates(ocmClient *sdk.Connection, clusters []*cmv1.Cluster) []duplicateCheck {
+	since := time.Now().Add(-o.dedupeWindow)
+
+	concurrency := o.concurrency
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	checks := make([]duplicateCheck, len(clusters))
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			duplicate, err := findDuplicateServiceLog(ocmClient, cluster, o.clusterMessage(cluster), since)
+			checks[i] = duplicateCheck{cluster: cluster, duplicate: duplicate, err: err}
+		}()
+	}
+	wg.Wait()
+
+	return checks
+}
+
+// filterDuplicates removes the clusters which already received an identical
+// service log within the dedupe window. Unless prompts are skipped, the user
+// is asked whether to post to them anyway, dry-runs only report them. The skipped clusters are returned
+// separately, and recorded with the reason they were skipped.
+func (o *PostCmdOptions) filterDuplicates(ocmClient *sdk.Connection, clusters []*cmv1.Cluster) (clustersToPost []*cmv1.Cluster, skippedClusters []*cmv1.Cluster) {
+	if o.allowDuplicates || o.dedupeWindow <= 0 || len(clusters) == 0 {
+		return clusters, nil
+	}
+
+	log.Infof("Checking for identical service logs sent in the last %s...", o.dedupeWindow)
+
+	for _, check := range o.checkDuplicates(ocmClient, clusters) {
+		if check.err != nil {
+			log.Warnf("Cannot check for duplicate service logs on cluster %s, please verify that you are not sending a duplicate: %v", check.cluster.ID(), check.err)
+			clustersToPost = append(clustersToPost, check.cluster)
+			continue
+		}
+		if check.duplicate == nil {
+			clustersToPost = append(clustersToPost, check.cluster)
+			continue
+		}
+
+		reason := fmt.Sprintf("identical service log %s already sent at %s", check.duplicate.ID(), check.duplicate.CreatedAt().Format(time.RFC3339))
+		log.Warnf("Cluster ID: %s, Name: %s: %s", check.cluster.ID(), check.cluster.Name(), reason)
+		if !o.skipPrompts && !o.isDryRun && ocmutils.ConfirmPrompt() {
+			clustersToPost = append(clustersToPost, check.cluster)
+			continue
+		}
+
+		log.Info("Skipping cluster ID: ", check.cluster.ID(), ", Name: ", check.cluster.Name())
+		o.recordSkipped(check.cluster, reason)
+		skippedClusters = append(skippedClusters, check.cluster)
+	}
+
+	return clustersToPost, skippedClusters
+}
+
+// recordSkipped records a cluster the service log was deliberately not sent to
+func (o *PostCmdOptions) recordSkipped(cluster *cmv1.Cluster, reason string) {
+	o.resultsMutex.Lock()
+	o.skippedClusters[cluster.ExternalID()] = reason
+	o.resultsMutex.Unlock()
+}
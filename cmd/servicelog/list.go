@@ -1,16 +1,24 @@
 package servicelog
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
 
 	"github.com/openshift-online/ocm-cli/pkg/dump"
+	"github.com/openshift/osdctl/internal/servicelog"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/openshift/osdctl/pkg/utils"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 )
 
 const (
@@ -19,12 +27,34 @@ const (
 	InternalFlag         = "internal"
 	InternalShortFlag    = "i"
 	ListclusterIDFlag    = "cluster-id"
+
+	listOutputTable = "table"
+	listOutputJSON  = "json"
+	listOutputYAML  = "yaml"
+	listOutputCSV   = "csv"
 )
 
 type listCmdOptions struct {
-	allMessages bool
-	internal    bool
-	clusterID   string
+	allMessages  bool
+	internalOnly bool
+	customer     bool
+	clusterID    string
+	severities   []string
+	serviceNames []string
+	since        string
+	until        string
+	search       string
+	output       string
+}
+
+// listFilter selects the service logs to print, all set fields have to match
+type listFilter struct {
+	severities   []string
+	serviceNames []string
+	since        time.Time
+	until        time.Time
+	search       string
+	customerOnly bool
 }
 
 func newListCmd() *cobra.Command {
@@ -39,8 +69,17 @@ osdctl servicelog list --cluster-id=my-cluster-id
 # To return all service logs, including those by automated systems
 osdctl servicelog list --cluster-id=my-cluster-id --all-messages
 
-# To return all service logs, as well as internal service logs
-osdctl servicelog list --cluster-id=my-cluster-id --all-messages --internal
+# To return all service logs, internal ones included
+osdctl servicelog list --cluster-id=my-cluster-id --all-messages
+
+# To return only internal service logs
+osdctl servicelog list --cluster-id=my-cluster-id --all-messages --internal-only
+
+# To return the customer facing warnings of the last week mentioning "upgrade" as a table
+osdctl servicelog list --cluster-id=my-cluster-id -A --customer --severity Warning --since 168h --search upgrade -o table
+
+# To export the service logs sent in a time range as csv
+osdctl servicelog list --cluster-id=my-cluster-id -A --since 2024-01-01 --until 2024-02-01T12:00:00Z -o csv > service-logs.csv
 `,
 		Short: "Get service logs for a given cluster identifier.",
 		Args:  cobra.NoArgs,
@@ -50,36 +89,147 @@ osdctl servicelog list --cluster-id=my-cluster-id --all-messages --internal
 		},
 	}
 
-	cmd.Flags().BoolP(AllMessagesFlag, AllMessagesShortFlag, opts.allMessages, "Toggle if we should see all of the messages or only SRE-P specific ones")
-	cmd.Flags().BoolP(InternalFlag, InternalShortFlag, opts.internal, "Toggle if we should see internal messages")
+	cmd.Flags().BoolVarP(&opts.allMessages, AllMessagesFlag, AllMessagesShortFlag, opts.allMessages, "Toggle if we should see all of the messages or only SRE-P specific ones")
+	cmd.Flags().BoolVarP(&opts.internalOnly, InternalFlag, InternalShortFlag, false, "Only show internal messages")
+	_ = cmd.Flags().MarkDeprecated(InternalFlag, "internal messages are shown by default, use --internal-only to only show them")
+	cmd.Flags().BoolVar(&opts.internalOnly, "internal-only", false, "Only show internal messages")
+	cmd.Flags().BoolVar(&opts.customer, "customer", false, "Only show customer facing messages")
 	cmd.Flags().StringVar(&opts.clusterID, ListclusterIDFlag, "", "Internal Cluster identifier (required)")
+	cmd.Flags().StringSliceVar(&opts.severities, "severity", nil, "Only show messages with the given severities (Debug, Info, Warning, Error, Fatal)")
+	cmd.Flags().StringSliceVar(&opts.serviceNames, "service", nil, "Only show messages from the given services, implies --all-messages")
+	cmd.Flags().StringVar(&opts.since, "since", "", "Only show messages created after this time, either a duration (eg. 24h) or a date (eg. 2024-01-31 or 2024-01-31T12:00:00Z)")
+	cmd.Flags().StringVar(&opts.until, "until", "", "Only show messages created before this time, either a duration (eg. 24h) or a date (eg. 2024-01-31 or 2024-01-31T12:00:00Z)")
+	cmd.Flags().StringVar(&opts.search, "search", "", "Only show messages with a summary or description containing this text (case insensitive)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", listOutputJSON, "Output format, one of table, json, yaml or csv")
 	cmd.MarkFlagRequired(ListclusterIDFlag)
+	cmd.MarkFlagsMutuallyExclusive("internal-only", "customer")
+	cmd.MarkFlagsMutuallyExclusive(InternalFlag, "customer")
 
 	return cmd
 }
 
 func listServiceLogs(clusterID string, opts *listCmdOptions) error {
-	response, err := FetchServiceLogs(clusterID, opts.allMessages, opts.internal)
+	filter, err := opts.filter(time.Now())
+	if err != nil {
+		return err
+	}
+	if !slices.Contains([]string{listOutputTable, listOutputJSON, listOutputYAML, listOutputCSV}, opts.output) {
+		return fmt.Errorf("invalid output format %q, valid formats are table, json, yaml and csv", opts.output)
+	}
+
+	allMessages := opts.allMessages || len(opts.serviceNames) > 0
+	entries, err := FetchAllServiceLogs(clusterID, allMessages, opts.internalOnly)
 	if err != nil {
 		return fmt.Errorf("failed to fetch service logs: %w", err)
 	}
 
-	if err = printServiceLogResponse(response); err != nil {
+	if err = printServiceLogs(entries, filter, opts.output, os.Stdout); err != nil {
 		return fmt.Errorf("failed to print service logs: %w", err)
 	}
 
 	return nil
 }
 
-func printServiceLogResponse(response *slv1.ClustersClusterLogsListResponse) error {
-	entryViews := logEntryToView(response.Items().Slice())
+// filter validates the filter flags and converts them to a listFilter
+func (o *listCmdOptions) filter(now time.Time) (*listFilter, error) {
+	filter := &listFilter{
+		serviceNames: o.serviceNames,
+		search:       strings.ToLower(o.search),
+		customerOnly: o.customer,
+	}
+
+	for _, severity := range o.severities {
+		valid := false
+		for _, s := range servicelog.ValidSeverities() {
+			if strings.EqualFold(severity, string(s)) {
+				filter.severities = append(filter.severities, string(s))
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid severity %q", severity)
+		}
+	}
+
+	var err error
+	if o.since != "" {
+		if filter.since, err = utils.ParseTime(o.since, now); err != nil {
+			return nil, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if o.until != "" {
+		if filter.until, err = utils.ParseTime(o.until, now); err != nil {
+			return nil, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	if !filter.since.IsZero() && !filter.until.IsZero() && filter.until.Before(filter.since) {
+		return nil, fmt.Errorf("--until cannot be before --since")
+	}
+
+	return filter, nil
+}
+
+// matches returns true if the entry passes all filters
+func (f *listFilter) matches(entry *slv1.LogEntry) bool {
+	if len(f.severities) > 0 && !slices.Contains(f.severities, string(entry.Severity())) {
+		return false
+	}
+	if len(f.serviceNames) > 0 && !slices.ContainsFunc(f.serviceNames, func(name string) bool {
+		return strings.EqualFold(name, entry.ServiceName())
+	}) {
+		return false
+	}
+	if !f.since.IsZero() && entry.CreatedAt().Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && entry.CreatedAt().After(f.until) {
+		return false
+	}
+	if f.customerOnly && entry.InternalOnly() {
+		return false
+	}
+	if f.search != "" &&
+		!strings.Contains(strings.ToLower(entry.Summary()), f.search) &&
+		!strings.Contains(strings.ToLower(entry.Description()), f.search) {
+		return false
+	}
+	return true
+}
+
+// printServiceLogs prints the entries passing the filter, the counts of the json and yaml output refer to those
+func printServiceLogs(entries []*slv1.LogEntry, filter *listFilter, output string, w io.Writer) error {
+	var matches []*slv1.LogEntry
+	for _, entry := range entries {
+		if filter.matches(entry) {
+			matches = append(matches, entry)
+		}
+	}
+
+	entryViews := logEntryToView(matches)
 	slices.Reverse(entryViews)
+
+	switch output {
+	case listOutputTable:
+		return printServiceLogTable(entryViews, w)
+	case listOutputCSV:
+		return printServiceLogCSV(entryViews, w)
+	}
+
 	view := LogEntryResponseView{
 		Items: entryViews,
 		Kind:  "ClusterLogList",
-		Page:  response.Page(),
-		Size:  response.Size(),
-		Total: response.Total(),
+		Page:  1,
+		Size:  len(entryViews),
+		Total: len(entryViews),
+	}
+
+	if output == listOutputYAML {
+		viewBytes, err := yaml.Marshal(view)
+		if err != nil {
+			return fmt.Errorf("failed to marshal response for output: %w", err)
+		}
+		_, err = w.Write(viewBytes)
+		return err
 	}
 
 	viewBytes, err := json.Marshal(view)
@@ -87,7 +237,47 @@ func printServiceLogResponse(response *slv1.ClustersClusterLogsListResponse) err
 		return fmt.Errorf("failed to marshal response for output: %w", err)
 	}
 
-	return dump.Pretty(os.Stdout, viewBytes)
+	return dump.Pretty(w, viewBytes)
+}
+
+func printServiceLogTable(entries []*LogEntryView, w io.Writer) error {
+	table := printer.NewTablePrinter(w, 20, 1, 3, ' ')
+	table.AddRow([]string{"CREATED AT", "SEVERITY", "SERVICE", "INTERNAL", "ID", "SUMMARY"})
+	for _, entry := range entries {
+		table.AddRow([]string{
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.Severity,
+			entry.ServiceName,
+			strconv.FormatBool(entry.InternalOnly),
+			entry.ID,
+			entry.Summary,
+		})
+	}
+	return table.Flush()
+}
+
+func printServiceLogCSV(entries []*LogEntryView, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"created_at", "severity", "service_name", "internal_only", "id", "username", "summary", "description", "doc_references"}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := writer.Write([]string{
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.Severity,
+			entry.ServiceName,
+			strconv.FormatBool(entry.InternalOnly),
+			entry.ID,
+			entry.Username,
+			entry.Summary,
+			entry.Description,
+			strings.Join(entry.DocReferences, " "),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
 }
 
 type LogEntryResponseView struct {
@@ -0,0 +1,147 @@
+package servicelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFilter(t *testing.T) {
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	newEntry := func(severity slv1.Severity, serviceName string, createdAt time.Time, internal bool, summary string) *slv1.LogEntry {
+		entry, err := slv1.NewLogEntry().Severity(severity).ServiceName(serviceName).CreatedAt(createdAt).
+			InternalOnly(internal).Summary(summary).Description("Cluster needs an upgrade").Build()
+		assert.NoError(t, err)
+		return entry
+	}
+	entry := newEntry(slv1.SeverityWarning, "SREManualAction", now.Add(-2*time.Hour), false, "Action required")
+
+	tests := []struct {
+		name     string
+		options  listCmdOptions
+		entry    *slv1.LogEntry
+		expected bool
+		wantErr  string
+	}{
+		{
+			name:     "no filters",
+			entry:    entry,
+			expected: true,
+		},
+		{
+			name:     "matching severity and service, case insensitive",
+			options:  listCmdOptions{severities: []string{"info", "warning"}, serviceNames: []string{"sremanualaction"}},
+			entry:    entry,
+			expected: true,
+		},
+		{
+			name:    "other severity",
+			options: listCmdOptions{severities: []string{"Error"}},
+			entry:   entry,
+		},
+		{
+			name:    "other service",
+			options: listCmdOptions{serviceNames: []string{"LimitedSupport"}},
+			entry:   entry,
+		},
+		{
+			name:     "within time range",
+			options:  listCmdOptions{since: "3h", until: "1h"},
+			entry:    entry,
+			expected: true,
+		},
+		{
+			name:    "before since",
+			options: listCmdOptions{since: "1h"},
+			entry:   entry,
+		},
+		{
+			name:    "after until",
+			options: listCmdOptions{until: "2024-01-31"},
+			entry:   entry,
+		},
+		{
+			name:     "search matches description",
+			options:  listCmdOptions{search: "UPGRADE"},
+			entry:    entry,
+			expected: true,
+		},
+		{
+			name:    "search doesn't match",
+			options: listCmdOptions{search: "network"},
+			entry:   entry,
+		},
+		{
+			name:    "customer only excludes internal",
+			options: listCmdOptions{customer: true},
+			entry:   newEntry(slv1.SeverityInfo, "SREManualAction", now, true, "INTERNAL ONLY"),
+		},
+		{
+			name:    "invalid severity",
+			options: listCmdOptions{severities: []string{"Critical"}},
+			wantErr: `invalid severity "Critical"`,
+		},
+		{
+			name:    "until before since",
+			options: listCmdOptions{since: "1h", until: "2h"},
+			wantErr: "--until cannot be before --since",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := tt.options.filter(now)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, filter.matches(tt.entry))
+		})
+	}
+}
+
+func TestPrintServiceLogCSV(t *testing.T) {
+	entries := []*LogEntryView{
+		{
+			CreatedAt:     time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC),
+			Severity:      "Warning",
+			ServiceName:   "SREManualAction",
+			ID:            "abc",
+			Username:      "sre",
+			Summary:       "Action required",
+			Description:   "Please, fix \"it\"",
+			DocReferences: []string{"https://docs.openshift.com/a", "https://docs.openshift.com/b"},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printServiceLogCSV(entries, out))
+	assert.Equal(t, `created_at,severity,service_name,internal_only,id,username,summary,description,doc_references
+2024-01-15T08:30:00Z,Warning,SREManualAction,false,abc,sre,Action required,"Please, fix ""it""",https://docs.openshift.com/a https://docs.openshift.com/b
+`, out.String())
+}
+
+func TestPrintServiceLogsCounts(t *testing.T) {
+	var entries []*slv1.LogEntry
+	for _, severity := range []slv1.Severity{slv1.SeverityInfo, slv1.SeverityWarning, slv1.SeverityWarning} {
+		entry, err := slv1.NewLogEntry().Severity(severity).Build()
+		assert.NoError(t, err)
+		entries = append(entries, entry)
+	}
+	filter, err := (&listCmdOptions{severities: []string{"Warning"}}).filter(time.Now())
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printServiceLogs(entries, filter, listOutputJSON, out))
+
+	var view LogEntryResponseView
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &view))
+	assert.Len(t, view.Items, 2)
+	assert.Equal(t, 2, view.Size)
+	assert.Equal(t, 2, view.Total)
+}
@@ -1,6 +1,7 @@
 package servicelog
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"k8s.io/utils/strings/slices"
@@ -28,6 +30,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
+	"golang.org/x/time/rate"
 )
 
 type PostCmdOptions struct {
@@ -45,12 +48,30 @@ type PostCmdOptions struct {
 	InternalOnly    bool
 	ClusterId       string
 
+	// Bulk post job state
+	jobFile     string
+	resume      string
+	retryFailed bool
+	concurrency int
+	rateLimit   float64
+	job         *servicelog.Job
+
+	// Duplicate detection
+	dedupeWindow    time.Duration
+	allowDuplicates bool
+
 	// Messaged clusters
 	successfulClusters map[string]string
 	failedClusters     map[string]string
+	skippedClusters    map[string]string
+	resultsMutex       *sync.Mutex
 }
 
-const documentationBaseURL = "https://docs.openshift.com"
+const (
+	documentationBaseURL = "https://docs.openshift.com"
+	defaultConcurrency   = 1
+	defaultRateLimit     = 5
+)
 
 func newPostCmd() *cobra.Command {
 	var opts = PostCmdOptions{}
@@ -76,6 +97,15 @@ func newPostCmd() *cobra.Command {
   # Post a service log to a group of clusters, determined by an OCM query
   ocm list cluster -p search="cloud_provider.id is 'gcp' and managed='true' and state is 'ready'"
   osdctl servicelog post -q "cloud_provider.id is 'gcp' and managed='true' and state is 'ready'" -t file.json
+
+  # Post to a large group of clusters in parallel, persisting the progress to a job file
+  osdctl servicelog post -q "cloud_provider.id is 'gcp'" -t file.json --job-file gcp-post.json --concurrency 5 --rate-limit 10
+
+  # Resume an interrupted bulk post
+  osdctl servicelog post --resume gcp-post.json
+
+  # Skip clusters which received the same service log in the last 3 days without prompting
+  osdctl servicelog post -q "cloud_provider.id is 'gcp'" -t file.json --dedupe-window 72h -y
 `,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -94,6 +124,13 @@ func newPostCmd() *cobra.Command {
 	postCmd.Flags().StringArrayVarP(&opts.filterFiles, "query-file", "f", []string{}, "File containing search queries to apply. All lines in the file will be concatenated into a single query. If this flag is called multiple times, every file's search query will be combined with logical AND.")
 	postCmd.Flags().StringVarP(&opts.clustersFile, "clusters-file", "c", "", `Read a list of clusters to post the servicelog to. the format of the file is: {"clusters":["$CLUSTERID"]}`)
 	postCmd.Flags().BoolVarP(&opts.InternalOnly, "internal", "i", false, "Internal only service log. Use MESSAGE for template parameter (eg. -p MESSAGE='My super secret message').")
+	postCmd.Flags().StringVar(&opts.jobFile, "job-file", "", "File to persist the state of a bulk post to. Defaults to servicelog-post-<timestamp>.json in the current directory when posting to more than one cluster. The results are appended to <job-file>.results until the post ends.")
+	postCmd.Flags().StringVar(&opts.resume, "resume", "", "Resume the bulk post persisted in the given job file, sending the service log to all clusters still pending")
+	postCmd.Flags().BoolVar(&opts.retryFailed, "retry-failed", false, "When resuming a bulk post, also retry the clusters the service log failed to be sent to")
+	postCmd.Flags().IntVar(&opts.concurrency, "concurrency", defaultConcurrency, "Number of service logs to post in parallel")
+	postCmd.Flags().Float64Var(&opts.rateLimit, "rate-limit", defaultRateLimit, "Maximum number of service logs to post per second, 0 disables rate limiting")
+	postCmd.Flags().DurationVar(&opts.dedupeWindow, "dedupe-window", defaultDedupeWindow, "Look for an identical service log (same severity, summary and description) sent within this window before posting. Clusters with a duplicate are skipped when prompts are skipped, 0 disables the check")
+	postCmd.Flags().BoolVar(&opts.allowDuplicates, "allow-duplicates", false, "Post the service log even to clusters which already received an identical one within the dedupe window")
 
 	return postCmd
 }
@@ -103,13 +140,37 @@ func (o *PostCmdOptions) Init() error {
 	userParameterValues = []string{}
 	o.successfulClusters = make(map[string]string)
 	o.failedClusters = make(map[string]string)
+	o.skippedClusters = make(map[string]string)
+	o.resultsMutex = &sync.Mutex{}
 	return nil
 }
 
 func (o *PostCmdOptions) Validate() error {
-	if o.ClusterId == "" && len(o.filterParams) == 0 && o.clustersFile == "" {
+	if o.resume != "" {
+		if o.ClusterId != "" || len(o.filterParams) != 0 || len(o.filterFiles) != 0 || o.clustersFile != "" {
+			return fmt.Errorf("--resume cannot be combined with --cluster-id, -q, -f or -c, the clusters are read from the job file")
+		}
+		if o.Template != "" || len(o.TemplateParams) != 0 || len(o.Overrides) != 0 || o.InternalOnly {
+			return fmt.Errorf("--resume cannot be combined with -t, -p, -r or -i, the message is read from the job file")
+		}
+		if o.jobFile != "" {
+			return fmt.Errorf("--resume cannot be combined with --job-file, the job file given to --resume is updated")
+		}
+	} else if o.ClusterId == "" && len(o.filterParams) == 0 && o.clustersFile == "" {
 		return fmt.Errorf("no cluster identifier has been found, please specify --cluster-id, -q, or -c")
 	}
+	if o.retryFailed && o.resume == "" {
+		return fmt.Errorf("--retry-failed can only be used with --resume")
+	}
+	if o.concurrency < 0 {
+		return fmt.Errorf("--concurrency cannot be negative")
+	}
+	if o.rateLimit < 0 {
+		return fmt.Errorf("--rate-limit cannot be negative")
+	}
+	if o.dedupeWindow < 0 {
+		return fmt.Errorf("--dedupe-window cannot be negative")
+	}
 	return nil
 }
 
@@ -138,6 +199,147 @@ func (o *PostCmdOptions) Run() error {
 		return err
 	}
 
+	if o.resume != "" {
+		if err := o.loadJob(); err != nil {
+			return err
+		}
+		if len(o.ClustersFile.Clusters) == 0 {
+			log.Infof("All clusters of the job %s have been processed, nothing to resume", o.resume)
+			return nil
+		}
+	} else {
+		o.prepareMessage()
+	}
+
+	// Create an OCM client to talk to the cluster API
+	// the user has to be logged in (e.g. 'ocm login')
+	ocmClient, err := ocmutils.CreateConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := ocmClient.Close(); err != nil {
+			log.Errorf("Cannot close the ocmClient (possible memory leak): %q", err)
+		}
+	}()
+
+	if err := o.buildFilters(); err != nil {
+		return err
+	}
+
+	clusters, err := ocmutils.ApplyFilters(ocmClient, o.filterParams)
+	if err != nil {
+		return fmt.Errorf("failed to search for clusters with provided filters (%v): %v", o.filterParams, err)
+	}
+	if o.resume != "" {
+		o.skipUnmatchedJobClusters(clusters)
+	}
+	if len(clusters) < 1 {
+		return fmt.Errorf("no clusters match the given filters (%v)", o.filterParams)
+	}
+
+	log.Infoln("The following clusters match the given parameters:")
+	if err := o.printClusters(clusters); err != nil {
+		return fmt.Errorf("could not print matching clusters: %v", err)
+	}
+
+	// If sending a service log to one cluster, print recent service logs so that we can verify we aren't sending
+	// duplicate messages in quick succession
+	if len(clusters) == 1 {
+		if term.IsTerminal(int(os.Stdout.Fd())) && CheckServiceLogsLastHour(clusters[0].ID()) {
+			if !ocmutils.ConfirmPrompt() {
+				return nil
+			}
+		}
+	}
+
+	// Skip the clusters which already received the same service log recently,
+	// also on dry-runs so they show which clusters would be skipped
+	clustersToCheck, skippedClusters := o.filterDuplicates(ocmClient, clusters)
+
+	log.Infoln("The following template will be sent:")
+	if err := o.printTemplate(); err != nil {
+		return fmt.Errorf("cannot read generated template: %w", err)
+	}
+
+	// If this is a dry-run, don't proceed further.
+	if o.isDryRun {
+		return nil
+	}
+
+	if !o.skipPrompts {
+		if !ocmutils.ConfirmPrompt() {
+			return nil
+		}
+	}
+
+	// cluster type for which documentation link is provided in servicelog description
+	docClusterType := getDocClusterType(o.Message.Description)
+
+	// Verify the documentation links before sending anything, so the prompts
+	// aren't interleaved with the parallel posts
+	var clustersToPost []*v1.Cluster
+	for _, cluster := range clustersToCheck {
+		// if servicelog description contains a documentation link, verify that
+		// documentation link matches the cluster product (rosa, dedicated)
+		if !o.skipPrompts && docClusterType != "" {
+			clusterType := cluster.Product().ID()
+
+			if docClusterType != clusterType {
+				log.Warn("The documentation mentioned in the servicelog is for '", docClusterType, "' while the product is '", clusterType, "'.")
+				if !ocmutils.ConfirmPrompt() {
+					log.Info("Skipping cluster ID: ", cluster.ID(), ", Name: ", cluster.Name())
+					o.recordSkipped(cluster, "skipped on user request")
+					skippedClusters = append(skippedClusters, cluster)
+					continue
+				}
+			}
+		}
+		clustersToPost = append(clustersToPost, cluster)
+	}
+
+	if err := o.prepareJob(clusters); err != nil {
+		return err
+	}
+	for _, cluster := range skippedClusters {
+		o.updateJob(cluster, servicelog.JobClusterSkipped, "", o.skippedClusters[cluster.ExternalID()])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handler if the program terminates abruptly, stop posting and wait for the in-flight requests
+	sigchan := make(chan os.Signal, 1)
+	signal.Notify(sigchan, os.Interrupt)
+	defer signal.Stop(sigchan)
+	go func() {
+		select {
+		case <-sigchan:
+			log.Error("program abruptly terminated, waiting for in-flight service logs before cleaning up...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	o.postToClusters(ctx, ocmClient, clustersToPost)
+	o.saveJob()
+
+	if ctx.Err() != nil {
+		// perform final cleanup actions
+		o.cleanUp(clustersToPost)
+		if o.job != nil {
+			log.Infof("To continue the post, run: osdctl servicelog post --resume %s", o.job.Path())
+		}
+		return fmt.Errorf("servicelog post command terminated")
+	}
+
+	o.printPostOutput()
+	return nil
+}
+
+// prepareMessage reads the template and filter files, and replaces all
+// parameters and overrides in them
+func (o *PostCmdOptions) prepareMessage() {
 	o.parseUserParameters()                // parse all the '-p' user flags
 	overrideMap, err := o.parseOverrides() // parse all the '-o' flags
 	if err != nil {
@@ -163,19 +365,11 @@ func (o *PostCmdOptions) Run() error {
 	// Check if there are any remaining placeholders in the template that are not replaced by a parameter,
 	// excluding '${CLUSTER_UUID}' which will be replaced for each cluster later
 	o.checkLeftovers([]string{"${CLUSTER_UUID}"})
+}
 
-	// Create an OCM client to talk to the cluster API
-	// the user has to be logged in (e.g. 'ocm login')
-	ocmClient, err := ocmutils.CreateConnection()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := ocmClient.Close(); err != nil {
-			log.Errorf("Cannot close the ocmClient (possible memory leak): %q", err)
-		}
-	}()
-
+// buildFilters combines the search queries, filter files and cluster
+// identifiers into the OCM filters used to find the clusters to post to
+func (o *PostCmdOptions) buildFilters() error {
 	// Merge OCM filters from all custom filter-related flags
 	if o.filtersFromFile != "" {
 		if len(o.filterParams) != 0 {
@@ -187,7 +381,12 @@ func (o *PostCmdOptions) Run() error {
 
 	// Combine existing OCM filters with any cluster id-related flags
 	var queries []string
-	if o.clustersFile != "" {
+	if o.clustersFile == "" {
+		// the clusters were set up from a job file
+		for _, cluster := range o.ClustersFile.Clusters {
+			queries = append(queries, ocmutils.GenerateQuery(cluster))
+		}
+	} else {
 		contents, err := o.accessFile(o.clustersFile)
 		if err != nil {
 			return fmt.Errorf("cannot read file %s: %w", o.clustersFile, err)
@@ -214,93 +413,163 @@ func (o *PostCmdOptions) Run() error {
 		log.Debugf("applied filters: %v", o.filterParams)
 	}
 
-	clusters, err := ocmutils.ApplyFilters(ocmClient, o.filterParams)
-	if err != nil {
-		return fmt.Errorf("failed to search for clusters with provided filters (%v): %v", o.filterParams, err)
-	} else if len(clusters) < 1 {
-		return fmt.Errorf("no clusters match the given filters (%v)", o.filterParams)
+	return nil
+}
+
+// postToClusters posts the service log to all clusters, honoring the
+// configured concurrency and rate limit. Posting stops when ctx is cancelled.
+func (o *PostCmdOptions) postToClusters(ctx context.Context, ocmClient *sdk.Connection, clusters []*v1.Cluster) {
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if o.rateLimit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(o.rateLimit), 1)
 	}
 
-	log.Infoln("The following clusters match the given parameters:")
-	if err := o.printClusters(clusters); err != nil {
-		return fmt.Errorf("could not print matching clusters: %v", err)
+	concurrency := o.concurrency
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
 	}
+	semaphore := make(chan struct{}, concurrency)
 
-	// If sending a service log to one cluster, print recent service logs so that we can verify we aren't sending
-	// duplicate messages in quick succession
-	if len(clusters) == 1 {
-		if term.IsTerminal(int(os.Stdout.Fd())) && CheckServiceLogsLastHour(clusters[0].ID()) {
-			if !ocmutils.ConfirmPrompt() {
-				return nil
-			}
+	var wg sync.WaitGroup
+	for _, cluster := range clusters {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
 		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := limiter.Wait(ctx); err != nil {
+				return
+			}
+			o.postToCluster(ocmClient, cluster)
+		}()
 	}
+	wg.Wait()
+}
 
-	log.Infoln("The following template will be sent:")
-	if err := o.printTemplate(); err != nil {
-		return fmt.Errorf("cannot read generated template: %w", err)
+// postToCluster posts the service log to a single cluster and records the result
+func (o *PostCmdOptions) postToCluster(ocmClient *sdk.Connection, cluster *v1.Cluster) {
+	clusterMessage := o.clusterMessage(cluster)
+
+	request, err := o.createPostRequest(ocmClient, clusterMessage)
+	if err != nil {
+		o.recordFailure(cluster, err.Error())
+		return
 	}
 
-	// If this is a dry-run, don't proceed further.
-	if o.isDryRun {
-		return nil
+	response, err := ocmutils.SendRequest(request)
+	if err != nil {
+		o.recordFailure(cluster, err.Error())
+		return
 	}
 
-	if !o.skipPrompts {
-		if !ocmutils.ConfirmPrompt() {
-			return nil
-		}
+	goodReply, err := o.check(response, clusterMessage)
+	if err != nil {
+		o.updateJob(cluster, servicelog.JobClusterFailed, "", err.Error())
+		return
 	}
+	o.updateJob(cluster, servicelog.JobClusterSent, goodReply.ID, "")
+}
 
-	// Handler if the program terminates abruptly
-	go func() {
-		sigchan := make(chan os.Signal, 1)
-		signal.Notify(sigchan, os.Interrupt)
-		<-sigchan
+func (o *PostCmdOptions) recordFailure(cluster *v1.Cluster, reason string) {
+	o.resultsMutex.Lock()
+	o.failedClusters[cluster.ExternalID()] = reason
+	o.resultsMutex.Unlock()
 
-		// perform final cleanup actions
-		log.Error("program abruptly terminated, performing clean-up...")
-		o.cleanUp(clusters)
-		log.Fatal("servicelog post command terminated")
-	}()
+	o.updateJob(cluster, servicelog.JobClusterFailed, "", reason)
+}
 
-	// cluster type for which documentation link is provided in servicelog description
-	docClusterType := getDocClusterType(o.Message.Description)
+// loadJob reads the job to resume, and sets up the message and clusters from it
+func (o *PostCmdOptions) loadJob() error {
+	job, err := servicelog.LoadJob(o.resume)
+	if err != nil {
+		return err
+	}
 
-	for _, cluster := range clusters {
-		request, err := o.createPostRequest(ocmClient, cluster)
-		if err != nil {
-			o.failedClusters[cluster.ExternalID()] = err.Error()
-			continue
-		}
+	o.job = job
+	o.Message = job.Message
+	o.InternalOnly = job.Message.InternalOnly
 
-		// if servicelog description contains a documentation link, verify that
-		// documentation link matches the cluster product (rosa, dedicated)
-		if !o.skipPrompts && docClusterType != "" {
-			clusterType := cluster.Product().ID()
+	statuses := []servicelog.JobClusterStatus{servicelog.JobClusterPending}
+	if o.retryFailed {
+		statuses = append(statuses, servicelog.JobClusterFailed)
+	}
+	o.ClustersFile.Clusters = job.ClusterIDs(statuses...)
 
-			if docClusterType != clusterType {
-				log.Warn("The documentation mentioned in the servicelog is for '", docClusterType, "' while the product is '", clusterType, "'.")
-				if !ocmutils.ConfirmPrompt() {
-					log.Info("Skipping cluster ID: ", cluster.ID(), ", Name: ", cluster.Name())
-					continue
-				}
-			}
-		}
+	counts := job.Counts()
+	log.Infof("Resuming job %s: %d sent, %d failed, %d skipped, %d pending", o.resume,
+		counts[servicelog.JobClusterSent], counts[servicelog.JobClusterFailed], counts[servicelog.JobClusterSkipped], counts[servicelog.JobClusterPending])
 
-		response, err := ocmutils.SendRequest(request)
-		if err != nil {
-			o.failedClusters[cluster.ExternalID()] = err.Error()
-			continue
+	return nil
+}
+
+// skipUnmatchedJobClusters marks the pending clusters of the resumed job which don't match
+// the query anymore as skipped, otherwise they would stay pending in the job file forever
+func (o *PostCmdOptions) skipUnmatchedJobClusters(clusters []*v1.Cluster) {
+	var matchedIDs []string
+	for _, cluster := range clusters {
+		matchedIDs = append(matchedIDs, cluster.ID())
+	}
+	skipped, err := o.job.SkipUnmatched(matchedIDs, "cluster no longer matches, it might have been deleted")
+	if err != nil {
+		log.Errorf("Cannot update job file: %v", err)
+	}
+	if len(skipped) > 0 {
+		log.Warnf("Skipping %d clusters of the job which no longer match: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+}
+
+// prepareJob creates the job file for bulk posts, so they can be resumed if interrupted
+func (o *PostCmdOptions) prepareJob(clusters []*v1.Cluster) error {
+	if o.job != nil {
+		return nil
+	}
+	if o.jobFile == "" {
+		if len(clusters) < 2 {
+			return nil
 		}
+		o.jobFile = fmt.Sprintf("servicelog-post-%s.json", time.Now().Format("20060102-150405"))
+	}
 
-		o.check(response, o.Message)
+	o.job = servicelog.NewJob(o.jobFile, o.Message)
+	for _, cluster := range clusters {
+		o.job.AddCluster(cluster.ID(), cluster.ExternalID())
 	}
+	if err := o.job.Save(); err != nil {
+		return fmt.Errorf("cannot create job file: %w", err)
+	}
+	log.Infof("The progress of this post is persisted to %s", o.jobFile)
 
-	o.printPostOutput()
 	return nil
 }
 
+// updateJob records the state of a cluster in the job file, if there is one
+func (o *PostCmdOptions) updateJob(cluster *v1.Cluster, status servicelog.JobClusterStatus, serviceLogID string, reason string) {
+	if o.job == nil {
+		return
+	}
+	if err := o.job.Update(cluster.ID(), status, serviceLogID, reason); err != nil {
+		log.Errorf("Cannot update job file: %v", err)
+	}
+}
+
+// saveJob merges the cluster results into the job file, if there is one
+func (o *PostCmdOptions) saveJob() {
+	if o.job == nil {
+		return
+	}
+	if err := o.job.Save(); err != nil {
+		log.Errorf("Cannot save job file: %v", err)
+	}
+}
+
 // if servicelog description contains documentation link, parse and return the cluster type from the url
 func getDocClusterType(message string) string {
 
@@ -323,23 +592,29 @@ func getDocClusterType(message string) string {
 	return ""
 }
 
-func (o *PostCmdOptions) check(response *sdk.Response, clusterMessage servicelog.Message) {
+// check validates the response of a post and records the result for the cluster
+func (o *PostCmdOptions) check(response *sdk.Response, clusterMessage servicelog.Message) (*servicelog.GoodReply, error) {
+	o.resultsMutex.Lock()
+	defer o.resultsMutex.Unlock()
+
 	body := response.Bytes()
 	if response.Status() < 400 {
-		_, err := validateGoodResponse(body, clusterMessage)
-		if err != nil {
-			o.failedClusters[clusterMessage.ClusterUUID] = err.Error()
-		} else {
-			o.successfulClusters[clusterMessage.ClusterUUID] = fmt.Sprintf("Message has been successfully sent to %s", clusterMessage.ClusterUUID)
-		}
-	} else {
-		badReply, err := validateBadResponse(body)
+		goodReply, err := validateGoodResponse(body, clusterMessage)
 		if err != nil {
 			o.failedClusters[clusterMessage.ClusterUUID] = err.Error()
-		} else {
-			o.failedClusters[clusterMessage.ClusterUUID] = badReply.Reason
+			return nil, err
 		}
+		o.successfulClusters[clusterMessage.ClusterUUID] = fmt.Sprintf("Message has been successfully sent to %s", clusterMessage.ClusterUUID)
+		return goodReply, nil
+	}
+
+	badReply, err := validateBadResponse(body)
+	if err != nil {
+		o.failedClusters[clusterMessage.ClusterUUID] = err.Error()
+		return nil, err
 	}
+	o.failedClusters[clusterMessage.ClusterUUID] = badReply.Reason
+	return nil, errors.New(badReply.Reason)
 }
 
 // parseUserParameters parse all the '-p FOO=BAR' parameters and checks for syntax errors
@@ -423,7 +698,11 @@ func (o *PostCmdOptions) overrideField(overrideKey string, overrideValue string)
 
 // accessFile returns the contents of a local file or url, and any errors encountered
 func (o *PostCmdOptions) accessFile(filePath string) ([]byte, error) {
+	return readFileOrURL(filePath)
+}
 
+// readFileOrURL returns the contents of a local file or url, and any errors encountered
+func readFileOrURL(filePath string) ([]byte, error) {
 	if utils.IsValidUrl(filePath) {
 		urlPage, _ := url.Parse(filePath)
 		if err := utils.IsOnline(*urlPage); err != nil {
@@ -593,7 +872,19 @@ func (o *PostCmdOptions) printTemplate() (err error) {
 	return dump.Pretty(os.Stdout, exampleMessage)
 }
 
-func (o *PostCmdOptions) createPostRequest(ocmClient *sdk.Connection, cluster *v1.Cluster) (request *sdk.Request, err error) {
+// clusterMessage returns a copy of the message addressed to the given cluster
+func (o *PostCmdOptions) clusterMessage(cluster *v1.Cluster) servicelog.Message {
+	message := o.Message
+	message.ClusterUUID = cluster.ExternalID()
+	message.ClusterID = cluster.ID()
+	message.InternalOnly = o.InternalOnly
+	if subscription := cluster.Subscription(); subscription != nil {
+		message.SubscriptionID = cluster.Subscription().ID()
+	}
+	return message
+}
+
+func (o *PostCmdOptions) createPostRequest(ocmClient *sdk.Connection, message servicelog.Message) (request *sdk.Request, err error) {
 	// Create and populate the request:
 	request = ocmClient.Post()
 	err = arguments.ApplyPathArg(request, targetAPIPath)
@@ -601,14 +892,7 @@ func (o *PostCmdOptions) createPostRequest(ocmClient *sdk.Connection, cluster *v
 		return nil, fmt.Errorf("cannot parse API path '%s': %v", targetAPIPath, err)
 	}
 
-	o.Message.ClusterUUID = cluster.ExternalID()
-	o.Message.ClusterID = cluster.ID()
-	o.Message.InternalOnly = o.InternalOnly
-	if subscription := cluster.Subscription(); subscription != nil {
-		o.Message.SubscriptionID = cluster.Subscription().ID()
-	}
-
-	messageBytes, err := json.Marshal(o.Message)
+	messageBytes, err := json.Marshal(message)
 	if err != nil {
 		return nil, fmt.Errorf("cannot marshal template to json: %v", err)
 	}
@@ -634,7 +918,7 @@ func (o *PostCmdOptions) listMessagedClusters(clusters map[string]string) error
 
 // printPostOutput prints the main servicelog post output.
 func (o *PostCmdOptions) printPostOutput() {
-	output := fmt.Sprintf("Success: %d, Failed: %d\n", len(o.successfulClusters), len(o.failedClusters))
+	output := fmt.Sprintf("Success: %d, Failed: %d, Skipped: %d\n", len(o.successfulClusters), len(o.failedClusters), len(o.skippedClusters))
 	log.Infoln(output + "\n")
 
 	// Print if any service logs were successfully sent
@@ -652,6 +936,14 @@ func (o *PostCmdOptions) printPostOutput() {
 			log.Fatalf("Cannot list failed clusters: %q", err)
 		}
 	}
+
+	// Print if clusters were skipped, e.g. because they already received the service log
+	if len(o.skippedClusters) > 0 {
+		log.Infoln("Skipped clusters:")
+		if err := o.listMessagedClusters(o.skippedClusters); err != nil {
+			log.Fatalf("Cannot list skipped clusters: %q", err)
+		}
+	}
 }
 
 // cleanUp performs final actions in case of program termination.
@@ -1,15 +1,22 @@
 package servicelog
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	sdk "github.com/openshift-online/ocm-sdk-go"
 	v1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
 	"github.com/openshift/osdctl/internal/servicelog"
 	"github.com/stretchr/testify/assert"
 )
@@ -666,3 +673,252 @@ func TestPrintPostOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateBulkPostOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     *PostCmdOptions
+		expectedErr string
+	}{
+		{
+			name:    "resume on its own",
+			options: &PostCmdOptions{resume: "job.json", retryFailed: true},
+		},
+		{
+			name:        "resume with a cluster",
+			options:     &PostCmdOptions{resume: "job.json", ClusterId: "cluster-1"},
+			expectedErr: "--resume cannot be combined with --cluster-id, -q, -f or -c, the clusters are read from the job file",
+		},
+		{
+			name:        "resume with a template",
+			options:     &PostCmdOptions{resume: "job.json", Template: "template.json"},
+			expectedErr: "--resume cannot be combined with -t, -p, -r or -i, the message is read from the job file",
+		},
+		{
+			name:        "retry failed without resume",
+			options:     &PostCmdOptions{ClusterId: "cluster-1", retryFailed: true},
+			expectedErr: "--retry-failed can only be used with --resume",
+		},
+		{
+			name:        "negative rate limit",
+			options:     &PostCmdOptions{ClusterId: "cluster-1", rateLimit: -1},
+			expectedErr: "--rate-limit cannot be negative",
+		},
+		{
+			name:        "negative dedupe window",
+			options:     &PostCmdOptions{ClusterId: "cluster-1", dedupeWindow: -time.Hour},
+			expectedErr: "--dedupe-window cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestLoadJob(t *testing.T) {
+	jobFile := t.TempDir() + "/job.json"
+	job := servicelog.NewJob(jobFile, servicelog.Message{Summary: "Job summary", InternalOnly: true})
+	job.AddCluster("cluster-1", "uuid-1")
+	job.AddCluster("cluster-2", "uuid-2")
+	job.AddCluster("cluster-3", "uuid-3")
+	assert.NoError(t, job.Save())
+	assert.NoError(t, job.Update("cluster-1", servicelog.JobClusterSent, "sl-1", ""))
+	assert.NoError(t, job.Update("cluster-2", servicelog.JobClusterFailed, "", "boom"))
+	assert.Error(t, job.Update("cluster-4", servicelog.JobClusterSent, "", ""))
+
+	options := &PostCmdOptions{resume: jobFile}
+	assert.NoError(t, options.loadJob())
+	assert.Equal(t, "Job summary", options.Message.Summary)
+	assert.True(t, options.InternalOnly)
+	assert.Equal(t, []string{"cluster-3"}, options.ClustersFile.Clusters)
+
+	options = &PostCmdOptions{resume: jobFile, retryFailed: true}
+	assert.NoError(t, options.loadJob())
+	assert.Equal(t, []string{"cluster-2", "cluster-3"}, options.ClustersFile.Clusters)
+}
+
+func TestSkipUnmatchedJobClusters(t *testing.T) {
+	jobFile := t.TempDir() + "/job.json"
+	job := servicelog.NewJob(jobFile, servicelog.Message{Summary: "Job summary"})
+	job.AddCluster("cluster-1", "uuid-1")
+	job.AddCluster("cluster-2", "uuid-2")
+	assert.NoError(t, job.Save())
+
+	options := &PostCmdOptions{resume: jobFile}
+	assert.NoError(t, options.loadJob())
+	cluster, err := v1.NewCluster().ID("cluster-2").ExternalID("uuid-2").Build()
+	assert.NoError(t, err)
+	options.skipUnmatchedJobClusters([]*v1.Cluster{cluster})
+
+	// The deleted cluster isn't resumed again
+	options = &PostCmdOptions{resume: jobFile}
+	assert.NoError(t, options.loadJob())
+	assert.Equal(t, []string{"cluster-2"}, options.ClustersFile.Clusters)
+	assert.Equal(t, []string{"cluster-1"}, options.job.ClusterIDs(servicelog.JobClusterSkipped))
+}
+
+// newTestConnection returns an OCM connection to a server answering the API requests with handler
+func newTestConnection(t *testing.T, handler http.HandlerFunc) *sdk.Connection {
+	// The SDK only parses the access token, so it doesn't have to be signed
+	claims := fmt.Sprintf(`{"typ": "Bearer", "exp": %d}`, time.Now().Add(time.Hour).Unix())
+	token := base64.RawURLEncoding.EncodeToString([]byte(`{"alg": "none", "typ": "JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims)) + "."
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			_, _ = fmt.Fprintf(w, `{"access_token": %q, "token_type": "Bearer"}`, token)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	ocmClient, err := sdk.NewConnectionBuilder().
+		URL(server.URL).
+		TokenURL(server.URL+"/token").
+		Insecure(true).
+		Client("client-id", "client-secret").
+		Build()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = ocmClient.Close() })
+
+	return ocmClient
+}
+
+// newServiceLogServer returns an OCM connection to a server accepting all posted service logs,
+// and the number of posts it received as well as the highest number of parallel ones
+func newServiceLogServer(t *testing.T) (*sdk.Connection, *atomic.Int32, *atomic.Int32) {
+	var posts, inFlight, maxInFlight atomic.Int32
+	ocmClient := newTestConnection(t, func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := maxInFlight.Load()
+			if current <= highest || maxInFlight.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		reply := map[string]any{}
+		if err := json.NewDecoder(r.Body).Decode(&reply); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reply["id"] = fmt.Sprintf("sl-%d", posts.Add(1))
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(reply)
+	})
+
+	return ocmClient, &posts, &maxInFlight
+}
+
+func TestPostToClusters(t *testing.T) {
+	var clusters []*v1.Cluster
+	for i := 1; i <= 6; i++ {
+		cluster, err := v1.NewCluster().ID(fmt.Sprintf("cluster-%d", i)).ExternalID(fmt.Sprintf("uuid-%d", i)).Build()
+		assert.NoError(t, err)
+		clusters = append(clusters, cluster)
+	}
+	newOptions := func(concurrency int, rateLimit float64) *PostCmdOptions {
+		options := &PostCmdOptions{
+			Message:     servicelog.Message{Severity: "Info", ServiceName: "SREManualAction", Summary: "Summary", Description: "Description"},
+			concurrency: concurrency,
+			rateLimit:   rateLimit,
+		}
+		assert.NoError(t, options.Init())
+		options.jobFile = t.TempDir() + "/job.json"
+		assert.NoError(t, options.prepareJob(clusters))
+		return options
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		ocmClient, posts, maxInFlight := newServiceLogServer(t)
+		options := newOptions(3, 0)
+
+		options.postToClusters(context.Background(), ocmClient, clusters)
+
+		assert.Equal(t, int32(6), posts.Load())
+		assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+		assert.Greater(t, maxInFlight.Load(), int32(1))
+		assert.Len(t, options.successfulClusters, 6)
+		assert.Empty(t, options.failedClusters)
+		job, err := servicelog.LoadJob(options.job.Path())
+		assert.NoError(t, err)
+		assert.Equal(t, 6, job.Counts()[servicelog.JobClusterSent])
+		assert.Len(t, job.ServiceLogIDs(), 6)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		ocmClient, posts, _ := newServiceLogServer(t)
+		options := newOptions(6, 20)
+
+		start := time.Now()
+		options.postToClusters(context.Background(), ocmClient, clusters)
+
+		// The first post is sent right away, the other five wait 50ms each for their turn
+		assert.GreaterOrEqual(t, time.Since(start), 240*time.Millisecond)
+		assert.Equal(t, int32(6), posts.Load())
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ocmClient, posts, _ := newServiceLogServer(t)
+		options := newOptions(1, 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		options.postToClusters(ctx, ocmClient, clusters)
+
+		assert.Zero(t, posts.Load())
+		assert.Equal(t, 6, options.job.Counts()[servicelog.JobClusterPending])
+	})
+}
+
+func TestIsDuplicateServiceLog(t *testing.T) {
+	message := servicelog.Message{
+		Severity:    "Warning",
+		Summary:     "Action required",
+		Description: "Please fix the cluster",
+	}
+
+	tests := []struct {
+		name     string
+		entry    *slv1.LogEntryBuilder
+		expected bool
+	}{
+		{
+			name:     "identical",
+			entry:    slv1.NewLogEntry().Severity(slv1.SeverityWarning).Summary("Action required").Description("Please fix the cluster\n"),
+			expected: true,
+		},
+		{
+			name:  "different severity",
+			entry: slv1.NewLogEntry().Severity(slv1.SeverityInfo).Summary("Action required").Description("Please fix the cluster"),
+		},
+		{
+			name:  "different summary",
+			entry: slv1.NewLogEntry().Severity(slv1.SeverityWarning).Summary("Action resolved").Description("Please fix the cluster"),
+		},
+		{
+			name:  "different description",
+			entry: slv1.NewLogEntry().Severity(slv1.SeverityWarning).Summary("Action required").Description("The cluster is fixed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := tt.entry.Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, isDuplicateServiceLog(message, entry))
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package servicelog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	"github.com/openshift/osdctl/internal/servicelog"
+	"github.com/openshift/osdctl/pkg/printer"
+	ocmutils "github.com/openshift/osdctl/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type retractCmdOptions struct {
+	PostCmdOptions
+
+	fromJob        string
+	summary        string
+	since          string
+	deleteInternal bool
+
+	// serviceLogIDs are the service logs to retract by cluster ID, when retracting a job
+	serviceLogIDs map[string]string
+	sinceTime     time.Time
+	correct       bool
+}
+
+// retractMatch is a service log to retract, and the cluster it was sent to
+type retractMatch struct {
+	cluster *cmv1.Cluster
+	entry   *slv1.LogEntry
+}
+
+func newRetractCmd() *cobra.Command {
+	opts := &retractCmdOptions{}
+	retractCmd := &cobra.Command{
+		Use:   "retract (--from-job <job-file> | --summary <summary> [--cluster-id <cluster-identifier> | -q <query> | -c <clusters-file>])",
+		Short: "Retract a service log sent to a cluster or list of clusters",
+		Long: `Retract a service log sent to a cluster or list of clusters.
+
+The service logs to retract are either the ones sent by a previous bulk post,
+read from its job file, or the ones with the given summary on the clusters
+matching the given query. The matching service logs are listed, and a
+correction service log is posted to the affected clusters. Internal only
+service logs can be deleted instead, customer facing ones cannot and are
+skipped when deleting without a correction.`,
+		Example: `
+  # List the service logs sent by a bulk post and post a correction to the affected clusters
+  osdctl servicelog retract --from-job servicelog-post-20240131-120000.json -t correction.json
+
+  # Post a correction to all clusters which received a service log with the given summary in the last day
+  osdctl servicelog retract -q "cloud_provider.id is 'gcp'" --summary "Action required: upgrade" --since 24h -t correction.json -p REASON="sent by mistake"
+
+  # Delete an internal only service log sent by mistake
+  osdctl servicelog retract --cluster-id ${CLUSTER_ID} --summary "INTERNAL ONLY, DO NOT SHARE WITH CUSTOMER" --since 1h --delete-internal
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.run()
+		},
+	}
+
+	retractCmd.Flags().StringVar(&opts.fromJob, "from-job", "", "Job file of a previous bulk post, the service logs it sent are retracted")
+	retractCmd.Flags().StringVar(&opts.summary, "summary", "", "Summary of the service logs to retract")
+	retractCmd.Flags().StringVar(&opts.since, "since", "", "Only retract service logs created after this time, either a duration (eg. 24h) or a date (eg. 2024-01-31 or 2024-01-31T12:00:00Z)")
+	retractCmd.Flags().BoolVar(&opts.deleteInternal, "delete-internal", false, "Delete the matching internal only service logs instead of posting a correction for them")
+	retractCmd.Flags().StringVarP(&opts.ClusterId, "cluster-id", "C", "", "Internal ID of the cluster to retract the service log from")
+	retractCmd.Flags().StringArrayVarP(&opts.filterParams, "query", "q", []string{}, "Specify a search query (eg. -q \"name like foo\") for the clusters to retract the service log from.")
+	retractCmd.Flags().StringArrayVarP(&opts.filterFiles, "query-file", "f", []string{}, "File containing search queries to apply. All lines in the file will be concatenated into a single query. If this flag is called multiple times, every file's search query will be combined with logical AND.")
+	retractCmd.Flags().StringVarP(&opts.clustersFile, "clusters-file", "c", "", `Read a list of clusters to retract the servicelog from. the format of the file is: {"clusters":["$CLUSTERID"]}`)
+	retractCmd.Flags().StringVarP(&opts.Template, "template", "t", "", "Correction message template file or URL")
+	retractCmd.Flags().StringArrayVarP(&opts.TemplateParams, "param", "p", opts.TemplateParams, "Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the correction template.")
+	retractCmd.Flags().StringArrayVarP(&opts.Overrides, "override", "r", opts.Overrides, "Specify a key-value pair (eg. -r FOO=BAR) to replace a JSON key in the correction template, only supports string fields.")
+	retractCmd.Flags().BoolVarP(&opts.InternalOnly, "internal", "i", false, "Post an internal only correction. Use MESSAGE for template parameter (eg. -p MESSAGE='My super secret message').")
+	retractCmd.Flags().BoolVarP(&opts.isDryRun, "dry-run", "d", false, "Dry-run - list the matching service logs and print the correction, but don't change anything.")
+	retractCmd.Flags().BoolVarP(&opts.skipPrompts, "yes", "y", false, "Skips all prompts.")
+	retractCmd.Flags().StringVar(&opts.jobFile, "job-file", "", "File to persist the state of the correction post to. Defaults to servicelog-post-<timestamp>.json in the current directory when posting to more than one cluster.")
+	retractCmd.Flags().IntVar(&opts.concurrency, "concurrency", defaultConcurrency, "Number of service logs to look up, delete and post in parallel")
+	retractCmd.Flags().Float64Var(&opts.rateLimit, "rate-limit", defaultRateLimit, "Maximum number of correction service logs to post per second, 0 disables rate limiting")
+	retractCmd.Flags().DurationVar(&opts.dedupeWindow, "dedupe-window", defaultDedupeWindow, "Skip clusters which already received the correction within this window, 0 disables the check")
+
+	return retractCmd
+}
+
+func (o *retractCmdOptions) validate() error {
+	if o.fromJob != "" {
+		if o.ClusterId != "" || len(o.filterParams) != 0 || len(o.filterFiles) != 0 || o.clustersFile != "" {
+			return fmt.Errorf("--from-job cannot be combined with --cluster-id, -q, -f or -c, the clusters are read from the job file")
+		}
+		if o.summary != "" || o.since != "" {
+			return fmt.Errorf("--from-job cannot be combined with --summary or --since, the service logs are read from the job file")
+		}
+	} else {
+		if o.ClusterId == "" && len(o.filterParams) == 0 && len(o.filterFiles) == 0 && o.clustersFile == "" {
+			return fmt.Errorf("no cluster identifier has been found, please specify --from-job, --cluster-id, -q, -f or -c")
+		}
+		if o.summary == "" {
+			return fmt.Errorf("--summary is required to find the service logs to retract, unless --from-job is used")
+		}
+	}
+
+	o.correct = o.Template != "" || o.InternalOnly || len(o.Overrides) != 0
+	if !o.correct && !o.deleteInternal {
+		return fmt.Errorf("nothing to do, please specify a correction with -t, -i or -r, or use --delete-internal")
+	}
+	if !o.correct && len(o.TemplateParams) != 0 && len(o.filterFiles) == 0 {
+		return fmt.Errorf("-p can only be used with a correction template or query file")
+	}
+	if o.concurrency < 0 {
+		return fmt.Errorf("--concurrency cannot be negative")
+	}
+	if o.rateLimit < 0 {
+		return fmt.Errorf("--rate-limit cannot be negative")
+	}
+	if o.dedupeWindow < 0 {
+		return fmt.Errorf("--dedupe-window cannot be negative")
+	}
+
+	if o.since != "" {
+		since, err := ocmutils.ParseTime(o.since, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		o.sinceTime = since
+	}
+
+	return nil
+}
+
+func (o *retractCmdOptions) run() error {
+	if err := o.Init(); err != nil {
+		return err
+	}
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	if o.correct {
+		o.prepareMessage()
+	} else {
+		o.parseUserParameters()
+		o.readFilterFile()
+		for k := range userParameterNames {
+			o.replaceFlags(userParameterNames[k], userParameterValues[k])
+		}
+	}
+
+	if o.fromJob != "" {
+		if err := o.loadRetractJob(); err != nil {
+			return err
+		}
+	}
+
+	ocmClient, err := ocmutils.CreateConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := ocmClient.Close(); err != nil {
+			log.Errorf("Cannot close the ocmClient (possible memory leak): %q", err)
+		}
+	}()
+
+	if err := o.buildFilters(); err != nil {
+		return err
+	}
+	clusters, err := ocmutils.ApplyFilters(ocmClient, o.filterParams)
+	if err != nil {
+		return fmt.Errorf("failed to search for clusters with provided filters (%v): %v", o.filterParams, err)
+	} else if len(clusters) < 1 {
+		return fmt.Errorf("no clusters match the given filters (%v)", o.filterParams)
+	}
+
+	matches, err := o.findMatches(ocmClient, clusters)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		log.Infoln("No matching service logs found, nothing to retract")
+		return nil
+	}
+
+	log.Infoln("The following service logs will be retracted:")
+	if err := printRetractMatches(matches, o.deleteInternal, o.correct); err != nil {
+		return fmt.Errorf("could not print matching service logs: %v", err)
+	}
+
+	clustersToCorrect := clustersToCorrect(matches, o.deleteInternal)
+	var skippedClusters []*cmv1.Cluster
+	if o.correct && len(clustersToCorrect) > 0 {
+		clustersToCorrect, skippedClusters = o.filterDuplicates(ocmClient, clustersToCorrect)
+		log.Infof("The following correction will be sent to %d clusters:", len(clustersToCorrect))
+		if err := o.printTemplate(); err != nil {
+			return fmt.Errorf("cannot read generated template: %w", err)
+		}
+	}
+	if !o.correct && len(clustersToCorrect) > 0 {
+		log.Warnf("Customer facing service logs cannot be deleted, skipping them on %d clusters. Use -t, -i or -r to post a correction instead", len(clustersToCorrect))
+	}
+
+	if o.isDryRun {
+		return nil
+	}
+	if !o.skipPrompts {
+		if !ocmutils.ConfirmPrompt() {
+			return nil
+		}
+	}
+
+	var deleteErr error
+	if o.deleteInternal {
+		deleteErr = o.deleteInternalServiceLogs(ocmClient, matches)
+	}
+
+	if !o.correct || len(clustersToCorrect)+len(skippedClusters) == 0 {
+		return deleteErr
+	}
+
+	if err := o.prepareJob(append(clustersToCorrect, skippedClusters...)); err != nil {
+		return err
+	}
+	for _, cluster := range skippedClusters {
+		o.updateJob(cluster, servicelog.JobClusterSkipped, "", o.skippedClusters[cluster.ExternalID()])
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	o.postToClusters(ctx, ocmClient, clustersToCorrect)
+
+	if ctx.Err() != nil {
+		o.cleanUp(clustersToCorrect)
+		if o.job != nil {
+			log.Infof("To continue posting the correction, run: osdctl servicelog post --resume %s", o.job.Path())
+		}
+		return fmt.Errorf("servicelog retract command terminated")
+	}
+
+	o.printPostOutput()
+	return deleteErr
+}
+
+// loadRetractJob reads the service logs sent by a previous bulk post
+func (o *retractCmdOptions) loadRetractJob() error {
+	job, err := servicelog.LoadJob(o.fromJob)
+	if err != nil {
+		return err
+	}
+
+	o.serviceLogIDs = job.ServiceLogIDs()
+	if len(o.serviceLogIDs) == 0 {
+		return fmt.Errorf("the job %s didn't send any service logs, nothing to retract", o.fromJob)
+	}
+
+	o.ClustersFile.Clusters = nil
+	for clusterID := range o.serviceLogIDs {
+		o.ClustersFile.Clusters = append(o.ClustersFile.Clusters, clusterID)
+	}
+	sort.Strings(o.ClustersFile.Clusters)
+
+	return nil
+}
+
+// matches returns true if the service log sent to the cluster should be retracted
+func (o *retractCmdOptions) matches(cluster *cmv1.Cluster, entry *slv1.LogEntry) bool {
+	if o.serviceLogIDs != nil {
+		return o.serviceLogIDs[cluster.ID()] == entry.ID()
+	}
+	if !o.sinceTime.IsZero() && entry.CreatedAt().Before(o.sinceTime) {
+		return false
+	}
+	return entry.Summary() == o.summary
+}
+
+// findMatches lists the service logs of all clusters, in parallel, and
+// returns the ones to retract in the order of the clusters
+func (o *retractCmdOptions) findMatches(ocmClient *sdk.Connection, clusters []*cmv1.Cluster) ([]retractMatch, error) {
+	concurrency := o.concurrency
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	clusterMatches := make([][]retractMatch, len(clusters))
+	errs := make([]error, len(clusters))
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			entries, err := o.clusterLogs(ocmClient, cluster)
+			if err != nil {
+				errs[i] = fmt.Errorf("cluster %s: %w", cluster.ID(), err)
+				return
+			}
+			for _, entry := range entries {
+				if o.matches(cluster, entry) {
+					clusterMatches[i] = append(clusterMatches[i], retractMatch{cluster: cluster, entry: entry})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var matches []retractMatch
+	for i := range clusters {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("cannot list service logs: %w", errs[i])
+		}
+		matches = append(matches, clusterMatches[i]...)
+	}
+	return matches, nil
+}
+
+// clusterLogs returns the service logs of the cluster which may have to be retracted. The ones
+// recorded in a job are fetched directly instead of listing the whole history of the cluster.
+func (o *retractCmdOptions) clusterLogs(ocmClient *sdk.Connection, cluster *cmv1.Cluster) ([]*slv1.LogEntry, error) {
+	if o.serviceLogIDs == nil {
+		return listAllClusterLogs(context.Background(), ocmClient, cluster, o.sinceTime, true, false)
+	}
+
+	id, ok := o.serviceLogIDs[cluster.ID()]
+	if !ok {
+		return nil, nil
+	}
+	response, err := ocmClient.ServiceLogs().V1().ClusterLogs().LogEntry(id).Get().Send()
+	if err != nil {
+		// The service log was already retracted
+		if response != nil && response.Status() == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch service log %s: %w", id, err)
+	}
+	return []*slv1.LogEntry{response.Body()}, nil
+}
+
+// clustersToCorrect returns the clusters which need a correction, ie. all
+// clusters with a matching service log which isn't going to be deleted
+func clustersToCorrect(matches []retractMatch, deleteInternal bool) []*cmv1.Cluster {
+	var clusters []*cmv1.Cluster
+	seen := map[string]bool{}
+	for _, match := range matches {
+		if deleteInternal && match.entry.InternalOnly() {
+			continue
+		}
+		if seen[match.cluster.ID()] {
+			continue
+		}
+		seen[match.cluster.ID()] = true
+		clusters = append(clusters, match.cluster)
+	}
+	return clusters
+}
+
+// retractAction returns what is done with a matching service log: internal only ones are
+// deleted with --delete-internal, the others are corrected, or skipped without a correction
+func retractAction(entry *slv1.LogEntry, deleteInternal bool, correct bool) string {
+	if deleteInternal && entry.InternalOnly() {
+		return "delete"
+	}
+	if !correct {
+		return "skip"
+	}
+	return "correct"
+}
+
+func printRetractMatches(matches []retractMatch, deleteInternal bool, correct bool) error {
+	table := printer.NewTablePrinter(os.Stdout, 20, 1, 3, ' ')
+	table.AddRow([]string{"Cluster ID", "Name", "Service Log ID", "Created At", "Internal", "Action", "Summary"})
+	for _, match := range matches {
+		action := retractAction(match.entry, deleteInternal, correct)
+		table.AddRow([]string{
+			match.cluster.ID(),
+			match.cluster.Name(),
+			match.entry.ID(),
+			match.entry.CreatedAt().Format(time.RFC3339),
+			strconv.FormatBool(match.entry.InternalOnly()),
+			action,
+			match.entry.Summary(),
+		})
+	}
+
+	// Add empty row for readability
+	table.AddRow([]string{})
+	return table.Flush()
+}
+
+// deleteInternalServiceLogs deletes the matching internal only service logs, in parallel.
+// The service logs API doesn't allow deleting customer facing ones.
+func (o *retractCmdOptions) deleteInternalServiceLogs(ocmClient *sdk.Connection, matches []retractMatch) error {
+	concurrency := o.concurrency
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	failed := 0
+	for _, match := range matches {
+		if !match.entry.InternalOnly() {
+			continue
+		}
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			_, err := ocmClient.ServiceLogs().V1().ClusterLogs().LogEntry(match.entry.ID()).Delete().Send()
+			if err != nil {
+				log.Errorf("Cannot delete service log %s from cluster %s: %v", match.entry.ID(), match.cluster.ID(), err)
+				mutex.Lock()
+				failed++
+				mutex.Unlock()
+				return
+			}
+			log.Infof("Deleted service log %s from cluster %s", match.entry.ID(), match.cluster.ID())
+		}()
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d service logs", failed)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package servicelog
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	"github.com/openshift/osdctl/internal/servicelog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRetractOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     *retractCmdOptions
+		expectedErr string
+	}{
+		{
+			name:    "from job with a correction",
+			options: &retractCmdOptions{fromJob: "job.json", PostCmdOptions: PostCmdOptions{Template: "correction.json"}},
+		},
+		{
+			name:    "query and summary, deleting internal service logs",
+			options: &retractCmdOptions{summary: "Wrong summary", since: "24h", deleteInternal: true, PostCmdOptions: PostCmdOptions{filterParams: []string{"name like foo"}}},
+		},
+		{
+			name:        "from job with a cluster",
+			options:     &retractCmdOptions{fromJob: "job.json", PostCmdOptions: PostCmdOptions{ClusterId: "cluster-1", Template: "correction.json"}},
+			expectedErr: "--from-job cannot be combined with --cluster-id, -q, -f or -c, the clusters are read from the job file",
+		},
+		{
+			name:        "from job with a summary",
+			options:     &retractCmdOptions{fromJob: "job.json", summary: "Wrong summary", PostCmdOptions: PostCmdOptions{Template: "correction.json"}},
+			expectedErr: "--from-job cannot be combined with --summary or --since, the service logs are read from the job file",
+		},
+		{
+			name:        "no clusters",
+			options:     &retractCmdOptions{summary: "Wrong summary", PostCmdOptions: PostCmdOptions{Template: "correction.json"}},
+			expectedErr: "no cluster identifier has been found, please specify --from-job, --cluster-id, -q, -f or -c",
+		},
+		{
+			name:        "no summary",
+			options:     &retractCmdOptions{PostCmdOptions: PostCmdOptions{ClusterId: "cluster-1", Template: "correction.json"}},
+			expectedErr: "--summary is required to find the service logs to retract, unless --from-job is used",
+		},
+		{
+			name:        "no correction",
+			options:     &retractCmdOptions{fromJob: "job.json"},
+			expectedErr: "nothing to do, please specify a correction with -t, -i or -r, or use --delete-internal",
+		},
+		{
+			name:        "invalid since",
+			options:     &retractCmdOptions{summary: "Wrong summary", since: "yesterday", PostCmdOptions: PostCmdOptions{ClusterId: "cluster-1", Template: "correction.json"}},
+			expectedErr: `invalid --since: "yesterday" is neither a duration (eg. 2h) nor a date (eg. 2024-01-31 or 2024-01-31T12:00:00Z)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.validate()
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRetractMatches(t *testing.T) {
+	cluster1, _ := cmv1.NewCluster().ID("cluster-1").Build()
+	cluster2, _ := cmv1.NewCluster().ID("cluster-2").Build()
+	recent, _ := slv1.NewLogEntry().ID("log-1").Summary("Wrong summary").CreatedAt(time.Now()).Build()
+	old, _ := slv1.NewLogEntry().ID("log-2").Summary("Wrong summary").CreatedAt(time.Now().Add(-48 * time.Hour)).Build()
+	other, _ := slv1.NewLogEntry().ID("log-3").Summary("Other summary").CreatedAt(time.Now()).Build()
+
+	bySummary := &retractCmdOptions{summary: "Wrong summary", sinceTime: time.Now().Add(-24 * time.Hour)}
+	assert.True(t, bySummary.matches(cluster1, recent))
+	assert.False(t, bySummary.matches(cluster1, old))
+	assert.False(t, bySummary.matches(cluster1, other))
+
+	byJob := &retractCmdOptions{serviceLogIDs: map[string]string{"cluster-1": "log-3"}}
+	assert.True(t, byJob.matches(cluster1, other))
+	assert.False(t, byJob.matches(cluster1, recent))
+	assert.False(t, byJob.matches(cluster2, other))
+}
+
+func TestClustersToCorrect(t *testing.T) {
+	cluster1, _ := cmv1.NewCluster().ID("cluster-1").Build()
+	cluster2, _ := cmv1.NewCluster().ID("cluster-2").Build()
+	external, _ := slv1.NewLogEntry().ID("log-1").Build()
+	internal, _ := slv1.NewLogEntry().ID("log-2").InternalOnly(true).Build()
+
+	matches := []retractMatch{
+		{cluster: cluster1, entry: external},
+		{cluster: cluster1, entry: internal},
+		{cluster: cluster2, entry: internal},
+	}
+
+	assert.Equal(t, []*cmv1.Cluster{cluster1, cluster2}, clustersToCorrect(matches, false))
+	assert.Equal(t, []*cmv1.Cluster{cluster1}, clustersToCorrect(matches, true))
+}
+
+func TestRetractAction(t *testing.T) {
+	external, _ := slv1.NewLogEntry().ID("log-1").Build()
+	internal, _ := slv1.NewLogEntry().ID("log-2").InternalOnly(true).Build()
+
+	assert.Equal(t, "correct", retractAction(external, false, true))
+	assert.Equal(t, "correct", retractAction(internal, false, true))
+	assert.Equal(t, "correct", retractAction(external, true, true))
+	assert.Equal(t, "delete", retractAction(internal, true, true))
+	assert.Equal(t, "skip", retractAction(external, true, false))
+	assert.Equal(t, "delete", retractAction(internal, true, false))
+}
+
+func TestLoadRetractJob(t *testing.T) {
+	jobFile := t.TempDir() + "/job.json"
+	job := servicelog.NewJob(jobFile, servicelog.Message{Summary: "Wrong summary"})
+	job.AddCluster("cluster-1", "uuid-1")
+	job.AddCluster("cluster-2", "uuid-2")
+	job.AddCluster("cluster-3", "uuid-3")
+	assert.NoError(t, job.Save())
+	assert.NoError(t, job.Update("cluster-2", servicelog.JobClusterSent, "log-2", ""))
+	assert.NoError(t, job.Update("cluster-3", servicelog.JobClusterFailed, "", "boom"))
+
+	o := &retractCmdOptions{fromJob: jobFile}
+	assert.NoError(t, o.loadRetractJob())
+	assert.Equal(t, map[string]string{"cluster-2": "log-2"}, o.serviceLogIDs)
+	assert.Equal(t, []string{"cluster-2"}, o.ClustersFile.Clusters)
+
+	assert.NoError(t, job.Update("cluster-2", servicelog.JobClusterFailed, "", "boom"))
+	assert.EqualError(t, (&retractCmdOptions{fromJob: jobFile}).loadRetractJob(), "the job "+jobFile+" didn't send any service logs, nothing to retract")
+}
+
+func TestFindMatchesFromJob(t *testing.T) {
+	var paths []string
+	var mu sync.Mutex
+	ocmClient := newTestConnection(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/api/service_logs/v1/cluster_logs/log-1":
+			_, _ = fmt.Fprint(w, `{"kind": "ClusterLog", "id": "log-1", "summary": "Wrong summary"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"kind": "Error", "id": "404", "reason": "Not found"}`)
+		}
+	})
+
+	var clusters []*cmv1.Cluster
+	for _, id := range []string{"cluster-1", "cluster-2", "cluster-3"} {
+		cluster, _ := cmv1.NewCluster().ID(id).Build()
+		clusters = append(clusters, cluster)
+	}
+
+	// Only the recorded service logs are fetched, the one already retracted is skipped
+	o := &retractCmdOptions{serviceLogIDs: map[string]string{"cluster-1": "log-1", "cluster-2": "log-2"}}
+	matches, err := o.findMatches(ocmClient, clusters)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "cluster-1", matches[0].cluster.ID())
+	assert.Equal(t, "log-1", matches[0].entry.ID())
+	assert.ElementsMatch(t, []string{"/api/service_logs/v1/cluster_logs/log-1", "/api/service_logs/v1/cluster_logs/log-2"}, paths)
+}
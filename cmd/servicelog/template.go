@@ -0,0 +1,167 @@
+package servicelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/openshift-online/ocm-cli/pkg/dump"
+	"github.com/openshift/osdctl/internal/servicelog"
+	"github.com/openshift/osdctl/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+type templateLintOptions struct {
+	params       []string
+	skipURLCheck bool
+	strict       bool
+}
+
+type templateRenderOptions struct {
+	params []string
+}
+
+func newTemplateCmd() *cobra.Command {
+	templateCmd := &cobra.Command{
+		Use:   "template",
+		Short: "Check and render service log templates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	templateCmd.AddCommand(newTemplateLintCmd())
+	templateCmd.AddCommand(newTemplateRenderCmd())
+
+	return templateCmd
+}
+
+func newTemplateLintCmd() *cobra.Command {
+	opts := &templateLintOptions{}
+	cmd := &cobra.Command{
+		Use:   "lint <file|url>...",
+		Short: "Check service log templates for common mistakes",
+		Long: `Check service log templates for common mistakes.
+
+The templates are checked for unknown fields, missing required fields, invalid
+severities, overlong summaries, internal_only consistency, malformed
+placeholders and unreachable documentation references. Placeholders without a
+value are reported as warnings, or as errors along with the unused parameters
+when parameters are given.`,
+		Example: `  # Lint a local template
+  osdctl servicelog template lint template.json
+
+  # Lint a template and check it works with the given parameters
+  osdctl servicelog template lint https://raw.githubusercontent.com/openshift/managed-notifications/master/osd/aws/InstallFailed_NoRouteToInternet.json -p REASON=foo
+
+  # Lint all templates in a directory, failing on warnings as well
+  osdctl servicelog template lint --strict --skip-url-check templates/*.json`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.run(args, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&opts.params, "param", "p", nil, "Specify a key-value pair (eg. -p FOO=BAR) the template is expected to be used with")
+	cmd.Flags().BoolVar(&opts.skipURLCheck, "skip-url-check", false, "Don't check if the doc_references are reachable")
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "Treat warnings as errors")
+
+	return cmd
+}
+
+func (o *templateLintOptions) run(templates []string, out io.Writer) error {
+	linter := &servicelog.TemplateLinter{}
+	if !o.skipURLCheck {
+		linter.CheckURL = utils.IsOnline
+	}
+	if len(o.params) > 0 {
+		params, err := servicelog.ParseTemplateParams(o.params)
+		if err != nil {
+			return err
+		}
+		linter.Params = params
+	}
+
+	failed := 0
+	for _, template := range templates {
+		content, err := readFileOrURL(template)
+		if err != nil {
+			fmt.Fprintf(out, "%s: error: %v\n", template, err)
+			failed++
+			continue
+		}
+
+		findings := linter.Lint(content)
+		if lintFailed(findings, o.strict) {
+			failed++
+		}
+		if len(findings) == 0 {
+			fmt.Fprintf(out, "%s: ok\n", template)
+			continue
+		}
+		for _, finding := range findings {
+			fmt.Fprintf(out, "%s: %s\n", template, finding)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d templates failed linting", failed, len(templates))
+	}
+	return nil
+}
+
+// lintFailed returns true if the findings contain errors, or warnings in strict mode
+func lintFailed(findings []servicelog.LintFinding, strict bool) bool {
+	for _, finding := range findings {
+		if finding.Level == servicelog.LintError || strict {
+			return true
+		}
+	}
+	return false
+}
+
+func newTemplateRenderCmd() *cobra.Command {
+	opts := &templateRenderOptions{}
+	cmd := &cobra.Command{
+		Use:   "render <file|url>",
+		Short: "Print a service log template with all parameters substituted",
+		Example: `  # Render a template with its parameters
+  osdctl servicelog template render template.json -p REASON=foo -p NAMESPACE=bar`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.run(args[0], cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&opts.params, "param", "p", nil, "Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template")
+
+	return cmd
+}
+
+func (o *templateRenderOptions) run(template string, out io.Writer) error {
+	params, err := servicelog.ParseTemplateParams(o.params)
+	if err != nil {
+		return err
+	}
+
+	content, err := readFileOrURL(template)
+	if err != nil {
+		return err
+	}
+
+	message := servicelog.Message{}
+	if err := json.Unmarshal(content, &message); err != nil {
+		return fmt.Errorf("cannot parse the template %s: %w", template, err)
+	}
+
+	if err := servicelog.RenderTemplate(&message, params); err != nil {
+		return err
+	}
+
+	rendered, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return dump.Pretty(out, rendered)
+}
@@ -0,0 +1,172 @@
+package servicelog
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/osdctl/internal/servicelog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateLinter(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   map[string]string
+		checkURL func(url.URL) error
+		expected []string
+	}{
+		{
+			name: "valid template",
+			template: `{
+				"severity": "Info",
+				"service_name": "SREManualAction",
+				"summary": "Action required: ${REASON}",
+				"description": "Your cluster ${CLUSTER_UUID} needs attention because of ${REASON}.",
+				"doc_references": ["https://docs.openshift.com/"],
+				"internal_only": false
+			}`,
+			params: map[string]string{"REASON": "foo"},
+		},
+		{
+			name:     "invalid json",
+			template: `{"severity": "Info"`,
+			expected: []string{"error: invalid template: unexpected end of JSON input"},
+		},
+		{
+			name: "missing fields and invalid severity",
+			template: `{
+				"severity": "Critical",
+				"service_name": "SREManualAction",
+				"summary": "",
+				"desc": "typo"
+			}`,
+			expected: []string{
+				`warning: unknown field "desc"`,
+				"error: summary: required field is missing or empty",
+				"error: description: required field is missing or empty",
+				`error: severity: invalid severity "Critical", valid severities are Debug, Info, Warning, Error, Fatal`,
+			},
+		},
+		{
+			name: "summary too long",
+			template: `{
+				"severity": "Info",
+				"service_name": "SREManualAction",
+				"summary": "` + strings.Repeat("a", servicelog.MaxSummaryLength+1) + `",
+				"description": "description"
+			}`,
+			expected: []string{"error: summary: summary is 256 characters long, the maximum is 255"},
+		},
+		{
+			name: "internal only inconsistency",
+			template: `{
+				"severity": "Info",
+				"service_name": "SREManualAction",
+				"summary": "INTERNAL ONLY, DO NOT SHARE WITH CUSTOMER",
+				"description": "description"
+			}`,
+			expected: []string{"warning: summary: the text looks internal, but internal_only is not set"},
+		},
+		{
+			name: "placeholders",
+			template: `{
+				"severity": "Info",
+				"service_name": "SREManualAction",
+				"summary": "Summary ${reason}",
+				"description": "Description ${NAMESPACE} ${UNTERMINATED"
+			}`,
+			params: map[string]string{"UNUSED": "foo"},
+			expected: []string{
+				`error: summary: malformed placeholder "${reason}", placeholders must look like ${UPPER_CASE}`,
+				"error: description: unterminated placeholder, placeholders must look like ${UPPER_CASE}",
+				"error: placeholder ${NAMESPACE} has no parameter, use -p NAMESPACE=VALUE",
+				"error: parameter UNUSED is not used by the template",
+			},
+		},
+		{
+			name: "placeholders without parameters",
+			template: `{
+				"severity": "Info",
+				"service_name": "SREManualAction",
+				"summary": "Summary ${REASON}",
+				"description": "Cluster ${CLUSTER_UUID} in ${NAMESPACE}"
+			}`,
+			expected: []string{
+				"warning: placeholder ${NAMESPACE} has no parameter, use -p NAMESPACE=VALUE",
+				"warning: placeholder ${REASON} has no parameter, use -p REASON=VALUE",
+			},
+		},
+		{
+			name: "doc references",
+			template: `{
+				"severity": "Info",
+				"service_name": "SREManualAction",
+				"summary": "Summary",
+				"description": "Description",
+				"doc_references": ["http://example.com", "https://unreachable.example.com"]
+			}`,
+			checkURL: func(u url.URL) error {
+				return errors.New("connection refused")
+			},
+			expected: []string{
+				`error: doc_references: "http://example.com" is not a valid https URL`,
+				"error: doc_references: https://unreachable.example.com is not reachable: connection refused",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linter := &servicelog.TemplateLinter{CheckURL: tt.checkURL, Params: tt.params}
+
+			var findings []string
+			for _, finding := range linter.Lint([]byte(tt.template)) {
+				findings = append(findings, finding.String())
+			}
+
+			assert.Equal(t, tt.expected, findings)
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	newMessage := func() *servicelog.Message {
+		return &servicelog.Message{
+			Summary:     "Summary ${REASON}",
+			Description: "Cluster ${CLUSTER_UUID} in ${NAMESPACE}",
+		}
+	}
+
+	message := newMessage()
+	assert.NoError(t, servicelog.RenderTemplate(message, map[string]string{"REASON": "foo", "NAMESPACE": "bar"}))
+	assert.Equal(t, "Summary foo", message.Summary)
+	assert.Equal(t, "Cluster ${CLUSTER_UUID} in bar", message.Description)
+
+	assert.EqualError(t, servicelog.RenderTemplate(newMessage(), map[string]string{"REASON": "foo"}), "the template is using parameters without a value: ${NAMESPACE}")
+	assert.EqualError(t, servicelog.RenderTemplate(newMessage(), map[string]string{"REASON": "foo", "NAMESPACE": "bar", "OTHER": "baz"}), "the template is not using the ${OTHER} parameter")
+}
+
+func TestTemplateLintCommand(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	warning := filepath.Join(dir, "warning.json")
+	assert.NoError(t, os.WriteFile(valid, []byte(`{"severity": "Info", "service_name": "SREManualAction", "summary": "Summary", "description": "Description"}`), 0600))
+	assert.NoError(t, os.WriteFile(warning, []byte(`{"severity": "Info", "service_name": "SREManualAction", "summary": "Summary", "description": "Description", "extra": true}`), 0600))
+
+	out := &bytes.Buffer{}
+	opts := &templateLintOptions{skipURLCheck: true}
+	assert.NoError(t, opts.run([]string{valid, warning}, out))
+	assert.Equal(t, valid+": ok\n"+warning+": warning: unknown field \"extra\"\n", out.String())
+
+	opts.strict = true
+	assert.EqualError(t, opts.run([]string{valid, warning}, &bytes.Buffer{}), "1 of 2 templates failed linting")
+
+	opts.params = []string{"broken"}
+	assert.Error(t, opts.run([]string{valid}, &bytes.Buffer{}))
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/openshift/osdctl/pkg/osdctlConfig"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -52,6 +53,7 @@ func NewCmdSetup() *cobra.Command {
 				JiraToken,
 				CloudTrailCmdLists,
 				GitLabToken,
+				osdctlConfig.LimitedSupportTemplatesDir,
 			}
 
 			values := make(map[string]string)
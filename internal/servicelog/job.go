@@ -0,0 +1,290 @@
+package servicelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JobVersion is the version of the job file format
+const JobVersion = 1
+
+// JobClusterStatus is the state of a single cluster within a bulk post job
+type JobClusterStatus string
+
+const (
+	JobClusterPending JobClusterStatus = "pending"
+	JobClusterSent    JobClusterStatus = "sent"
+	JobClusterFailed  JobClusterStatus = "failed"
+	JobClusterSkipped JobClusterStatus = "skipped"
+)
+
+// JobCluster records the outcome of posting the service log to one cluster
+type JobCluster struct {
+	ID           string           `json:"id"`
+	ExternalID   string           `json:"external_id"`
+	Status       JobClusterStatus `json:"status"`
+	Error        string           `json:"error,omitempty"`
+	ServiceLogID string           `json:"service_log_id,omitempty"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// Job is the persisted state of a bulk service log post, so an interrupted post can be
+// resumed. Rewriting the whole file after every cluster would be quadratic in the number
+// of clusters, so the updates are appended to a results log next to it instead, one JSON
+// line per cluster, and only merged into the job file by Save.
+type Job struct {
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Message   Message       `json:"message"`
+	Clusters  []*JobCluster `json:"clusters"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// NewJob creates an empty job posting message, persisted to path
+func NewJob(path string, message Message) *Job {
+	now := time.Now().UTC()
+	return &Job{
+		Version:   JobVersion,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Message:   message,
+		path:      path,
+	}
+}
+
+// AddCluster adds a cluster to the job in pending state
+func (j *Job) AddCluster(id string, externalID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.Clusters = append(j.Clusters, &JobCluster{
+		ID:         id,
+		ExternalID: externalID,
+		Status:     JobClusterPending,
+		UpdatedAt:  time.Now().UTC(),
+	})
+}
+
+// LoadJob reads a job previously written by Save, along with the updates appended since
+func LoadJob(path string) (*Job, error) {
+	content, err := os.ReadFile(filepath.Clean(path)) //#nosec G304 -- Potential file inclusion via variable
+	if err != nil {
+		return nil, fmt.Errorf("cannot read job file %s: %w", path, err)
+	}
+
+	job := &Job{}
+	if err := json.Unmarshal(content, job); err != nil {
+		return nil, fmt.Errorf("cannot parse job file %s: %w", path, err)
+	}
+	if job.Version != JobVersion {
+		return nil, fmt.Errorf("unsupported job file version %d in %s, expected %d", job.Version, path, JobVersion)
+	}
+	job.path = path
+
+	if err := job.applyResults(); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Path returns the file the job is persisted to
+func (j *Job) Path() string {
+	return j.path
+}
+
+// resultsPath returns the file the updates since the last Save are appended to
+func (j *Job) resultsPath() string {
+	return j.path + ".results"
+}
+
+// applyResults applies the updates of the results log to the clusters of the job
+func (j *Job) applyResults() error {
+	content, err := os.ReadFile(filepath.Clean(j.resultsPath())) //#nosec G304 -- Potential file inclusion via variable
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read job results %s: %w", j.resultsPath(), err)
+	}
+
+	clusters := map[string]*JobCluster{}
+	for _, cluster := range j.Clusters {
+		clusters[cluster.ID] = cluster
+	}
+
+	// A last line without newline was cut off while being appended, its cluster is
+	// still in its previous state
+	lines := bytes.Split(content, []byte("\n"))
+	for i, line := range lines[:len(lines)-1] {
+		result := &JobCluster{}
+		if err := json.Unmarshal(line, result); err != nil {
+			return fmt.Errorf("cannot parse line %d of job results %s: %w", i+1, j.resultsPath(), err)
+		}
+		cluster, ok := clusters[result.ID]
+		if !ok {
+			return fmt.Errorf("cluster %s of job results %s is not part of the job", result.ID, j.resultsPath())
+		}
+		*cluster = *result
+		if cluster.UpdatedAt.After(j.UpdatedAt) {
+			j.UpdatedAt = cluster.UpdatedAt
+		}
+	}
+	return nil
+}
+
+// appendResults appends the state of the clusters to the results log
+func (j *Job) appendResults(clusters ...*JobCluster) error {
+	var content []byte
+	for _, cluster := range clusters {
+		line, err := json.Marshal(cluster)
+		if err != nil {
+			return fmt.Errorf("cannot marshal job cluster %s: %w", cluster.ID, err)
+		}
+		content = append(append(content, line...), '\n')
+	}
+
+	file, err := os.OpenFile(j.resultsPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot open job results %s: %w", j.resultsPath(), err)
+	}
+	if _, err := file.Write(content); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("cannot write job results %s: %w", j.resultsPath(), err)
+	}
+	return file.Close()
+}
+
+// Save persists the job and merges the results log into it. The file is replaced
+// atomically so a crash while writing doesn't corrupt the previous state.
+func (j *Job) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.save()
+}
+
+func (j *Job) save() error {
+	content, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal job: %w", err)
+	}
+
+	tmpFile := j.path + ".tmp"
+	if err := os.WriteFile(tmpFile, content, 0600); err != nil {
+		return fmt.Errorf("cannot write job file %s: %w", tmpFile, err)
+	}
+	if err := os.Rename(tmpFile, j.path); err != nil {
+		return err
+	}
+
+	// Replaying the results onto the saved state wouldn't change it, so a crash before
+	// they are removed is harmless
+	if err := os.Remove(j.resultsPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot remove job results %s: %w", j.resultsPath(), err)
+	}
+	return nil
+}
+
+// Update sets the state of a cluster in the job and appends it to the results log
+func (j *Job) Update(clusterID string, status JobClusterStatus, serviceLogID string, errMsg string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, cluster := range j.Clusters {
+		if cluster.ID != clusterID {
+			continue
+		}
+		cluster.Status = status
+		cluster.ServiceLogID = serviceLogID
+		cluster.Error = errMsg
+		cluster.UpdatedAt = time.Now().UTC()
+		j.UpdatedAt = cluster.UpdatedAt
+		return j.appendResults(cluster)
+	}
+
+	return fmt.Errorf("cluster %s is not part of the job", clusterID)
+}
+
+// SkipUnmatched marks the pending clusters which are not in matchedIDs as skipped with the
+// given reason, eg. clusters deleted since the job was created. It returns their IDs.
+func (j *Job) SkipUnmatched(matchedIDs []string, reason string) ([]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	matched := map[string]bool{}
+	for _, id := range matchedIDs {
+		matched[id] = true
+	}
+
+	var skipped []string
+	var updated []*JobCluster
+	now := time.Now().UTC()
+	for _, cluster := range j.Clusters {
+		if cluster.Status != JobClusterPending || matched[cluster.ID] {
+			continue
+		}
+		cluster.Status = JobClusterSkipped
+		cluster.Error = reason
+		cluster.UpdatedAt = now
+		skipped = append(skipped, cluster.ID)
+		updated = append(updated, cluster)
+	}
+	if len(skipped) == 0 {
+		return nil, nil
+	}
+
+	j.UpdatedAt = now
+	return skipped, j.appendResults(updated...)
+}
+
+// ClusterIDs returns the IDs of the clusters in any of the given states
+func (j *Job) ClusterIDs(statuses ...JobClusterStatus) []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var ids []string
+	for _, cluster := range j.Clusters {
+		for _, status := range statuses {
+			if cluster.Status == status {
+				ids = append(ids, cluster.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// Counts returns the number of clusters per state
+func (j *Job) Counts() map[JobClusterStatus]int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	counts := map[JobClusterStatus]int{}
+	for _, cluster := range j.Clusters {
+		counts[cluster.Status]++
+	}
+	return counts
+}
+
+// ServiceLogIDs returns the IDs of the service logs sent by the job, by cluster ID
+func (j *Job) ServiceLogIDs() map[string]string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	ids := map[string]string{}
+	for _, cluster := range j.Clusters {
+		if cluster.Status == JobClusterSent && cluster.ServiceLogID != "" {
+			ids[cluster.ID] = cluster.ServiceLogID
+		}
+	}
+	return ids
+}
@@ -0,0 +1,113 @@
+package servicelog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestJob(t *testing.T) *Job {
+	job := NewJob(filepath.Join(t.TempDir(), "job.json"), Message{Summary: "Job summary"})
+	job.AddCluster("cluster-1", "uuid-1")
+	job.AddCluster("cluster-2", "uuid-2")
+	job.AddCluster("cluster-3", "uuid-3")
+	assert.NoError(t, job.Save())
+	return job
+}
+
+func TestJobRoundTrip(t *testing.T) {
+	job := newTestJob(t)
+	assert.NoError(t, job.Update("cluster-1", JobClusterSent, "sl-1", ""))
+	assert.NoError(t, job.Update("cluster-2", JobClusterFailed, "", "boom"))
+	assert.EqualError(t, job.Update("cluster-4", JobClusterSent, "", ""), "cluster cluster-4 is not part of the job")
+
+	loaded, err := LoadJob(job.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, job.Path(), loaded.Path())
+	assert.Equal(t, "Job summary", loaded.Message.Summary)
+	assert.Equal(t, []string{"cluster-3"}, loaded.ClusterIDs(JobClusterPending))
+	assert.Equal(t, []string{"cluster-2", "cluster-3"}, loaded.ClusterIDs(JobClusterPending, JobClusterFailed))
+	assert.Equal(t, map[JobClusterStatus]int{JobClusterSent: 1, JobClusterFailed: 1, JobClusterPending: 1}, loaded.Counts())
+	assert.Equal(t, map[string]string{"cluster-1": "sl-1"}, loaded.ServiceLogIDs())
+	assert.Equal(t, "boom", loaded.Clusters[1].Error)
+
+	// The temporary file used for the atomic write doesn't stay around
+	assert.NoFileExists(t, job.Path()+".tmp")
+}
+
+func TestJobResults(t *testing.T) {
+	job := newTestJob(t)
+	saved, err := os.ReadFile(job.Path())
+	assert.NoError(t, err)
+
+	// Updates are appended to the results log, the job file isn't rewritten
+	assert.NoError(t, job.Update("cluster-1", JobClusterSent, "sl-1", ""))
+	assert.NoError(t, job.Update("cluster-2", JobClusterFailed, "", "boom"))
+	assert.NoError(t, job.Update("cluster-2", JobClusterSent, "sl-2", ""))
+	content, err := os.ReadFile(job.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, saved, content)
+
+	// A line cut off while being appended is ignored
+	results, err := os.OpenFile(job.Path()+".results", os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	_, err = results.WriteString(`{"id": "cluster-3", "status": "se`)
+	assert.NoError(t, err)
+	assert.NoError(t, results.Close())
+
+	loaded, err := LoadJob(job.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"cluster-1": "sl-1", "cluster-2": "sl-2"}, loaded.ServiceLogIDs())
+	assert.Equal(t, []string{"cluster-3"}, loaded.ClusterIDs(JobClusterPending))
+
+	// Saving merges the results into the job file
+	assert.NoError(t, job.Save())
+	assert.NoFileExists(t, job.Path()+".results")
+	loaded, err = LoadJob(job.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"cluster-1": "sl-1", "cluster-2": "sl-2"}, loaded.ServiceLogIDs())
+
+	assert.NoError(t, os.WriteFile(job.Path()+".results", []byte(`{"id": "cluster-4"}`+"\n"), 0600))
+	_, err = LoadJob(job.Path())
+	assert.ErrorContains(t, err, "cluster cluster-4 of job results")
+}
+
+func TestLoadJobErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadJob(filepath.Join(dir, "missing.json"))
+	assert.ErrorContains(t, err, "cannot read job file")
+
+	invalid := filepath.Join(dir, "invalid.json")
+	assert.NoError(t, os.WriteFile(invalid, []byte("{"), 0600))
+	_, err = LoadJob(invalid)
+	assert.ErrorContains(t, err, "cannot parse job file")
+
+	future := filepath.Join(dir, "future.json")
+	assert.NoError(t, os.WriteFile(future, []byte(`{"version": 2}`), 0600))
+	_, err = LoadJob(future)
+	assert.ErrorContains(t, err, "unsupported job file version 2")
+}
+
+func TestJobSkipUnmatched(t *testing.T) {
+	job := newTestJob(t)
+	assert.NoError(t, job.Update("cluster-1", JobClusterSent, "sl-1", ""))
+
+	// Only pending clusters are skipped, the sent one keeps its state
+	skipped, err := job.SkipUnmatched([]string{"cluster-3"}, "cluster no longer matches")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cluster-2"}, skipped)
+
+	loaded, err := LoadJob(job.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cluster-3"}, loaded.ClusterIDs(JobClusterPending))
+	assert.Equal(t, []string{"cluster-2"}, loaded.ClusterIDs(JobClusterSkipped))
+	assert.Equal(t, "cluster no longer matches", loaded.Clusters[1].Error)
+	assert.Equal(t, map[string]string{"cluster-1": "sl-1"}, loaded.ServiceLogIDs())
+
+	skipped, err = job.SkipUnmatched([]string{"cluster-3"}, "cluster no longer matches")
+	assert.NoError(t, err)
+	assert.Empty(t, skipped)
+}
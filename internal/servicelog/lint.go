@@ -0,0 +1,277 @@
+package servicelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+)
+
+const (
+	// MaxSummaryLength is the longest summary accepted for a service log
+	MaxSummaryLength = 255
+	// ClusterUUIDPlaceholder is replaced for every cluster when posting and never needs a value
+	ClusterUUIDPlaceholder = "${CLUSTER_UUID}"
+)
+
+// LintLevel is the severity of a lint finding
+type LintLevel string
+
+const (
+	LintError   LintLevel = "error"
+	LintWarning LintLevel = "warning"
+)
+
+// LintFinding is a single problem found in a template
+type LintFinding struct {
+	Level   LintLevel `json:"level"`
+	Field   string    `json:"field,omitempty"`
+	Message string    `json:"message"`
+}
+
+func (f LintFinding) String() string {
+	if f.Field == "" {
+		return fmt.Sprintf("%s: %s", f.Level, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Level, f.Field, f.Message)
+}
+
+var (
+	validSeverities = []slv1.Severity{
+		slv1.SeverityDebug,
+		slv1.SeverityInfo,
+		slv1.SeverityWarning,
+		slv1.SeverityError,
+		slv1.SeverityFatal,
+	}
+
+	placeholderRegex          = regexp.MustCompile(`\${[^{}]*}`)
+	validPlaceholderRegex     = regexp.MustCompile(`^\${[A-Z][A-Z0-9_]*}$`)
+	unterminatedPlaceholderRe = regexp.MustCompile(`\${[^{}]*$`)
+	internalOnlyWordingRegex  = regexp.MustCompile(`(?i)internal only|do not share`)
+)
+
+// TemplateLinter checks service log templates for common mistakes
+type TemplateLinter struct {
+	// CheckURL verifies a documentation reference is reachable. URLs are
+	// not checked if it is nil.
+	CheckURL func(u url.URL) error
+	// Params are the parameters the template is expected to be used with.
+	// Placeholders without a parameter are reported as warnings if it is nil,
+	// otherwise as errors along with the parameters without a placeholder.
+	Params map[string]string
+}
+
+// Lint parses the raw template and returns all findings
+func (l *TemplateLinter) Lint(content []byte) []LintFinding {
+	var findings []LintFinding
+
+	message := Message{}
+	if err := json.Unmarshal(content, &message); err != nil {
+		return []LintFinding{{Level: LintError, Message: fmt.Sprintf("invalid template: %v", err)}}
+	}
+
+	// Unknown fields are ignored when posting, but are likely typos
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&Message{}); err != nil {
+		findings = append(findings, LintFinding{Level: LintWarning, Message: strings.TrimPrefix(err.Error(), "json: ")})
+	}
+
+	findings = append(findings, l.lintRequiredFields(message)...)
+	findings = append(findings, l.lintSeverity(message)...)
+	findings = append(findings, l.lintSummary(message)...)
+	findings = append(findings, l.lintInternalOnly(message)...)
+	findings = append(findings, l.lintPlaceholders(message)...)
+	findings = append(findings, l.lintDocReferences(message)...)
+
+	return findings
+}
+
+// ValidSeverities returns the severities a service log can be posted with
+func ValidSeverities() []slv1.Severity {
+	return slices.Clone(validSeverities)
+}
+
+func (l *TemplateLinter) lintRequiredFields(message Message) []LintFinding {
+	var findings []LintFinding
+	required := map[string]string{
+		"severity":     message.Severity,
+		"service_name": message.ServiceName,
+		"summary":      message.Summary,
+		"description":  message.Description,
+	}
+	for _, field := range []string{"severity", "service_name", "summary", "description"} {
+		if strings.TrimSpace(required[field]) == "" {
+			findings = append(findings, LintFinding{Level: LintError, Field: field, Message: "required field is missing or empty"})
+		}
+	}
+	return findings
+}
+
+func (l *TemplateLinter) lintSeverity(message Message) []LintFinding {
+	if message.Severity == "" {
+		return nil
+	}
+
+	var valid []string
+	for _, severity := range validSeverities {
+		if string(severity) == message.Severity {
+			return nil
+		}
+		valid = append(valid, string(severity))
+	}
+	return []LintFinding{{
+		Level:   LintError,
+		Field:   "severity",
+		Message: fmt.Sprintf("invalid severity %q, valid severities are %s", message.Severity, strings.Join(valid, ", ")),
+	}}
+}
+
+func (l *TemplateLinter) lintSummary(message Message) []LintFinding {
+	if len(message.Summary) > MaxSummaryLength {
+		return []LintFinding{{
+			Level:   LintError,
+			Field:   "summary",
+			Message: fmt.Sprintf("summary is %d characters long, the maximum is %d", len(message.Summary), MaxSummaryLength),
+		}}
+	}
+	return nil
+}
+
+func (l *TemplateLinter) lintInternalOnly(message Message) []LintFinding {
+	var findings []LintFinding
+	if message.InternalOnly {
+		if len(message.DocReferences) > 0 {
+			findings = append(findings, LintFinding{Level: LintWarning, Field: "doc_references", Message: "internal only service logs are not shown to customers, documentation references are unused"})
+		}
+		return findings
+	}
+
+	for field, value := range map[string]string{"summary": message.Summary, "description": message.Description} {
+		if internalOnlyWordingRegex.MatchString(value) {
+			findings = append(findings, LintFinding{Level: LintWarning, Field: field, Message: "the text looks internal, but internal_only is not set"})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Field < findings[j].Field })
+	return findings
+}
+
+func (l *TemplateLinter) lintPlaceholders(message Message) []LintFinding {
+	var findings []LintFinding
+
+	fields := map[string]string{
+		"severity":        message.Severity,
+		"service_name":    message.ServiceName,
+		"summary":         message.Summary,
+		"description":     message.Description,
+		"event_stream_id": message.EventStreamID,
+	}
+	fieldNames := []string{"severity", "service_name", "summary", "description", "event_stream_id"}
+
+	used := map[string]bool{}
+	for _, field := range fieldNames {
+		value := fields[field]
+		for _, placeholder := range placeholderRegex.FindAllString(value, -1) {
+			if !validPlaceholderRegex.MatchString(placeholder) {
+				findings = append(findings, LintFinding{Level: LintError, Field: field, Message: fmt.Sprintf("malformed placeholder %q, placeholders must look like ${UPPER_CASE}", placeholder)})
+				continue
+			}
+			used[placeholder] = true
+		}
+		if unterminatedPlaceholderRe.MatchString(value) {
+			findings = append(findings, LintFinding{Level: LintError, Field: field, Message: "unterminated placeholder, placeholders must look like ${UPPER_CASE}"})
+		}
+	}
+
+	// Without parameters, the template may be meant to be used with some, so
+	// the placeholders left without a value are only a warning
+	missingLevel := LintError
+	if l.Params == nil {
+		missingLevel = LintWarning
+	}
+
+	var usedNames []string
+	for placeholder := range used {
+		usedNames = append(usedNames, placeholder)
+	}
+	sort.Strings(usedNames)
+	for _, placeholder := range usedNames {
+		if placeholder == ClusterUUIDPlaceholder {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(placeholder, "${"), "}")
+		if _, found := l.Params[name]; !found {
+			findings = append(findings, LintFinding{Level: missingLevel, Message: fmt.Sprintf("placeholder %s has no parameter, use -p %s=VALUE", placeholder, name)})
+		}
+	}
+
+	var paramNames []string
+	for name := range l.Params {
+		paramNames = append(paramNames, name)
+	}
+	sort.Strings(paramNames)
+	for _, name := range paramNames {
+		if !used[fmt.Sprintf("${%s}", name)] {
+			findings = append(findings, LintFinding{Level: LintError, Message: fmt.Sprintf("parameter %s is not used by the template", name)})
+		}
+	}
+
+	return findings
+}
+
+func (l *TemplateLinter) lintDocReferences(message Message) []LintFinding {
+	var findings []LintFinding
+	for _, reference := range message.DocReferences {
+		u, err := url.Parse(reference)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			findings = append(findings, LintFinding{Level: LintError, Field: "doc_references", Message: fmt.Sprintf("%q is not a valid https URL", reference)})
+			continue
+		}
+		if l.CheckURL == nil {
+			continue
+		}
+		if err := l.CheckURL(*u); err != nil {
+			findings = append(findings, LintFinding{Level: LintError, Field: "doc_references", Message: fmt.Sprintf("%s is not reachable: %v", reference, err)})
+		}
+	}
+	return findings
+}
+
+// RenderTemplate replaces all ${PARAM} placeholders in the message with the
+// given parameters. An error is returned if a parameter isn't used, or a
+// placeholder is left without a value, except for ${CLUSTER_UUID}.
+func RenderTemplate(message *Message, params map[string]string) error {
+	var names []string
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		placeholder := fmt.Sprintf("${%s}", name)
+		if !message.SearchFlag(placeholder) {
+			return fmt.Errorf("the template is not using the %s parameter", placeholder)
+		}
+		message.ReplaceWithFlag(placeholder, params[name])
+	}
+
+	leftovers, _ := message.FindLeftovers()
+	var missing []string
+	for _, leftover := range leftovers {
+		if leftover != ClusterUUIDPlaceholder {
+			missing = append(missing, leftover)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the template is using parameters without a value: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package servicelog
 
 import (
+	"errors"
 	"regexp"
 	"strings"
 )
@@ -107,3 +108,16 @@ func (m *Message) FindLeftovers() (matches []string, found bool) {
 	}
 	return matches, found
 }
+
+// ParseTemplateParams parses '-p FOO=BAR' flags into a map
+func ParseTemplateParams(flags []string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, v := range flags {
+		param := strings.SplitN(v, "=", 2)
+		if len(param) != 2 || param[0] == "" || param[1] == "" {
+			return nil, errors.New("wrong syntax of '-p' flag. Please use it like this: '-p FOO=BAR'")
+		}
+		params[param[0]] = param[1]
+	}
+	return params, nil
+}
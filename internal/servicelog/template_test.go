@@ -0,0 +1,18 @@
+package servicelog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemplateParams(t *testing.T) {
+	params, err := ParseTemplateParams([]string{"FOO=BAR", "BAZ=a=b"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"FOO": "BAR", "BAZ": "a=b"}, params)
+
+	for _, invalid := range []string{"FOO", "=BAR", "FOO="} {
+		_, err := ParseTemplateParams([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
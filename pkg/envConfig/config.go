@@ -26,10 +26,26 @@ type PDConfig struct {
 // cloudtrailCmd configuration struct for parsing configuration options
 type CloudTrailConfig struct {
 	CloudTrailList struct {
-		FilterPatternList []string `mapstructure:"filter_regex_patterns"`
+		FilterPatternList     []string               `mapstructure:"filter_regex_patterns"`
+		SuspiciousChangeRules []SuspiciousChangeRule `mapstructure:"suspicious_change_rules"`
 	} `mapstructure:"cloudtrail_cmd_lists"`
 }
 
+// SuspiciousChangeRule classifies cloudtrail write events as suspicious changes.
+// Rules with the name of a built-in rule replace it, disabled rules are skipped.
+type SuspiciousChangeRule struct {
+	Name         string   `mapstructure:"name"`
+	Description  string   `mapstructure:"description"`
+	Severity     string   `mapstructure:"severity"`
+	EventSources []string `mapstructure:"event_sources"`
+	EventNames   []string `mapstructure:"event_names"`
+	// RequestPattern is matched against the request parameters of the event, ${INFRA_ID} is replaced with the cluster infra ID
+	RequestPattern string `mapstructure:"request_pattern"`
+	// InstallerOwned only matches events touching resources tagged kubernetes.io/cluster/<infraID>
+	InstallerOwned bool `mapstructure:"installer_owned"`
+	Disabled       bool `mapstructure:"disabled"`
+}
+
 func LoadYaml(paramFilePath string) Config {
 	config := Config{
 		LoginScripts: map[string]string{},
@@ -90,3 +106,16 @@ func LoadCloudTrailConfig() ([]string, error) {
 
 	return configuration.CloudTrailList.FilterPatternList, err
 }
+
+// LoadSuspiciousChangeRules loads the suspicious change rules of the cloudtrail_cmd_lists from ~/.config/osdctl
+func LoadSuspiciousChangeRules() ([]SuspiciousChangeRule, error) {
+	var configuration *CloudTrailConfig
+	osdctlConfig.EnsureConfigFile()
+	err := viper.Unmarshal(&configuration)
+	if err != nil {
+		log.Printf("[ERROR] Failed to unmarshal Cloudtrail config yaml %s %v", viper.ConfigFileUsed(), err)
+		return nil, err
+	}
+
+	return configuration.CloudTrailList.SuspiciousChangeRules, nil
+}
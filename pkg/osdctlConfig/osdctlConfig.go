@@ -9,6 +9,8 @@ import (
 
 const (
 	ConfigFileName = "osdctl"
+	// LimitedSupportTemplatesDir is the key of the limited support template registry
+	LimitedSupportTemplatesDir = "limited_support_templates_dir"
 )
 
 func EnsureConfigFile() error {
@@ -0,0 +1,185 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+const (
+	protocolTCP = 6
+	protocolUDP = 17
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88a8
+
+	tcpFlagFIN = 0x01
+	tcpFlagSYN = 0x02
+	tcpFlagRST = 0x04
+)
+
+// ipPacket is the IP and transport layers of a packet, decoded as far as the captured data allows
+type ipPacket struct {
+	src      netip.Addr
+	dst      netip.Addr
+	protocol uint8
+	// transport is set when the TCP or UDP header was captured
+	transport bool
+	srcPort   uint16
+	dstPort   uint16
+	tcpFlags  uint8
+	tcpSeq    uint32
+	// payloadLen is the length of the transport payload on the wire, payload is the captured part of it
+	payloadLen int
+	payload    []byte
+}
+
+// decodePacket decodes the IP packet of a captured frame, it returns false if the frame isn't IP
+func decodePacket(linkType uint32, data []byte) (ipPacket, bool) {
+	var etherType uint16
+	switch linkType {
+	case LinkTypeEthernet:
+		if len(data) < 14 {
+			return ipPacket{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[12:14]), data[14:]
+		for (etherType == etherTypeVLAN || etherType == etherTypeQinQ) && len(data) >= 4 {
+			etherType, data = binary.BigEndian.Uint16(data[2:4]), data[4:]
+		}
+	case LinkTypeLinuxSLL:
+		if len(data) < 16 {
+			return ipPacket{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:16]), data[16:]
+	case LinkTypeLinuxSLL2:
+		if len(data) < 20 {
+			return ipPacket{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[0:2]), data[20:]
+	case LinkTypeNull:
+		if len(data) < 4 {
+			return ipPacket{}, false
+		}
+		// The address family is in the byte order of the capturing host
+		family := binary.LittleEndian.Uint32(data[0:4])
+		if family > 0xffff {
+			family = binary.BigEndian.Uint32(data[0:4])
+		}
+		data = data[4:]
+		switch family {
+		case 2:
+			etherType = etherTypeIPv4
+		case 24, 28, 30:
+			etherType = etherTypeIPv6
+		}
+	case LinkTypeRaw, LinkTypeIPv4, LinkTypeIPv6:
+		if len(data) == 0 {
+			return ipPacket{}, false
+		}
+		switch data[0] >> 4 {
+		case 4:
+			etherType = etherTypeIPv4
+		case 6:
+			etherType = etherTypeIPv6
+		}
+	}
+
+	switch etherType {
+	case etherTypeIPv4:
+		return decodeIPv4(data)
+	case etherTypeIPv6:
+		return decodeIPv6(data)
+	}
+	return ipPacket{}, false
+}
+
+func decodeIPv4(data []byte) (ipPacket, bool) {
+	if len(data) < 20 || data[0]>>4 != 4 {
+		return ipPacket{}, false
+	}
+	headerLen := int(data[0]&0x0f) * 4
+	if headerLen < 20 || len(data) < headerLen {
+		return ipPacket{}, false
+	}
+	packet := ipPacket{
+		src:      netip.AddrFrom4([4]byte(data[12:16])),
+		dst:      netip.AddrFrom4([4]byte(data[16:20])),
+		protocol: data[9],
+	}
+	// The total length is 0 for the segments captured before TCP segmentation offload
+	length := int(binary.BigEndian.Uint16(data[2:4])) - headerLen
+	if length < 0 || binary.BigEndian.Uint16(data[2:4]) == 0 {
+		length = len(data) - headerLen
+	}
+	// Only the first fragment holds the transport header
+	if binary.BigEndian.Uint16(data[6:8])&0x1fff != 0 {
+		return packet, true
+	}
+	decodeTransport(&packet, data[headerLen:], length)
+	return packet, true
+}
+
+func decodeIPv6(data []byte) (ipPacket, bool) {
+	if len(data) < 40 || data[0]>>4 != 6 {
+		return ipPacket{}, false
+	}
+	packet := ipPacket{
+		src: netip.AddrFrom16([16]byte(data[8:24])),
+		dst: netip.AddrFrom16([16]byte(data[24:40])),
+	}
+	length := int(binary.BigEndian.Uint16(data[4:6]))
+	if length == 0 {
+		length = len(data) - 40
+	}
+	next, data := data[6], data[40:]
+	// Skip the hop-by-hop, routing and destination options extension headers
+	for next == 0 || next == 43 || next == 60 {
+		if len(data) < 8 || len(data) < (int(data[1])+1)*8 {
+			return packet, true
+		}
+		headerLen := (int(data[1]) + 1) * 8
+		next, data, length = data[0], data[headerLen:], length-headerLen
+	}
+	packet.protocol = next
+	if next == 44 {
+		// Fragments aren't reassembled
+		return packet, true
+	}
+	decodeTransport(&packet, data, length)
+	return packet, true
+}
+
+// decodeTransport decodes the TCP or UDP header of the IP payload of the given length on the wire
+func decodeTransport(packet *ipPacket, data []byte, length int) {
+	switch packet.protocol {
+	case protocolTCP:
+		if len(data) < 20 {
+			return
+		}
+		headerLen := int(data[12]>>4) * 4
+		if headerLen < 20 || len(data) < headerLen {
+			return
+		}
+		packet.srcPort = binary.BigEndian.Uint16(data[0:2])
+		packet.dstPort = binary.BigEndian.Uint16(data[2:4])
+		packet.tcpSeq = binary.BigEndian.Uint32(data[4:8])
+		packet.tcpFlags = data[13]
+		packet.payloadLen = max(length-headerLen, 0)
+		packet.payload = data[headerLen:]
+		packet.transport = true
+	case protocolUDP:
+		if len(data) < 8 {
+			return
+		}
+		packet.srcPort = binary.BigEndian.Uint16(data[0:2])
+		packet.dstPort = binary.BigEndian.Uint16(data[2:4])
+		packet.payloadLen = max(length-8, 0)
+		packet.payload = data[8:]
+		packet.transport = true
+	}
+	if len(packet.payload) > packet.payloadLen {
+		// Ethernet padding of small frames
+		packet.payload = packet.payload[:packet.payloadLen]
+	}
+}
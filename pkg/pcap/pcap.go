@@ -0,0 +1,250 @@
+// Package pcap reads, writes and merges the classic pcap files written by tcpdump -w
+package pcap
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	magicNanoseconds  = 0xa1b23c4d
+	magicPcapng       = 0x0a0d0d0a
+
+	fileHeaderLen   = 24
+	recordHeaderLen = 16
+	// maxPacketLen bounds the packets read, to fail on corrupted files instead of allocating gigabytes
+	maxPacketLen = 256 * 1024
+
+	// Link types of the packets, see https://www.tcpdump.org/linktypes.html
+	LinkTypeNull      uint32 = 0
+	LinkTypeEthernet  uint32 = 1
+	LinkTypeRaw       uint32 = 101
+	LinkTypeLinuxSLL  uint32 = 113
+	LinkTypeIPv4      uint32 = 228
+	LinkTypeIPv6      uint32 = 229
+	LinkTypeLinuxSLL2 uint32 = 276
+)
+
+// Packet is a captured packet
+type Packet struct {
+	Timestamp time.Time
+	// Data is the captured part of the packet, at most the snapshot length of the capture
+	Data []byte
+	// Length is the length of the packet on the wire
+	Length int
+}
+
+// Reader reads the packets of a pcap file
+type Reader struct {
+	r           io.Reader
+	order       binary.ByteOrder
+	nanoseconds bool
+	// LinkType is the link layer of the packets
+	LinkType uint32
+	// Snaplen is the maximum length of the captured packets
+	Snaplen uint32
+}
+
+// NewReader reads the header of the pcap file
+func NewReader(r io.Reader) (*Reader, error) {
+	header := make([]byte, fileHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read the pcap header: %w", err)
+	}
+
+	reader := &Reader{r: r}
+	switch magic := binary.LittleEndian.Uint32(header); {
+	case magic == magicMicroseconds:
+		reader.order = binary.LittleEndian
+	case magic == magicNanoseconds:
+		reader.order, reader.nanoseconds = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(header) == magicMicroseconds:
+		reader.order = binary.BigEndian
+	case binary.BigEndian.Uint32(header) == magicNanoseconds:
+		reader.order, reader.nanoseconds = binary.BigEndian, true
+	case magic == magicPcapng:
+		return nil, errors.New("pcapng files aren't supported, convert them with 'editcap -F pcap'")
+	default:
+		return nil, fmt.Errorf("not a pcap file, unknown magic number %#x", magic)
+	}
+	reader.Snaplen = reader.order.Uint32(header[16:20])
+	// The upper bits of the link type field hold the FCS length
+	reader.LinkType = reader.order.Uint32(header[20:24]) & 0x0fffffff
+	return reader, nil
+}
+
+// Next returns the next packet, io.EOF at the end of the file and io.ErrUnexpectedEOF if
+// the last packet is truncated, eg. when tcpdump was killed
+func (r *Reader) Next() (Packet, error) {
+	header := make([]byte, recordHeaderLen)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return Packet{}, err
+	}
+	seconds := r.order.Uint32(header[0:4])
+	fraction := r.order.Uint32(header[4:8])
+	capturedLen := r.order.Uint32(header[8:12])
+	if capturedLen > maxPacketLen {
+		return Packet{}, fmt.Errorf("corrupted pcap file, packet of %d bytes", capturedLen)
+	}
+
+	packet := Packet{Data: make([]byte, capturedLen), Length: int(r.order.Uint32(header[12:16]))}
+	if !r.nanoseconds {
+		fraction *= 1000
+	}
+	packet.Timestamp = time.Unix(int64(seconds), int64(fraction)).UTC()
+	if _, err := io.ReadFull(r.r, packet.Data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Packet{}, err
+	}
+	return packet, nil
+}
+
+// Writer writes packets to a pcap file with microsecond timestamps
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the header of the pcap file
+func NewWriter(w io.Writer, linkType uint32, snaplen uint32) (*Writer, error) {
+	header := make([]byte, fileHeaderLen)
+	binary.LittleEndian.PutUint32(header[0:4], magicMicroseconds)
+	binary.LittleEndian.PutUint16(header[4:6], 2)
+	binary.LittleEndian.PutUint16(header[6:8], 4)
+	binary.LittleEndian.PutUint32(header[16:20], snaplen)
+	binary.LittleEndian.PutUint32(header[20:24], linkType)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// WritePacket appends the packet to the file
+func (w *Writer) WritePacket(packet Packet) error {
+	header := make([]byte, recordHeaderLen)
+	binary.LittleEndian.PutUint32(header[0:4], uint32(packet.Timestamp.Unix()))
+	binary.LittleEndian.PutUint32(header[4:8], uint32(packet.Timestamp.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(len(packet.Data)))
+	binary.LittleEndian.PutUint32(header[12:16], uint32(packet.Length))
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.w.Write(packet.Data)
+	return err
+}
+
+// mergeSource is the next packet of one of the merged files
+type mergeSource struct {
+	reader *Reader
+	next   Packet
+	path   string
+}
+
+type mergeHeap []*mergeSource
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return h[i].next.Timestamp.Before(h[j].next.Timestamp) }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)        { *h = append(*h, x.(*mergeSource)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	source := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return source
+}
+
+// Merge writes the packets of the files to w ordered by their timestamps, and returns the number
+// of packets written. The files must have the same link type.
+func Merge(w io.Writer, paths []string) (int, error) {
+	var writer *Writer
+	count := 0
+	_, err := mergeFiles(paths, func(linkType uint32, snaplen uint32) error {
+		var err error
+		writer, err = NewWriter(w, linkType, snaplen)
+		return err
+	}, func(packet Packet) error {
+		if err := writer.WritePacket(packet); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// mergeFiles reads the packets of the files ordered by their timestamps, the order of the files
+// doesn't matter, eg. for rotated files once the ring of files wrapped around. The files must have
+// the same link type, open is called with it before the packets are passed to fn. It returns true
+// if the last packet of a file was truncated.
+func mergeFiles(paths []string, open func(linkType uint32, snaplen uint32) error, fn func(Packet) error) (bool, error) {
+	if len(paths) == 0 {
+		return false, errors.New("no pcap files to merge")
+	}
+
+	sources := &mergeHeap{}
+	var linkType, snaplen uint32
+	truncated := false
+	for i, path := range paths {
+		file, err := os.Open(path) //#nosec G304 -- the files are given by the user
+		if err != nil {
+			return false, err
+		}
+		defer file.Close()
+		reader, err := NewReader(bufio.NewReader(file))
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+		if i == 0 {
+			linkType = reader.LinkType
+		} else if reader.LinkType != linkType {
+			return false, fmt.Errorf("%s: link type %d differs from the link type %d of %s", path, reader.LinkType, linkType, paths[0])
+		}
+		snaplen = max(snaplen, reader.Snaplen)
+
+		next, err := reader.Next()
+		switch {
+		case err == io.EOF:
+			continue
+		case err == io.ErrUnexpectedEOF:
+			truncated = true
+			continue
+		case err != nil:
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+		*sources = append(*sources, &mergeSource{reader: reader, next: next, path: path})
+	}
+	heap.Init(sources)
+
+	if err := open(linkType, snaplen); err != nil {
+		return false, err
+	}
+	for sources.Len() > 0 {
+		source := (*sources)[0]
+		if err := fn(source.next); err != nil {
+			return truncated, err
+		}
+
+		var err error
+		source.next, err = source.reader.Next()
+		switch {
+		case err == io.EOF:
+			heap.Pop(sources)
+		case err == io.ErrUnexpectedEOF:
+			truncated = true
+			heap.Pop(sources)
+		case err != nil:
+			return truncated, fmt.Errorf("%s: %w", source.path, err)
+		default:
+			heap.Fix(sources, 0)
+		}
+	}
+	return truncated, nil
+}
@@ -0,0 +1,219 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ethernetIPv4 returns an ethernet frame of an IPv4 packet with the given transport segment
+func ethernetIPv4(src string, dst string, protocol uint8, segment []byte) []byte {
+	frame := make([]byte, 14+20)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv4)
+	ip := frame[14:]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(segment)))
+	ip[8] = 64
+	ip[9] = protocol
+	srcAddr, dstAddr := netip.MustParseAddr(src).As4(), netip.MustParseAddr(dst).As4()
+	copy(ip[12:16], srcAddr[:])
+	copy(ip[16:20], dstAddr[:])
+	return append(frame, segment...)
+}
+
+func tcpSegment(srcPort uint16, dstPort uint16, seq uint32, flags uint8, payload []byte) []byte {
+	segment := make([]byte, 20)
+	binary.BigEndian.PutUint16(segment[0:2], srcPort)
+	binary.BigEndian.PutUint16(segment[2:4], dstPort)
+	binary.BigEndian.PutUint32(segment[4:8], seq)
+	segment[12] = 5 << 4
+	segment[13] = flags
+	return append(segment, payload...)
+}
+
+func udpDatagram(srcPort uint16, dstPort uint16, payload []byte) []byte {
+	datagram := make([]byte, 8)
+	binary.BigEndian.PutUint16(datagram[0:2], srcPort)
+	binary.BigEndian.PutUint16(datagram[2:4], dstPort)
+	binary.BigEndian.PutUint16(datagram[4:6], uint16(8+len(payload)))
+	return append(datagram, payload...)
+}
+
+// dnsMessage returns a DNS query or response for an A record of the name
+func dnsMessage(id uint16, response bool, rcode byte, name string) []byte {
+	message := make([]byte, 12)
+	binary.BigEndian.PutUint16(message[0:2], id)
+	if response {
+		message[2] = 0x80
+	}
+	message[3] = rcode
+	binary.BigEndian.PutUint16(message[4:6], 1)
+	for _, label := range bytes.Split([]byte(name), []byte(".")) {
+		message = append(message, byte(len(label)))
+		message = append(message, label...)
+	}
+	return append(message, 0, 0, 1, 0, 1)
+}
+
+// clientHello returns a TLS record with a ClientHello message for the server name
+func clientHello(serverName string) []byte {
+	// server_name extension with a single host_name entry
+	name := append([]byte{0, byte(len(serverName) >> 8), byte(len(serverName))}, serverName...)
+	list := append([]byte{byte(len(name) >> 8), byte(len(name))}, name...)
+	sni := append([]byte{0, 0, byte(len(list) >> 8), byte(len(list))}, list...)
+
+	body := []byte{3, 3}
+	body = append(body, make([]byte, 32)...)
+	// Session ID, a cipher suite, the null compression method
+	body = append(body, 0, 0, 2, 0x13, 0x01, 1, 0)
+	body = append(body, byte(len(sni)>>8), byte(len(sni)))
+	body = append(body, sni...)
+
+	message := append([]byte{1, 0, byte(len(body) >> 8), byte(len(body))}, body...)
+	return append([]byte{22, 3, 1, byte(len(message) >> 8), byte(len(message))}, message...)
+}
+
+func writeTestFile(t *testing.T, path string, packets []Packet) {
+	var buffer bytes.Buffer
+	writer, err := NewWriter(&buffer, LinkTypeEthernet, 65535)
+	require.NoError(t, err)
+	for _, packet := range packets {
+		require.NoError(t, writer.WritePacket(packet))
+	}
+	require.NoError(t, os.WriteFile(path, buffer.Bytes(), 0600))
+}
+
+func newPacket(offset time.Duration, data []byte) Packet {
+	return Packet{Timestamp: testStart.Add(offset), Data: data, Length: len(data)}
+}
+
+func TestReaderWriter(t *testing.T) {
+	packets := []Packet{
+		newPacket(0, ethernetIPv4("10.0.0.1", "10.0.0.2", protocolUDP, udpDatagram(1234, 53, []byte("query")))),
+		newPacket(1500*time.Microsecond, ethernetIPv4("10.0.0.2", "10.0.0.1", protocolUDP, udpDatagram(53, 1234, []byte("answer")))),
+	}
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	writeTestFile(t, path, packets)
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := NewReader(file)
+	require.NoError(t, err)
+	assert.Equal(t, LinkTypeEthernet, reader.LinkType)
+	for _, expected := range packets {
+		packet, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, expected, packet)
+	}
+	_, err = reader.Next()
+	assert.Equal(t, "EOF", err.Error())
+
+	_, err = NewReader(bytes.NewReader([]byte{0x0a, 0x0d, 0x0d, 0x0a, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}))
+	assert.ErrorContains(t, err, "pcapng")
+}
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "node-a.pcap"), filepath.Join(dir, "node-b.pcap")
+	frame := ethernetIPv4("10.0.0.1", "10.0.0.2", protocolUDP, udpDatagram(1234, 80, nil))
+	writeTestFile(t, first, []Packet{newPacket(0, frame), newPacket(2*time.Second, frame)})
+	writeTestFile(t, second, []Packet{newPacket(time.Second, frame), newPacket(3*time.Second, frame)})
+
+	var merged bytes.Buffer
+	count, err := Merge(&merged, []string{first, second})
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	reader, err := NewReader(&merged)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		packet, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, testStart.Add(time.Duration(i)*time.Second), packet.Timestamp)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	client, server, dns := "10.128.2.15", "52.1.2.3", "172.30.0.10"
+	packets := []Packet{
+		// Resolved, NXDOMAIN and unanswered DNS queries
+		newPacket(0, ethernetIPv4(client, dns, protocolUDP, udpDatagram(40000, 53, dnsMessage(1, false, 0, "quay.io")))),
+		newPacket(time.Millisecond, ethernetIPv4(dns, client, protocolUDP, udpDatagram(53, 40000, dnsMessage(1, true, 0, "quay.io")))),
+		newPacket(2*time.Millisecond, ethernetIPv4(client, dns, protocolUDP, udpDatagram(40001, 53, dnsMessage(2, false, 0, "missing.example.com")))),
+		newPacket(3*time.Millisecond, ethernetIPv4(dns, client, protocolUDP, udpDatagram(53, 40001, dnsMessage(2, true, 3, "missing.example.com")))),
+		newPacket(4*time.Millisecond, ethernetIPv4(client, dns, protocolUDP, udpDatagram(40002, 53, dnsMessage(3, false, 0, "slow.example.com")))),
+		// A TLS handshake rejected by the server, with a retransmitted ClientHello
+		newPacket(10*time.Millisecond, ethernetIPv4(client, server, protocolTCP, tcpSegment(50000, 443, 100, tcpFlagSYN, nil))),
+		newPacket(11*time.Millisecond, ethernetIPv4(server, client, protocolTCP, tcpSegment(443, 50000, 900, tcpFlagSYN|0x10, nil))),
+		newPacket(12*time.Millisecond, ethernetIPv4(client, server, protocolTCP, tcpSegment(50000, 443, 101, 0x10, clientHello("quay.io")))),
+		newPacket(300*time.Millisecond, ethernetIPv4(client, server, protocolTCP, tcpSegment(50000, 443, 101, 0x10, clientHello("quay.io")))),
+		newPacket(301*time.Millisecond, ethernetIPv4(server, client, protocolTCP, tcpSegment(443, 50000, 901, 0x10, []byte{21, 3, 3, 0, 2, 2, 40}))),
+		// A connection reset during the handshake
+		newPacket(400*time.Millisecond, ethernetIPv4(client, server, protocolTCP, tcpSegment(50001, 443, 100, 0x10, clientHello("api.openshift.com")))),
+		newPacket(401*time.Millisecond, ethernetIPv4(server, client, protocolTCP, tcpSegment(443, 50001, 900, tcpFlagRST, nil))),
+	}
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	writeTestFile(t, path, packets)
+
+	summary, err := SummarizeFiles([]string{path}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, len(packets), summary.Packets)
+	assert.Equal(t, testStart, summary.Start)
+	assert.Equal(t, testStart.Add(401*time.Millisecond), summary.End)
+	assert.Equal(t, "10.128.2.15", summary.TopTalkers[0].A)
+	assert.Equal(t, "52.1.2.3", summary.TopTalkers[0].B)
+	assert.Equal(t, 1, summary.TCPResets)
+	assert.Equal(t, 1, summary.TCPRetransmissions)
+	assert.Equal(t, []DNSFailure{
+		{Name: "missing.example.com.", Server: dns, Result: "NXDOMAIN", Count: 1},
+		{Name: "slow.example.com.", Server: dns, Result: "no response", Count: 1},
+	}, summary.DNSFailures)
+	assert.Equal(t, []TLSFailure{
+		{Server: "52.1.2.3:443", ServerName: "api.openshift.com", Reason: "connection reset during handshake", Count: 1},
+		{Server: "52.1.2.3:443", ServerName: "quay.io", Reason: "server sent handshake_failure alert", Count: 1},
+	}, summary.TLSFailures)
+}
+
+func TestSummarizeRotatedFiles(t *testing.T) {
+	client, server := "10.128.2.15", "52.1.2.3"
+	dir := t.TempDir()
+	// The ring of files wrapped around, the first packets are in the last file
+	first, second := filepath.Join(dir, "capture.pcap2"), filepath.Join(dir, "capture.pcap0")
+	writeTestFile(t, first, []Packet{
+		newPacket(0, ethernetIPv4(client, server, protocolTCP, tcpSegment(50000, 443, 100, 0x10, []byte("first")))),
+		newPacket(time.Second, ethernetIPv4(client, server, protocolTCP, tcpSegment(50000, 443, 105, 0x10, []byte("second")))),
+	})
+	writeTestFile(t, second, []Packet{
+		newPacket(2*time.Second, ethernetIPv4(client, server, protocolTCP, tcpSegment(50000, 443, 111, 0x10, []byte("third")))),
+	})
+
+	summary, err := SummarizeFiles([]string{second, first}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Packets)
+	assert.Equal(t, 0, summary.TCPRetransmissions)
+	assert.Equal(t, testStart, summary.Start)
+	assert.Equal(t, testStart.Add(2*time.Second), summary.End)
+}
+
+func TestSummarizeTruncatedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	writeTestFile(t, path, []Packet{newPacket(0, ethernetIPv4("10.0.0.1", "10.0.0.2", protocolUDP, udpDatagram(1, 2, []byte("data"))))})
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content[:len(content)-2], 0600))
+
+	summary, err := SummarizeFiles([]string{path}, 10)
+	require.NoError(t, err)
+	assert.True(t, summary.Truncated)
+	assert.Equal(t, 0, summary.Packets)
+}
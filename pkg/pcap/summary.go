@@ -0,0 +1,525 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Summary is a first pass analysis of captured packets
+type Summary struct {
+	Packets int       `json:"packets"`
+	Bytes   int       `json:"bytes"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	// Truncated is set if the last packet of a file was truncated, eg. when tcpdump was killed
+	Truncated bool `json:"truncated,omitempty"`
+
+	TopTalkers         []Conversation `json:"topTalkers"`
+	TCPResets          int            `json:"tcpResets"`
+	TCPRetransmissions int            `json:"tcpRetransmissions"`
+	// TCPIssues are the TCP connections with the most resets and retransmissions
+	TCPIssues   []TCPIssue   `json:"tcpIssues"`
+	DNSFailures []DNSFailure `json:"dnsFailures"`
+	TLSFailures []TLSFailure `json:"tlsFailures"`
+}
+
+// Conversation is the traffic between two IP addresses, in both directions
+type Conversation struct {
+	A       string `json:"a"`
+	B       string `json:"b"`
+	Packets int    `json:"packets"`
+	Bytes   int    `json:"bytes"`
+}
+
+// TCPIssue counts the resets and retransmissions of a TCP connection
+type TCPIssue struct {
+	A               string `json:"a"`
+	B               string `json:"b"`
+	Resets          int    `json:"resets"`
+	Retransmissions int    `json:"retransmissions"`
+}
+
+// DNSFailure counts the failed DNS queries of a name
+type DNSFailure struct {
+	Name   string `json:"name"`
+	Server string `json:"server"`
+	// Result is the response code of the failed queries, eg. NXDOMAIN, or "no response"
+	Result string `json:"result"`
+	Count  int    `json:"count"`
+}
+
+// TLSFailure counts the failed TLS handshakes with a server
+type TLSFailure struct {
+	Server     string `json:"server"`
+	ServerName string `json:"serverName,omitempty"`
+	Reason     string `json:"reason"`
+	Count      int    `json:"count"`
+}
+
+// connKey identifies a TCP connection or a UDP flow regardless of the direction of the packets
+type connKey struct {
+	a, b netip.AddrPort
+}
+
+func newConnKey(src netip.AddrPort, dst netip.AddrPort) connKey {
+	if src.Compare(dst) > 0 {
+		return connKey{a: dst, b: src}
+	}
+	return connKey{a: src, b: dst}
+}
+
+// flowKey identifies the packets sent from src to dst
+type flowKey struct {
+	src, dst netip.AddrPort
+}
+
+type addrPair struct {
+	a, b netip.Addr
+}
+
+type tcpStream struct {
+	nextSeq uint32
+	started bool
+}
+
+type tlsHandshake struct {
+	server      netip.AddrPort
+	serverName  string
+	serverHello bool
+	failed      bool
+}
+
+type dnsQuery struct {
+	client netip.AddrPort
+	server netip.AddrPort
+	id     uint16
+}
+
+type dnsKey struct {
+	name, server, result string
+}
+
+type tlsKey struct {
+	server, serverName, reason string
+}
+
+// Summarizer accumulates the summary of the packets added to it
+type Summarizer struct {
+	top     int
+	summary Summary
+
+	conversations map[addrPair]*Conversation
+	tcpIssues     map[connKey]*TCPIssue
+	streams       map[flowKey]*tcpStream
+	handshakes    map[connKey]*tlsHandshake
+	dnsQueries    map[dnsQuery]string
+	dnsFailures   map[dnsKey]int
+	tlsFailures   map[tlsKey]int
+}
+
+// NewSummarizer returns a summarizer keeping the top entries of every list of the summary
+func NewSummarizer(top int) *Summarizer {
+	return &Summarizer{
+		top:           top,
+		conversations: map[addrPair]*Conversation{},
+		tcpIssues:     map[connKey]*TCPIssue{},
+		streams:       map[flowKey]*tcpStream{},
+		handshakes:    map[connKey]*tlsHandshake{},
+		dnsQueries:    map[dnsQuery]string{},
+		dnsFailures:   map[dnsKey]int{},
+		tlsFailures:   map[tlsKey]int{},
+	}
+}
+
+// SummarizeFiles summarizes the packets of pcap files, eg. the rotated files of a capture. The
+// packets of the files are merged by timestamp, so the TCP streams are followed in order
+// whatever the order of the files.
+func SummarizeFiles(paths []string, top int) (*Summary, error) {
+	summarizer := NewSummarizer(top)
+	var linkType uint32
+	truncated, err := mergeFiles(paths, func(fileLinkType uint32, _ uint32) error {
+		linkType = fileLinkType
+		return nil
+	}, func(packet Packet) error {
+		summarizer.Add(linkType, packet)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		summarizer.summary.Truncated = true
+	}
+	return summarizer.Summary(), nil
+}
+
+// AddFile adds the packets of a pcap file
+func (s *Summarizer) AddFile(path string) error {
+	file, err := os.Open(path) //#nosec G304 -- the files are given by the user
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := NewReader(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+	for {
+		packet, err := reader.Next()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err == io.ErrUnexpectedEOF:
+			s.summary.Truncated = true
+			return nil
+		case err != nil:
+			return err
+		}
+		s.Add(reader.LinkType, packet)
+	}
+}
+
+// Add adds a packet of the given link type
+func (s *Summarizer) Add(linkType uint32, packet Packet) {
+	s.summary.Packets++
+	s.summary.Bytes += packet.Length
+	if s.summary.Start.IsZero() || packet.Timestamp.Before(s.summary.Start) {
+		s.summary.Start = packet.Timestamp
+	}
+	if packet.Timestamp.After(s.summary.End) {
+		s.summary.End = packet.Timestamp
+	}
+
+	ip, ok := decodePacket(linkType, packet.Data)
+	if !ok {
+		return
+	}
+	pair := addrPair{a: ip.src, b: ip.dst}
+	if ip.src.Compare(ip.dst) > 0 {
+		pair = addrPair{a: ip.dst, b: ip.src}
+	}
+	conversation, ok := s.conversations[pair]
+	if !ok {
+		conversation = &Conversation{A: pair.a.String(), B: pair.b.String()}
+		s.conversations[pair] = conversation
+	}
+	conversation.Packets++
+	conversation.Bytes += packet.Length
+
+	if !ip.transport {
+		return
+	}
+	src := netip.AddrPortFrom(ip.src, ip.srcPort)
+	dst := netip.AddrPortFrom(ip.dst, ip.dstPort)
+	switch ip.protocol {
+	case protocolTCP:
+		s.addTCP(ip, src, dst)
+	case protocolUDP:
+		if isDNSPort(ip.srcPort) || isDNSPort(ip.dstPort) {
+			s.addDNS(ip.payload, src, dst)
+		}
+	}
+}
+
+// isDNSPort returns true for the DNS port and the port of the cluster DNS pods
+func isDNSPort(port uint16) bool {
+	return port == 53 || port == 5353
+}
+
+func (s *Summarizer) tcpIssue(key connKey) *TCPIssue {
+	issue, ok := s.tcpIssues[key]
+	if !ok {
+		issue = &TCPIssue{A: key.a.String(), B: key.b.String()}
+		s.tcpIssues[key] = issue
+	}
+	return issue
+}
+
+func (s *Summarizer) addTCP(ip ipPacket, src netip.AddrPort, dst netip.AddrPort) {
+	key := newConnKey(src, dst)
+	if ip.tcpFlags&tcpFlagRST != 0 {
+		s.summary.TCPResets++
+		s.tcpIssue(key).Resets++
+		if handshake, ok := s.handshakes[key]; ok && !handshake.serverHello && !handshake.failed {
+			handshake.failed = true
+			s.tlsFailures[tlsKey{server: handshake.server.String(), serverName: handshake.serverName, reason: "connection reset during handshake"}]++
+		}
+	}
+
+	// A segment is a retransmission if its sequence numbers were already seen, SYN and FIN count
+	// for one sequence number
+	length := ip.payloadLen
+	if ip.tcpFlags&(tcpFlagSYN|tcpFlagFIN) != 0 {
+		length++
+	}
+	if length > 0 {
+		stream, ok := s.streams[flowKey{src: src, dst: dst}]
+		if !ok {
+			stream = &tcpStream{}
+			s.streams[flowKey{src: src, dst: dst}] = stream
+		}
+		end := ip.tcpSeq + uint32(length)
+		// Keep-alives resend the last sequence number with at most one byte
+		keepAlive := ip.payloadLen <= 1 && ip.tcpFlags&(tcpFlagSYN|tcpFlagFIN) == 0 && ip.tcpSeq == stream.nextSeq-1
+		switch {
+		case !stream.started || int32(end-stream.nextSeq) > 0:
+			stream.nextSeq, stream.started = end, true
+		case !keepAlive:
+			s.summary.TCPRetransmissions++
+			s.tcpIssue(key).Retransmissions++
+		}
+	}
+
+	if len(ip.payload) > 0 {
+		s.addTLS(ip.payload, key, src, dst)
+	}
+}
+
+// addTLS looks for the handshake messages and the alerts at the start of a TCP segment, the
+// TCP streams aren't reassembled
+func (s *Summarizer) addTLS(payload []byte, key connKey, src netip.AddrPort, dst netip.AddrPort) {
+	if len(payload) < 6 || payload[1] != 3 {
+		return
+	}
+	recordLen := int(binary.BigEndian.Uint16(payload[3:5]))
+	switch payload[0] {
+	case 22:
+		// Handshake record
+		switch payload[5] {
+		case 1:
+			s.handshakes[key] = &tlsHandshake{server: dst, serverName: parseServerName(payload[5:])}
+		case 2:
+			if handshake, ok := s.handshakes[key]; ok {
+				handshake.serverHello = true
+			}
+		}
+	case 21:
+		// Alerts are only readable before the keys are exchanged, encrypted alerts are longer
+		if recordLen != 2 || len(payload) < 7 || payload[5] != 2 {
+			return
+		}
+		handshake, ok := s.handshakes[key]
+		if !ok {
+			handshake = &tlsHandshake{server: dst}
+			s.handshakes[key] = handshake
+		}
+		if handshake.failed {
+			return
+		}
+		handshake.failed = true
+		sender := "client"
+		if src == handshake.server {
+			sender = "server"
+		}
+		s.tlsFailures[tlsKey{server: handshake.server.String(), serverName: handshake.serverName, reason: fmt.Sprintf("%s sent %s alert", sender, alertDescription(payload[6]))}]++
+	}
+}
+
+// parseServerName returns the server name indication of a ClientHello message, if it was captured
+func parseServerName(message []byte) string {
+	// Message type and length, version, random
+	offset := 4 + 2 + 32
+	skip := func(lengthBytes int) bool {
+		if len(message) < offset+lengthBytes {
+			return false
+		}
+		length := 0
+		for _, b := range message[offset : offset+lengthBytes] {
+			length = length<<8 | int(b)
+		}
+		offset += lengthBytes + length
+		return len(message) >= offset
+	}
+	// Session ID, cipher suites and compression methods
+	if !skip(1) || !skip(2) || !skip(1) || len(message) < offset+2 {
+		return ""
+	}
+	offset += 2
+	for len(message) >= offset+4 {
+		extensionType := binary.BigEndian.Uint16(message[offset : offset+2])
+		extensionLen := int(binary.BigEndian.Uint16(message[offset+2 : offset+4]))
+		offset += 4
+		if len(message) < offset+extensionLen {
+			return ""
+		}
+		extension := message[offset : offset+extensionLen]
+		offset += extensionLen
+		// server_name extension with a host_name entry
+		if extensionType != 0 || len(extension) < 5 || extension[2] != 0 {
+			continue
+		}
+		nameLen := int(binary.BigEndian.Uint16(extension[3:5]))
+		if len(extension) < 5+nameLen {
+			return ""
+		}
+		return string(extension[5 : 5+nameLen])
+	}
+	return ""
+}
+
+var alertDescriptions = map[byte]string{
+	10:  "unexpected_message",
+	20:  "bad_record_mac",
+	40:  "handshake_failure",
+	42:  "bad_certificate",
+	43:  "unsupported_certificate",
+	44:  "certificate_revoked",
+	45:  "certificate_expired",
+	46:  "certificate_unknown",
+	47:  "illegal_parameter",
+	48:  "unknown_ca",
+	49:  "access_denied",
+	50:  "decode_error",
+	51:  "decrypt_error",
+	70:  "protocol_version",
+	71:  "insufficient_security",
+	80:  "internal_error",
+	86:  "inappropriate_fallback",
+	90:  "user_canceled",
+	109: "missing_extension",
+	110: "unsupported_extension",
+	112: "unrecognized_name",
+	116: "certificate_required",
+	120: "no_application_protocol",
+}
+
+func alertDescription(code byte) string {
+	if description, ok := alertDescriptions[code]; ok {
+		return description
+	}
+	return "alert " + strconv.Itoa(int(code))
+}
+
+var dnsResponseCodes = map[byte]string{
+	1: "FORMERR",
+	2: "SERVFAIL",
+	3: "NXDOMAIN",
+	4: "NOTIMP",
+	5: "REFUSED",
+}
+
+// addDNS matches the DNS queries with their responses, queries never answered are failures as well
+func (s *Summarizer) addDNS(payload []byte, src netip.AddrPort, dst netip.AddrPort) {
+	if len(payload) < 12 {
+		return
+	}
+	id := binary.BigEndian.Uint16(payload[0:2])
+	response := payload[2]&0x80 != 0
+	if !response {
+		s.dnsQueries[dnsQuery{client: src, server: dst, id: id}] = parseQuestionName(payload)
+		return
+	}
+
+	query := dnsQuery{client: dst, server: src, id: id}
+	name, ok := s.dnsQueries[query]
+	if !ok {
+		name = parseQuestionName(payload)
+	}
+	delete(s.dnsQueries, query)
+	if code := payload[3] & 0x0f; code != 0 {
+		result, ok := dnsResponseCodes[code]
+		if !ok {
+			result = "RCODE " + strconv.Itoa(int(code))
+		}
+		s.dnsFailures[dnsKey{name: name, server: src.Addr().String(), result: result}]++
+	}
+}
+
+// parseQuestionName returns the name of the first question of a DNS message
+func parseQuestionName(message []byte) string {
+	if binary.BigEndian.Uint16(message[4:6]) == 0 {
+		return ""
+	}
+	labels := []string{}
+	for offset := 12; offset < len(message); {
+		length := int(message[offset])
+		// Compression pointers aren't expected in questions
+		if length == 0 || length&0xc0 != 0 || offset+1+length > len(message) {
+			break
+		}
+		labels = append(labels, string(message[offset+1:offset+1+length]))
+		offset += 1 + length
+	}
+	return strings.Join(labels, ".") + "."
+}
+
+// Summary returns the summary of the packets added so far
+func (s *Summarizer) Summary() *Summary {
+	summary := s.summary
+
+	summary.TopTalkers = []Conversation{}
+	for _, conversation := range s.conversations {
+		summary.TopTalkers = append(summary.TopTalkers, *conversation)
+	}
+	sort.Slice(summary.TopTalkers, func(i, j int) bool {
+		if summary.TopTalkers[i].Bytes != summary.TopTalkers[j].Bytes {
+			return summary.TopTalkers[i].Bytes > summary.TopTalkers[j].Bytes
+		}
+		return summary.TopTalkers[i].A+summary.TopTalkers[i].B < summary.TopTalkers[j].A+summary.TopTalkers[j].B
+	})
+	summary.TopTalkers = truncate(summary.TopTalkers, s.top)
+
+	summary.TCPIssues = []TCPIssue{}
+	for _, issue := range s.tcpIssues {
+		summary.TCPIssues = append(summary.TCPIssues, *issue)
+	}
+	sort.Slice(summary.TCPIssues, func(i, j int) bool {
+		left, right := summary.TCPIssues[i], summary.TCPIssues[j]
+		if left.Resets+left.Retransmissions != right.Resets+right.Retransmissions {
+			return left.Resets+left.Retransmissions > right.Resets+right.Retransmissions
+		}
+		return left.A+left.B < right.A+right.B
+	})
+	summary.TCPIssues = truncate(summary.TCPIssues, s.top)
+
+	dnsFailures := map[dnsKey]int{}
+	for key, count := range s.dnsFailures {
+		dnsFailures[key] = count
+	}
+	for query, name := range s.dnsQueries {
+		dnsFailures[dnsKey{name: name, server: query.server.Addr().String(), result: "no response"}]++
+	}
+	summary.DNSFailures = []DNSFailure{}
+	for key, count := range dnsFailures {
+		summary.DNSFailures = append(summary.DNSFailures, DNSFailure{Name: key.name, Server: key.server, Result: key.result, Count: count})
+	}
+	sort.Slice(summary.DNSFailures, func(i, j int) bool {
+		left, right := summary.DNSFailures[i], summary.DNSFailures[j]
+		if left.Count != right.Count {
+			return left.Count > right.Count
+		}
+		return left.Name+left.Result+left.Server < right.Name+right.Result+right.Server
+	})
+	summary.DNSFailures = truncate(summary.DNSFailures, s.top)
+
+	summary.TLSFailures = []TLSFailure{}
+	for key, count := range s.tlsFailures {
+		summary.TLSFailures = append(summary.TLSFailures, TLSFailure{Server: key.server, ServerName: key.serverName, Reason: key.reason, Count: count})
+	}
+	sort.Slice(summary.TLSFailures, func(i, j int) bool {
+		left, right := summary.TLSFailures[i], summary.TLSFailures[j]
+		if left.Count != right.Count {
+			return left.Count > right.Count
+		}
+		return left.Server+left.Reason < right.Server+right.Reason
+	})
+	summary.TLSFailures = truncate(summary.TLSFailures, s.top)
+	return &summary
+}
+
+// truncate keeps the first top items, all of them if top isn't positive
+func truncate[T any](items []T, top int) []T {
+	if top > 0 && len(items) > top {
+		return items[:top]
+	}
+	return items
+}
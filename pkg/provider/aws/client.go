@@ -54,6 +54,7 @@ type Client interface {
 	ListBuckets(*s3.ListBucketsInput) (*s3.ListBucketsOutput, error)
 	DeleteBucket(*s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error)
 	ListObjects(*s3.ListObjectsInput) (*s3.ListObjectsOutput, error)
+	GetObject(*s3.GetObjectInput) (*s3.GetObjectOutput, error)
 	DeleteObjects(*s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error)
 
 	//iam
@@ -285,6 +286,10 @@ func (c *AwsClient) ListObjects(input *s3.ListObjectsInput) (*s3.ListObjectsOutp
 	return c.s3Client.ListObjects(context.TODO(), input)
 }
 
+func (c *AwsClient) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	return c.s3Client.GetObject(context.TODO(), input)
+}
+
 func (c *AwsClient) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
 	return c.s3Client.DeleteObjects(context.TODO(), input)
 }
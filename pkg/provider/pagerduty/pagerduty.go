@@ -31,6 +31,7 @@ type pdClientInterface interface {
 }
 
 type client struct {
+	ctx        context.Context
 	pdclient   pdClientInterface
 	baseDomain string
 	teamIds    []string
@@ -39,7 +40,13 @@ type client struct {
 }
 
 func NewClient() *client {
-	return &client{}
+	return &client{ctx: context.TODO()}
+}
+
+// WithContext sets the context of all requests to PagerDuty
+func (c *client) WithContext(ctx context.Context) *client {
+	c.ctx = ctx
+	return c
 }
 
 func (c *client) WithBaseDomain(baseDomain string) *client {
@@ -86,7 +93,7 @@ func (c *client) buildClient() error {
 
 func (c *client) GetPDServiceIDs() ([]string, error) {
 	// TODO : do we need this to be an exposed function or could we do this when we build the client?
-	lsResponse, err := c.pdclient.ListServicesWithContext(context.TODO(), pd.ListServiceOptions{Query: c.baseDomain, TeamIDs: c.teamIds})
+	lsResponse, err := c.pdclient.ListServicesWithContext(c.ctx, pd.ListServiceOptions{Query: c.baseDomain, TeamIDs: c.teamIds})
 	if err != nil {
 		return []string{}, fmt.Errorf("failed to ListServicesWithContext: %w", err)
 	}
@@ -107,7 +114,7 @@ func (c *client) GetFiringAlertsForCluster(pdServiceIDs []string) (map[string][]
 	for _, pdServiceID := range pdServiceIDs {
 		for {
 			listIncidentsResponse, err := c.pdclient.ListIncidentsWithContext(
-				context.TODO(),
+				c.ctx,
 				pd.ListIncidentsOptions{
 					ServiceIDs: []string{pdServiceID},
 					Statuses:   []string{"triggered", "acknowledged"},
@@ -136,13 +143,12 @@ func (c *client) GetHistoricalAlertsForCluster(pdServiceIDs []string) (map[strin
 	var currentOffset uint
 	var limit uint = 100
 	var incidents []pd.Incident
-	var ctx = context.TODO()
 	incidentMap := map[string][]*IncidentOccurrenceTracker{}
 
 	for _, pdServiceID := range pdServiceIDs {
 		for currentOffset = 0; true; currentOffset += limit {
 			liResponse, err := c.pdclient.ListIncidentsWithContext(
-				ctx,
+				c.ctx,
 				pd.ListIncidentsOptions{
 					ServiceIDs: []string{pdServiceID},
 					Statuses:   []string{"resolved", "triggered", "acknowledged"},
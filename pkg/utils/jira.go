@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -35,6 +36,11 @@ func GetJiraClient(jiratoken string) (*jira.Client, error) {
 }
 
 func GetJiraIssuesForCluster(clusterID string, externalClusterID string, jiratoken string) ([]jira.Issue, error) {
+	return GetJiraIssuesForClusterWithContext(context.Background(), clusterID, externalClusterID, jiratoken)
+}
+
+// GetJiraIssuesForClusterWithContext is GetJiraIssuesForCluster, cancelling the search once ctx is done
+func GetJiraIssuesForClusterWithContext(ctx context.Context, clusterID string, externalClusterID string, jiratoken string) ([]jira.Issue, error) {
 	jiraClient, err := GetJiraClient(jiratoken)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to jira: %v", err)
@@ -48,7 +54,7 @@ func GetJiraIssuesForCluster(clusterID string, externalClusterID string, jiratok
 		clusterID,
 	)
 
-	issues, _, err := jiraClient.Issue.Search(jql, nil)
+	issues, _, err := jiraClient.Issue.SearchWithContext(ctx, jql, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search for jira issues: %w\n", err)
 	}
@@ -57,6 +63,11 @@ func GetJiraIssuesForCluster(clusterID string, externalClusterID string, jiratok
 }
 
 func GetJiraSupportExceptionsForOrg(organizationID string, jiratoken string) ([]jira.Issue, error) {
+	return GetJiraSupportExceptionsForOrgWithContext(context.Background(), organizationID, jiratoken)
+}
+
+// GetJiraSupportExceptionsForOrgWithContext is GetJiraSupportExceptionsForOrg, cancelling the search once ctx is done
+func GetJiraSupportExceptionsForOrgWithContext(ctx context.Context, organizationID string, jiratoken string) ([]jira.Issue, error) {
 	jiraClient, err := GetJiraClient(jiratoken)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to jira: %v", err)
@@ -68,7 +79,7 @@ func GetJiraSupportExceptionsForOrg(organizationID string, jiratoken string) ([]
 		organizationID,
 	)
 
-	issues, _, err := jiraClient.Issue.Search(jql, nil)
+	issues, _, err := jiraClient.Issue.SearchWithContext(ctx, jql, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search for jira issues %w", err)
 	}
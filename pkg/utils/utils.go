@@ -2,11 +2,13 @@ package utils
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
 	"runtime/debug"
 	"strings"
+	"time"
 
 	sdk "github.com/openshift-online/ocm-sdk-go"
 	amv1 "github.com/openshift-online/ocm-sdk-go/accountsmgmt/v1"
@@ -139,12 +141,17 @@ func GetCluster(connection *sdk.Connection, key string) (cluster *cmv1.Cluster,
 }
 
 func GetClusterLimitedSupportReasons(connection *sdk.Connection, clusterID string) ([]*cmv1.LimitedSupportReason, error) {
+	return GetClusterLimitedSupportReasonsWithContext(context.Background(), connection, clusterID)
+}
+
+// GetClusterLimitedSupportReasonsWithContext is GetClusterLimitedSupportReasons, cancelling the request once ctx is done
+func GetClusterLimitedSupportReasonsWithContext(ctx context.Context, connection *sdk.Connection, clusterID string) ([]*cmv1.LimitedSupportReason, error) {
 	limitedSupportReasons, err := connection.ClustersMgmt().V1().
 		Clusters().
 		Cluster(clusterID).
 		LimitedSupportReasons().
 		List().
-		Send()
+		SendContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get limited Support Reasons: %s", err)
 	}
@@ -286,6 +293,19 @@ func StreamRead(stream genericclioptions.IOStreams, delim byte) (string, error)
 	return reader.ReadString(delim)
 }
 
+// ParseTime parses either a duration relative to now or an absolute time
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration (eg. 2h) nor a date (eg. 2024-01-31 or 2024-01-31T12:00:00Z)", value)
+}
+
 var ReadBuildInfo = debug.ReadBuildInfo
 
 func GetDependencyVersion(dependencyPath string) (string, error) {